@@ -0,0 +1,10 @@
+package quic
+
+import "github.com/lucas-clemente/quic-go/internal/handshake"
+
+// NewInMemoryAntiReplayStore creates an AntiReplayStore that keeps the IDs of the session tickets used for 0-RTT in memory.
+// It remembers up to maxEntries session tickets. Once that number is reached, 0-RTT is rejected until entries expire.
+// It can't be shared by multiple servers running in different processes.
+func NewInMemoryAntiReplayStore(maxEntries int) AntiReplayStore {
+	return handshake.NewInMemoryAntiReplayStore(maxEntries)
+}
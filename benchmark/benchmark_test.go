@@ -8,6 +8,7 @@ import (
 	"io"
 	"math/rand"
 	"net"
+	"os"
 
 	quic "github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -31,62 +32,78 @@ var _ = Describe("Benchmarks", func() {
 				rand.Read(data) // no need to check for an error. math.Rand.Read never errors
 			})
 
-			Measure("transferring a file", func(b Benchmarker) {
-				var ln quic.Listener
-				serverAddr := make(chan net.Addr)
-				handshakeChan := make(chan struct{})
-				// start the server
-				go func() {
-					defer GinkgoRecover()
-					var err error
-					tlsConf := testdata.GetTLSConfig()
-					tlsConf.NextProtos = []string{"benchmark"}
-					ln, err = quic.ListenAddr(
-						"localhost:0",
-						tlsConf,
+			// batched sends and receives can be disabled using environment variables (on Linux)
+			for _, m := range []struct {
+				name string
+				env  map[string]string
+			}{
+				{name: "with sendmmsg and GSO"},
+				{name: "with sendmmsg, without GSO", env: map[string]string{"QUIC_GO_DISABLE_GSO": "true"}},
+				{name: "without batching", env: map[string]string{"QUIC_GO_DISABLE_SENDMMSG": "true", "QUIC_GO_DISABLE_RECVMMSG": "true"}},
+			} {
+				env := m.env
+
+				Measure("transferring a file, "+m.name, func(b Benchmarker) {
+					for k, v := range env {
+						Expect(os.Setenv(k, v)).To(Succeed())
+						defer os.Unsetenv(k)
+					}
+					var ln quic.Listener
+					serverAddr := make(chan net.Addr)
+					handshakeChan := make(chan struct{})
+					// start the server
+					go func() {
+						defer GinkgoRecover()
+						var err error
+						tlsConf := testdata.GetTLSConfig()
+						tlsConf.NextProtos = []string{"benchmark"}
+						ln, err = quic.ListenAddr(
+							"localhost:0",
+							tlsConf,
+							&quic.Config{Versions: []protocol.VersionNumber{version}},
+						)
+						Expect(err).ToNot(HaveOccurred())
+						serverAddr <- ln.Addr()
+						sess, err := ln.Accept(context.Background())
+						Expect(err).ToNot(HaveOccurred())
+						// wait for the client to complete the handshake before sending the data
+						// this should not be necessary, but due to timing issues on the CIs, this is necessary to avoid sending too many undecryptable packets
+						<-handshakeChan
+						str, err := sess.OpenStream()
+						Expect(err).ToNot(HaveOccurred())
+						_, err = str.Write(data)
+						Expect(err).ToNot(HaveOccurred())
+						err = str.Close()
+						Expect(err).ToNot(HaveOccurred())
+					}()
+
+					// start the client
+					addr := <-serverAddr
+					sess, err := quic.DialAddr(
+						addr.String(),
+						&tls.Config{InsecureSkipVerify: true, NextProtos: []string{"benchmark"}},
 						&quic.Config{Versions: []protocol.VersionNumber{version}},
 					)
 					Expect(err).ToNot(HaveOccurred())
-					serverAddr <- ln.Addr()
-					sess, err := ln.Accept(context.Background())
-					Expect(err).ToNot(HaveOccurred())
-					// wait for the client to complete the handshake before sending the data
-					// this should not be necessary, but due to timing issues on the CIs, this is necessary to avoid sending too many undecryptable packets
-					<-handshakeChan
-					str, err := sess.OpenStream()
+					close(handshakeChan)
+					str, err := sess.AcceptStream(context.Background())
 					Expect(err).ToNot(HaveOccurred())
-					_, err = str.Write(data)
-					Expect(err).ToNot(HaveOccurred())
-					err = str.Close()
-					Expect(err).ToNot(HaveOccurred())
-				}()
-
-				// start the client
-				addr := <-serverAddr
-				sess, err := quic.DialAddr(
-					addr.String(),
-					&tls.Config{InsecureSkipVerify: true, NextProtos: []string{"benchmark"}},
-					&quic.Config{Versions: []protocol.VersionNumber{version}},
-				)
-				Expect(err).ToNot(HaveOccurred())
-				close(handshakeChan)
-				str, err := sess.AcceptStream(context.Background())
-				Expect(err).ToNot(HaveOccurred())
 
-				buf := &bytes.Buffer{}
-				// measure the time it takes to download the dataLen bytes
-				// note we're measuring the time for the transfer, i.e. excluding the handshake
-				runtime := b.Time("transfer time", func() {
-					_, err := io.Copy(buf, str)
-					Expect(err).NotTo(HaveOccurred())
-				})
-				Expect(buf.Bytes()).To(Equal(data))
+					buf := &bytes.Buffer{}
+					// measure the time it takes to download the dataLen bytes
+					// note we're measuring the time for the transfer, i.e. excluding the handshake
+					runtime := b.Time("transfer time", func() {
+						_, err := io.Copy(buf, str)
+						Expect(err).NotTo(HaveOccurred())
+					})
+					Expect(buf.Bytes()).To(Equal(data))
 
-				b.RecordValue("transfer rate [MB/s]", float64(dataLen)/1e6/runtime.Seconds())
+					b.RecordValue("transfer rate [MB/s]", float64(dataLen)/1e6/runtime.Seconds())
 
-				ln.Close()
-				sess.CloseWithError(0, "")
-			}, 3)
+					ln.Close()
+					sess.CloseWithError(0, "")
+				}, 3)
+			}
 		})
 	}
 })
@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -56,13 +57,17 @@ func (b *packetBuffer) Len() protocol.ByteCount {
 }
 
 func (b *packetBuffer) putBack() {
-	if cap(b.Data) != int(protocol.MaxReceivePacketSize) {
+	switch cap(b.Data) {
+	case int(protocol.MaxReceivePacketSize):
+		bufferPool.Put(b)
+	case int(protocol.MaxJumboPacketSize):
+		largeBufferPool.Put(b)
+	default:
 		panic("putPacketBuffer called with packet of wrong size!")
 	}
-	bufferPool.Put(b)
 }
 
-var bufferPool sync.Pool
+var bufferPool, largeBufferPool sync.Pool
 
 func getPacketBuffer() *packetBuffer {
 	buf := bufferPool.Get().(*packetBuffer)
@@ -71,10 +76,31 @@ func getPacketBuffer() *packetBuffer {
 	return buf
 }
 
+// getPacketBufferWithSize gets a packet buffer that can hold a packet of size bytes.
+// Packets larger than protocol.MaxReceivePacketSize are only sent and received
+// once a larger MTU was discovered or configured, so large buffers are rarely used.
+func getPacketBufferWithSize(size protocol.ByteCount) *packetBuffer {
+	if size <= protocol.MaxReceivePacketSize {
+		return getPacketBuffer()
+	}
+	if size > protocol.MaxJumboPacketSize {
+		panic(fmt.Sprintf("no packet buffer for packets of %d bytes", size))
+	}
+	buf := largeBufferPool.Get().(*packetBuffer)
+	buf.refCount = 1
+	buf.Data = buf.Data[:0]
+	return buf
+}
+
 func init() {
 	bufferPool.New = func() interface{} {
 		return &packetBuffer{
 			Data: make([]byte, 0, protocol.MaxReceivePacketSize),
 		}
 	}
+	largeBufferPool.New = func() interface{} {
+		return &packetBuffer{
+			Data: make([]byte, 0, protocol.MaxJumboPacketSize),
+		}
+	}
 }
@@ -13,6 +13,16 @@ var _ = Describe("Buffer Pool", func() {
 		Expect(buf.Data).To(HaveCap(int(protocol.MaxReceivePacketSize)))
 	})
 
+	It("returns large buffers for large packets", func() {
+		buf := getPacketBufferWithSize(protocol.MaxReceivePacketSize)
+		Expect(buf.Data).To(HaveCap(int(protocol.MaxReceivePacketSize)))
+		buf.Release()
+		buf = getPacketBufferWithSize(protocol.MaxReceivePacketSize + 1)
+		Expect(buf.Data).To(HaveCap(int(protocol.MaxJumboPacketSize)))
+		buf.Release()
+		Expect(func() { getPacketBufferWithSize(protocol.MaxJumboPacketSize + 1) }).To(Panic())
+	})
+
 	It("releases buffers", func() {
 		buf := getPacketBuffer()
 		buf.Release()
@@ -167,7 +167,7 @@ func dialContext(
 		return nil, errors.New("quic: tls.Config not set")
 	}
 	config = populateClientConfig(config, createdPacketConn)
-	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength, config.StatelessResetKey)
+	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength, protocol.ByteCount(config.MaxUDPPayloadSize), config.StatelessResetKey)
 	if err != nil {
 		return nil, err
 	}
@@ -229,9 +229,14 @@ func newClient(
 		return nil, err
 	}
 	c := &client{
-		srcConnID:         srcConnID,
-		destConnID:        destConnID,
-		conn:              &conn{pconn: pconn, currentAddr: remoteAddr},
+		srcConnID:  srcConnID,
+		destConnID: destConnID,
+		conn: &conn{
+			pconn:       pconn,
+			currentAddr: remoteAddr,
+			supportsDF:  !config.DisablePathMTUDiscovery && setDF(pconn),
+			supportsECN: setReceiveECN(pconn),
+		},
 		createdPacketConn: createdPacketConn,
 		use0RTT:           use0RTT,
 		tlsConf:           tlsConf,
@@ -13,11 +13,18 @@ func (c *Config) Clone() *Config {
 func populateServerConfig(config *Config) *Config {
 	config = populateConfig(config)
 	if config.ConnectionIDLength == 0 {
-		config.ConnectionIDLength = protocol.DefaultConnectionIDLength
+		if config.ConnectionIDGenerator != nil {
+			config.ConnectionIDLength = config.ConnectionIDGenerator.ConnectionIDLen()
+		} else {
+			config.ConnectionIDLength = protocol.DefaultConnectionIDLength
+		}
 	}
 	if config.AcceptToken == nil {
 		config.AcceptToken = defaultAcceptToken
 	}
+	if config.AntiReplayStore == nil {
+		config.AntiReplayStore = NewInMemoryAntiReplayStore(protocol.DefaultMaxAntiReplayEntries)
+	}
 	return config
 }
 
@@ -67,20 +74,45 @@ func populateConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	maxUDPPayloadSize := config.MaxUDPPayloadSize
+	if maxUDPPayloadSize == 0 {
+		maxUDPPayloadSize = uint64(protocol.MaxReceivePacketSize)
+	} else if maxUDPPayloadSize < protocol.MinInitialPacketSize {
+		maxUDPPayloadSize = protocol.MinInitialPacketSize
+	} else if maxUDPPayloadSize > uint64(protocol.MaxJumboPacketSize) {
+		maxUDPPayloadSize = uint64(protocol.MaxJumboPacketSize)
+	}
 
 	return &Config{
 		Versions:                              versions,
 		HandshakeTimeout:                      handshakeTimeout,
 		MaxIdleTimeout:                        idleTimeout,
+		Allow0RTT:                             config.Allow0RTT,
+		AntiReplayStore:                       config.AntiReplayStore,
 		AcceptToken:                           config.AcceptToken,
+		TokenKeys:                             config.TokenKeys,
+		GetTokenData:                          config.GetTokenData,
+		MaxSessions:                           config.MaxSessions,
+		MaxHandshakes:                         config.MaxHandshakes,
+		RetryThreshold:                        config.RetryThreshold,
+		HandshakeRateLimit:                    config.HandshakeRateLimit,
 		KeepAlive:                             config.KeepAlive,
+		KeyUpdateInterval:                     config.KeyUpdateInterval,
+		EnableDatagrams:                       config.EnableDatagrams,
+		DisablePathMTUDiscovery:               config.DisablePathMTUDiscovery,
+		MaxUDPPayloadSize:                     maxUDPPayloadSize,
+		GreaseQUICBit:                         config.GreaseQUICBit,
+		PreferredAddress:                      config.PreferredAddress,
 		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
 		MaxIncomingStreams:                    maxIncomingStreams,
 		MaxIncomingUniStreams:                 maxIncomingUniStreams,
 		ConnectionIDLength:                    config.ConnectionIDLength,
+		ConnectionIDGenerator:                 config.ConnectionIDGenerator,
 		StatelessResetKey:                     config.StatelessResetKey,
 		TokenStore:                            config.TokenStore,
+		CongestionControl:                     config.CongestionControl,
+		StreamScheduler:                       config.StreamScheduler,
 		QuicTracer:                            config.QuicTracer,
 		Tracer:                                config.Tracer,
 	}
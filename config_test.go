@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/quictrace"
@@ -28,18 +29,30 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "AcceptToken", "GetLogWriter":
+			case "AcceptToken", "Allow0RTT", "TokenKeys", "GetTokenData", "GetLogWriter", "CongestionControl", "StreamScheduler":
 				// Can't compare functions.
 			case "Versions":
 				f.Set(reflect.ValueOf([]VersionNumber{1, 2, 3}))
 			case "ConnectionIDLength":
 				f.Set(reflect.ValueOf(8))
+			case "ConnectionIDGenerator":
+				f.Set(reflect.ValueOf(NewMockConnectionIDGenerator(mockCtrl)))
 			case "HandshakeTimeout":
 				f.Set(reflect.ValueOf(time.Second))
 			case "MaxIdleTimeout":
 				f.Set(reflect.ValueOf(time.Hour))
+			case "MaxSessions":
+				f.Set(reflect.ValueOf(14))
+			case "MaxHandshakes":
+				f.Set(reflect.ValueOf(15))
+			case "RetryThreshold":
+				f.Set(reflect.ValueOf(16))
+			case "HandshakeRateLimit":
+				f.Set(reflect.ValueOf(&HandshakeRateLimit{Rate: 10, Burst: 20}))
 			case "TokenStore":
 				f.Set(reflect.ValueOf(NewLRUTokenStore(2, 3)))
+			case "AntiReplayStore":
+				f.Set(reflect.ValueOf(NewInMemoryAntiReplayStore(14)))
 			case "MaxReceiveStreamFlowControlWindow":
 				f.Set(reflect.ValueOf(uint64(9)))
 			case "MaxReceiveConnectionFlowControlWindow":
@@ -52,6 +65,18 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf([]byte{1, 2, 3, 4}))
 			case "KeepAlive":
 				f.Set(reflect.ValueOf(true))
+			case "KeyUpdateInterval":
+				f.Set(reflect.ValueOf(uint64(13)))
+			case "EnableDatagrams":
+				f.Set(reflect.ValueOf(true))
+			case "DisablePathMTUDiscovery":
+				f.Set(reflect.ValueOf(true))
+			case "MaxUDPPayloadSize":
+				f.Set(reflect.ValueOf(uint64(4000)))
+			case "GreaseQUICBit":
+				f.Set(reflect.ValueOf(true))
+			case "PreferredAddress":
+				f.Set(reflect.ValueOf(&PreferredAddress{IPv4: &net.UDPAddr{IP: net.IPv4(192, 168, 1, 1), Port: 443}}))
 			case "QuicTracer":
 				f.Set(reflect.ValueOf(quictrace.NewTracer()))
 			case "Tracer":
@@ -103,6 +128,32 @@ var _ = Describe("Config", func() {
 			Expect(calledAcceptToken).To(BeTrue())
 		})
 
+		It("populates the congestion control", func() {
+			var called bool
+			c1 := &Config{
+				CongestionControl: func(rttStats *congestion.RTTStats) congestion.SendAlgorithmWithDebugInfos {
+					called = true
+					return congestion.NewCubicSender(congestion.DefaultClock{}, rttStats, false)
+				},
+			}
+			c2 := populateConfig(c1)
+			Expect(c2.CongestionControl(&congestion.RTTStats{})).ToNot(BeNil())
+			Expect(called).To(BeTrue())
+		})
+
+		It("populates the stream scheduler", func() {
+			var called bool
+			c1 := &Config{
+				StreamScheduler: func() StreamScheduler {
+					called = true
+					return NewWeightedFairScheduler()
+				},
+			}
+			c2 := populateConfig(c1)
+			Expect(c2.StreamScheduler()).ToNot(BeNil())
+			Expect(called).To(BeTrue())
+		})
+
 		It("copies non-function fields", func() {
 			c := configWithNonZeroNonFunctionFields()
 			Expect(populateConfig(c)).To(Equal(c))
@@ -116,12 +167,26 @@ var _ = Describe("Config", func() {
 			Expect(c.MaxReceiveConnectionFlowControlWindow).To(BeEquivalentTo(protocol.DefaultMaxReceiveConnectionFlowControlWindow))
 			Expect(c.MaxIncomingStreams).To(Equal(protocol.DefaultMaxIncomingStreams))
 			Expect(c.MaxIncomingUniStreams).To(Equal(protocol.DefaultMaxIncomingUniStreams))
+			Expect(c.MaxUDPPayloadSize).To(BeEquivalentTo(protocol.MaxReceivePacketSize))
+		})
+
+		It("limits the max UDP payload size", func() {
+			Expect(populateConfig(&Config{MaxUDPPayloadSize: 1000}).MaxUDPPayloadSize).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+			Expect(populateConfig(&Config{MaxUDPPayloadSize: 20000}).MaxUDPPayloadSize).To(BeEquivalentTo(protocol.MaxJumboPacketSize))
 		})
 
 		It("populates empty fields with default values, for the server", func() {
 			c := populateServerConfig(&Config{})
 			Expect(c.ConnectionIDLength).To(Equal(protocol.DefaultConnectionIDLength))
 			Expect(c.AcceptToken).ToNot(BeNil())
+			Expect(c.AntiReplayStore).ToNot(BeNil())
+		})
+
+		It("uses the length of the ConnectionIDGenerator, for the server", func() {
+			gen := NewMockConnectionIDGenerator(mockCtrl)
+			gen.EXPECT().ConnectionIDLen().Return(10)
+			c := populateServerConfig(&Config{ConnectionIDGenerator: gen})
+			Expect(c.ConnectionIDLength).To(Equal(10))
 		})
 
 		It("sets a default connection ID length if we didn't create the conn, for the client", func() {
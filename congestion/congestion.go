@@ -0,0 +1,83 @@
+// Package congestion defines the congestion control interface for quic-go.
+// It allows applications to provide their own congestion controller,
+// using the same inputs as the built-in Cubic / NewReno implementation.
+// This package should not be considered stable
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+type (
+	// A SendAlgorithm performs congestion control.
+	// Its methods are called by the connection's sent packet handler, and never concurrently.
+	SendAlgorithm = congestion.SendAlgorithm
+	// A SendAlgorithmWithDebugInfos is a SendAlgorithm that exposes some debug infos.
+	// This is the interface that needs to be implemented to be used with quic-go.
+	SendAlgorithmWithDebugInfos = congestion.SendAlgorithmWithDebugInfos
+
+	// RTTStats are the RTT statistics of a connection.
+	// They are updated by quic-go when ACKs are received.
+	// Congestion controllers should only read them.
+	RTTStats = congestion.RTTStats
+
+	// Bandwidth of a connection, in bits/s
+	Bandwidth = congestion.Bandwidth
+
+	// A Pacer implements a token bucket pacing algorithm.
+	Pacer = congestion.Pacer
+
+	// A Clock returns the current time
+	Clock = congestion.Clock
+	// DefaultClock implements the Clock interface using the Go stdlib clock.
+	DefaultClock = congestion.DefaultClock
+
+	// A ByteCount in QUIC
+	ByteCount = protocol.ByteCount
+	// A PacketNumber in QUIC
+	PacketNumber = protocol.PacketNumber
+)
+
+const (
+	// BitsPerSecond is 1 bit per second
+	BitsPerSecond = congestion.BitsPerSecond
+	// BytesPerSecond is 1 byte per second
+	BytesPerSecond = congestion.BytesPerSecond
+)
+
+// BandwidthFromDelta calculates the bandwidth from a number of bytes and a time delta
+func BandwidthFromDelta(bytes ByteCount, delta time.Duration) Bandwidth {
+	return congestion.BandwidthFromDelta(bytes, delta)
+}
+
+// NewPacer creates a new Pacer.
+// getBandwidth returns the current bandwidth estimate of the congestion controller.
+func NewPacer(getBandwidth func() Bandwidth) *Pacer {
+	return congestion.NewPacer(getBandwidth)
+}
+
+// NewCubicSender creates the congestion controller that quic-go uses by default.
+// If reno is set, it uses NewReno instead of Cubic.
+func NewCubicSender(clock Clock, rttStats *RTTStats, reno bool) SendAlgorithmWithDebugInfos {
+	return congestion.NewCubicSender(clock, rttStats, reno)
+}
+
+// NewBBRSender creates a BBR (version 1) congestion controller.
+// It can be used for a connection by setting the Config.CongestionControl callback:
+//
+//	config.CongestionControl = func(rttStats *congestion.RTTStats) congestion.SendAlgorithmWithDebugInfos {
+//		return congestion.NewBBRSender(congestion.DefaultClock{}, rttStats)
+//	}
+func NewBBRSender(clock Clock, rttStats *RTTStats) SendAlgorithmWithDebugInfos {
+	return congestion.NewBBRSender(clock, rttStats)
+}
+
+// NewLEDBATSender creates a LEDBAT (RFC 6817) congestion controller.
+// LEDBAT is a "less than best effort" congestion controller: It backs off as soon as it detects
+// a growing queuing delay, yielding to other flows. This makes it useful for background transfers.
+func NewLEDBATSender(clock Clock, rttStats *RTTStats) SendAlgorithmWithDebugInfos {
+	return congestion.NewLEDBATSender(clock, rttStats)
+}
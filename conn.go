@@ -3,15 +3,68 @@ package quic
 import (
 	"net"
 	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
+// The ECN bits are the two least significant bits of the TOS / traffic class field.
+const ecnMask = 0x3
+
+// An ecnCapableConn is a connection that allows reading and writing control messages.
+// These are used to receive and set the ECN bits.
+type ecnCapableConn interface {
+	net.PacketConn
+	ReadMsgUDP(b, oob []byte) (n, oobn, flags int, addr *net.UDPAddr, err error)
+	WriteMsgUDP(b, oob []byte, addr *net.UDPAddr) (n, oobn int, err error)
+}
+
+// A batchWriter writes multiple packets to the same address, using as few syscalls as possible.
+type batchWriter interface {
+	// WriteBatch writes the packets.
+	// If an error occurs, it returns the number of packets that were written before the error.
+	WriteBatch(packets [][]byte, addr net.Addr, ecn protocol.ECN) (int, error)
+}
+
+// The packetWriter is the batchWriter used when batching is not available.
+// It writes one packet per syscall.
+type packetWriter struct {
+	conn net.PacketConn
+}
+
+func (w *packetWriter) WriteBatch(packets [][]byte, addr net.Addr, ecn protocol.ECN) (int, error) {
+	for i, p := range packets {
+		if err := writePacket(w.conn, p, addr, ecn); err != nil {
+			return i, err
+		}
+	}
+	return len(packets), nil
+}
+
+// A batchReader reads multiple packets, using as few syscalls as possible.
+type batchReader interface {
+	// ReadBatch blocks until at least one packet was received.
+	// The returned slice is only valid until the next call to ReadBatch,
+	// but the packet buffers are owned by the caller.
+	ReadBatch() ([]receivedPacket, error)
+}
+
 type connection interface {
-	Write([]byte) error
+	Write([]byte, protocol.ECN) error
+	// WritePackets writes multiple packets to the current remote address.
+	// If an error occurs, it returns the number of packets that were written before the error.
+	WritePackets([][]byte, protocol.ECN) (int, error)
+	WriteTo([]byte, net.Addr, protocol.ECN) error
 	Read([]byte) (int, net.Addr, error)
 	Close() error
 	LocalAddr() net.Addr
 	RemoteAddr() net.Addr
 	SetCurrentRemoteAddr(net.Addr)
+	SetPacketConn(net.PacketConn)
+	// SupportsDF says if the DF bit is set on packets sent on this connection.
+	SupportsDF() bool
+	// SupportsECN says if the ECN bits can be set on packets sent on this connection,
+	// and read on packets received.
+	SupportsECN() bool
 }
 
 type conn struct {
@@ -19,17 +72,67 @@ type conn struct {
 
 	pconn       net.PacketConn
 	currentAddr net.Addr
+	supportsDF  bool
+	supportsECN bool
+	// created on first use, and reset when the packet conn is replaced
+	batchWriter batchWriter
 }
 
 var _ connection = &conn{}
 
-func (c *conn) Write(p []byte) error {
-	_, err := c.pconn.WriteTo(p, c.currentAddr)
+func (c *conn) Write(p []byte, ecn protocol.ECN) error {
+	c.mutex.RLock()
+	pconn := c.pconn
+	addr := c.currentAddr
+	c.mutex.RUnlock()
+	return writePacket(pconn, p, addr, ecn)
+}
+
+func (c *conn) WritePackets(packets [][]byte, ecn protocol.ECN) (int, error) {
+	c.mutex.RLock()
+	w := c.batchWriter
+	addr := c.currentAddr
+	c.mutex.RUnlock()
+	if w == nil {
+		c.mutex.Lock()
+		if c.batchWriter == nil {
+			c.batchWriter = newBatchWriter(c.pconn)
+		}
+		w = c.batchWriter
+		c.mutex.Unlock()
+	}
+	return w.WriteBatch(packets, addr, ecn)
+}
+
+// WriteTo writes a packet to the given address, which may differ from the current remote address.
+// It is used to probe a new path.
+func (c *conn) WriteTo(p []byte, addr net.Addr, ecn protocol.ECN) error {
+	c.mutex.RLock()
+	pconn := c.pconn
+	c.mutex.RUnlock()
+	return writePacket(pconn, p, addr, ecn)
+}
+
+// writePacket writes a packet, setting the ECN bits if the connection allows it.
+func writePacket(c net.PacketConn, p []byte, addr net.Addr, ecn protocol.ECN) error {
+	if ecn != protocol.ECNNon {
+		ecnConn, ok := c.(ecnCapableConn)
+		udpAddr, isUDPAddr := addr.(*net.UDPAddr)
+		if ok && isUDPAddr {
+			oob := appendECN(make([]byte, 0, ecnOOBSize), ecn, udpAddr.IP.To4() != nil)
+			_, _, err := ecnConn.WriteMsgUDP(p, oob, udpAddr)
+			return err
+		}
+	}
+	_, err := c.WriteTo(p, addr)
 	return err
 }
 
 func (c *conn) Read(p []byte) (int, net.Addr, error) {
-	return c.pconn.ReadFrom(p)
+	c.mutex.RLock()
+	pconn := c.pconn
+	c.mutex.RUnlock()
+	return pconn.ReadFrom(p)
 }
 
 func (c *conn) SetCurrentRemoteAddr(addr net.Addr) {
@@ -38,8 +141,39 @@ func (c *conn) SetCurrentRemoteAddr(addr net.Addr) {
 	c.mutex.Unlock()
 }
 
+// SetPacketConn replaces the underlying net.PacketConn.
+// It is used when the connection is migrated to a new local address.
+// If the DF bit was set on the old connection, it is also set on the new connection.
+func (c *conn) SetPacketConn(pconn net.PacketConn) {
+	c.mutex.Lock()
+	c.pconn = pconn
+	c.batchWriter = nil
+	if c.supportsDF {
+		c.supportsDF = setDF(pconn)
+	}
+	c.supportsECN = setReceiveECN(pconn)
+	c.mutex.Unlock()
+}
+
+func (c *conn) SupportsDF() bool {
+	c.mutex.RLock()
+	supportsDF := c.supportsDF
+	c.mutex.RUnlock()
+	return supportsDF
+}
+
+func (c *conn) SupportsECN() bool {
+	c.mutex.RLock()
+	supportsECN := c.supportsECN
+	c.mutex.RUnlock()
+	return supportsECN
+}
+
 func (c *conn) LocalAddr() net.Addr {
-	return c.pconn.LocalAddr()
+	c.mutex.RLock()
+	addr := c.pconn.LocalAddr()
+	c.mutex.RUnlock()
+	return addr
 }
 
 func (c *conn) RemoteAddr() net.Addr {
@@ -50,5 +184,8 @@ func (c *conn) RemoteAddr() net.Addr {
 }
 
 func (c *conn) Close() error {
-	return c.pconn.Close()
+	c.mutex.RLock()
+	pconn := c.pconn
+	c.mutex.RUnlock()
+	return pconn.Close()
 }
@@ -16,6 +16,7 @@ type connIDGenerator struct {
 	activeSrcConnIDs        map[uint64]protocol.ConnectionID
 	initialClientDestConnID protocol.ConnectionID
 
+	generateConnectionID   func() (protocol.ConnectionID, error)
 	addConnectionID        func(protocol.ConnectionID)
 	getStatelessResetToken func(protocol.ConnectionID) [16]byte
 	removeConnectionID     func(protocol.ConnectionID)
@@ -27,6 +28,7 @@ type connIDGenerator struct {
 func newConnIDGenerator(
 	initialConnectionID protocol.ConnectionID,
 	initialClientDestConnID protocol.ConnectionID, // nil for the client
+	generateConnectionID func() (protocol.ConnectionID, error),
 	addConnectionID func(protocol.ConnectionID),
 	getStatelessResetToken func(protocol.ConnectionID) [16]byte,
 	removeConnectionID func(protocol.ConnectionID),
@@ -37,6 +39,7 @@ func newConnIDGenerator(
 	m := &connIDGenerator{
 		connIDLen:              initialConnectionID.Len(),
 		activeSrcConnIDs:       make(map[uint64]protocol.ConnectionID),
+		generateConnectionID:   generateConnectionID,
 		addConnectionID:        addConnectionID,
 		getStatelessResetToken: getStatelessResetToken,
 		removeConnectionID:     removeConnectionID,
@@ -57,9 +60,7 @@ func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
 	// connection IDs the peer will store. This limit includes the connection ID
 	// used during the handshake, and the one sent in the preferred_address
 	// transport parameter.
-	// We currently don't send the preferred_address transport parameter,
-	// so we can issue (limit - 1) connection IDs.
-	for i := uint64(1); i < utils.MinUint64(limit, protocol.MaxIssuedConnectionIDs); i++ {
+	for i := uint64(len(m.activeSrcConnIDs)); i < utils.MinUint64(limit, protocol.MaxIssuedConnectionIDs); i++ {
 		if err := m.issueNewConnID(); err != nil {
 			return err
 		}
@@ -67,6 +68,22 @@ func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
 	return nil
 }
 
+// GenerateForPreferredAddress generates the connection ID sent in the preferred_address transport parameter.
+// It must be called before any other connection ID is issued, since this connection ID uses sequence number 1.
+func (m *connIDGenerator) GenerateForPreferredAddress() (protocol.ConnectionID, [16]byte, error) {
+	if m.highestSeq != 0 {
+		panic("expected preferred_address connection ID to have sequence number 1")
+	}
+	connID, err := m.generateConnectionID()
+	if err != nil {
+		return nil, [16]byte{}, err
+	}
+	m.highestSeq = 1
+	m.activeSrcConnIDs[1] = connID
+	m.addConnectionID(connID)
+	return connID, m.getStatelessResetToken(connID), nil
+}
+
 func (m *connIDGenerator) Retire(seq uint64) error {
 	if seq > m.highestSeq {
 		return qerr.NewError(qerr.ProtocolViolation, fmt.Sprintf("tried to retire connection ID %d. Highest issued: %d", seq, m.highestSeq))
@@ -86,7 +103,7 @@ func (m *connIDGenerator) Retire(seq uint64) error {
 }
 
 func (m *connIDGenerator) issueNewConnID() error {
-	connID, err := protocol.GenerateConnectionID(m.connIDLen)
+	connID, err := m.generateConnectionID()
 	if err != nil {
 		return err
 	}
@@ -108,6 +125,15 @@ func (m *connIDGenerator) SetHandshakeComplete() {
 	}
 }
 
+// ActiveConnectionIDs returns all connection IDs that were issued and not yet retired by the peer.
+func (m *connIDGenerator) ActiveConnectionIDs() []protocol.ConnectionID {
+	connIDs := make([]protocol.ConnectionID, 0, len(m.activeSrcConnIDs))
+	for _, connID := range m.activeSrcConnIDs {
+		connIDs = append(connIDs, connID)
+	}
+	return connIDs
+}
+
 func (m *connIDGenerator) RemoveAll() {
 	if m.initialClientDestConnID != nil {
 		m.removeConnectionID(m.initialClientDestConnID)
@@ -125,3 +151,19 @@ func (m *connIDGenerator) ReplaceWithClosed(handler packetHandler) {
 		m.replaceWithClosed(connID, handler)
 	}
 }
+
+// generateServerConnectionID generates a connection ID for the server.
+// It uses the ConnectionIDGenerator, if one is configured, and random connection IDs otherwise.
+func generateServerConnectionID(gen ConnectionIDGenerator, connIDLen int) (protocol.ConnectionID, error) {
+	if gen == nil {
+		return protocol.GenerateConnectionID(connIDLen)
+	}
+	connID, err := gen.GenerateConnectionID()
+	if err != nil {
+		return nil, err
+	}
+	if len(connID) != connIDLen {
+		return nil, fmt.Errorf("ConnectionIDGenerator generated a connection ID of length %d, expected %d", len(connID), connIDLen)
+	}
+	return protocol.ConnectionID(connID), nil
+}
@@ -33,6 +33,7 @@ var _ = Describe("Connection ID Generator", func() {
 		g = newConnIDGenerator(
 			initialConnID,
 			initialClientDestConnID,
+			func() (protocol.ConnectionID, error) { return protocol.GenerateConnectionID(initialConnID.Len()) },
 			func(c protocol.ConnectionID) { addedConnIDs = append(addedConnIDs, c) },
 			connIDToToken,
 			func(c protocol.ConnectionID) { removedConnIDs = append(removedConnIDs, c) },
@@ -67,6 +68,20 @@ var _ = Describe("Connection ID Generator", func() {
 		Expect(queuedFrames).To(HaveLen(protocol.MaxIssuedConnectionIDs - 1))
 	})
 
+	It("generates the connection ID for the preferred_address", func() {
+		connID, token, err := g.GenerateForPreferredAddress()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID.Len()).To(Equal(7))
+		Expect(token).To(Equal(connIDToToken(connID)))
+		Expect(addedConnIDs).To(Equal([]protocol.ConnectionID{connID}))
+		Expect(queuedFrames).To(BeEmpty())
+		// the preferred_address connection ID counts towards the limit
+		Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(2))
+		Expect(queuedFrames[0].(*wire.NewConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(2))
+		Expect(queuedFrames[1].(*wire.NewConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(3))
+	})
+
 	It("errors if the peers tries to retire a connection ID that wasn't yet issued", func() {
 		Expect(g.Retire(1)).To(MatchError("PROTOCOL_VIOLATION: tried to retire connection ID 1. Highest issued: 0"))
 	})
@@ -109,6 +124,19 @@ var _ = Describe("Connection ID Generator", func() {
 		Expect(retiredConnIDs[0]).To(Equal(initialClientDestConnID))
 	})
 
+	It("returns all active connection IDs", func() {
+		Expect(g.SetMaxActiveConnIDs(5)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(4))
+		Expect(g.Retire(2)).To(Succeed())
+		connIDs := g.ActiveConnectionIDs()
+		Expect(connIDs).To(HaveLen(5)) // initial conn ID, and newly issued ones
+		Expect(connIDs).To(ContainElement(initialConnID))
+		Expect(connIDs).ToNot(ContainElement(queuedFrames[1].(*wire.NewConnectionIDFrame).ConnectionID))
+		for _, f := range queuedFrames[2:] {
+			Expect(connIDs).To(ContainElement(f.(*wire.NewConnectionIDFrame).ConnectionID))
+		}
+	})
+
 	It("removes all connection IDs", func() {
 		Expect(g.SetMaxActiveConnIDs(5)).To(Succeed())
 		Expect(queuedFrames).To(HaveLen(4))
@@ -135,4 +163,27 @@ var _ = Describe("Connection ID Generator", func() {
 			Expect(replacedWithClosed).To(HaveKeyWithValue(string(nf.ConnectionID), sess))
 		}
 	})
+
+	Context("generating server connection IDs", func() {
+		It("generates random connection IDs", func() {
+			c, err := generateServerConnectionID(nil, 9)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Len()).To(Equal(9))
+		})
+
+		It("uses the ConnectionIDGenerator", func() {
+			gen := NewMockConnectionIDGenerator(mockCtrl)
+			gen.EXPECT().GenerateConnectionID().Return([]byte{1, 2, 3, 4, 5}, nil)
+			c, err := generateServerConnectionID(gen, 5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c).To(Equal(protocol.ConnectionID{1, 2, 3, 4, 5}))
+		})
+
+		It("errors when the ConnectionIDGenerator generates a connection ID of the wrong length", func() {
+			gen := NewMockConnectionIDGenerator(mockCtrl)
+			gen.EXPECT().GenerateConnectionID().Return([]byte{1, 2, 3, 4}, nil)
+			_, err := generateServerConnectionID(gen, 5)
+			Expect(err).To(MatchError("ConnectionIDGenerator generated a connection ID of length 4, expected 5"))
+		})
+	})
 })
@@ -53,10 +53,6 @@ func newConnIDManager(
 	}
 }
 
-func (h *connIDManager) AddFromPreferredAddress(connID protocol.ConnectionID, resetToken *[16]byte) error {
-	return h.addConnectionID(1, connID, resetToken)
-}
-
 func (h *connIDManager) Add(f *wire.NewConnectionIDFrame) error {
 	if err := h.add(f); err != nil {
 		return err
@@ -144,6 +140,22 @@ func (h *connIDManager) addConnectionID(seq uint64, connID protocol.ConnectionID
 }
 
 func (h *connIDManager) updateConnectionID() {
+	h.SwitchTo(h.queue.Remove(h.queue.Front()))
+}
+
+// GetUnused removes the next unused connection ID from the queue,
+// such that it can be used when probing a new path.
+// It returns false if no unused connection ID is available.
+func (h *connIDManager) GetUnused() (utils.NewConnectionID, bool) {
+	if h.queue.Len() == 0 {
+		return utils.NewConnectionID{}, false
+	}
+	return h.queue.Remove(h.queue.Front()), true
+}
+
+// SwitchTo retires the active connection ID and starts using the given connection ID.
+// It is called with connection IDs obtained from GetUnused, once the new path has been validated.
+func (h *connIDManager) SwitchTo(c utils.NewConnectionID) {
 	h.queueControlFrame(&wire.RetireConnectionIDFrame{
 		SequenceNumber: h.activeSequenceNumber,
 	})
@@ -152,15 +164,30 @@ func (h *connIDManager) updateConnectionID() {
 		h.retireStatelessResetToken(*h.activeStatelessResetToken)
 	}
 
-	front := h.queue.Remove(h.queue.Front())
-	h.activeSequenceNumber = front.SequenceNumber
-	h.activeConnectionID = front.ConnectionID
-	h.activeStatelessResetToken = front.StatelessResetToken
+	h.activeSequenceNumber = c.SequenceNumber
+	h.activeConnectionID = c.ConnectionID
+	h.activeStatelessResetToken = c.StatelessResetToken
 	h.packetsSinceLastChange = 0
 	h.packetsPerConnectionID = protocol.PacketsPerConnectionID/2 + uint64(h.rand.Int63n(protocol.PacketsPerConnectionID))
 	h.addStatelessResetToken(*h.activeStatelessResetToken)
 }
 
+// RetireUnused retires a connection ID obtained from GetUnused.
+// It is called when validation of the new path fails.
+// The connection ID must not be used on any other path.
+func (h *connIDManager) RetireUnused(c utils.NewConnectionID) {
+	h.queueControlFrame(&wire.RetireConnectionIDFrame{
+		SequenceNumber: c.SequenceNumber,
+	})
+	h.highestRetired = utils.MaxUint64(h.highestRetired, c.SequenceNumber)
+}
+
+// StatelessResetToken returns the stateless reset token of the active connection ID.
+// It returns nil if the peer didn't provide a stateless reset token.
+func (h *connIDManager) StatelessResetToken() *[16]byte {
+	return h.activeStatelessResetToken
+}
+
 func (h *connIDManager) Close() {
 	if h.activeStatelessResetToken != nil {
 		h.removeStatelessResetToken(*h.activeStatelessResetToken)
@@ -312,6 +312,55 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(retiredTokens[0]).To(Equal([16]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
 	})
 
+	Context("using connection IDs for new paths", func() {
+		BeforeEach(func() {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      1,
+				ConnectionID:        protocol.ConnectionID{1, 1, 1, 1},
+				StatelessResetToken: [16]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+			})).To(Succeed())
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
+			frameQueue = nil
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      2,
+				ConnectionID:        protocol.ConnectionID{2, 2, 2, 2},
+				StatelessResetToken: [16]byte{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2},
+			})).To(Succeed())
+		})
+
+		It("returns unused connection IDs", func() {
+			c, ok := m.GetUnused()
+			Expect(ok).To(BeTrue())
+			Expect(c.SequenceNumber).To(BeEquivalentTo(2))
+			Expect(c.ConnectionID).To(Equal(protocol.ConnectionID{2, 2, 2, 2}))
+			Expect(m.queue.Len()).To(BeZero())
+			_, ok = m.GetUnused()
+			Expect(ok).To(BeFalse())
+			// the active connection ID is not changed
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
+			Expect(frameQueue).To(BeEmpty())
+		})
+
+		It("switches to an unused connection ID", func() {
+			c, ok := m.GetUnused()
+			Expect(ok).To(BeTrue())
+			m.SwitchTo(c)
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 2, 2, 2}))
+			Expect(*m.StatelessResetToken()).To(Equal([16]byte{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}))
+			Expect(*tokenAdded).To(Equal([16]byte{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}))
+			Expect(retiredTokens).To(ContainElement([16]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
+			Expect(frameQueue).To(Equal([]wire.Frame{&wire.RetireConnectionIDFrame{SequenceNumber: 1}}))
+		})
+
+		It("retires unused connection IDs", func() {
+			c, ok := m.GetUnused()
+			Expect(ok).To(BeTrue())
+			m.RetireUnused(c)
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
+			Expect(frameQueue).To(Equal([]wire.Frame{&wire.RetireConnectionIDFrame{SequenceNumber: 2}}))
+		})
+	})
+
 	It("removes the currently active stateless reset token when it is closed", func() {
 		m.Close()
 		Expect(retiredTokens).To(BeEmpty())
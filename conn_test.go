@@ -5,6 +5,8 @@ import (
 	"net"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -83,13 +85,40 @@ var _ = Describe("Connection", func() {
 	})
 
 	It("writes", func() {
-		Expect(c.Write([]byte("foobar"))).To(Succeed())
+		Expect(c.Write([]byte("foobar"), protocol.ECNNon)).To(Succeed())
 		var write mockPacketConnWrite
 		Expect(packetConn.dataWritten).To(Receive(&write))
 		Expect(write.to.String()).To(Equal("192.168.100.200:1337"))
 		Expect(write.data).To(Equal([]byte("foobar")))
 	})
 
+	It("writes packets without ECN marking if the connection doesn't support it", func() {
+		Expect(c.Write([]byte("foobar"), protocol.ECT0)).To(Succeed())
+		var write mockPacketConnWrite
+		Expect(packetConn.dataWritten).To(Receive(&write))
+		Expect(write.data).To(Equal([]byte("foobar")))
+	})
+
+	It("writes multiple packets", func() {
+		n, err := c.WritePackets([][]byte{[]byte("foo"), []byte("bar")}, protocol.ECNNon)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(2))
+		var write mockPacketConnWrite
+		Expect(packetConn.dataWritten).To(Receive(&write))
+		Expect(write.to.String()).To(Equal("192.168.100.200:1337"))
+		Expect(write.data).To(Equal([]byte("foo")))
+		Expect(packetConn.dataWritten).To(Receive(&write))
+		Expect(write.data).To(Equal([]byte("bar")))
+	})
+
+	It("writes to a different address", func() {
+		addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7331}
+		Expect(c.WriteTo([]byte("foobar"), addr, protocol.ECNNon)).To(Succeed())
+		var write mockPacketConnWrite
+		Expect(packetConn.dataWritten).To(Receive(&write))
+		Expect(write.to.String()).To(Equal("127.0.0.1:7331"))
+	})
+
 	It("reads", func() {
 		packetConn.dataToRead <- []byte("foo")
 		packetConn.dataReadFrom = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1336}
@@ -123,6 +152,41 @@ var _ = Describe("Connection", func() {
 		Expect(c.RemoteAddr().String()).To(Equal(addr.String()))
 	})
 
+	It("changes the packet conn", func() {
+		newPacketConn := newMockPacketConn()
+		newPacketConn.addr = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 4321}
+		c.SetPacketConn(newPacketConn)
+		Expect(c.LocalAddr()).To(Equal(newPacketConn.addr))
+		Expect(c.Write([]byte("foobar"), protocol.ECNNon)).To(Succeed())
+		Expect(packetConn.dataWritten).To(BeEmpty())
+		var write mockPacketConnWrite
+		Expect(newPacketConn.dataWritten).To(Receive(&write))
+		Expect(write.to.String()).To(Equal("192.168.100.200:1337"))
+		Expect(write.data).To(Equal([]byte("foobar")))
+		n, err := c.WritePackets([][]byte{[]byte("raboof")}, protocol.ECNNon)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(1))
+		Expect(packetConn.dataWritten).To(BeEmpty())
+		Expect(newPacketConn.dataWritten).To(Receive(&write))
+		Expect(write.data).To(Equal([]byte("raboof")))
+	})
+
+	It("only keeps the DF bit if it can be set on the new packet conn", func() {
+		c.supportsDF = true
+		Expect(c.SupportsDF()).To(BeTrue())
+		// the DF bit can't be set on the mockPacketConn
+		c.SetPacketConn(newMockPacketConn())
+		Expect(c.SupportsDF()).To(BeFalse())
+	})
+
+	It("checks ECN support on the new packet conn", func() {
+		c.supportsECN = true
+		Expect(c.SupportsECN()).To(BeTrue())
+		// the ECN bits can't be read on the mockPacketConn
+		c.SetPacketConn(newMockPacketConn())
+		Expect(c.SupportsECN()).To(BeFalse())
+	})
+
 	It("closes", func() {
 		err := c.Close()
 		Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,135 @@
+package quic
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Datagram Queue", func() {
+	var queue *datagramQueue
+	var queued chan struct{}
+
+	BeforeEach(func() {
+		queued = make(chan struct{}, 100)
+		queue = newDatagramQueue(func() {
+			queued <- struct{}{}
+		}, utils.DefaultLogger)
+	})
+
+	Context("sending", func() {
+		It("returns nil when there's no datagram to send", func() {
+			Expect(queue.Peek()).To(BeNil())
+		})
+
+		It("queues a datagram", func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")})).To(Succeed())
+			}()
+
+			Eventually(queued).Should(HaveLen(1))
+			Consistently(done).ShouldNot(BeClosed())
+			f := queue.Peek()
+			Expect(f).ToNot(BeNil())
+			Expect(f.Data).To(Equal([]byte("foobar")))
+			Eventually(done).Should(BeClosed())
+			// Peek returns the same frame until Pop is called
+			Expect(queue.Peek()).To(Equal(f))
+			queue.Pop()
+			Expect(queue.Peek()).To(BeNil())
+		})
+
+		It("closes", func() {
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				errChan <- queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")})
+			}()
+
+			Consistently(errChan).ShouldNot(Receive())
+			queue.CloseWithError(errors.New("test error"))
+			Eventually(errChan).Should(Receive(MatchError("test error")))
+		})
+
+		It("doesn't block when dequeueing a frame after AddAndWait returned", func() {
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				errChan <- queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")})
+			}()
+
+			Eventually(queued).Should(HaveLen(1))
+			queue.CloseWithError(errors.New("test error"))
+			Eventually(errChan).Should(Receive(MatchError("test error")))
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(queue.Peek()).ToNot(BeNil())
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	Context("receiving", func() {
+		It("receives DATAGRAM frames", func() {
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("bar")})
+			data, err := queue.Receive(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foo")))
+			data, err = queue.Receive(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("bar")))
+		})
+
+		It("blocks until a frame is received", func() {
+			c := make(chan []byte, 1)
+			go func() {
+				defer GinkgoRecover()
+				data, err := queue.Receive(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				c <- data
+			}()
+
+			Consistently(c).ShouldNot(Receive())
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foobar")})
+			Eventually(c).Should(Receive(Equal([]byte("foobar"))))
+		})
+
+		It("returns when the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				_, err := queue.Receive(ctx)
+				errChan <- err
+			}()
+
+			Consistently(errChan).ShouldNot(Receive())
+			cancel()
+			Eventually(errChan).Should(Receive(Equal(context.Canceled)))
+		})
+
+		It("closes", func() {
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				_, err := queue.Receive(context.Background())
+				errChan <- err
+			}()
+
+			Consistently(errChan).ShouldNot(Receive())
+			queue.CloseWithError(errors.New("test error"))
+			Eventually(errChan).Should(Receive(MatchError("test error")))
+		})
+	})
+})
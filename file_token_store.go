@@ -0,0 +1,217 @@
+package quic
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// the format of the file written by the fileTokenStore
+type tokenStoreFile struct {
+	Origins []tokenStoreFileOrigin `json:"origins"` // the most recently used origin first
+}
+
+type tokenStoreFileOrigin struct {
+	Key    string                `json:"key"`
+	Tokens []tokenStoreFileToken `json:"tokens"` // the oldest token first
+}
+
+type tokenStoreFileToken struct {
+	Data     []byte    `json:"data"`
+	Received time.Time `json:"received"`
+}
+
+// A FileTokenStore is a TokenStore that persists tokens to a file.
+type FileTokenStore struct {
+	mutex sync.Mutex
+
+	filename string
+	maxAge   time.Duration
+	lru      *lruTokenStore
+
+	// The file is written by a separate go routine, so that Put and Pop don't block on disk I/O.
+	// Modifications that happen while the file is being written are coalesced into the next write.
+	dirty     bool
+	writing   bool
+	writeErr  error
+	writeDone *sync.Cond
+
+	logger utils.Logger
+}
+
+var _ TokenStore = &FileTokenStore{}
+
+// NewFileTokenStore creates a TokenStore that persists tokens to a file,
+// such that they can be used across process restarts.
+// The file is loaded when the store is created, and rewritten atomically every time the store is modified.
+// Writing happens asynchronously. Flush should be called before the process exits.
+// maxOrigins specifies how many origins this store is saving tokens for.
+// tokensPerOrigin specifies the maximum number of tokens per origin.
+// Tokens that were received more than maxAge ago are discarded.
+// If maxAge is 0, tokens don't expire.
+func NewFileTokenStore(filename string, maxOrigins, tokensPerOrigin int, maxAge time.Duration) (*FileTokenStore, error) {
+	s := &FileTokenStore{
+		filename: filename,
+		maxAge:   maxAge,
+		lru:      NewLRUTokenStore(maxOrigins, tokensPerOrigin).(*lruTokenStore),
+		logger:   utils.DefaultLogger.WithPrefix("token store"),
+	}
+	s.writeDone = sync.NewCond(&s.mutex)
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileTokenStore) load() error {
+	data, err := ioutil.ReadFile(s.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var f tokenStoreFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	// Insert the least recently used origin first, so that the LRU order is restored.
+	for i := len(f.Origins) - 1; i >= 0; i-- {
+		origin := f.Origins[i]
+		for _, t := range origin.Tokens {
+			token := &ClientToken{data: t.Data, rcvTime: t.Received}
+			if s.isExpired(token) {
+				continue
+			}
+			s.lru.Put(origin.Key, token)
+		}
+	}
+	return nil
+}
+
+func (s *FileTokenStore) isExpired(token *ClientToken) bool {
+	return s.maxAge > 0 && time.Since(token.rcvTime) > s.maxAge
+}
+
+// Put adds a token for an origin.
+func (s *FileTokenStore) Put(key string, token *ClientToken) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lru.Put(key, token)
+	s.persist()
+}
+
+// Pop returns the most recently received token for an origin, that has not expired yet.
+func (s *FileTokenStore) Pop(key string) *ClientToken {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	token := s.lru.Pop(key)
+	if token == nil {
+		return nil
+	}
+	// Tokens are popped newest first.
+	// If this token is expired, all other tokens for this origin are expired as well.
+	for token != nil && s.isExpired(token) {
+		token = s.lru.Pop(key)
+	}
+	s.persist()
+	return token
+}
+
+// Flush blocks until all modifications have been written to the file.
+// It returns the error that occurred when writing the file, if any.
+func (s *FileTokenStore) Flush() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for s.writing {
+		s.writeDone.Wait()
+	}
+	err := s.writeErr
+	s.writeErr = nil
+	return err
+}
+
+// persist schedules writing the file.
+// It must be called with the mutex held.
+func (s *FileTokenStore) persist() {
+	s.dirty = true
+	if s.writing {
+		return
+	}
+	s.writing = true
+	go s.runWriter()
+}
+
+func (s *FileTokenStore) runWriter() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for s.dirty {
+		s.dirty = false
+		data, err := s.marshal()
+		if err == nil {
+			s.mutex.Unlock()
+			err = s.writeFile(data)
+			s.mutex.Lock()
+		}
+		if err != nil {
+			s.logger.Errorf("Failed to persist tokens: %s", err)
+		}
+		s.writeErr = err
+	}
+	s.writing = false
+	s.writeDone.Broadcast()
+}
+
+// marshal serializes all tokens.
+// It must be called with the mutex held.
+func (s *FileTokenStore) marshal() ([]byte, error) {
+	var f tokenStoreFile
+	s.lru.mutex.Lock()
+	for el := s.lru.q.Front(); el != nil; el = el.Next() {
+		entry := el.Value.(*lruTokenStoreEntry)
+		origin := tokenStoreFileOrigin{Key: entry.key}
+		for _, t := range entry.cache.Tokens() {
+			origin.Tokens = append(origin.Tokens, tokenStoreFileToken{Data: t.data, Received: t.rcvTime})
+		}
+		f.Origins = append(f.Origins, origin)
+	}
+	s.lru.mutex.Unlock()
+	return json.Marshal(&f)
+}
+
+// writeFile writes the data to a temporary file, and then renames that file.
+// This makes sure that the file is never left in a partially written state.
+func (s *FileTokenStore) writeFile(data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(s.filename), filepath.Base(s.filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.filename); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package quic
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("File Token Store", func() {
+	var (
+		dir      string
+		filename string
+		stores   []*FileTokenStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "quic-go-token-store")
+		Expect(err).ToNot(HaveOccurred())
+		filename = filepath.Join(dir, "tokens.json")
+	})
+
+	AfterEach(func() {
+		// wait for all writes to complete before removing the directory
+		for _, s := range stores {
+			s.Flush()
+		}
+		stores = nil
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	mockToken := func(num int, rcvTime time.Time) *ClientToken {
+		return &ClientToken{data: []byte(fmt.Sprintf("%d", num)), rcvTime: rcvTime}
+	}
+
+	newStore := func() *FileTokenStore {
+		s, err := NewFileTokenStore(filename, 2, 3, time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		stores = append(stores, s)
+		return s
+	}
+
+	expectTokenEqual := func(token, expected *ClientToken) {
+		ExpectWithOffset(1, token).ToNot(BeNil())
+		ExpectWithOffset(1, token.data).To(Equal(expected.data))
+		ExpectWithOffset(1, token.rcvTime).To(BeTemporally("==", expected.rcvTime))
+	}
+
+	It("starts empty if the file doesn't exist", func() {
+		s := newStore()
+		Expect(s.Pop("localhost")).To(BeNil())
+	})
+
+	It("adds and gets tokens", func() {
+		now := time.Now()
+		s := newStore()
+		s.Put("localhost", mockToken(1, now))
+		s.Put("localhost", mockToken(2, now))
+		expectTokenEqual(s.Pop("localhost"), mockToken(2, now))
+		expectTokenEqual(s.Pop("localhost"), mockToken(1, now))
+		Expect(s.Pop("localhost")).To(BeNil())
+	})
+
+	It("restores tokens from the file", func() {
+		now := time.Now()
+		s := newStore()
+		s.Put("localhost", mockToken(1, now))
+		s.Put("localhost", mockToken(2, now))
+		s.Put("quic.clemente.io", mockToken(3, now))
+		// restore the tokens into a new store
+		Expect(s.Flush()).To(Succeed())
+		s = newStore()
+		expectTokenEqual(s.Pop("localhost"), mockToken(2, now))
+		expectTokenEqual(s.Pop("localhost"), mockToken(1, now))
+		Expect(s.Pop("localhost")).To(BeNil())
+		expectTokenEqual(s.Pop("quic.clemente.io"), mockToken(3, now))
+		Expect(s.Pop("quic.clemente.io")).To(BeNil())
+	})
+
+	It("persists when tokens are removed", func() {
+		now := time.Now()
+		s := newStore()
+		s.Put("localhost", mockToken(1, now))
+		s.Put("localhost", mockToken(2, now))
+		expectTokenEqual(s.Pop("localhost"), mockToken(2, now))
+		Expect(s.Flush()).To(Succeed())
+		s = newStore()
+		expectTokenEqual(s.Pop("localhost"), mockToken(1, now))
+		Expect(s.Pop("localhost")).To(BeNil())
+	})
+
+	It("limits the number of tokens per origin", func() {
+		now := time.Now()
+		s := newStore()
+		for i := 1; i <= 4; i++ {
+			s.Put("localhost", mockToken(i, now))
+		}
+		Expect(s.Flush()).To(Succeed())
+		s = newStore()
+		expectTokenEqual(s.Pop("localhost"), mockToken(4, now))
+		expectTokenEqual(s.Pop("localhost"), mockToken(3, now))
+		expectTokenEqual(s.Pop("localhost"), mockToken(2, now))
+		Expect(s.Pop("localhost")).To(BeNil())
+	})
+
+	It("restores the LRU order of the origins", func() {
+		now := time.Now()
+		s := newStore()
+		s.Put("quic.clemente.io", mockToken(1, now))
+		s.Put("localhost", mockToken(2, now))
+		s.Put("quic.clemente.io", mockToken(3, now))
+		Expect(s.Flush()).To(Succeed())
+		s = newStore()
+		// This evicts localhost, since it's the least recently used origin.
+		s.Put("example.com", mockToken(4, now))
+		Expect(s.Pop("localhost")).To(BeNil())
+		expectTokenEqual(s.Pop("quic.clemente.io"), mockToken(3, now))
+		expectTokenEqual(s.Pop("example.com"), mockToken(4, now))
+	})
+
+	It("doesn't return expired tokens", func() {
+		now := time.Now()
+		s := newStore()
+		s.Put("localhost", mockToken(1, now.Add(-2*time.Hour)))
+		s.Put("localhost", mockToken(2, now.Add(-90*time.Minute)))
+		Expect(s.Pop("localhost")).To(BeNil())
+		s.Put("localhost", mockToken(3, now.Add(-2*time.Hour)))
+		s.Put("localhost", mockToken(4, now))
+		expectTokenEqual(s.Pop("localhost"), mockToken(4, now))
+		Expect(s.Pop("localhost")).To(BeNil())
+	})
+
+	It("drops expired tokens when loading the file", func() {
+		now := time.Now()
+		s := newStore()
+		s.Put("localhost", mockToken(1, now.Add(-2*time.Hour)))
+		s.Put("localhost", mockToken(2, now))
+		Expect(s.Flush()).To(Succeed())
+		s = newStore()
+		expectTokenEqual(s.Pop("localhost"), mockToken(2, now))
+		Expect(s.Pop("localhost")).To(BeNil())
+	})
+
+	It("doesn't leave temporary files behind", func() {
+		s := newStore()
+		s.Put("localhost", mockToken(1, time.Now()))
+		s.Put("localhost", mockToken(2, time.Now()))
+		Expect(s.Flush()).To(Succeed())
+		files, err := ioutil.ReadDir(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].Name()).To(Equal("tokens.json"))
+	})
+
+	It("doesn't expire tokens if the maximum age is 0", func() {
+		s, err := NewFileTokenStore(filename, 2, 3, 0)
+		Expect(err).ToNot(HaveOccurred())
+		rcvTime := time.Now().Add(-24 * 365 * time.Hour)
+		s.Put("localhost", mockToken(1, rcvTime))
+		Expect(s.Flush()).To(Succeed())
+		s, err = NewFileTokenStore(filename, 2, 3, 0)
+		Expect(err).ToNot(HaveOccurred())
+		expectTokenEqual(s.Pop("localhost"), mockToken(1, rcvTime))
+		Expect(s.Flush()).To(Succeed())
+	})
+
+	It("returns the error when the file can't be written", func() {
+		now := time.Now()
+		s := newStore()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+		s.Put("localhost", mockToken(1, now))
+		Expect(s.Flush()).ToNot(Succeed())
+		// the token is still available from memory
+		expectTokenEqual(s.Pop("localhost"), mockToken(1, now))
+	})
+
+	It("errors when the file can't be parsed", func() {
+		Expect(ioutil.WriteFile(filename, []byte("foobar"), 0600)).To(Succeed())
+		_, err := NewFileTokenStore(filename, 2, 3, time.Hour)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -222,3 +222,22 @@ func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
 func (s *frameSorter) HasMoreData() bool {
 	return len(s.queue) > 0
 }
+
+// QueuedBytes returns the number of bytes queued.
+// Bytes received out of order can't be popped yet, since data at a lower offset is still missing.
+func (s *frameSorter) QueuedBytes() (inOrder, outOfOrder protocol.ByteCount) {
+	firstGap := s.gaps.Front().Value.Start
+	for offset, entry := range s.queue {
+		if offset < firstGap {
+			inOrder += protocol.ByteCount(len(entry.Data))
+		} else {
+			outOfOrder += protocol.ByteCount(len(entry.Data))
+		}
+	}
+	return
+}
+
+// HighestContiguousOffset is the offset up to which all data has been received.
+func (s *frameSorter) HighestContiguousOffset() protocol.ByteCount {
+	return s.gaps.Front().Value.Start
+}
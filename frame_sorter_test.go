@@ -123,6 +123,20 @@ var _ = Describe("frame sorter", func() {
 		Expect(s.HasMoreData()).To(BeFalse())
 	})
 
+	It("counts the queued bytes", func() {
+		Expect(s.Push([]byte("foo"), 0, nil)).To(Succeed())
+		Expect(s.Push([]byte("foobar"), 6, nil)).To(Succeed())
+		inOrder, outOfOrder := s.QueuedBytes()
+		Expect(inOrder).To(Equal(protocol.ByteCount(3)))
+		Expect(outOfOrder).To(Equal(protocol.ByteCount(6)))
+		Expect(s.HighestContiguousOffset()).To(Equal(protocol.ByteCount(3)))
+		Expect(s.Push([]byte("bar"), 3, nil)).To(Succeed())
+		inOrder, outOfOrder = s.QueuedBytes()
+		Expect(inOrder).To(Equal(protocol.ByteCount(12)))
+		Expect(outOfOrder).To(BeZero())
+		Expect(s.HighestContiguousOffset()).To(Equal(protocol.ByteCount(12)))
+	})
+
 	Context("Gap handling", func() {
 		var dataCounter uint8
 
@@ -15,7 +15,7 @@ type framer interface {
 	QueueControlFrame(wire.Frame)
 	AppendControlFrames([]ackhandler.Frame, protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount)
 
-	AddActiveStream(protocol.StreamID)
+	AddActiveStream(protocol.StreamID, StreamPriority)
 	AppendStreamFrames([]ackhandler.Frame, protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount)
 }
 
@@ -25,8 +25,9 @@ type framerI struct {
 	streamGetter streamGetter
 	version      protocol.VersionNumber
 
-	activeStreams map[protocol.StreamID]struct{}
-	streamQueue   []protocol.StreamID
+	// the priorities of all streams that have data to send
+	activeStreams map[protocol.StreamID]StreamPriority
+	scheduler     StreamScheduler
 
 	controlFrameMutex sync.Mutex
 	controlFrames     []wire.Frame
@@ -36,18 +37,20 @@ var _ framer = &framerI{}
 
 func newFramer(
 	streamGetter streamGetter,
+	scheduler StreamScheduler,
 	v protocol.VersionNumber,
 ) framer {
 	return &framerI{
 		streamGetter:  streamGetter,
-		activeStreams: make(map[protocol.StreamID]struct{}),
+		activeStreams: make(map[protocol.StreamID]StreamPriority),
+		scheduler:     scheduler,
 		version:       v,
 	}
 }
 
 func (f *framerI) HasData() bool {
 	f.mutex.Lock()
-	hasData := len(f.streamQueue) > 0
+	hasData := len(f.activeStreams) > 0
 	f.mutex.Unlock()
 	if hasData {
 		return true
@@ -81,11 +84,11 @@ func (f *framerI) AppendControlFrames(frames []ackhandler.Frame, maxLen protocol
 	return frames, length
 }
 
-func (f *framerI) AddActiveStream(id protocol.StreamID) {
+func (f *framerI) AddActiveStream(id protocol.StreamID, priority StreamPriority) {
 	f.mutex.Lock()
-	if _, ok := f.activeStreams[id]; !ok {
-		f.streamQueue = append(f.streamQueue, id)
-		f.activeStreams[id] = struct{}{}
+	if p, ok := f.activeStreams[id]; !ok || p != priority {
+		f.scheduler.Push(id, priority)
+		f.activeStreams[id] = priority
 	}
 	f.mutex.Unlock()
 }
@@ -93,43 +96,61 @@ func (f *framerI) AddActiveStream(id protocol.StreamID) {
 func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
 	var length protocol.ByteCount
 	var lastFrame *ackhandler.Frame
+	// streams that still have data to send after filling this packet
+	var requeue []protocol.StreamID
 	f.mutex.Lock()
 	// pop STREAM frames, until less than MinStreamFrameSize bytes are left in the packet
-	numActiveStreams := len(f.streamQueue)
+	numActiveStreams := f.scheduler.Len()
 	for i := 0; i < numActiveStreams; i++ {
 		if protocol.MinStreamFrameSize+length > maxLen {
 			break
 		}
-		id := f.streamQueue[0]
-		f.streamQueue = f.streamQueue[1:]
+		id, ok := f.scheduler.Pop()
+		if !ok {
+			break
+		}
 		// This should never return an error. Better check it anyway.
-		// The stream will only be in the streamQueue, if it enqueued itself there.
+		// The stream will only be in the scheduler, if it enqueued itself there.
 		str, err := f.streamGetter.GetOrOpenSendStream(id)
 		// The stream can be nil if it completed after it said it had data.
 		if str == nil || err != nil {
 			delete(f.activeStreams, id)
 			continue
 		}
-		remainingLen := maxLen - length
-		// For the last STREAM frame, we'll remove the DataLen field later.
-		// Therefore, we can pretend to have more bytes available when popping
-		// the STREAM frame (which will always have the DataLen set).
-		remainingLen += utils.VarIntLen(uint64(remainingLen))
-		frame, hasMoreData := str.popStreamFrame(remainingLen)
-		if hasMoreData { // put the stream back in the queue (at the end)
-			f.streamQueue = append(f.streamQueue, id)
+		var hasMoreData bool
+		// Usually, a stream is only dequeued once per packet, since the STREAM frame fills the rest of the packet.
+		// However, STREAM frames are limited by the size of their buffer.
+		// If the packet is larger (after Path MTU Discovery), more STREAM frames are added for the same stream.
+		for {
+			remainingLen := maxLen - length
+			// For the last STREAM frame, we'll remove the DataLen field later.
+			// Therefore, we can pretend to have more bytes available when popping
+			// the STREAM frame (which will always have the DataLen set).
+			remainingLen += utils.VarIntLen(uint64(remainingLen))
+			var frame *ackhandler.Frame
+			frame, hasMoreData = str.popStreamFrame(remainingLen)
+			// The frame can be nil
+			// * if the receiveStream was canceled after it said it had data
+			// * the remaining size doesn't allow us to add another STREAM frame
+			if frame == nil {
+				break
+			}
+			frames = append(frames, *frame)
+			length += frame.Length(f.version)
+			lastFrame = frame
+			if !hasMoreData || frame.Frame.(*wire.StreamFrame).DataLen() < protocol.MaxStreamFrameBufferSize ||
+				protocol.MinStreamFrameSize+length > maxLen {
+				break
+			}
+		}
+		if hasMoreData { // put the stream back into the scheduler, after this packet was filled
+			requeue = append(requeue, id)
 		} else { // no more data to send. Stream is not active any more
 			delete(f.activeStreams, id)
 		}
-		// The frame can be nil
-		// * if the receiveStream was canceled after it said it had data
-		// * the remaining size doesn't allow us to add another STREAM frame
-		if frame == nil {
-			continue
-		}
-		frames = append(frames, *frame)
-		length += frame.Length(f.version)
-		lastFrame = frame
+	}
+	for _, id := range requeue {
+		f.scheduler.Push(id, f.activeStreams[id])
 	}
 	f.mutex.Unlock()
 	if lastFrame != nil {
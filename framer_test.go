@@ -33,7 +33,7 @@ var _ = Describe("Framer", func() {
 		stream1.EXPECT().StreamID().Return(protocol.StreamID(5)).AnyTimes()
 		stream2 = NewMockSendStreamI(mockCtrl)
 		stream2.EXPECT().StreamID().Return(protocol.StreamID(6)).AnyTimes()
-		framer = newFramer(streamGetter, version)
+		framer = newFramer(streamGetter, NewStrictPriorityScheduler(), version)
 	})
 
 	Context("handling control frames", func() {
@@ -102,7 +102,7 @@ var _ = Describe("Framer", func() {
 				DataLenPresent: true,
 			}
 			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f}, false)
-			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id1, defaultStreamPriority())
 			fs, length := framer.AppendStreamFrames(nil, 1000)
 			Expect(fs).To(HaveLen(1))
 			Expect(fs[0].Frame.(*wire.StreamFrame).DataLenPresent).To(BeFalse())
@@ -112,7 +112,7 @@ var _ = Describe("Framer", func() {
 		It("says if it has data", func() {
 			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).Times(2)
 			Expect(framer.HasData()).To(BeFalse())
-			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id1, defaultStreamPriority())
 			Expect(framer.HasData()).To(BeTrue())
 			f1 := &wire.StreamFrame{StreamID: id1, Data: []byte("foo")}
 			f2 := &wire.StreamFrame{StreamID: id1, Data: []byte("bar")}
@@ -136,7 +136,7 @@ var _ = Describe("Framer", func() {
 				DataLenPresent: true,
 			}
 			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f}, false)
-			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id1, defaultStreamPriority())
 			mdf := &wire.MaxDataFrame{ByteOffset: 1337}
 			frames := []ackhandler.Frame{{Frame: mdf}}
 			fs, length := framer.AppendStreamFrames(frames, 1000)
@@ -156,8 +156,8 @@ var _ = Describe("Framer", func() {
 				DataLenPresent: true,
 			}
 			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f}, false)
-			framer.AddActiveStream(id1)
-			framer.AddActiveStream(id2)
+			framer.AddActiveStream(id1, defaultStreamPriority())
+			framer.AddActiveStream(id2, defaultStreamPriority())
 			frames, _ := framer.AppendStreamFrames(nil, 1000)
 			Expect(frames).To(HaveLen(1))
 			Expect(frames[0].Frame).To(Equal(f))
@@ -173,8 +173,8 @@ var _ = Describe("Framer", func() {
 			}
 			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(nil, false)
 			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f}, false)
-			framer.AddActiveStream(id1)
-			framer.AddActiveStream(id2)
+			framer.AddActiveStream(id1, defaultStreamPriority())
+			framer.AddActiveStream(id2, defaultStreamPriority())
 			frames, _ := framer.AppendStreamFrames(nil, 1000)
 			Expect(frames).To(HaveLen(1))
 			Expect(frames[0].Frame).To(Equal(f))
@@ -186,7 +186,7 @@ var _ = Describe("Framer", func() {
 			f2 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobaz")}
 			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, true)
 			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
-			framer.AddActiveStream(id1) // only add it once
+			framer.AddActiveStream(id1, defaultStreamPriority()) // only add it once
 			frames, _ := framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
 			Expect(frames).To(HaveLen(1))
 			Expect(frames[0].Frame).To(Equal(f1))
@@ -207,8 +207,8 @@ var _ = Describe("Framer", func() {
 			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f11}, true)
 			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f12}, false)
 			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
-			framer.AddActiveStream(id1) // only add it once
-			framer.AddActiveStream(id2)
+			framer.AddActiveStream(id1, defaultStreamPriority()) // only add it once
+			framer.AddActiveStream(id2, defaultStreamPriority())
 			// first a frame from stream 1
 			frames, _ := framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
 			Expect(frames).To(HaveLen(1))
@@ -231,8 +231,8 @@ var _ = Describe("Framer", func() {
 			// both streams have more data, and will be re-queued
 			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, true)
 			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, true)
-			framer.AddActiveStream(id1)
-			framer.AddActiveStream(id2)
+			framer.AddActiveStream(id1, defaultStreamPriority())
+			framer.AddActiveStream(id2, defaultStreamPriority())
 			frames, length := framer.AppendStreamFrames(nil, 1000)
 			Expect(frames).To(HaveLen(2))
 			Expect(frames[0].Frame).To(Equal(f1))
@@ -240,6 +240,20 @@ var _ = Describe("Framer", func() {
 			Expect(length).To(Equal(f1.Length(version) + f2.Length(version)))
 		})
 
+		It("dequeues data from a stream multiple times, if the packet is larger than the STREAM frame buffer", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			f1 := &wire.StreamFrame{StreamID: id1, Data: make([]byte, protocol.MaxStreamFrameBufferSize)}
+			f2 := &wire.StreamFrame{StreamID: id1, Offset: f1.DataLen(), Data: []byte("foobar")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, true)
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.AddActiveStream(id1, defaultStreamPriority())
+			frames, _ := framer.AppendStreamFrames(nil, 5000)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame).To(Equal(f1))
+			Expect(frames[1].Frame).To(Equal(f2))
+			Expect(framer.HasData()).To(BeFalse())
+		})
+
 		It("returns multiple normal frames in the order they were reported active", func() {
 			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
 			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
@@ -247,20 +261,98 @@ var _ = Describe("Framer", func() {
 			f2 := &wire.StreamFrame{Data: []byte("foobaz")}
 			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, false)
 			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
-			framer.AddActiveStream(id2)
-			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2, defaultStreamPriority())
+			framer.AddActiveStream(id1, defaultStreamPriority())
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame).To(Equal(f2))
+			Expect(frames[1].Frame).To(Equal(f1))
+		})
+
+		It("returns frames of more urgent streams first", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
+			f1 := &wire.StreamFrame{Data: []byte("foobar")}
+			f2 := &wire.StreamFrame{Data: []byte("foobaz")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.AddActiveStream(id1, defaultStreamPriority())
+			framer.AddActiveStream(id2, StreamPriority{Urgency: 0})
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame).To(Equal(f2))
+			Expect(frames[1].Frame).To(Equal(f1))
+		})
+
+		It("keeps sending a more urgent stream until it doesn't have any more data", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).Times(2)
+			f1 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}
+			f21 := &wire.StreamFrame{StreamID: id2, Data: []byte("foobaz")}
+			f22 := &wire.StreamFrame{StreamID: id2, Data: []byte("raboof")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f21}, true)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f22}, false)
+			framer.AddActiveStream(id1, defaultStreamPriority())
+			framer.AddActiveStream(id2, StreamPriority{Urgency: 1})
+			frames, _ := framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f21))
+			frames, _ = framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f22))
+			frames, _ = framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f1))
+		})
+
+		It("updates the priority of an active stream", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
+			f1 := &wire.StreamFrame{Data: []byte("foobar")}
+			f2 := &wire.StreamFrame{Data: []byte("foobaz")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.AddActiveStream(id1, defaultStreamPriority())
+			framer.AddActiveStream(id2, defaultStreamPriority())
+			framer.AddActiveStream(id2, StreamPriority{Urgency: 0})
 			frames, _ := framer.AppendStreamFrames(nil, 1000)
 			Expect(frames).To(HaveLen(2))
 			Expect(frames[0].Frame).To(Equal(f2))
 			Expect(frames[1].Frame).To(Equal(f1))
 		})
 
+		It("uses the stream scheduler", func() {
+			framer = newFramer(streamGetter, NewWeightedFairScheduler(), version)
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).AnyTimes()
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).AnyTimes()
+			f1 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("foobaz")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, true).AnyTimes()
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, true).AnyTimes()
+			framer.AddActiveStream(id1, StreamPriority{Urgency: 0, Incremental: true})
+			framer.AddActiveStream(id2, StreamPriority{Urgency: 7, Incremental: true})
+			var numFrames1, numFrames2 int
+			for i := 0; i < 9*10; i++ {
+				frames, _ := framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
+				Expect(frames).To(HaveLen(1))
+				switch frames[0].Frame {
+				case f1:
+					numFrames1++
+				case f2:
+					numFrames2++
+				}
+			}
+			Expect(numFrames1).To(Equal(8 * 10))
+			Expect(numFrames2).To(Equal(10))
+		})
+
 		It("only asks a stream for data once, even if it was reported active multiple times", func() {
 			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
 			f := &wire.StreamFrame{Data: []byte("foobar")}
 			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f}, false) // only one call to this function
-			framer.AddActiveStream(id1)
-			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id1, defaultStreamPriority())
+			framer.AddActiveStream(id1, defaultStreamPriority())
 			frames, _ := framer.AppendStreamFrames(nil, 1000)
 			Expect(frames).To(HaveLen(1))
 		})
@@ -283,7 +375,7 @@ var _ = Describe("Framer", func() {
 					Expect(f.Length(version)).To(Equal(size))
 					return &ackhandler.Frame{Frame: f}, false
 				})
-				framer.AddActiveStream(id1)
+				framer.AddActiveStream(id1, defaultStreamPriority())
 				frames, _ := framer.AppendStreamFrames(nil, i)
 				Expect(frames).To(HaveLen(1))
 				f := frames[0].Frame.(*wire.StreamFrame)
@@ -313,8 +405,8 @@ var _ = Describe("Framer", func() {
 					Expect(f.Length(version)).To(Equal(size))
 					return &ackhandler.Frame{Frame: f}, false
 				})
-				framer.AddActiveStream(id1)
-				framer.AddActiveStream(id2)
+				framer.AddActiveStream(id1, defaultStreamPriority())
+				framer.AddActiveStream(id2, defaultStreamPriority())
 				frames, _ := framer.AppendStreamFrames(nil, i)
 				Expect(frames).To(HaveLen(2))
 				f1 := frames[0].Frame.(*wire.StreamFrame)
@@ -329,7 +421,7 @@ var _ = Describe("Framer", func() {
 			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
 			f := &wire.StreamFrame{Data: []byte("foobar")}
 			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f}, false)
-			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id1, defaultStreamPriority())
 			framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)
 		})
 
@@ -347,7 +439,7 @@ var _ = Describe("Framer", func() {
 				DataLenPresent: true,
 			}
 			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f}, false)
-			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id1, defaultStreamPriority())
 			fs, length := framer.AppendStreamFrames(nil, 500)
 			Expect(fs).To(HaveLen(1))
 			Expect(fs[0].Frame).To(Equal(f))
@@ -0,0 +1,108 @@
+package quic
+
+import (
+	"math"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// minRateLimiterPurgeSize is the number of prefixes that are tracked before
+// the handshakeRateLimiter starts removing prefixes that didn't use up any of their budget.
+const minRateLimiterPurgeSize = 1024
+
+type rateLimiterBucket struct {
+	tokens     float64
+	lastUpdate time.Time
+}
+
+// The handshakeRateLimiter implements a token bucket for every network prefix.
+// It is only used from the server's run loop, and therefore not safe for concurrent use.
+type handshakeRateLimiter struct {
+	rate  float64
+	burst float64
+
+	ipv4Mask net.IPMask
+	ipv6Mask net.IPMask
+
+	buckets   map[string]*rateLimiterBucket
+	nextPurge int
+}
+
+func newHandshakeRateLimiter(conf *HandshakeRateLimit) *handshakeRateLimiter {
+	burst := conf.Burst
+	if burst == 0 {
+		burst = int(math.Ceil(conf.Rate))
+	}
+	ipv4PrefixLen := conf.IPv4PrefixLen
+	if ipv4PrefixLen == 0 {
+		ipv4PrefixLen = protocol.DefaultHandshakeRateLimitIPv4PrefixLen
+	}
+	ipv6PrefixLen := conf.IPv6PrefixLen
+	if ipv6PrefixLen == 0 {
+		ipv6PrefixLen = protocol.DefaultHandshakeRateLimitIPv6PrefixLen
+	}
+	return &handshakeRateLimiter{
+		rate:      conf.Rate,
+		burst:     float64(burst),
+		ipv4Mask:  net.CIDRMask(ipv4PrefixLen, 8*net.IPv4len),
+		ipv6Mask:  net.CIDRMask(ipv6PrefixLen, 8*net.IPv6len),
+		buckets:   make(map[string]*rateLimiterBucket),
+		nextPurge: minRateLimiterPurgeSize,
+	}
+}
+
+func (l *handshakeRateLimiter) getPrefix(addr net.Addr) string {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return addr.String()
+	}
+	if ip := udpAddr.IP.To4(); ip != nil {
+		return string(ip.Mask(l.ipv4Mask))
+	}
+	return string(udpAddr.IP.To16().Mask(l.ipv6Mask))
+}
+
+// Allow says if a client with this address is allowed to start a new handshake.
+func (l *handshakeRateLimiter) Allow(addr net.Addr, now time.Time) bool {
+	if len(l.buckets) >= l.nextPurge {
+		l.purge(now)
+	}
+	prefix := l.getPrefix(addr)
+	b, ok := l.buckets[prefix]
+	if !ok {
+		b = &rateLimiterBucket{tokens: l.burst, lastUpdate: now}
+		l.buckets[prefix] = b
+	}
+	l.refill(b, now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (l *handshakeRateLimiter) refill(b *rateLimiterBucket, now time.Time) {
+	if elapsed := now.Sub(b.lastUpdate); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed.Seconds()*l.rate)
+		b.lastUpdate = now
+	}
+}
+
+// purge removes all buckets that are full.
+// A new bucket would be created in the same state.
+func (l *handshakeRateLimiter) purge(now time.Time) {
+	for prefix, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= l.burst {
+			delete(l.buckets, prefix)
+		}
+	}
+	// Only purge again once the map has doubled in size.
+	// This keeps the cost of purging constant per call to Allow, even if many prefixes are active.
+	l.nextPurge = 2 * len(l.buckets)
+	if l.nextPurge < minRateLimiterPurgeSize {
+		l.nextPurge = minRateLimiterPurgeSize
+	}
+}
@@ -0,0 +1,98 @@
+package quic
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handshake Rate Limiter", func() {
+	addr := func(ip string) net.Addr {
+		return &net.UDPAddr{IP: net.ParseIP(ip), Port: 1337}
+	}
+
+	It("allows a burst of handshakes", func() {
+		l := newHandshakeRateLimiter(&HandshakeRateLimit{Rate: 1, Burst: 3})
+		now := time.Now()
+		for i := 0; i < 3; i++ {
+			Expect(l.Allow(addr("192.168.1.1"), now)).To(BeTrue())
+		}
+		Expect(l.Allow(addr("192.168.1.1"), now)).To(BeFalse())
+	})
+
+	It("uses the rate as burst size, if no burst size is set", func() {
+		l := newHandshakeRateLimiter(&HandshakeRateLimit{Rate: 1.5})
+		now := time.Now()
+		Expect(l.Allow(addr("192.168.1.1"), now)).To(BeTrue())
+		Expect(l.Allow(addr("192.168.1.1"), now)).To(BeTrue())
+		Expect(l.Allow(addr("192.168.1.1"), now)).To(BeFalse())
+	})
+
+	It("allows new handshakes after some time", func() {
+		l := newHandshakeRateLimiter(&HandshakeRateLimit{Rate: 10, Burst: 1})
+		now := time.Now()
+		Expect(l.Allow(addr("192.168.1.1"), now)).To(BeTrue())
+		Expect(l.Allow(addr("192.168.1.1"), now.Add(50*time.Millisecond))).To(BeFalse())
+		Expect(l.Allow(addr("192.168.1.1"), now.Add(100*time.Millisecond))).To(BeTrue())
+		Expect(l.Allow(addr("192.168.1.1"), now.Add(150*time.Millisecond))).To(BeFalse())
+	})
+
+	It("doesn't allow bursts larger than the burst size after a long time", func() {
+		l := newHandshakeRateLimiter(&HandshakeRateLimit{Rate: 10, Burst: 2})
+		now := time.Now().Add(time.Hour)
+		Expect(l.Allow(addr("192.168.1.1"), now)).To(BeTrue())
+		Expect(l.Allow(addr("192.168.1.1"), now)).To(BeTrue())
+		Expect(l.Allow(addr("192.168.1.1"), now)).To(BeFalse())
+	})
+
+	It("groups IPv4 addresses by prefix", func() {
+		l := newHandshakeRateLimiter(&HandshakeRateLimit{Rate: 1, Burst: 1})
+		now := time.Now()
+		Expect(l.Allow(addr("192.168.1.1"), now)).To(BeTrue())
+		Expect(l.Allow(addr("192.168.1.200"), now)).To(BeFalse())
+		Expect(l.Allow(addr("192.168.2.1"), now)).To(BeTrue())
+	})
+
+	It("groups IPv6 addresses by prefix", func() {
+		l := newHandshakeRateLimiter(&HandshakeRateLimit{Rate: 1, Burst: 1})
+		now := time.Now()
+		Expect(l.Allow(addr("2001:db8:0:1::1"), now)).To(BeTrue())
+		Expect(l.Allow(addr("2001:db8:0:2::1"), now)).To(BeFalse())
+		Expect(l.Allow(addr("2001:db8:0:100::1"), now)).To(BeTrue())
+	})
+
+	It("uses the configured prefix lengths", func() {
+		l := newHandshakeRateLimiter(&HandshakeRateLimit{Rate: 1, Burst: 1, IPv4PrefixLen: 32, IPv6PrefixLen: 128})
+		now := time.Now()
+		Expect(l.Allow(addr("192.168.1.1"), now)).To(BeTrue())
+		Expect(l.Allow(addr("192.168.1.2"), now)).To(BeTrue())
+		Expect(l.Allow(addr("2001:db8::1"), now)).To(BeTrue())
+		Expect(l.Allow(addr("2001:db8::2"), now)).To(BeTrue())
+		Expect(l.Allow(addr("2001:db8::2"), now)).To(BeFalse())
+	})
+
+	It("purges prefixes that didn't use up any of their budget", func() {
+		l := newHandshakeRateLimiter(&HandshakeRateLimit{Rate: 1, Burst: 1, IPv4PrefixLen: 32})
+		now := time.Now()
+		for i := 0; i < minRateLimiterPurgeSize; i++ {
+			Expect(l.Allow(&net.UDPAddr{IP: net.IPv4(10, 0, byte(i>>8), byte(i))}, now)).To(BeTrue())
+		}
+		Expect(l.buckets).To(HaveLen(minRateLimiterPurgeSize))
+		// all buckets are full again after one second
+		Expect(l.Allow(addr("192.168.1.1"), now.Add(time.Second))).To(BeTrue())
+		Expect(l.buckets).To(HaveLen(1))
+	})
+
+	It("doesn't purge prefixes that are rate limited", func() {
+		l := newHandshakeRateLimiter(&HandshakeRateLimit{Rate: 1, Burst: 1, IPv4PrefixLen: 32})
+		now := time.Now()
+		for i := 0; i < minRateLimiterPurgeSize; i++ {
+			Expect(l.Allow(&net.UDPAddr{IP: net.IPv4(10, 0, byte(i>>8), byte(i))}, now)).To(BeTrue())
+		}
+		Expect(l.Allow(addr("192.168.1.1"), now.Add(time.Second/2))).To(BeTrue())
+		Expect(l.buckets).To(HaveLen(minRateLimiterPurgeSize + 1))
+		Expect(l.Allow(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 0)}, now.Add(time.Second/2))).To(BeFalse())
+	})
+})
@@ -51,6 +51,13 @@ type client struct {
 	hostname string
 	session  quic.EarlySession
 
+	controlStrReady chan struct{} // closed when setting up the control stream completed or failed
+	controlStrMutex sync.Mutex    // writes to the control stream must not be interleaved
+	controlStr      quic.SendStream
+
+	requestsMutex sync.Mutex
+	requests      map[*http.Request]quic.Stream // requests that are still in flight
+
 	logger utils.Logger
 }
 
@@ -67,7 +74,7 @@ func newClient(
 		tlsConf = tlsConf.Clone()
 	}
 	// Replace existing ALPNs by H3
-	tlsConf.NextProtos = []string{nextProtoH3}
+	tlsConf.NextProtos = nextProtosH3
 	if quicConfig == nil {
 		quicConfig = defaultQuicConfig
 	}
@@ -75,14 +82,16 @@ func newClient(
 	logger := utils.DefaultLogger.WithPrefix("h3 client")
 
 	return &client{
-		hostname:      authorityAddr("https", hostname),
-		tlsConf:       tlsConf,
-		requestWriter: newRequestWriter(logger),
-		decoder:       qpack.NewDecoder(func(hf qpack.HeaderField) {}),
-		config:        quicConfig,
-		opts:          opts,
-		dialer:        dialer,
-		logger:        logger,
+		hostname:        authorityAddr("https", hostname),
+		tlsConf:         tlsConf,
+		requestWriter:   newRequestWriter(logger),
+		decoder:         qpack.NewDecoder(func(hf qpack.HeaderField) {}),
+		config:          quicConfig,
+		opts:            opts,
+		dialer:          dialer,
+		controlStrReady: make(chan struct{}),
+		requests:        make(map[*http.Request]quic.Stream),
+		logger:          logger,
 	}
 }
 
@@ -109,6 +118,7 @@ func (c *client) dial() error {
 }
 
 func (c *client) setupSession() error {
+	defer close(c.controlStrReady)
 	// open the control stream
 	str, err := c.session.OpenUniStream()
 	if err != nil {
@@ -119,10 +129,41 @@ func (c *client) setupSession() error {
 	buf.Write([]byte{0x0})
 	// send the SETTINGS frame
 	(&settingsFrame{}).Write(buf)
+	c.controlStrMutex.Lock()
+	defer c.controlStrMutex.Unlock()
 	if _, err := str.Write(buf.Bytes()); err != nil {
 		return err
 	}
+	c.controlStr = str
+	return nil
+}
 
+// UpdatePriority changes the priority of a request that is still in flight,
+// by sending a PRIORITY_UPDATE frame on the control stream, see RFC 9218, section 7.
+// The priority uses the syntax of the Priority header field.
+func (c *client) UpdatePriority(req *http.Request, priority string) error {
+	c.requestsMutex.Lock()
+	str, ok := c.requests[req]
+	c.requestsMutex.Unlock()
+	if !ok {
+		return errors.New("http3: request not in flight")
+	}
+	<-c.controlStrReady
+	c.controlStrMutex.Lock()
+	defer c.controlStrMutex.Unlock()
+	if c.controlStr == nil {
+		return errors.New("http3: control stream not available")
+	}
+	buf := &bytes.Buffer{}
+	(&priorityUpdateFrame{
+		PrioritizedElementID: uint64(str.StreamID()),
+		PriorityFieldValue:   priority,
+	}).Write(buf)
+	if _, err := c.controlStr.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	// Use the same priority for sending the rest of the request body.
+	str.SetPriority(parsePriority(priority))
 	return nil
 }
 
@@ -173,12 +214,25 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
+	// The Priority header field is sent to the server, which uses it to schedule the response.
+	// Use the same priority for sending the request body.
+	str.SetPriority(priorityFromHeader(req.Header))
+
+	// The priority of the request can be changed while it is in flight, see UpdatePriority.
+	c.requestsMutex.Lock()
+	c.requests[req] = str
+	c.requestsMutex.Unlock()
 
 	// Request Cancellation:
 	// This go routine keeps running even after RoundTrip() returns.
 	// It is shut down when the application is done processing the body.
 	reqDone := make(chan struct{})
 	go func() {
+		defer func() {
+			c.requestsMutex.Lock()
+			delete(c.requests, req)
+			c.requestsMutex.Unlock()
+		}()
 		select {
 		case <-req.Context().Done():
 			str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
@@ -53,7 +53,7 @@ var _ = Describe("Client", func() {
 		var dialAddrCalled bool
 		dialAddr = func(_ string, tlsConf *tls.Config, quicConf *quic.Config) (quic.EarlySession, error) {
 			Expect(quicConf).To(Equal(defaultQuicConfig))
-			Expect(tlsConf.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(tlsConf.NextProtos).To(Equal(nextProtosH3))
 			dialAddrCalled = true
 			return nil, errors.New("test done")
 		}
@@ -90,7 +90,7 @@ var _ = Describe("Client", func() {
 		) (quic.EarlySession, error) {
 			Expect(hostname).To(Equal("localhost:1337"))
 			Expect(tlsConfP.ServerName).To(Equal(tlsConf.ServerName))
-			Expect(tlsConfP.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(tlsConfP.NextProtos).To(Equal(nextProtosH3))
 			Expect(quicConfP.MaxIdleTimeout).To(Equal(quicConf.MaxIdleTimeout))
 			dialAddrCalled = true
 			return nil, errors.New("test done")
@@ -154,9 +154,10 @@ var _ = Describe("Client", func() {
 
 	Context("Doing requests", func() {
 		var (
-			request *http.Request
-			str     *mockquic.MockStream
-			sess    *mockquic.MockEarlySession
+			request    *http.Request
+			str        *mockquic.MockStream
+			controlStr *mockquic.MockStream
+			sess       *mockquic.MockEarlySession
 		)
 
 		decodeHeader := func(str io.Reader) map[string]string {
@@ -179,10 +180,11 @@ var _ = Describe("Client", func() {
 		}
 
 		BeforeEach(func() {
-			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr = mockquic.NewMockStream(mockCtrl)
 			controlStr.EXPECT().Write([]byte{0x0}).Return(1, nil).MaxTimes(1)
 			controlStr.EXPECT().Write(gomock.Any()).MaxTimes(1) // SETTINGS frame
 			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().SetPriority(gomock.Any()).AnyTimes()
 			sess = mockquic.NewMockEarlySession(mockCtrl)
 			sess.EXPECT().OpenUniStream().Return(controlStr, nil).MaxTimes(1)
 			dialAddr = func(hostname string, _ *tls.Config, _ *quic.Config) (quic.EarlySession, error) {
@@ -234,6 +236,72 @@ var _ = Describe("Client", func() {
 			Expect(rsp.StatusCode).To(Equal(418))
 		})
 
+		It("sets the priority of the request stream", func() {
+			request.Header.Set("Priority", "u=1, i")
+			str = mockquic.NewMockStream(mockCtrl)
+			gomock.InOrder(
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+			)
+			str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 1, Incremental: true})
+			buf := &bytes.Buffer{}
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write).AnyTimes()
+			str.EXPECT().Close()
+			str.EXPECT().CancelWrite(gomock.Any())
+			testErr := errors.New("test done")
+			str.EXPECT().Read(gomock.Any()).Return(0, testErr)
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError(testErr))
+			Expect(decodeHeader(buf)).To(HaveKeyWithValue("priority", "u=1, i"))
+		})
+
+		Context("updating the priority", func() {
+			It("sends a PRIORITY_UPDATE frame on the control stream", func() {
+				rspBuf := &bytes.Buffer{}
+				rw := newResponseWriter(rspBuf, utils.DefaultLogger)
+				rw.WriteHeader(200)
+				rw.Flush()
+
+				str = mockquic.NewMockStream(mockCtrl)
+				gomock.InOrder(
+					sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+					sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+				)
+				str.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+				gomock.InOrder(
+					str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 3}),
+					str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 1, Incremental: true}),
+				)
+				controlBuf := &bytes.Buffer{}
+				controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(controlBuf.Write)
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(client.UpdatePriority(request, "u=1, i")).To(Succeed())
+				frame, err := parseNextFrame(controlBuf)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(&priorityUpdateFrame{
+					PrioritizedElementID: 4,
+					PriorityFieldValue:   "u=1, i",
+				}))
+				// once the request is done, its priority can't be updated any more
+				Expect(rsp.Body.Close()).To(Succeed())
+				Eventually(func() int {
+					client.requestsMutex.Lock()
+					defer client.requestsMutex.Unlock()
+					return len(client.requests)
+				}).Should(BeZero())
+				Expect(client.UpdatePriority(request, "u=2")).To(MatchError("http3: request not in flight"))
+			})
+
+			It("errors for unknown requests", func() {
+				Expect(client.UpdatePriority(request, "u=1")).To(MatchError("http3: request not in flight"))
+			})
+		})
+
 		Context("validating the address", func() {
 			It("refuses to do requests for the wrong host", func() {
 				req, err := http.NewRequest("https", "https://quic.clemente.io:1336/foobar.html", nil)
@@ -2,6 +2,7 @@ package http3
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -25,6 +26,20 @@ func (br *byteReaderImpl) ReadByte() (byte, error) {
 	return b[0], nil
 }
 
+// The HTTP/3 unidirectional stream types
+const (
+	streamTypeControlStream      = 0x0
+	streamTypePushStream         = 0x1
+	streamTypeQPACKEncoderStream = 0x2
+	streamTypeQPACKDecoderStream = 0x3
+)
+
+// The PRIORITY_UPDATE frame types, see RFC 9218, section 7
+const (
+	frameTypePriorityUpdateRequest = 0xf0700
+	frameTypePriorityUpdatePush    = 0xf0701
+)
+
 type frame interface{}
 
 func parseNextFrame(b io.Reader) (frame, error) {
@@ -48,6 +63,10 @@ func parseNextFrame(b io.Reader) (frame, error) {
 		return &headersFrame{Length: l}, nil
 	case 0x4:
 		return parseSettingsFrame(br, l)
+	case frameTypePriorityUpdateRequest:
+		return parsePriorityUpdateFrame(br, l, false)
+	case frameTypePriorityUpdatePush:
+		return parsePriorityUpdateFrame(br, l, true)
 	case 0x3: // CANCEL_PUSH
 		fallthrough
 	case 0x5: // PUSH_PROMISE
@@ -131,3 +150,47 @@ func (f *settingsFrame) Write(b *bytes.Buffer) {
 		utils.WriteVarInt(b, val)
 	}
 }
+
+// A priorityUpdateFrame changes the priority of a request or a push, see RFC 9218, section 7.
+// It is sent on the control stream.
+type priorityUpdateFrame struct {
+	// set for PRIORITY_UPDATE frames that reference a push stream
+	IsPush               bool
+	PrioritizedElementID uint64
+	// the value uses the same syntax as the Priority header field
+	PriorityFieldValue string
+}
+
+func parsePriorityUpdateFrame(r io.Reader, l uint64, isPush bool) (*priorityUpdateFrame, error) {
+	if l > 8*(1<<10) {
+		return nil, fmt.Errorf("unexpected size for PRIORITY_UPDATE frame: %d", l)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	b := bytes.NewReader(buf)
+	id, err := utils.ReadVarInt(b)
+	if err != nil {
+		return nil, errors.New("PRIORITY_UPDATE frame too short")
+	}
+	return &priorityUpdateFrame{
+		IsPush:               isPush,
+		PrioritizedElementID: id,
+		PriorityFieldValue:   string(buf[len(buf)-b.Len():]),
+	}, nil
+}
+
+func (f *priorityUpdateFrame) Write(b *bytes.Buffer) {
+	if f.IsPush {
+		utils.WriteVarInt(b, frameTypePriorityUpdatePush)
+	} else {
+		utils.WriteVarInt(b, frameTypePriorityUpdateRequest)
+	}
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(f.PrioritizedElementID))+uint64(len(f.PriorityFieldValue)))
+	utils.WriteVarInt(b, f.PrioritizedElementID)
+	b.WriteString(f.PriorityFieldValue)
+}
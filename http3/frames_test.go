@@ -133,4 +133,83 @@ var _ = Describe("Frames", func() {
 			}
 		})
 	})
+
+	Context("PRIORITY_UPDATE frames", func() {
+		It("parses a frame for a request", func() {
+			data := appendVarInt(nil, 0xf0700) // type byte
+			data = appendVarInt(data, 2+3)
+			data = appendVarInt(data, 0x1337)
+			data = append(data, []byte("u=1")...)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&priorityUpdateFrame{
+				PrioritizedElementID: 0x1337,
+				PriorityFieldValue:   "u=1",
+			}))
+		})
+
+		It("parses a frame for a push", func() {
+			data := appendVarInt(nil, 0xf0701) // type byte
+			data = appendVarInt(data, 1+5)
+			data = appendVarInt(data, 0x2a)
+			data = append(data, []byte("u=2,i")...)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&priorityUpdateFrame{
+				IsPush:               true,
+				PrioritizedElementID: 0x2a,
+				PriorityFieldValue:   "u=2,i",
+			}))
+		})
+
+		It("parses a frame with an empty priority field value", func() {
+			data := appendVarInt(nil, 0xf0700) // type byte
+			data = appendVarInt(data, 1)
+			data = appendVarInt(data, 4)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&priorityUpdateFrame{PrioritizedElementID: 4}))
+		})
+
+		It("rejects frames that are too short", func() {
+			data := appendVarInt(nil, 0xf0700) // type byte
+			data = appendVarInt(data, 0)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("PRIORITY_UPDATE frame too short"))
+		})
+
+		It("rejects frames that are too long", func() {
+			data := appendVarInt(nil, 0xf0700) // type byte
+			data = appendVarInt(data, 8*(1<<10)+1)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("unexpected size for PRIORITY_UPDATE frame: 8193"))
+		})
+
+		It("writes", func() {
+			for _, isPush := range []bool{false, true} {
+				f := &priorityUpdateFrame{
+					IsPush:               isPush,
+					PrioritizedElementID: 0xdeadbeef,
+					PriorityFieldValue:   "u=0, i",
+				}
+				buf := &bytes.Buffer{}
+				f.Write(buf)
+				frame, err := parseNextFrame(buf)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(f))
+			}
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&priorityUpdateFrame{PrioritizedElementID: 0x1337, PriorityFieldValue: "u=5"}).Write(buf)
+			data := buf.Bytes()
+			for i := range data {
+				b := make([]byte, i)
+				copy(b, data[:i])
+				_, err := parseNextFrame(bytes.NewReader(b))
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
 })
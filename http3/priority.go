@@ -0,0 +1,149 @@
+package http3
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// defaultPriority is the priority of requests that don't specify a priority, see RFC 9218, section 4.
+var defaultPriority = quic.StreamPriority{Urgency: 3}
+
+// the maximum number of PRIORITY_UPDATE frames buffered for requests that haven't been received yet
+const maxPendingPriorityUpdates = 100
+
+// priorityFromHeader returns the priority given by the Priority header field.
+func priorityFromHeader(h http.Header) quic.StreamPriority {
+	return parsePriority(strings.Join(h.Values("Priority"), ","))
+}
+
+// parsePriority parses the value of a Priority header field or a PRIORITY_UPDATE frame.
+// The value is a Structured Fields Dictionary (RFC 8941).
+// Parameters that are missing or invalid use the default value.
+func parsePriority(value string) quic.StreamPriority {
+	p := defaultPriority
+	for _, member := range strings.Split(value, ",") {
+		// ignore all parameters of the dictionary member
+		if i := strings.IndexByte(member, ';'); i >= 0 {
+			member = member[:i]
+		}
+		key, val := strings.TrimSpace(member), "?1"
+		if i := strings.IndexByte(key, '='); i >= 0 {
+			key, val = key[:i], key[i+1:]
+		}
+		switch key {
+		case "u":
+			if len(val) == 1 && val[0] >= '0' && val[0] <= '7' {
+				p.Urgency = val[0] - '0'
+			}
+		case "i":
+			switch val {
+			case "?1":
+				p.Incremental = true
+			case "?0":
+				p.Incremental = false
+			}
+		}
+	}
+	return p
+}
+
+type requestPriority struct {
+	str quic.Stream // nil if the request stream wasn't accepted yet
+	// set once the priority from the request headers was applied
+	applied bool
+	// the priority field value of the most recent PRIORITY_UPDATE frame
+	update    string
+	hasUpdate bool
+}
+
+// The priorityHandler applies the priorities of the requests of a connection to the request streams.
+// The priority is either sent in the Priority header field, or in a PRIORITY_UPDATE frame on the control stream.
+// PRIORITY_UPDATE frames can arrive before the request stream.
+type priorityHandler struct {
+	mutex sync.Mutex
+
+	largestStreamID quic.StreamID
+	requests        map[quic.StreamID]*requestPriority
+}
+
+func newPriorityHandler() *priorityHandler {
+	return &priorityHandler{
+		largestStreamID: -1,
+		requests:        make(map[quic.StreamID]*requestPriority),
+	}
+}
+
+// Accepted is called when a request stream is accepted.
+func (h *priorityHandler) Accepted(str quic.Stream) {
+	id := str.StreamID()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if id > h.largestStreamID {
+		h.largestStreamID = id
+	}
+	r, ok := h.requests[id]
+	if !ok {
+		r = &requestPriority{}
+		h.requests[id] = r
+	}
+	r.str = str
+}
+
+// SetRequestPriority applies the priority from the request headers.
+// A priority received in a PRIORITY_UPDATE frame takes precedence.
+func (h *priorityHandler) SetRequestPriority(id quic.StreamID, priority quic.StreamPriority) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	r, ok := h.requests[id]
+	if !ok || r.str == nil {
+		return
+	}
+	r.applied = true
+	if r.hasUpdate {
+		r.str.SetPriority(parsePriority(r.update))
+		return
+	}
+	r.str.SetPriority(priority)
+}
+
+// Update is called when a PRIORITY_UPDATE frame is received.
+func (h *priorityHandler) Update(id quic.StreamID, value string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	r, ok := h.requests[id]
+	if !ok {
+		// The request was already completed.
+		if id <= h.largestStreamID {
+			return
+		}
+		if len(h.requests) >= maxPendingPriorityUpdates {
+			return
+		}
+		r = &requestPriority{}
+		h.requests[id] = r
+	}
+	r.update = value
+	r.hasUpdate = true
+	if r.applied {
+		r.str.SetPriority(parsePriority(value))
+	}
+}
+
+// Completed is called when the request was completed.
+func (h *priorityHandler) Completed(id quic.StreamID) {
+	h.mutex.Lock()
+	delete(h.requests, id)
+	h.mutex.Unlock()
+}
+
+// isRequestStreamID says if a stream ID can be used for a request.
+func isRequestStreamID(id quic.StreamID) bool {
+	return id.InitiatedBy() == protocol.PerspectiveClient && id.Type() == protocol.StreamTypeBidi
+}
@@ -0,0 +1,125 @@
+package http3
+
+import (
+	"net/http"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Priorities", func() {
+	Context("parsing", func() {
+		It("uses the default priority", func() {
+			Expect(parsePriority("")).To(Equal(quic.StreamPriority{Urgency: 3}))
+		})
+
+		It("parses the urgency", func() {
+			Expect(parsePriority("u=0")).To(Equal(quic.StreamPriority{Urgency: 0}))
+			Expect(parsePriority("u=7")).To(Equal(quic.StreamPriority{Urgency: 7}))
+		})
+
+		It("parses the incremental flag", func() {
+			Expect(parsePriority("i")).To(Equal(quic.StreamPriority{Urgency: 3, Incremental: true}))
+			Expect(parsePriority("i=?1")).To(Equal(quic.StreamPriority{Urgency: 3, Incremental: true}))
+			Expect(parsePriority("i=?0")).To(Equal(quic.StreamPriority{Urgency: 3}))
+		})
+
+		It("parses both parameters", func() {
+			Expect(parsePriority("u=1, i")).To(Equal(quic.StreamPriority{Urgency: 1, Incremental: true}))
+			Expect(parsePriority("i,u=5")).To(Equal(quic.StreamPriority{Urgency: 5, Incremental: true}))
+		})
+
+		It("ignores invalid values", func() {
+			Expect(parsePriority("u=8")).To(Equal(quic.StreamPriority{Urgency: 3}))
+			Expect(parsePriority("u=-1")).To(Equal(quic.StreamPriority{Urgency: 3}))
+			Expect(parsePriority("u=12")).To(Equal(quic.StreamPriority{Urgency: 3}))
+			Expect(parsePriority("i=1")).To(Equal(quic.StreamPriority{Urgency: 3}))
+		})
+
+		It("ignores unknown members and parameters", func() {
+			Expect(parsePriority("foo=bar, u=2;x=y, baz")).To(Equal(quic.StreamPriority{Urgency: 2}))
+		})
+
+		It("uses the last value if a member is repeated", func() {
+			Expect(parsePriority("u=1, u=4")).To(Equal(quic.StreamPriority{Urgency: 4}))
+		})
+
+		It("parses the Priority header field", func() {
+			hdr := http.Header{}
+			hdr.Add("Priority", "u=2")
+			hdr.Add("Priority", "i")
+			Expect(priorityFromHeader(hdr)).To(Equal(quic.StreamPriority{Urgency: 2, Incremental: true}))
+			Expect(priorityFromHeader(http.Header{})).To(Equal(quic.StreamPriority{Urgency: 3}))
+		})
+	})
+
+	Context("handling", func() {
+		var h *priorityHandler
+
+		newStream := func(id quic.StreamID) *mockquic.MockStream {
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().Return(id).AnyTimes()
+			return str
+		}
+
+		BeforeEach(func() {
+			h = newPriorityHandler()
+		})
+
+		It("applies the priority from the request headers", func() {
+			str := newStream(4)
+			h.Accepted(str)
+			str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 1})
+			h.SetRequestPriority(4, quic.StreamPriority{Urgency: 1})
+		})
+
+		It("applies a PRIORITY_UPDATE received after the request", func() {
+			str := newStream(4)
+			h.Accepted(str)
+			gomock.InOrder(
+				str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 1}),
+				str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 6, Incremental: true}),
+			)
+			h.SetRequestPriority(4, quic.StreamPriority{Urgency: 1})
+			h.Update(4, "u=6, i")
+		})
+
+		It("prefers a PRIORITY_UPDATE received before the request headers", func() {
+			str := newStream(8)
+			h.Update(8, "u=0")
+			h.Accepted(str)
+			str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 0})
+			h.SetRequestPriority(8, quic.StreamPriority{Urgency: 5})
+		})
+
+		It("doesn't apply a PRIORITY_UPDATE before the request headers were parsed", func() {
+			str := newStream(4)
+			h.Accepted(str)
+			h.Update(4, "u=0") // no call to SetPriority expected
+			str.EXPECT().SetPriority(quic.StreamPriority{Urgency: 0})
+			h.SetRequestPriority(4, quic.StreamPriority{Urgency: 5})
+		})
+
+		It("ignores PRIORITY_UPDATE frames for completed requests", func() {
+			str := newStream(4)
+			h.Accepted(str)
+			str.EXPECT().SetPriority(gomock.Any())
+			h.SetRequestPriority(4, quic.StreamPriority{Urgency: 1})
+			h.Completed(4)
+			h.Update(4, "u=0")
+			h.Update(0, "u=0")
+			Expect(h.requests).To(BeEmpty())
+		})
+
+		It("limits the number of buffered PRIORITY_UPDATE frames", func() {
+			for i := 0; i < 2*maxPendingPriorityUpdates; i++ {
+				h.Update(quic.StreamID(4*i), "u=1")
+			}
+			Expect(h.requests).To(HaveLen(maxPendingPriorityUpdates))
+		})
+	})
+})
@@ -19,6 +19,10 @@ type roundTripCloser interface {
 	io.Closer
 }
 
+type priorityUpdater interface {
+	UpdatePriority(req *http.Request, priority string) error
+}
+
 // RoundTripper implements the http.RoundTripper interface
 type RoundTripper struct {
 	mutex sync.Mutex
@@ -158,6 +162,24 @@ func (r *RoundTripper) Close() error {
 	return nil
 }
 
+// UpdatePriority changes the priority of a request that is still in flight.
+// The priority uses the syntax of the Priority header field, e.g. "u=1, i".
+// It is sent to the server in a PRIORITY_UPDATE frame on the control stream.
+func (r *RoundTripper) UpdatePriority(req *http.Request, priority string) error {
+	if req.URL == nil {
+		return errors.New("http3: nil Request.URL")
+	}
+	cl, err := r.getClient(authorityAddr("https", hostnameFromRequest(req)), true)
+	if err != nil {
+		return err
+	}
+	pu, ok := cl.(priorityUpdater)
+	if !ok {
+		return errors.New("http3: client doesn't support priority updates")
+	}
+	return pu.UpdatePriority(req, priority)
+}
+
 func closeRequestBody(req *http.Request) {
 	if req.Body != nil {
 		req.Body.Close()
@@ -17,7 +17,8 @@ import (
 )
 
 type mockClient struct {
-	closed bool
+	closed     bool
+	priorities []string
 }
 
 func (m *mockClient) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -27,8 +28,13 @@ func (m *mockClient) Close() error {
 	m.closed = true
 	return nil
 }
+func (m *mockClient) UpdatePriority(_ *http.Request, priority string) error {
+	m.priorities = append(m.priorities, priority)
+	return nil
+}
 
 var _ roundTripCloser = &mockClient{}
+var _ priorityUpdater = &mockClient{}
 
 type mockBody struct {
 	reader   bytes.Reader
@@ -222,6 +228,21 @@ var _ = Describe("RoundTripper", func() {
 		})
 	})
 
+	Context("updating the priority", func() {
+		It("updates the priority using the client for the host", func() {
+			rt.clients = make(map[string]roundTripCloser)
+			cl := &mockClient{}
+			rt.clients["www.example.org:443"] = cl
+			Expect(rt.UpdatePriority(req1, "u=1")).To(Succeed())
+			Expect(cl.priorities).To(Equal([]string{"u=1"}))
+		})
+
+		It("doesn't create new clients", func() {
+			Expect(rt.UpdatePriority(req1, "u=1")).To(MatchError(ErrNoCachedConn))
+			Expect(rt.clients).To(BeEmpty())
+		})
+	})
+
 	Context("closing", func() {
 		It("closes", func() {
 			rt.clients = make(map[string]roundTripCloser)
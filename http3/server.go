@@ -10,6 +10,7 @@ import (
 	"net"
 	"net/http"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -25,7 +26,13 @@ var (
 	quicListenAddr = quic.ListenAddrEarly
 )
 
-const nextProtoH3 = "h3-29"
+const (
+	nextProtoH3Draft29 = "h3-29"
+	nextProtoH3        = "h3"
+)
+
+// the ALPNs used for HTTP/3, in order of preference
+var nextProtosH3 = []string{nextProtoH3, nextProtoH3Draft29}
 
 // contextKey is a value for use with context.WithValue. It's used as
 // a pointer so it fits in an interface{} without allocation.
@@ -123,7 +130,7 @@ func (s *Server) serveImpl(tlsConf *tls.Config, conn net.PacketConn) error {
 		tlsConf = tlsConf.Clone()
 	}
 	// Replace existing ALPNs by H3
-	tlsConf.NextProtos = []string{nextProtoH3}
+	tlsConf.NextProtos = nextProtosH3
 	if tlsConf.GetConfigForClient != nil {
 		getConfigForClient := tlsConf.GetConfigForClient
 		tlsConf.GetConfigForClient = func(ch *tls.ClientHelloInfo) (*tls.Config, error) {
@@ -132,7 +139,7 @@ func (s *Server) serveImpl(tlsConf *tls.Config, conn net.PacketConn) error {
 				return conf, err
 			}
 			conf = conf.Clone()
-			conf.NextProtos = []string{nextProtoH3}
+			conf.NextProtos = nextProtosH3
 			return conf, nil
 		}
 	}
@@ -178,8 +185,8 @@ func (s *Server) removeListener(l *quic.EarlyListener) {
 }
 
 func (s *Server) handleConn(sess quic.EarlySession) {
-	// TODO: accept control streams
 	decoder := qpack.NewDecoder(nil)
+	priorities := newPriorityHandler()
 
 	// send a SETTINGS frame
 	str, err := sess.OpenUniStream()
@@ -191,6 +198,8 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 	(&settingsFrame{}).Write(buf)
 	str.Write(buf.Bytes())
 
+	go s.handleUnidirectionalStreams(sess, priorities)
+
 	// Process all requests immediately.
 	// It's the client's responsibility to decide which requests are eligible for 0-RTT.
 	for {
@@ -199,8 +208,10 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 			s.logger.Debugf("Accepting stream failed: %s", err)
 			return
 		}
+		priorities.Accepted(str)
 		go func() {
-			rerr := s.handleRequest(sess, str, decoder, func() {
+			defer priorities.Completed(str.StreamID())
+			rerr := s.handleRequest(sess, str, decoder, priorities, func() {
 				sess.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
 			})
 			if rerr.err != nil || rerr.streamErr != 0 || rerr.connErr != 0 {
@@ -222,6 +233,77 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 	}
 }
 
+func (s *Server) handleUnidirectionalStreams(sess quic.EarlySession, priorities *priorityHandler) {
+	for {
+		str, err := sess.AcceptUniStream(context.Background())
+		if err != nil {
+			s.logger.Debugf("Accepting unidirectional stream failed: %s", err)
+			return
+		}
+
+		go func(str quic.ReceiveStream) {
+			streamType, err := utils.ReadVarInt(&byteReaderImpl{str})
+			if err != nil {
+				s.logger.Debugf("Reading stream type on stream %d failed: %s", str.StreamID(), err)
+				return
+			}
+			switch streamType {
+			case streamTypeControlStream:
+			case streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream:
+				// Our QPACK implementation doesn't use the dynamic table yet.
+				return
+			case streamTypePushStream:
+				// only the server can push
+				sess.CloseWithError(quic.ErrorCode(errorStreamCreationError), "")
+				return
+			default:
+				str.CancelRead(quic.ErrorCode(errorStreamCreationError))
+				return
+			}
+			s.handleControlStream(sess, str, priorities)
+		}(str)
+	}
+}
+
+func (s *Server) handleControlStream(sess quic.EarlySession, str quic.ReceiveStream, priorities *priorityHandler) {
+	f, err := parseNextFrame(str)
+	if err != nil {
+		sess.CloseWithError(quic.ErrorCode(errorFrameError), "")
+		return
+	}
+	if _, ok := f.(*settingsFrame); !ok {
+		sess.CloseWithError(quic.ErrorCode(errorMissingSettings), "")
+		return
+	}
+	for {
+		f, err := parseNextFrame(str)
+		if err != nil {
+			if err == io.EOF {
+				sess.CloseWithError(quic.ErrorCode(errorClosedCriticalStream), "")
+			} else {
+				sess.CloseWithError(quic.ErrorCode(errorFrameError), "")
+			}
+			return
+		}
+		switch f := f.(type) {
+		case *priorityUpdateFrame:
+			// We don't support server push, so there's no need to prioritize pushes.
+			if f.IsPush {
+				continue
+			}
+			id := quic.StreamID(f.PrioritizedElementID)
+			if !isRequestStreamID(id) {
+				sess.CloseWithError(quic.ErrorCode(errorIDError), "")
+				return
+			}
+			priorities.Update(id, f.PriorityFieldValue)
+		default:
+			sess.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
+			return
+		}
+	}
+}
+
 func (s *Server) maxHeaderBytes() uint64 {
 	if s.Server.MaxHeaderBytes <= 0 {
 		return http.DefaultMaxHeaderBytes
@@ -229,7 +311,7 @@ func (s *Server) maxHeaderBytes() uint64 {
 	return uint64(s.Server.MaxHeaderBytes)
 }
 
-func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpack.Decoder, onFrameError func()) requestError {
+func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpack.Decoder, priorities *priorityHandler, onFrameError func()) requestError {
 	frame, err := parseNextFrame(str)
 	if err != nil {
 		return newStreamError(errorRequestIncomplete, err)
@@ -256,6 +338,8 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 		return newStreamError(errorGeneralProtocolError, err)
 	}
 
+	priorities.SetRequestPriority(str.StreamID(), priorityFromHeader(req.Header))
+
 	req.RemoteAddr = sess.RemoteAddr().String()
 	req.Body = newRequestBody(str, onFrameError)
 
@@ -328,7 +412,7 @@ func (s *Server) CloseGracefully(timeout time.Duration) error {
 
 // SetQuicHeaders can be used to set the proper headers that announce that this server supports QUIC.
 // The values that are set depend on the port information from s.Server.Addr, and currently look like this (if Addr has port 443):
-//  Alt-Svc: quic=":443"; ma=2592000; v="33,32,31,30"
+//  Alt-Svc: h3=":443"; ma=2592000,h3-29=":443"; ma=2592000
 func (s *Server) SetQuicHeaders(hdr http.Header) error {
 	port := atomic.LoadUint32(&s.port)
 
@@ -346,7 +430,11 @@ func (s *Server) SetQuicHeaders(hdr http.Header) error {
 		atomic.StoreUint32(&s.port, port)
 	}
 
-	hdr.Add("Alt-Svc", fmt.Sprintf(`%s=":%d"; ma=2592000`, nextProtoH3, port))
+	altSvc := make([]string, 0, len(nextProtosH3))
+	for _, proto := range nextProtosH3 {
+		altSvc = append(altSvc, fmt.Sprintf(`%s=":%d"; ma=2592000`, proto, port))
+	}
+	hdr.Add("Alt-Svc", strings.Join(altSvc, ","))
 
 	return nil
 }
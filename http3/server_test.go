@@ -5,7 +5,6 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
-	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -104,6 +103,8 @@ var _ = Describe("Server", func() {
 
 			qpackDecoder = qpack.NewDecoder(nil)
 			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
+			str.EXPECT().SetPriority(gomock.Any()).AnyTimes()
 
 			sess = mockquic.NewMockEarlySession(mockCtrl)
 			addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
@@ -124,7 +125,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			Expect(s.handleRequest(sess, str, qpackDecoder, nil)).To(Equal(requestError{}))
+			Expect(s.handleRequest(sess, str, qpackDecoder, newPriorityHandler(), nil)).To(Equal(requestError{}))
 			var req *http.Request
 			Eventually(requestChan).Should(Receive(&req))
 			Expect(req.Host).To(Equal("www.example.com"))
@@ -143,7 +144,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, qpackDecoder, newPriorityHandler(), nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
@@ -162,7 +163,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, qpackDecoder, newPriorityHandler(), nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"500"}))
@@ -179,6 +180,7 @@ var _ = Describe("Server", func() {
 				sess.EXPECT().OpenUniStream().Return(controlStr, nil)
 				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil)
 				sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
+				sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
 				sess.EXPECT().RemoteAddr().Return(addr).AnyTimes()
 				sess.EXPECT().LocalAddr().AnyTimes()
 			})
@@ -307,7 +309,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.ErrorCode(errorEarlyResponse))
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, qpackDecoder, newPriorityHandler(), nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -330,7 +332,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.ErrorCode(errorEarlyResponse))
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, qpackDecoder, newPriorityHandler(), nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -341,12 +343,12 @@ var _ = Describe("Server", func() {
 
 		getExpectedHeader := func() http.Header {
 			return http.Header{
-				"Alt-Svc": {fmt.Sprintf(`%s=":443"; ma=2592000`, nextProtoH3)},
+				"Alt-Svc": {`h3=":443"; ma=2592000,h3-29=":443"; ma=2592000`},
 			}
 		}
 
 		BeforeEach(func() {
-			Expect(getExpectedHeader()).To(Equal(http.Header{"Alt-Svc": {nextProtoH3 + `=":443"; ma=2592000`}}))
+			Expect(getExpectedHeader()).To(Equal(http.Header{"Alt-Svc": {`h3=":443"; ma=2592000,h3-29=":443"; ma=2592000`}}))
 			expected = getExpectedHeader()
 		})
 
@@ -394,6 +396,111 @@ var _ = Describe("Server", func() {
 		Expect((&Server{}).Close()).To(Succeed())
 	})
 
+	Context("unidirectional streams", func() {
+		var (
+			sess       *mockquic.MockEarlySession
+			priorities *priorityHandler
+		)
+
+		BeforeEach(func() {
+			sess = mockquic.NewMockEarlySession(mockCtrl)
+			priorities = newPriorityHandler()
+		})
+
+		acceptUniStream := func(data []byte) *mockquic.MockStream {
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
+			buf := bytes.NewBuffer(data)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(str, nil)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done"))
+			return str
+		}
+
+		controlStream := func(frames ...interface{ Write(*bytes.Buffer) }) []byte {
+			b := &bytes.Buffer{}
+			utils.WriteVarInt(b, streamTypeControlStream)
+			for _, f := range frames {
+				f.Write(b)
+			}
+			return b.Bytes()
+		}
+
+		It("resets unknown unidirectional streams", func() {
+			b := &bytes.Buffer{}
+			utils.WriteVarInt(b, 0x54)
+			str := acceptUniStream(b.Bytes())
+			done := make(chan struct{})
+			str.EXPECT().CancelRead(quic.ErrorCode(errorStreamCreationError)).Do(func(quic.ErrorCode) { close(done) })
+			s.handleUnidirectionalStreams(sess, priorities)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes the connection when the client opens a push stream", func() {
+			b := &bytes.Buffer{}
+			utils.WriteVarInt(b, streamTypePushStream)
+			acceptUniStream(b.Bytes())
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorStreamCreationError), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+			s.handleUnidirectionalStreams(sess, priorities)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes the connection if the first frame on the control stream is not a SETTINGS frame", func() {
+			acceptUniStream(controlStream(&dataFrame{Length: 0}))
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorMissingSettings), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+			s.handleUnidirectionalStreams(sess, priorities)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes the connection when the control stream is closed", func() {
+			acceptUniStream(controlStream(&settingsFrame{}))
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+			s.handleUnidirectionalStreams(sess, priorities)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes the connection when receiving an unexpected frame on the control stream", func() {
+			acceptUniStream(controlStream(&settingsFrame{}, &headersFrame{Length: 0}))
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorFrameUnexpected), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+			s.handleUnidirectionalStreams(sess, priorities)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("applies PRIORITY_UPDATE frames", func() {
+			reqStr := mockquic.NewMockStream(mockCtrl)
+			reqStr.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+			priorities.Accepted(reqStr)
+			reqStr.EXPECT().SetPriority(defaultPriority)
+			priorities.SetRequestPriority(4, defaultPriority)
+
+			acceptUniStream(controlStream(
+				&settingsFrame{},
+				&priorityUpdateFrame{IsPush: true, PrioritizedElementID: 1, PriorityFieldValue: "u=7"},
+				&priorityUpdateFrame{PrioritizedElementID: 4, PriorityFieldValue: "u=0, i"},
+			))
+			done := make(chan struct{})
+			reqStr.EXPECT().SetPriority(quic.StreamPriority{Urgency: 0, Incremental: true})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+			s.handleUnidirectionalStreams(sess, priorities)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes the connection when receiving a PRIORITY_UPDATE frame for an invalid stream ID", func() {
+			acceptUniStream(controlStream(
+				&settingsFrame{},
+				&priorityUpdateFrame{PrioritizedElementID: 2, PriorityFieldValue: "u=1"},
+			))
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+			s.handleUnidirectionalStreams(sess, priorities)
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
 	It("errors when ListenAndServe is called after Close", func() {
 		serv := &Server{Server: &http.Server{}}
 		Expect(serv.Close()).To(Succeed())
@@ -515,7 +622,7 @@ var _ = Describe("Server", func() {
 			}
 			s.TLSConfig = tlsConf
 			Expect(s.ListenAndServe()).To(HaveOccurred())
-			Expect(receivedConf.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(receivedConf.NextProtos).To(Equal(nextProtosH3))
 			// make sure the original tls.Config was not modified
 			Expect(tlsConf.NextProtos).To(Equal([]string{"foo", "bar"}))
 		})
@@ -527,7 +634,7 @@ var _ = Describe("Server", func() {
 				return nil, errors.New("listen err")
 			}
 			Expect(s.ListenAndServe()).To(HaveOccurred())
-			Expect(receivedConf.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(receivedConf.NextProtos).To(Equal(nextProtosH3))
 		})
 
 		It("sets the ALPN for tls.Configs returned by the tls.GetConfigForClient", func() {
@@ -547,7 +654,7 @@ var _ = Describe("Server", func() {
 			// check that the config used by QUIC uses the h3 ALPN
 			conf, err := receivedConf.GetConfigForClient(&tls.ClientHelloInfo{})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(conf.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(conf.NextProtos).To(Equal(nextProtosH3))
 			// check that the original config was not modified
 			conf, err = tlsConf.GetConfigForClient(&tls.ClientHelloInfo{})
 			Expect(err).ToNot(HaveOccurred())
@@ -572,7 +679,7 @@ var _ = Describe("Server", func() {
 			// check that the config used by QUIC uses the h3 ALPN
 			conf, err := receivedConf.GetConfigForClient(&tls.ClientHelloInfo{})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(conf.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(conf.NextProtos).To(Equal(nextProtosH3))
 			// check that the original config was not modified
 			conf, err = tlsConf.GetConfigForClient(&tls.ClientHelloInfo{})
 			Expect(err).ToNot(HaveOccurred())
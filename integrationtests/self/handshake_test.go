@@ -405,6 +405,39 @@ var _ = Describe("Handshake tests", func() {
 		})
 	})
 
+	Context("greasing the QUIC bit", func() {
+		It("transfers data when both endpoints grease the QUIC bit", func() {
+			serverConfig.GreaseQUICBit = true
+			ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+
+			go func() {
+				defer GinkgoRecover()
+				sess, err := ln.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				str, err := sess.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				defer str.Close()
+				_, err = str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+			}()
+
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfigForClient(&quic.Config{GreaseQUICBit: true}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRData))
+			Expect(sess.CloseWithError(0, "")).To(Succeed())
+		})
+	})
+
 	Context("using tokens", func() {
 		It("uses tokens provided in NEW_TOKEN frames", func() {
 			tokenChan := make(chan *quic.Token, 100)
@@ -87,6 +87,7 @@ var _ = Describe("0-RTT", func() {
 					Expect(err).ToNot(HaveOccurred())
 					Expect(data).To(Equal(testdata))
 					Expect(sess.ConnectionState().Used0RTT).To(Equal(expect0RTT))
+					Expect(sess.Received0RTT()).To(Equal(expect0RTT))
 					close(done)
 				}()
 
@@ -362,6 +363,68 @@ var _ = Describe("0-RTT", func() {
 				Expect(num0RTT).ToNot(BeZero())
 			})
 
+			It("rejects 0-RTT when the application doesn't allow it", func() {
+				var numCalls int32 // to be used as an atomic
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					getTLSConfig(),
+					getQuicConfigForServer(&quic.Config{
+						Versions:    []protocol.VersionNumber{version},
+						AcceptToken: func(_ net.Addr, _ *quic.Token) bool { return true },
+						Allow0RTT: func(net.Addr) bool {
+							atomic.AddInt32(&numCalls, 1)
+							return false
+						},
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				proxy, num0RTTPackets := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+
+				clientConf := dialAndReceiveSessionTicket(ln, proxy.LocalPort())
+				transfer0RTTData(ln, proxy.LocalPort(), clientConf, PRData, false)
+				Expect(atomic.LoadInt32(&numCalls)).To(BeEquivalentTo(1))
+
+				// The client should send 0-RTT packets, but the server doesn't process them.
+				num0RTT := atomic.LoadUint32(num0RTTPackets)
+				fmt.Fprintf(GinkgoWriter, "Sent %d 0-RTT packets.", num0RTT)
+				Expect(num0RTT).ToNot(BeZero())
+			})
+
+			It("rejects 0-RTT when a session ticket is used a second time", func() {
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					getTLSConfig(),
+					getQuicConfigForServer(&quic.Config{
+						Versions:    []protocol.VersionNumber{version},
+						AcceptToken: func(_ net.Addr, _ *quic.Token) bool { return true },
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				proxy, _ := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+
+				clientConf := dialAndReceiveSessionTicket(ln, proxy.LocalPort())
+				// save the session ticket, so it can be replayed
+				cache := clientConf.ClientSessionCache.(*clientSessionCache)
+				cache.mutex.Lock()
+				sessions := make(map[string]*tls.ClientSessionState)
+				for key, cs := range cache.cache {
+					sessions[key] = cs
+				}
+				cache.mutex.Unlock()
+
+				transfer0RTTData(ln, proxy.LocalPort(), clientConf, PRData, true)
+				cache.mutex.Lock()
+				cache.cache = sessions
+				cache.mutex.Unlock()
+				transfer0RTTData(ln, proxy.LocalPort(), clientConf, PRData, false)
+			})
+
 			It("rejects 0-RTT when the ALPN changed", func() {
 				tlsConf := getTLSConfig()
 				ln, err := quic.ListenAddrEarly(
@@ -8,8 +8,10 @@ import (
 
 	"github.com/lucas-clemente/quic-go/logging"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
 	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
@@ -27,12 +29,16 @@ type Token struct {
 	IsRetryToken bool
 	RemoteAddr   string
 	SentTime     time.Time
+	// ApplicationData is the data returned by Config.GetTokenData when the token was issued.
+	// It is only set for tokens sent in a NEW_TOKEN frame.
+	ApplicationData []byte
 }
 
 // A ClientToken is a token received by the client.
 // It can be used to skip address validation on future connection attempts.
 type ClientToken struct {
-	data []byte
+	data    []byte
+	rcvTime time.Time
 }
 
 type TokenStore interface {
@@ -46,6 +52,20 @@ type TokenStore interface {
 	Put(key string, token *ClientToken)
 }
 
+// A ConnectionIDGenerator generates connection IDs.
+type ConnectionIDGenerator interface {
+	// GenerateConnectionID generates a new connection ID.
+	// Connection IDs must not allow an observer to link them to each other.
+	GenerateConnectionID() ([]byte, error)
+	// ConnectionIDLen returns the length of the connection IDs generated by this generator.
+	// All connection IDs must have the same length.
+	ConnectionIDLen() int
+}
+
+// An AntiReplayStore protects servers against replays of 0-RTT data.
+// It makes sure that every session ticket is only used once for 0-RTT.
+type AntiReplayStore = handshake.AntiReplayStore
+
 // An ErrorCode is an application-defined error code.
 // Valid values range between 0 and MAX_UINT62.
 type ErrorCode = protocol.ApplicationErrorCode
@@ -77,6 +97,8 @@ type ReceiveStream interface {
 	// Read will unblock immediately, and future Read calls will fail.
 	// When called multiple times or after reading the io.EOF it is a no-op.
 	CancelRead(ErrorCode)
+	// Stats returns a snapshot of the state of the stream.
+	Stats() StreamStats
 	// SetReadDeadline sets the deadline for future Read calls and
 	// any currently-blocked Read call.
 	// A zero value for t means Read will not time out.
@@ -111,12 +133,43 @@ type SendStream interface {
 	// cancels the read-side of their stream.
 	// Warning: This API should not be considered stable and might change soon.
 	Context() context.Context
+	// Stats returns a snapshot of the state of the stream.
+	Stats() StreamStats
 	// SetWriteDeadline sets the deadline for future Write calls
 	// and any currently-blocked Write call.
 	// Even if write times out, it may return n > 0, indicating that
 	// some of the data was successfully written.
 	// A zero value for t means Write will not time out.
 	SetWriteDeadline(t time.Time) error
+	// SetPriority sets the priority of the stream.
+	// When multiple streams have data to send, the StreamScheduler uses the priority
+	// to decide which stream is allowed to send first.
+	// New streams have an urgency of 3 and are incremental.
+	SetPriority(StreamPriority)
+}
+
+// A StreamPriority is the priority of a stream.
+type StreamPriority struct {
+	// The Urgency ranges from 0 to 7. Streams with a lower value are more urgent.
+	// Values larger than 7 are treated as 7.
+	Urgency uint8
+	// Incremental streams of the same urgency share the bandwidth.
+	// Non-incremental streams are sent one after the other, in order of their stream ID.
+	Incremental bool
+}
+
+// A StreamScheduler decides which stream is allowed to send data next.
+// It is used for a single connection, and its methods are never called concurrently.
+type StreamScheduler interface {
+	// Push is called when a stream has data to send.
+	// It is called again for a stream that was already pushed if its priority changes.
+	Push(StreamID, StreamPriority)
+	// Pop removes the stream that is allowed to send data next from the scheduler.
+	// If the stream still has data to send after filling a packet, it is pushed again.
+	// It returns false if the scheduler is empty.
+	Pop() (StreamID, bool)
+	// Len returns the number of streams in the scheduler.
+	Len() int
 }
 
 // StreamError is returned by Read and Write when the peer cancels the stream.
@@ -128,6 +181,71 @@ type StreamError interface {
 
 type ConnectionState = handshake.ConnectionState
 
+// A ByteCount is a number of bytes.
+type ByteCount = protocol.ByteCount
+
+// TransportParameters are the QUIC transport parameters sent during the handshake.
+type TransportParameters = wire.TransportParameters
+
+// ConnectionStats is a snapshot of the transport statistics of a QUIC connection.
+type ConnectionStats struct {
+	// The RTT estimates, as defined in draft-ietf-quic-recovery, section 5.
+	MinRTT      time.Duration
+	LatestRTT   time.Duration
+	SmoothedRTT time.Duration
+	RTTVariance time.Duration
+
+	CongestionWindow ByteCount
+	BytesInFlight    ByteCount
+
+	// Counters for QUIC packets. Coalesced packets are counted individually.
+	// Packets containing a CONNECTION_CLOSE frame are not counted as sent.
+	PacketsSent     uint64
+	BytesSent       ByteCount
+	PacketsReceived uint64
+	BytesReceived   ByteCount
+	PacketsLost     uint64
+	BytesLost       ByteCount
+	// The number of packets whose frames were retransmitted.
+	// This includes lost packets, as well as packets retransmitted in probe packets.
+	PacketsRetransmitted uint64
+	BytesRetransmitted   ByteCount
+
+	// The time it took to complete the handshake. It is 0 if the handshake hasn't completed yet.
+	HandshakeDuration time.Duration
+	// The transport parameters sent by the peer. It is nil if they haven't been received yet.
+	PeerTransportParameters *TransportParameters
+}
+
+// StreamStats is a snapshot of the state of a stream.
+// For unidirectional streams, only the fields for the respective direction are set.
+type StreamStats struct {
+	// The number of bytes accepted by Write.
+	BytesWritten ByteCount
+	// The number of bytes sent in STREAM frames, not counting retransmissions.
+	BytesSent ByteCount
+	// The number of bytes sent in STREAM frames that were acknowledged by the peer.
+	BytesAcked ByteCount
+	// The number of bytes retransmitted in STREAM frames.
+	BytesRetransmitted ByteCount
+	// The number of bytes that can be sent before the stream is blocked by stream-level flow control.
+	// Connection-level flow control might impose a lower limit.
+	SendWindow ByteCount
+	// Set if the stream couldn't send any data the last time it tried to, because it was blocked by flow control.
+	// If both limits were reached, only BlockedOnStreamFlowControl is set.
+	BlockedOnStreamFlowControl     bool
+	BlockedOnConnectionFlowControl bool
+
+	// The number of bytes received in STREAM frames, not counting duplicate data.
+	BytesReceived ByteCount
+	// The number of bytes returned by Read.
+	BytesRead ByteCount
+	// The number of bytes buffered that can't be read yet, since data at a lower offset is missing.
+	BytesBufferedOutOfOrder ByteCount
+	// The number of bytes the peer is allowed to send beyond the highest offset received so far.
+	ReceiveWindow ByteCount
+}
+
 // A Session is a QUIC connection between two peers.
 type Session interface {
 	// AcceptStream returns the next stream opened by the peer, blocking until one is available.
@@ -174,6 +292,32 @@ type Session interface {
 	// It blocks until the handshake completes.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
+
+	// Stats returns a snapshot of the transport statistics of the connection.
+	// It can also be called after the connection was closed.
+	Stats() ConnectionStats
+	// InitiateKeyUpdate updates the 1-RTT keys, see section 6 of RFC 9001.
+	// Keys can only be updated after the handshake was confirmed,
+	// and once the peer acknowledged a packet sent with the current keys.
+	InitiateKeyUpdate() error
+
+	// SendMessage sends a message as a datagram.
+	// See https://datatracker.ietf.org/doc/draft-pauly-quic-datagram/.
+	// It fails if datagram support wasn't enabled in the Config, or if the peer doesn't support datagrams.
+	// Datagrams are subject to congestion control, but they are never retransmitted.
+	SendMessage([]byte) error
+	// ReceiveMessage gets a message received in a datagram.
+	// See https://datatracker.ietf.org/doc/draft-pauly-quic-datagram/.
+	ReceiveMessage(context.Context) ([]byte, error)
+
+	// MigrateTo migrates the connection to a new local address.
+	// It sends a PATH_CHALLENGE on the new path and blocks until the peer responds,
+	// or until path validation fails. Once the path is validated, all packets are sent on the new path.
+	// Only clients can migrate, and only after the handshake has been confirmed.
+	// The application owns the PacketConn: it is neither closed when the session is closed,
+	// nor when the migration fails.
+	// Warning: This API should not be considered stable and might change soon.
+	MigrateTo(net.PacketConn) error
 }
 
 // An EarlySession is a session that is handshaking.
@@ -187,6 +331,12 @@ type EarlySession interface {
 	// Data sent before completion of the handshake is encrypted with 1-RTT keys.
 	// Note that the client's identity hasn't been verified yet.
 	HandshakeComplete() context.Context
+	// Received0RTT says if data was received in 0-RTT packets.
+	// Every session ticket is only accepted once for 0-RTT, but 0-RTT data is not forward secure,
+	// and the replay protection only holds if all servers sharing the session ticket keys use the same AntiReplayStore.
+	// Applications should only act on 0-RTT data if this is safe, see section 8 of RFC 8446.
+	// It is always false for the client.
+	Received0RTT() bool
 }
 
 // Config contains all configuration data needed for a QUIC server or client.
@@ -202,6 +352,14 @@ type Config struct {
 	// If used for a server, or dialing on a packet conn, a 4 byte connection ID will be used.
 	// When dialing on a packet conn, the ConnectionIDLength value must be the same for every Dial call.
 	ConnectionIDLength int
+	// ConnectionIDGenerator generates the connection IDs used by the server.
+	// It is used for the connection ID chosen during the handshake, for the Retry,
+	// and for the connection IDs sent in NEW_CONNECTION_ID frames.
+	// This allows encoding routing information into connection IDs, e.g. using QUIC-LB (see the quiclb package).
+	// If ConnectionIDLength is not set, the length of the connection IDs is taken from the generator.
+	// If not set, random connection IDs of ConnectionIDLength bytes are used.
+	// This option is only valid for the server.
+	ConnectionIDGenerator ConnectionIDGenerator
 	// HandshakeTimeout is the maximum duration that the cryptographic handshake may take.
 	// If the timeout is exceeded, the connection is closed.
 	// If this value is zero, the timeout is set to 10 seconds.
@@ -212,6 +370,16 @@ type Config struct {
 	// If the timeout is exceeded, the connection is closed.
 	// If this value is zero, the timeout is set to 30 seconds.
 	MaxIdleTimeout time.Duration
+	// Allow0RTT is called when a client attempts 0-RTT.
+	// It decides if 0-RTT is accepted on this connection.
+	// If not set, 0-RTT is accepted.
+	// This option is only valid for the server, and only used when listening for early sessions.
+	Allow0RTT func(clientAddr net.Addr) bool
+	// AntiReplayStore makes sure that every session ticket is only used once for 0-RTT.
+	// Servers that share session ticket keys need to use a shared store.
+	// If not set, an in-memory store is used.
+	// This option is only valid for the server.
+	AntiReplayStore AntiReplayStore
 	// AcceptToken determines if a Token is accepted.
 	// It is called with token = nil if the client didn't send a token.
 	// If not set, a default verification function is used:
@@ -220,6 +388,44 @@ type Config struct {
 	//   * else, that it was issued within the last 24 hours.
 	// This option is only valid for the server.
 	AcceptToken func(clientAddr net.Addr, token *Token) bool
+	// TokenKeys returns the keys used to protect Retry tokens and tokens sent in NEW_TOKEN frames.
+	// Servers behind a load balancer need to use the same keys, so that tokens issued by one server
+	// are accepted by all of them.
+	// New tokens are protected with the first key, and all keys are tried when decoding a token.
+	// This allows rotating keys: a new key is added in the first position,
+	// and the old key is removed once all tokens issued with it have expired.
+	// It is called every time a token is issued or decoded.
+	// If not set, a random key is generated when the server is started.
+	// This option is only valid for the server.
+	TokenKeys func() [][]byte
+	// GetTokenData returns application data that is embedded in the token sent in the NEW_TOKEN frame.
+	// When the client uses this token on a later connection, the data is available
+	// to AcceptToken as Token.ApplicationData.
+	// The data is encrypted, but it increases the size of the token, and therefore of the client's Initial packets.
+	// This option is only valid for the server.
+	GetTokenData func(clientAddr net.Addr) []byte
+	// MaxSessions is the maximum number of concurrent sessions that the server accepts.
+	// A session counts towards this limit from the moment the first Initial packet is processed until it is closed.
+	// When the limit is reached, new connection attempts are refused with a CONNECTION_REFUSED error.
+	// If not set, the number of sessions is not limited.
+	// This option is only valid for the server.
+	MaxSessions int
+	// MaxHandshakes is the maximum number of handshakes that the server performs concurrently.
+	// When the limit is reached, new connection attempts are refused with a CONNECTION_REFUSED error.
+	// If not set, the number of handshakes is not limited.
+	// This option is only valid for the server.
+	MaxHandshakes int
+	// RetryThreshold is the number of concurrent handshakes at which the server starts validating the
+	// client's address by sending a Retry, for clients that didn't present a valid token.
+	// This happens in addition to the validation requested by AcceptToken.
+	// If not set, only AcceptToken decides if a Retry is sent.
+	// This option is only valid for the server.
+	RetryThreshold int
+	// HandshakeRateLimit limits the rate at which handshakes are started by clients from the same network.
+	// Connection attempts exceeding the limit are refused with a CONNECTION_REFUSED error.
+	// If not set, handshakes are not rate limited.
+	// This option is only valid for the server.
+	HandshakeRateLimit *HandshakeRateLimit
 	// The TokenStore stores tokens received from the server.
 	// Tokens are used to skip address validation on future connection attempts.
 	// The key used to store tokens is the ServerName from the tls.Config, if set
@@ -244,12 +450,73 @@ type Config struct {
 	StatelessResetKey []byte
 	// KeepAlive defines whether this peer will periodically send a packet to keep the connection alive.
 	KeepAlive bool
+	// KeyUpdateInterval is the number of packets sent or received with the current 1-RTT keys
+	// after which a key update is initiated.
+	// Key updates are initiated before the confidentiality limit of the AEAD is reached,
+	// even if a larger value is configured.
+	// If not set, keys are updated every 100000 packets.
+	KeyUpdateInterval uint64
+	// PreferredAddress is the address that the server asks clients to migrate to after the handshake.
+	// Only valid for the server.
+	// Packets sent to the preferred address must be received on the same net.PacketConn,
+	// e.g. by listening on the unspecified address.
+	PreferredAddress *PreferredAddress
+	// EnableDatagrams enables support for unreliable datagrams (DATAGRAM frames).
+	// See https://datatracker.ietf.org/doc/draft-pauly-quic-datagram/.
+	// Datagrams can only be sent if the peer enabled datagram support as well.
+	EnableDatagrams bool
+	// DisablePathMTUDiscovery disables Path MTU Discovery (RFC 8899).
+	// Packets will then be at most 1252 (IPv4) / 1232 (IPv6) bytes in size.
+	// Path MTU Discovery is only available on Linux, and requires setting the DF bit on the socket.
+	DisablePathMTUDiscovery bool
+	// MaxUDPPayloadSize is the size of the largest UDP payload that we're willing to receive.
+	// It is sent in the max_udp_payload_size transport parameter, and limits the packet size
+	// that the peer's Path MTU Discovery can find, e.g. use 8972 bytes on links with a 9000 byte MTU.
+	// Every packet received on the PacketConn uses a buffer of this size.
+	// If zero, it defaults to 1452 bytes, which fits an Ethernet MTU of 1500 bytes.
+	// Values below 1200 bytes are raised to 1200 bytes, values above 8972 bytes are lowered to 8972 bytes.
+	MaxUDPPayloadSize uint64
+	// GreaseQUICBit enables greasing of the QUIC bit, see RFC 9287.
+	// The grease_quic_bit transport parameter is sent, and packets that have the QUIC bit cleared are accepted.
+	// Independent of this option, the QUIC bit is greased on packets sent if the peer sent this transport parameter.
+	GreaseQUICBit bool
+	// CongestionControl creates the congestion controller for a new connection.
+	// It is called once per connection, with the RTT statistics of that connection.
+	// If nil, Cubic / NewReno is used.
+	CongestionControl func(rttStats *congestion.RTTStats) congestion.SendAlgorithmWithDebugInfos
+	// StreamScheduler creates the stream scheduler for a new connection.
+	// If nil, streams are scheduled by strict priority, see NewStrictPriorityScheduler.
+	StreamScheduler func() StreamScheduler
 	// QUIC Event Tracer.
 	// Warning: Experimental. This API should not be considered stable and will change soon.
 	QuicTracer quictrace.Tracer
 	Tracer     logging.Tracer
 }
 
+// A HandshakeRateLimit limits the rate at which new handshakes are started.
+// Client addresses are grouped by their network prefix, and the limit applies to each prefix separately.
+type HandshakeRateLimit struct {
+	// Rate is the number of handshakes per second that clients from the same prefix may start.
+	Rate float64
+	// Burst is the number of handshakes that clients from the same prefix may start at once.
+	// If not set, it defaults to Rate, rounded up.
+	Burst int
+	// IPv4PrefixLen is the length of the prefix used for grouping IPv4 addresses.
+	// If not set, it defaults to 24 bits.
+	IPv4PrefixLen int
+	// IPv6PrefixLen is the length of the prefix used for grouping IPv6 addresses.
+	// If not set, it defaults to 56 bits.
+	IPv6PrefixLen int
+}
+
+// A PreferredAddress is an address that a server asks clients to migrate to after the handshake.
+// At least one of IPv4 and IPv6 should be set.
+// Clients only migrate to an address of the same address family they used for the handshake.
+type PreferredAddress struct {
+	IPv4 *net.UDPAddr
+	IPv6 *net.UDPAddr
+}
+
 // A Listener for incoming QUIC connections
 type Listener interface {
 	// Close the server. All active sessions will be closed.
@@ -258,6 +525,13 @@ type Listener interface {
 	Addr() net.Addr
 	// Accept returns new sessions. It should be called in a loop.
 	Accept(context.Context) (Session, error)
+	// Shutdown gracefully shuts down the server.
+	// New connection attempts are refused, while existing sessions keep running.
+	// It returns once all sessions have been closed.
+	// If the context expires before that, the remaining sessions are closed with the given error code and reason.
+	Shutdown(ctx context.Context, code ErrorCode, desc string) error
+	// Stats returns counters that can be used for monitoring the server.
+	Stats() ListenerStats
 }
 
 // An EarlyListener listens for incoming QUIC connections,
@@ -269,4 +543,27 @@ type EarlyListener interface {
 	Addr() net.Addr
 	// Accept returns new early sessions. It should be called in a loop.
 	Accept(context.Context) (EarlySession, error)
+	// Shutdown gracefully shuts down the server.
+	// New connection attempts are refused, while existing sessions keep running.
+	// It returns once all sessions have been closed.
+	// If the context expires before that, the remaining sessions are closed with the given error code and reason.
+	Shutdown(ctx context.Context, code ErrorCode, desc string) error
+	// Stats returns counters that can be used for monitoring the server.
+	Stats() ListenerStats
+}
+
+// ListenerStats contains counters that can be used for monitoring a server.
+type ListenerStats struct {
+	// Sessions is the number of sessions that are currently open, including those still in the handshake.
+	Sessions int
+	// Handshakes is the number of handshakes currently in progress.
+	Handshakes int
+	// RetriesSent is the number of Retry packets sent.
+	RetriesSent uint64
+	// Refused is the number of connection attempts refused because the server was busy,
+	// i.e. because the accept queue was full or because MaxSessions or MaxHandshakes was reached,
+	// or because the server was shutting down.
+	Refused uint64
+	// RateLimited is the number of connection attempts refused because of the HandshakeRateLimit.
+	RateLimited uint64
 }
@@ -8,16 +8,20 @@ import (
 	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
-// NewAckHandler creates a new SentPacketHandler and a new ReceivedPacketHandler
+// NewAckHandler creates a new SentPacketHandler and a new ReceivedPacketHandler.
+// If enableECN is set, 1-RTT packets are marked with ECT(0), as long as ECN validation succeeds.
+// If cc is nil, Cubic / NewReno is used for congestion control.
 func NewAckHandler(
 	initialPacketNumber protocol.PacketNumber,
 	rttStats *congestion.RTTStats,
+	cc congestion.SendAlgorithmWithDebugInfos,
 	pers protocol.Perspective,
+	enableECN bool,
 	traceCallback func(quictrace.Event),
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) (SentPacketHandler, ReceivedPacketHandler) {
-	sph := newSentPacketHandler(initialPacketNumber, rttStats, pers, traceCallback, tracer, logger)
+	sph := newSentPacketHandler(initialPacketNumber, rttStats, cc, pers, enableECN, traceCallback, tracer, logger)
 	return sph, newReceivedPacketHandler(sph, rttStats, logger, version)
 }
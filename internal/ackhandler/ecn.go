@@ -0,0 +1,166 @@
+package ackhandler
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+type ecnState uint8
+
+const (
+	ecnStateTesting ecnState = iota
+	ecnStateUnknown
+	ecnStateCapable
+	ecnStateFailed
+)
+
+func (s ecnState) String() string {
+	switch s {
+	case ecnStateTesting:
+		return "testing"
+	case ecnStateUnknown:
+		return "unknown"
+	case ecnStateCapable:
+		return "capable"
+	case ecnStateFailed:
+		return "failed"
+	default:
+		return "unknown ECN state"
+	}
+}
+
+// During the testing phase, we send this many ECT(0) marked packets.
+// We then stop marking packets until we learn if they were received with the ECN marking intact.
+const numECNTestingPackets = 10
+
+// The ecnTracker performs ECN validation, as described in section 13.4.2 of RFC 9000.
+// When validation fails, it stops marking packets.
+type ecnTracker struct {
+	state ecnState
+
+	// the number of ECT(0) marked packets sent and lost during the testing phase
+	numSentTesting, numLostTesting uint64
+	// the number of ECT(0) marked packets sent on this connection
+	numSentECT0 uint64
+
+	// the ECN counts reported by the peer in the last ACK frame
+	ect0, ect1, ecnce uint64
+
+	logger utils.Logger
+}
+
+func newECNTracker(logger utils.Logger) *ecnTracker {
+	return &ecnTracker{logger: logger}
+}
+
+// Mode returns the ECN marking that should be used for the next 1-RTT packet.
+func (e *ecnTracker) Mode() protocol.ECN {
+	switch e.state {
+	case ecnStateTesting, ecnStateCapable:
+		return protocol.ECT0
+	default:
+		return protocol.ECNNon
+	}
+}
+
+// SentPacket is called for every 1-RTT packet sent.
+func (e *ecnTracker) SentPacket(ecn protocol.ECN) {
+	if ecn != protocol.ECT0 {
+		return
+	}
+	e.numSentECT0++
+	if e.state != ecnStateTesting {
+		return
+	}
+	e.numSentTesting++
+	if e.numSentTesting >= numECNTestingPackets {
+		e.setState(ecnStateUnknown)
+	}
+}
+
+// LostPacket is called when an ECT(0) marked 1-RTT packet is declared lost.
+// If all testing packets are lost, it is likely that the path drops ECN marked packets.
+func (e *ecnTracker) LostPacket() {
+	if e.state != ecnStateTesting && e.state != ecnStateUnknown {
+		return
+	}
+	e.numLostTesting++
+	if e.state == ecnStateUnknown && e.numLostTesting >= e.numSentTesting {
+		e.logger.Debugf("Disabling ECN. All testing packets were lost.")
+		e.setState(ecnStateFailed)
+	}
+}
+
+// HandleNewlyAcked is called when an ACK frame acknowledges new 1-RTT packets.
+// It returns true if the peer reported new CE marks, which should be treated as a congestion event.
+func (e *ecnTracker) HandleNewlyAcked(packets []*Packet, ack *wire.AckFrame) (congested bool) {
+	if e.state == ecnStateFailed {
+		return false
+	}
+
+	var numAckedECT0 uint64
+	for _, p := range packets {
+		if p.ECN == protocol.ECT0 {
+			numAckedECT0++
+		}
+	}
+	// Old ACK frames might not contain the ECN counts yet.
+	if numAckedECT0 == 0 && !ack.HasECN() {
+		return false
+	}
+	if !ack.HasECN() {
+		e.logger.Debugf("Disabling ECN. ECN-marked packet acknowledged, but no ECN counts on ACK frame.")
+		e.setState(ecnStateFailed)
+		return false
+	}
+	if ack.ECT0 < e.ect0 || ack.ECT1 < e.ect1 || ack.ECNCE < e.ecnce {
+		e.logger.Debugf("Disabling ECN. ECN counts decreased.")
+		e.setState(ecnStateFailed)
+		return false
+	}
+	// We never send ECT(1) marked packets.
+	if ack.ECT1 > 0 {
+		e.logger.Debugf("Disabling ECN. Received ECT(1) count.")
+		e.setState(ecnStateFailed)
+		return false
+	}
+	if ack.ECT0+ack.ECNCE > e.numSentECT0 {
+		e.logger.Debugf("Disabling ECN. Received more ECT(0) / ECN-CE than packets sent.")
+		e.setState(ecnStateFailed)
+		return false
+	}
+	newECT0 := ack.ECT0 - e.ect0
+	newCE := ack.ECNCE - e.ecnce
+	// The ECN marking was removed (or changed) on the path.
+	if newECT0+newCE < numAckedECT0 {
+		e.logger.Debugf("Disabling ECN. ECN marks were removed on the path.")
+		e.setState(ecnStateFailed)
+		return false
+	}
+	e.ect0 = ack.ECT0
+	e.ecnce = ack.ECNCE
+
+	if numAckedECT0 > 0 && (e.state == ecnStateTesting || e.state == ecnStateUnknown) {
+		e.setState(ecnStateCapable)
+	}
+	return newCE > 0
+}
+
+// OnConnectionMigration restarts ECN validation.
+// ECN validation is performed separately for every path.
+func (e *ecnTracker) OnConnectionMigration() {
+	e.numSentTesting = 0
+	e.numLostTesting = 0
+	e.setState(ecnStateTesting)
+}
+
+func (e *ecnTracker) setState(s ecnState) {
+	if e.state == s {
+		return
+	}
+	if e.logger.Debug() {
+		e.logger.Debugf("ECN state: %s -> %s", e.state, s)
+	}
+	e.state = s
+}
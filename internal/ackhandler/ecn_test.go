@@ -0,0 +1,109 @@
+package ackhandler
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ECN tracker", func() {
+	var e *ecnTracker
+
+	sendTestingPackets := func() []*Packet {
+		packets := make([]*Packet, 0, numECNTestingPackets)
+		for i := 0; i < numECNTestingPackets; i++ {
+			Expect(e.Mode()).To(Equal(protocol.ECT0))
+			e.SentPacket(protocol.ECT0)
+			packets = append(packets, &Packet{PacketNumber: protocol.PacketNumber(i), ECN: protocol.ECT0})
+		}
+		return packets
+	}
+
+	BeforeEach(func() {
+		e = newECNTracker(utils.DefaultLogger)
+	})
+
+	It("stops marking packets after sending the testing packets", func() {
+		sendTestingPackets()
+		Expect(e.state).To(Equal(ecnStateUnknown))
+		Expect(e.Mode()).To(Equal(protocol.ECNNon))
+		e.SentPacket(protocol.ECNNon)
+		Expect(e.numSentECT0).To(BeEquivalentTo(numECNTestingPackets))
+	})
+
+	It("is ECN capable when ECT(0) marked packets are acknowledged", func() {
+		packets := sendTestingPackets()
+		Expect(e.HandleNewlyAcked(packets[:3], &wire.AckFrame{ECT0: 3})).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateCapable))
+		Expect(e.Mode()).To(Equal(protocol.ECT0))
+	})
+
+	It("detects a congestion event when the CE count increases", func() {
+		packets := sendTestingPackets()
+		Expect(e.HandleNewlyAcked(packets[:3], &wire.AckFrame{ECT0: 2, ECNCE: 1})).To(BeTrue())
+		Expect(e.state).To(Equal(ecnStateCapable))
+		Expect(e.HandleNewlyAcked(packets[3:5], &wire.AckFrame{ECT0: 4, ECNCE: 1})).To(BeFalse())
+		Expect(e.HandleNewlyAcked(packets[5:6], &wire.AckFrame{ECT0: 4, ECNCE: 2})).To(BeTrue())
+	})
+
+	It("fails validation if the ACK doesn't contain ECN counts", func() {
+		packets := sendTestingPackets()
+		Expect(e.HandleNewlyAcked(packets[:3], &wire.AckFrame{})).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateFailed))
+		Expect(e.Mode()).To(Equal(protocol.ECNNon))
+	})
+
+	It("doesn't fail validation if an ACK without ECN counts doesn't acknowledge ECN marked packets", func() {
+		sendTestingPackets()
+		Expect(e.HandleNewlyAcked([]*Packet{{PacketNumber: 100}}, &wire.AckFrame{})).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateUnknown))
+	})
+
+	It("fails validation if the ECN marks were removed on the path", func() {
+		packets := sendTestingPackets()
+		Expect(e.HandleNewlyAcked(packets[:3], &wire.AckFrame{ECT0: 2})).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateFailed))
+	})
+
+	It("fails validation if the ECT(1) count is set", func() {
+		packets := sendTestingPackets()
+		Expect(e.HandleNewlyAcked(packets[:3], &wire.AckFrame{ECT0: 3, ECT1: 1})).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateFailed))
+	})
+
+	It("fails validation if more packets are reported than were sent", func() {
+		packets := sendTestingPackets()
+		Expect(e.HandleNewlyAcked(packets[:3], &wire.AckFrame{ECT0: numECNTestingPackets, ECNCE: 1})).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateFailed))
+	})
+
+	It("fails validation if the ECN counts decrease", func() {
+		packets := sendTestingPackets()
+		Expect(e.HandleNewlyAcked(packets[:3], &wire.AckFrame{ECT0: 3})).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateCapable))
+		Expect(e.HandleNewlyAcked(packets[3:4], &wire.AckFrame{ECT0: 2, ECNCE: 2})).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateFailed))
+	})
+
+	It("fails validation if all testing packets are lost", func() {
+		sendTestingPackets()
+		for i := 0; i < numECNTestingPackets-1; i++ {
+			e.LostPacket()
+		}
+		Expect(e.state).To(Equal(ecnStateUnknown))
+		e.LostPacket()
+		Expect(e.state).To(Equal(ecnStateFailed))
+	})
+
+	It("restarts validation on connection migration", func() {
+		packets := sendTestingPackets()
+		Expect(e.HandleNewlyAcked(packets[:3], &wire.AckFrame{})).To(BeFalse())
+		Expect(e.state).To(Equal(ecnStateFailed))
+		e.OnConnectionMigration()
+		Expect(e.state).To(Equal(ecnStateTesting))
+		Expect(e.Mode()).To(Equal(protocol.ECT0))
+	})
+})
@@ -16,6 +16,12 @@ type Packet struct {
 	Length          protocol.ByteCount
 	EncryptionLevel protocol.EncryptionLevel
 	SendTime        time.Time
+	// IsPathMTUProbePacket is set for packets sent for Path MTU Discovery.
+	// Losing such a packet is not considered a congestion signal.
+	IsPathMTUProbePacket bool
+	// ECN is the ECN marking of the packet.
+	// It is set by the SentPacketHandler.
+	ECN protocol.ECN
 
 	includedInBytesInFlight bool
 }
@@ -29,6 +35,10 @@ type SentPacketHandler interface {
 	DropPackets(protocol.EncryptionLevel)
 	ResetForRetry() error
 	SetHandshakeComplete()
+	// OnConnectionMigration resets the RTT estimate and the congestion controller,
+	// and restarts ECN validation.
+	// It is called when the connection is migrated to a new path.
+	OnConnectionMigration()
 
 	// The SendMode determines if and what kind of packets can be sent.
 	SendMode() SendMode
@@ -48,8 +58,24 @@ type SentPacketHandler interface {
 	GetLossDetectionTimeout() time.Time
 	OnLossDetectionTimeout() error
 
-	// report some congestion statistics. For tracing only.
+	// report some congestion statistics
 	GetStats() *quictrace.TransportState
+	// TransmissionStats returns the number of packets sent, lost and retransmitted.
+	TransmissionStats() TransmissionStats
+}
+
+// TransmissionStats count the packets handled by the SentPacketHandler.
+type TransmissionStats struct {
+	PacketsSent uint64
+	BytesSent   protocol.ByteCount
+	// packets declared lost by the loss detection
+	PacketsLost uint64
+	BytesLost   protocol.ByteCount
+	// Packets whose frames were queued for retransmission.
+	// This happens when a packet is declared lost, when it is retransmitted in a probe packet,
+	// and when its data has to be resent after a Retry or after 0-RTT was rejected.
+	PacketsRetransmitted uint64
+	BytesRetransmitted   protocol.ByteCount
 }
 
 type sentPacketTracker interface {
@@ -60,7 +86,7 @@ type sentPacketTracker interface {
 // ReceivedPacketHandler handles ACKs needed to send for incoming packets
 type ReceivedPacketHandler interface {
 	IsPotentiallyDuplicate(protocol.PacketNumber, protocol.EncryptionLevel) bool
-	ReceivedPacket(pn protocol.PacketNumber, encLevel protocol.EncryptionLevel, rcvTime time.Time, shouldInstigateAck bool) error
+	ReceivedPacket(pn protocol.PacketNumber, ecn protocol.ECN, encLevel protocol.EncryptionLevel, rcvTime time.Time, shouldInstigateAck bool) error
 	DropPackets(protocol.EncryptionLevel)
 
 	GetAlarmTimeout() time.Time
@@ -22,6 +22,8 @@ type receivedPacketTracker struct {
 	hasNewAck bool // true as soon as we received an ack-eliciting new packet
 	ackQueued bool // true once we received more than 2 (or later in the connection 10) ack-eliciting packets
 
+	ect0, ect1, ecnce uint64
+
 	ackElicitingPacketsReceivedSinceLastAck int
 	ackAlarm                                time.Time
 	lastAck                                 *wire.AckFrame
@@ -45,7 +47,7 @@ func newReceivedPacketTracker(
 	}
 }
 
-func (h *receivedPacketTracker) ReceivedPacket(packetNumber protocol.PacketNumber, rcvTime time.Time, shouldInstigateAck bool) {
+func (h *receivedPacketTracker) ReceivedPacket(packetNumber protocol.PacketNumber, ecn protocol.ECN, rcvTime time.Time, shouldInstigateAck bool) {
 	if packetNumber < h.ignoreBelow {
 		return
 	}
@@ -56,10 +58,21 @@ func (h *receivedPacketTracker) ReceivedPacket(packetNumber protocol.PacketNumbe
 		h.largestObservedReceivedTime = rcvTime
 	}
 
-	if isNew := h.packetHistory.ReceivedPacket(packetNumber); isNew && shouldInstigateAck {
+	isNew := h.packetHistory.ReceivedPacket(packetNumber)
+	if isNew && shouldInstigateAck {
 		h.hasNewAck = true
 	}
-	h.maybeQueueAck(packetNumber, rcvTime, shouldInstigateAck, isMissing)
+	if isNew {
+		switch ecn {
+		case protocol.ECT0:
+			h.ect0++
+		case protocol.ECT1:
+			h.ect1++
+		case protocol.ECNCE:
+			h.ecnce++
+		}
+	}
+	h.maybeQueueAck(packetNumber, ecn, rcvTime, shouldInstigateAck, isMissing)
 }
 
 // IgnoreBelow sets a lower limit for acking packets.
@@ -94,7 +107,7 @@ func (h *receivedPacketTracker) hasNewMissingPackets() bool {
 // maybeQueueAck queues an ACK, if necessary.
 // It is implemented analogously to Chrome's QuicConnection::MaybeQueueAck()
 // in ACK_DECIMATION_WITH_REORDERING mode.
-func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber, rcvTime time.Time, shouldInstigateAck, wasMissing bool) {
+func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber, ecn protocol.ECN, rcvTime time.Time, shouldInstigateAck, wasMissing bool) {
 	// always ack the first packet
 	if h.lastAck == nil {
 		if !h.ackQueued {
@@ -114,6 +127,15 @@ func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber
 		h.ackQueued = true
 	}
 
+	// Send an ACK immediately if the packet was CE marked,
+	// so that the peer can react to the congestion as fast as possible.
+	if ecn == protocol.ECNCE && shouldInstigateAck {
+		if h.logger.Debug() {
+			h.logger.Debugf("\tQueueing ACK because packet %d was CE marked.", packetNumber)
+		}
+		h.ackQueued = true
+	}
+
 	if !h.ackQueued && shouldInstigateAck {
 		h.ackElicitingPacketsReceivedSinceLastAck++
 
@@ -185,6 +207,9 @@ func (h *receivedPacketTracker) GetAckFrame(onlyIfQueued bool) *wire.AckFrame {
 		// Make sure that the DelayTime is always positive.
 		// This is not guaranteed on systems that don't have a monotonic clock.
 		DelayTime: utils.MaxDuration(0, now.Sub(h.largestObservedReceivedTime)),
+		ECT0:      h.ect0,
+		ECT1:      h.ect1,
+		ECNCE:     h.ecnce,
 	}
 
 	h.lastAck = ack
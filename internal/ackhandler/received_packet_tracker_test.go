@@ -25,7 +25,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 	Context("accepting packets", func() {
 		It("saves the time when each packet arrived", func() {
-			tracker.ReceivedPacket(protocol.PacketNumber(3), time.Now(), true)
+			tracker.ReceivedPacket(protocol.PacketNumber(3), protocol.ECNNon, time.Now(), true)
 			Expect(tracker.largestObservedReceivedTime).To(BeTemporally("~", time.Now(), 10*time.Millisecond))
 		})
 
@@ -33,7 +33,7 @@ var _ = Describe("Received Packet Tracker", func() {
 			now := time.Now()
 			tracker.largestObserved = 3
 			tracker.largestObservedReceivedTime = now.Add(-1 * time.Second)
-			tracker.ReceivedPacket(5, now, true)
+			tracker.ReceivedPacket(5, protocol.ECNNon, now, true)
 			Expect(tracker.largestObserved).To(Equal(protocol.PacketNumber(5)))
 			Expect(tracker.largestObservedReceivedTime).To(Equal(now))
 		})
@@ -43,7 +43,7 @@ var _ = Describe("Received Packet Tracker", func() {
 			timestamp := now.Add(-1 * time.Second)
 			tracker.largestObserved = 5
 			tracker.largestObservedReceivedTime = timestamp
-			tracker.ReceivedPacket(4, now, true)
+			tracker.ReceivedPacket(4, protocol.ECNNon, now, true)
 			Expect(tracker.largestObserved).To(Equal(protocol.PacketNumber(5)))
 			Expect(tracker.largestObservedReceivedTime).To(Equal(timestamp))
 		})
@@ -53,7 +53,7 @@ var _ = Describe("Received Packet Tracker", func() {
 		Context("queueing ACKs", func() {
 			receiveAndAck10Packets := func() {
 				for i := 1; i <= 10; i++ {
-					tracker.ReceivedPacket(protocol.PacketNumber(i), time.Time{}, true)
+					tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, time.Time{}, true)
 				}
 				Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
 				Expect(tracker.ackQueued).To(BeFalse())
@@ -61,21 +61,21 @@ var _ = Describe("Received Packet Tracker", func() {
 
 			receiveAndAckPacketsUntilAckDecimation := func() {
 				for i := 1; i <= minReceivedBeforeAckDecimation; i++ {
-					tracker.ReceivedPacket(protocol.PacketNumber(i), time.Time{}, true)
+					tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, time.Time{}, true)
 				}
 				Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
 				Expect(tracker.ackQueued).To(BeFalse())
 			}
 
 			It("always queues an ACK for the first packet", func() {
-				tracker.ReceivedPacket(1, time.Now(), true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
 				Expect(tracker.ackQueued).To(BeTrue())
 				Expect(tracker.GetAlarmTimeout()).To(BeZero())
 				Expect(tracker.GetAckFrame(true).DelayTime).To(BeNumerically("~", 0, time.Second))
 			})
 
 			It("works with packet number 0", func() {
-				tracker.ReceivedPacket(0, time.Now(), true)
+				tracker.ReceivedPacket(0, protocol.ECNNon, time.Now(), true)
 				Expect(tracker.ackQueued).To(BeTrue())
 				Expect(tracker.GetAlarmTimeout()).To(BeZero())
 				Expect(tracker.GetAckFrame(true).DelayTime).To(BeNumerically("~", 0, time.Second))
@@ -85,10 +85,10 @@ var _ = Describe("Received Packet Tracker", func() {
 				receiveAndAck10Packets()
 				p := protocol.PacketNumber(11)
 				for i := 0; i <= 20; i++ {
-					tracker.ReceivedPacket(p, time.Time{}, true)
+					tracker.ReceivedPacket(p, protocol.ECNNon, time.Time{}, true)
 					Expect(tracker.ackQueued).To(BeFalse())
 					p++
-					tracker.ReceivedPacket(p, time.Time{}, true)
+					tracker.ReceivedPacket(p, protocol.ECNNon, time.Time{}, true)
 					Expect(tracker.ackQueued).To(BeTrue())
 					p++
 					// dequeue the ACK frame
@@ -99,11 +99,11 @@ var _ = Describe("Received Packet Tracker", func() {
 			It("resets the counter when a non-queued ACK frame is generated", func() {
 				receiveAndAck10Packets()
 				rcvTime := time.Now()
-				tracker.ReceivedPacket(11, rcvTime, true)
+				tracker.ReceivedPacket(11, protocol.ECNNon, rcvTime, true)
 				Expect(tracker.GetAckFrame(false)).ToNot(BeNil())
-				tracker.ReceivedPacket(12, rcvTime, true)
+				tracker.ReceivedPacket(12, protocol.ECNNon, rcvTime, true)
 				Expect(tracker.GetAckFrame(true)).To(BeNil())
-				tracker.ReceivedPacket(13, rcvTime, true)
+				tracker.ReceivedPacket(13, protocol.ECNNon, rcvTime, true)
 				Expect(tracker.GetAckFrame(false)).ToNot(BeNil())
 			})
 
@@ -111,49 +111,49 @@ var _ = Describe("Received Packet Tracker", func() {
 				receiveAndAck10Packets()
 				p := protocol.PacketNumber(10000)
 				for i := 0; i < 9; i++ {
-					tracker.ReceivedPacket(p, time.Now(), true)
+					tracker.ReceivedPacket(p, protocol.ECNNon, time.Now(), true)
 					Expect(tracker.ackQueued).To(BeFalse())
 					p++
 				}
 				Expect(tracker.GetAlarmTimeout()).NotTo(BeZero())
-				tracker.ReceivedPacket(p, time.Now(), true)
+				tracker.ReceivedPacket(p, protocol.ECNNon, time.Now(), true)
 				Expect(tracker.ackQueued).To(BeTrue())
 				Expect(tracker.GetAlarmTimeout()).To(BeZero())
 			})
 
 			It("only sets the timer when receiving a ack-eliciting packets", func() {
 				receiveAndAck10Packets()
-				tracker.ReceivedPacket(11, time.Now(), false)
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), false)
 				Expect(tracker.ackQueued).To(BeFalse())
 				Expect(tracker.GetAlarmTimeout()).To(BeZero())
 				rcvTime := time.Now().Add(10 * time.Millisecond)
-				tracker.ReceivedPacket(12, rcvTime, true)
+				tracker.ReceivedPacket(12, protocol.ECNNon, rcvTime, true)
 				Expect(tracker.ackQueued).To(BeFalse())
 				Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(protocol.MaxAckDelay)))
 			})
 
 			It("queues an ACK if it was reported missing before", func() {
 				receiveAndAck10Packets()
-				tracker.ReceivedPacket(11, time.Time{}, true)
-				tracker.ReceivedPacket(13, time.Time{}, true)
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Time{}, true)
+				tracker.ReceivedPacket(13, protocol.ECNNon, time.Time{}, true)
 				ack := tracker.GetAckFrame(true) // ACK: 1-11 and 13, missing: 12
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.HasMissingRanges()).To(BeTrue())
 				Expect(tracker.ackQueued).To(BeFalse())
-				tracker.ReceivedPacket(12, time.Time{}, false)
+				tracker.ReceivedPacket(12, protocol.ECNNon, time.Time{}, false)
 				Expect(tracker.ackQueued).To(BeTrue())
 			})
 
 			It("doesn't queue an ACK if it was reported missing before, but is below the threshold", func() {
 				receiveAndAck10Packets()
 				// 11 is missing
-				tracker.ReceivedPacket(12, time.Time{}, true)
-				tracker.ReceivedPacket(13, time.Time{}, true)
+				tracker.ReceivedPacket(12, protocol.ECNNon, time.Time{}, true)
+				tracker.ReceivedPacket(13, protocol.ECNNon, time.Time{}, true)
 				ack := tracker.GetAckFrame(true) // ACK: 1-10, 12-13
 				Expect(ack).ToNot(BeNil())
 				// now receive 11
 				tracker.IgnoreBelow(12)
-				tracker.ReceivedPacket(11, time.Time{}, false)
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Time{}, false)
 				ack = tracker.GetAckFrame(true)
 				Expect(ack).To(BeNil())
 			})
@@ -161,13 +161,22 @@ var _ = Describe("Received Packet Tracker", func() {
 			It("doesn't queue an ACK if the packet closes a gap that was not yet reported", func() {
 				receiveAndAckPacketsUntilAckDecimation()
 				p := protocol.PacketNumber(minReceivedBeforeAckDecimation + 1)
-				tracker.ReceivedPacket(p+1, time.Now(), true) // p is missing now
+				tracker.ReceivedPacket(p+1, protocol.ECNNon, time.Now(), true) // p is missing now
 				Expect(tracker.ackQueued).To(BeFalse())
 				Expect(tracker.GetAlarmTimeout()).ToNot(BeZero())
-				tracker.ReceivedPacket(p, time.Now(), true) // p is not missing any more
+				tracker.ReceivedPacket(p, protocol.ECNNon, time.Now(), true) // p is not missing any more
 				Expect(tracker.ackQueued).To(BeFalse())
 			})
 
+			It("queues an ACK if the packet was CE marked", func() {
+				receiveAndAckPacketsUntilAckDecimation()
+				p := protocol.PacketNumber(minReceivedBeforeAckDecimation + 1)
+				tracker.ReceivedPacket(p, protocol.ECT0, time.Now(), true)
+				Expect(tracker.ackQueued).To(BeFalse())
+				tracker.ReceivedPacket(p+1, protocol.ECNCE, time.Now(), true)
+				Expect(tracker.ackQueued).To(BeTrue())
+			})
+
 			It("sets an ACK alarm after 1/4 RTT if it creates a new missing range", func() {
 				now := time.Now().Add(-time.Hour)
 				rtt := 80 * time.Millisecond
@@ -175,9 +184,9 @@ var _ = Describe("Received Packet Tracker", func() {
 				receiveAndAckPacketsUntilAckDecimation()
 				p := protocol.PacketNumber(minReceivedBeforeAckDecimation + 1)
 				for i := p; i < p+6; i++ {
-					tracker.ReceivedPacket(i, now, true)
+					tracker.ReceivedPacket(i, protocol.ECNNon, now, true)
 				}
-				tracker.ReceivedPacket(p+10, now, true) // we now know that packets p+7, p+8 and p+9
+				tracker.ReceivedPacket(p+10, protocol.ECNNon, now, true) // we now know that packets p+7, p+8 and p+9
 				Expect(rttStats.MinRTT()).To(Equal(rtt))
 				Expect(tracker.ackAlarm.Sub(now)).To(Equal(rtt / 8))
 				ack := tracker.GetAckFrame(true)
@@ -188,11 +197,11 @@ var _ = Describe("Received Packet Tracker", func() {
 
 		Context("ACK generation", func() {
 			It("generates an ACK for an ack-eliciting packet, if no ACK is queued yet", func() {
-				tracker.ReceivedPacket(1, time.Time{}, true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
 				// The first packet is always acknowledged.
 				Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
 
-				tracker.ReceivedPacket(2, time.Time{}, true)
+				tracker.ReceivedPacket(2, protocol.ECNNon, time.Time{}, true)
 				Expect(tracker.GetAckFrame(true)).To(BeNil())
 				ack := tracker.GetAckFrame(false)
 				Expect(ack).ToNot(BeNil())
@@ -201,13 +210,13 @@ var _ = Describe("Received Packet Tracker", func() {
 			})
 
 			It("doesn't generate ACK for a non-ack-eliciting packet, if no ACK is queued yet", func() {
-				tracker.ReceivedPacket(1, time.Time{}, true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
 				// The first packet is always acknowledged.
 				Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
 
-				tracker.ReceivedPacket(2, time.Time{}, false)
+				tracker.ReceivedPacket(2, protocol.ECNNon, time.Time{}, false)
 				Expect(tracker.GetAckFrame(false)).To(BeNil())
-				tracker.ReceivedPacket(3, time.Time{}, true)
+				tracker.ReceivedPacket(3, protocol.ECNNon, time.Time{}, true)
 				ack := tracker.GetAckFrame(false)
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.LowestAcked()).To(Equal(protocol.PacketNumber(1)))
@@ -220,8 +229,8 @@ var _ = Describe("Received Packet Tracker", func() {
 				})
 
 				It("generates a simple ACK frame", func() {
-					tracker.ReceivedPacket(1, time.Time{}, true)
-					tracker.ReceivedPacket(2, time.Time{}, true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
+					tracker.ReceivedPacket(2, protocol.ECNNon, time.Time{}, true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(2)))
@@ -230,7 +239,7 @@ var _ = Describe("Received Packet Tracker", func() {
 				})
 
 				It("generates an ACK for packet number 0", func() {
-					tracker.ReceivedPacket(0, time.Time{}, true)
+					tracker.ReceivedPacket(0, protocol.ECNNon, time.Time{}, true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(0)))
@@ -239,26 +248,46 @@ var _ = Describe("Received Packet Tracker", func() {
 				})
 
 				It("sets the delay time", func() {
-					tracker.ReceivedPacket(1, time.Time{}, true)
-					tracker.ReceivedPacket(2, time.Now().Add(-1337*time.Millisecond), true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
+					tracker.ReceivedPacket(2, protocol.ECNNon, time.Now().Add(-1337*time.Millisecond), true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.DelayTime).To(BeNumerically("~", 1337*time.Millisecond, 50*time.Millisecond))
 				})
 
 				It("uses a 0 delay time if the delay would be negative", func() {
-					tracker.ReceivedPacket(0, time.Now().Add(time.Hour), true)
+					tracker.ReceivedPacket(0, protocol.ECNNon, time.Now().Add(time.Hour), true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.DelayTime).To(BeZero())
 				})
 
+				It("reports the ECN counts", func() {
+					tracker.ReceivedPacket(1, protocol.ECT0, time.Time{}, true)
+					tracker.ReceivedPacket(2, protocol.ECT1, time.Time{}, true)
+					tracker.ReceivedPacket(3, protocol.ECNCE, time.Time{}, true)
+					tracker.ReceivedPacket(4, protocol.ECT0, time.Time{}, true)
+					tracker.ReceivedPacket(4, protocol.ECT0, time.Time{}, true) // duplicate, not counted
+					tracker.ReceivedPacket(5, protocol.ECNNon, time.Time{}, true)
+					ack := tracker.GetAckFrame(true)
+					Expect(ack).ToNot(BeNil())
+					Expect(ack.ECT0).To(BeEquivalentTo(2))
+					Expect(ack.ECT1).To(BeEquivalentTo(1))
+					Expect(ack.ECNCE).To(BeEquivalentTo(1))
+					// the counts are cumulative
+					tracker.ReceivedPacket(6, protocol.ECT0, time.Time{}, true)
+					tracker.ackQueued = true
+					ack = tracker.GetAckFrame(true)
+					Expect(ack).ToNot(BeNil())
+					Expect(ack.ECT0).To(BeEquivalentTo(3))
+				})
+
 				It("saves the last sent ACK", func() {
-					tracker.ReceivedPacket(1, time.Time{}, true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(tracker.lastAck).To(Equal(ack))
-					tracker.ReceivedPacket(2, time.Time{}, true)
+					tracker.ReceivedPacket(2, protocol.ECNNon, time.Time{}, true)
 					tracker.ackQueued = true
 					ack = tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
@@ -266,8 +295,8 @@ var _ = Describe("Received Packet Tracker", func() {
 				})
 
 				It("generates an ACK frame with missing packets", func() {
-					tracker.ReceivedPacket(1, time.Time{}, true)
-					tracker.ReceivedPacket(4, time.Time{}, true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
+					tracker.ReceivedPacket(4, protocol.ECNNon, time.Time{}, true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(4)))
@@ -279,9 +308,9 @@ var _ = Describe("Received Packet Tracker", func() {
 				})
 
 				It("generates an ACK for packet number 0 and other packets", func() {
-					tracker.ReceivedPacket(0, time.Time{}, true)
-					tracker.ReceivedPacket(1, time.Time{}, true)
-					tracker.ReceivedPacket(3, time.Time{}, true)
+					tracker.ReceivedPacket(0, protocol.ECNNon, time.Time{}, true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
+					tracker.ReceivedPacket(3, protocol.ECNNon, time.Time{}, true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(3)))
@@ -294,8 +323,8 @@ var _ = Describe("Received Packet Tracker", func() {
 
 				It("doesn't add delayed packets to the packetHistory", func() {
 					tracker.IgnoreBelow(7)
-					tracker.ReceivedPacket(4, time.Time{}, true)
-					tracker.ReceivedPacket(10, time.Time{}, true)
+					tracker.ReceivedPacket(4, protocol.ECNNon, time.Time{}, true)
+					tracker.ReceivedPacket(10, protocol.ECNNon, time.Time{}, true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(10)))
@@ -304,7 +333,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 				It("deletes packets from the packetHistory when a lower limit is set", func() {
 					for i := 1; i <= 12; i++ {
-						tracker.ReceivedPacket(protocol.PacketNumber(i), time.Time{}, true)
+						tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, time.Time{}, true)
 					}
 					tracker.IgnoreBelow(7)
 					// check that the packets were deleted from the receivedPacketHistory by checking the values in an ACK frame
@@ -318,14 +347,14 @@ var _ = Describe("Received Packet Tracker", func() {
 				// TODO: remove this test when dropping support for STOP_WAITINGs
 				It("handles a lower limit of 0", func() {
 					tracker.IgnoreBelow(0)
-					tracker.ReceivedPacket(1337, time.Time{}, true)
+					tracker.ReceivedPacket(1337, protocol.ECNNon, time.Time{}, true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(1337)))
 				})
 
 				It("resets all counters needed for the ACK queueing decision when sending an ACK", func() {
-					tracker.ReceivedPacket(1, time.Time{}, true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
 					tracker.ackAlarm = time.Now().Add(-time.Minute)
 					Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
 					Expect(tracker.GetAlarmTimeout()).To(BeZero())
@@ -334,21 +363,21 @@ var _ = Describe("Received Packet Tracker", func() {
 				})
 
 				It("doesn't generate an ACK when none is queued and the timer is not set", func() {
-					tracker.ReceivedPacket(1, time.Time{}, true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
 					tracker.ackQueued = false
 					tracker.ackAlarm = time.Time{}
 					Expect(tracker.GetAckFrame(true)).To(BeNil())
 				})
 
 				It("doesn't generate an ACK when none is queued and the timer has not yet expired", func() {
-					tracker.ReceivedPacket(1, time.Time{}, true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
 					tracker.ackQueued = false
 					tracker.ackAlarm = time.Now().Add(time.Minute)
 					Expect(tracker.GetAckFrame(true)).To(BeNil())
 				})
 
 				It("generates an ACK when the timer has expired", func() {
-					tracker.ReceivedPacket(1, time.Time{}, true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
 					tracker.ackQueued = false
 					tracker.ackAlarm = time.Now().Add(-time.Minute)
 					Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
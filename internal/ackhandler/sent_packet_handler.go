@@ -20,8 +20,6 @@ const (
 	timeThreshold = 9.0 / 8
 	// Maximum reordering in packets before packet threshold loss detection considers a packet lost.
 	packetThreshold = 3
-	// Before validating the client's address, the server won't send more than 3x bytes than it received.
-	amplificationFactor = 3
 )
 
 type packetNumberSpace struct {
@@ -70,6 +68,8 @@ type sentPacketHandler struct {
 
 	congestion congestion.SendAlgorithmWithDebugInfos
 	rttStats   *congestion.RTTStats
+	// nil if ECN is disabled
+	ecnTracker *ecnTracker
 
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
@@ -81,6 +81,8 @@ type sentPacketHandler struct {
 	// The alarm timeout
 	alarm time.Time
 
+	stats TransmissionStats
+
 	perspective protocol.Perspective
 
 	traceCallback func(quictrace.Event)
@@ -94,16 +96,24 @@ var _ sentPacketTracker = &sentPacketHandler{}
 func newSentPacketHandler(
 	initialPacketNumber protocol.PacketNumber,
 	rttStats *congestion.RTTStats,
+	cc congestion.SendAlgorithmWithDebugInfos,
 	pers protocol.Perspective,
+	enableECN bool,
 	traceCallback func(quictrace.Event),
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 ) *sentPacketHandler {
-	congestion := congestion.NewCubicSender(
-		congestion.DefaultClock{},
-		rttStats,
-		true, // use Reno
-	)
+	if cc == nil {
+		cc = congestion.NewCubicSender(
+			congestion.DefaultClock{},
+			rttStats,
+			true, // use Reno
+		)
+	}
+	var ecnTracker *ecnTracker
+	if enableECN {
+		ecnTracker = newECNTracker(logger)
+	}
 
 	return &sentPacketHandler{
 		peerCompletedAddressValidation: pers == protocol.PerspectiveServer,
@@ -112,7 +122,8 @@ func newSentPacketHandler(
 		handshakePackets:               newPacketNumberSpace(0),
 		appDataPackets:                 newPacketNumberSpace(0),
 		rttStats:                       rttStats,
-		congestion:                     congestion,
+		congestion:                     cc,
+		ecnTracker:                     ecnTracker,
 		perspective:                    pers,
 		traceCallback:                  traceCallback,
 		tracer:                         tracer,
@@ -200,10 +211,16 @@ func (h *sentPacketHandler) packetsInFlight() int {
 
 func (h *sentPacketHandler) SentPacket(packet *Packet) {
 	h.bytesSent += packet.Length
+	h.stats.PacketsSent++
+	h.stats.BytesSent += packet.Length
 	// For the client, drop the Initial packet number space when the first Handshake packet is sent.
 	if h.perspective == protocol.PerspectiveClient && packet.EncryptionLevel == protocol.EncryptionHandshake && h.initialPackets != nil {
 		h.dropPackets(protocol.EncryptionInitial)
 	}
+	if h.ecnTracker != nil && packet.EncryptionLevel == protocol.Encryption1RTT {
+		packet.ECN = h.ecnTracker.Mode()
+		h.ecnTracker.SentPacket(packet.ECN)
+	}
 	isAckEliciting := h.sentPacketImpl(packet)
 	if isAckEliciting {
 		h.getPacketNumberSpace(packet.EncryptionLevel).history.SentPacket(packet)
@@ -304,8 +321,21 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 		return err
 	}
 	for _, p := range lostPackets {
+		if h.ecnTracker != nil && p.ECN == protocol.ECT0 {
+			h.ecnTracker.LostPacket()
+		}
+		// Losing a Path MTU probe packet doesn't mean that the path is congested.
+		if p.IsPathMTUProbePacket {
+			continue
+		}
 		h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
 	}
+	// An increase of the ECN-CE counter is treated like a packet loss.
+	if h.ecnTracker != nil && encLevel == protocol.Encryption1RTT {
+		if congested := h.ecnTracker.HandleNewlyAcked(ackedPackets, ack); congested {
+			h.congestion.OnCongestionEvent(largestAcked, priorInFlight)
+		}
+	}
 	for _, p := range ackedPackets {
 		if p.includedInBytesInFlight {
 			h.congestion.OnPacketAcked(p.PacketNumber, p.Length, priorInFlight, rcvTime)
@@ -551,6 +581,8 @@ func (h *sentPacketHandler) detectAndRemoveLostPackets(now time.Time, encLevel p
 	}
 
 	for _, p := range lostPackets {
+		h.stats.PacketsLost++
+		h.stats.BytesLost += p.Length
 		h.queueFramesForRetransmission(p)
 		// the bytes in flight need to be reduced no matter if this packet will be retransmitted
 		if p.includedInBytesInFlight {
@@ -608,6 +640,12 @@ func (h *sentPacketHandler) onVerifiedLossDetectionTimeout() error {
 			return err
 		}
 		for _, p := range lostPackets {
+			if h.ecnTracker != nil && p.ECN == protocol.ECT0 {
+				h.ecnTracker.LostPacket()
+			}
+			if p.IsPathMTUProbePacket {
+				continue
+			}
 			h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
 		}
 		return nil
@@ -727,10 +765,10 @@ func (h *sentPacketHandler) AmplificationWindow() protocol.ByteCount {
 	if h.peerAddressValidated {
 		return protocol.MaxByteCount
 	}
-	if h.bytesSent >= amplificationFactor*h.bytesReceived {
+	if h.bytesSent >= protocol.AmplificationFactor*h.bytesReceived {
 		return 0
 	}
-	return amplificationFactor*h.bytesReceived - h.bytesSent
+	return protocol.AmplificationFactor*h.bytesReceived - h.bytesSent
 }
 
 func (h *sentPacketHandler) QueueProbePacket(encLevel protocol.EncryptionLevel) bool {
@@ -753,6 +791,10 @@ func (h *sentPacketHandler) QueueProbePacket(encLevel protocol.EncryptionLevel)
 }
 
 func (h *sentPacketHandler) queueFramesForRetransmission(p *Packet) {
+	if len(p.Frames) > 0 {
+		h.stats.PacketsRetransmitted++
+		h.stats.BytesRetransmitted += p.Length
+	}
 	for _, f := range p.Frames {
 		f.OnLost(f.Frame)
 	}
@@ -801,6 +843,17 @@ func (h *sentPacketHandler) ResetForRetry() error {
 	return nil
 }
 
+func (h *sentPacketHandler) OnConnectionMigration() {
+	h.rttStats.OnConnectionMigration()
+	h.congestion.OnConnectionMigration()
+	if h.ecnTracker != nil {
+		h.ecnTracker.OnConnectionMigration()
+	}
+	if h.tracer != nil {
+		h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
+	}
+}
+
 func (h *sentPacketHandler) SetHandshakeComplete() {
 	h.handshakeComplete = true
 	// We don't send PTOs for application data packets before the handshake completes.
@@ -819,3 +872,7 @@ func (h *sentPacketHandler) GetStats() *quictrace.TransportState {
 		InRecovery:       h.congestion.InRecovery(),
 	}
 }
+
+func (h *sentPacketHandler) TransmissionStats() TransmissionStats {
+	return h.stats
+}
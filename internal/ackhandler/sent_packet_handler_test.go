@@ -28,7 +28,7 @@ var _ = Describe("SentPacketHandler", func() {
 	JustBeforeEach(func() {
 		lostPackets = nil
 		rttStats := &congestion.RTTStats{}
-		handler = newSentPacketHandler(42, rttStats, perspective, nil, nil, utils.DefaultLogger)
+		handler = newSentPacketHandler(42, rttStats, nil, perspective, false, nil, nil, utils.DefaultLogger)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -412,6 +412,63 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("ECN", func() {
+		var cong *mocks.MockSendAlgorithmWithDebugInfos
+
+		JustBeforeEach(func() {
+			cong = mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			cong.EXPECT().MaybeExitSlowStart().AnyTimes()
+			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			handler.congestion = cong
+			handler.ecnTracker = newECNTracker(utils.DefaultLogger)
+		})
+
+		It("doesn't mark packets if ECN is disabled", func() {
+			handler.ecnTracker = nil
+			p := ackElicitingPacket(&Packet{PacketNumber: 1})
+			handler.SentPacket(p)
+			Expect(p.ECN).To(Equal(protocol.ECNNon))
+		})
+
+		It("only marks 1-RTT packets", func() {
+			p := handshakePacket(&Packet{PacketNumber: 1})
+			handler.SentPacket(p)
+			Expect(p.ECN).To(Equal(protocol.ECNNon))
+			p = ackElicitingPacket(&Packet{PacketNumber: 1})
+			handler.SentPacket(p)
+			Expect(p.ECN).To(Equal(protocol.ECT0))
+		})
+
+		It("treats an increase of the CE count as a congestion event", func() {
+			for i := protocol.PacketNumber(1); i <= 5; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+			cong.EXPECT().OnCongestionEvent(protocol.PacketNumber(3), protocol.ByteCount(5))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}, ECT0: 2, ECNCE: 1}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			// no new CE marks
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 5}}, ECT0: 4, ECNCE: 1}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+		})
+
+		It("stops marking packets when ECN validation fails", func() {
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1}))
+			// the ACK doesn't contain any ECN counts
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			p := ackElicitingPacket(&Packet{PacketNumber: 2})
+			handler.SentPacket(p)
+			Expect(p.ECN).To(Equal(protocol.ECNNon))
+			// validation is restarted on the new path
+			cong.EXPECT().OnConnectionMigration()
+			handler.OnConnectionMigration()
+			p = ackElicitingPacket(&Packet{PacketNumber: 3})
+			handler.SentPacket(p)
+			Expect(p.ECN).To(Equal(protocol.ECT0))
+		})
+	})
+
 	Context("congestion", func() {
 		var cong *mocks.MockSendAlgorithmWithDebugInfos
 
@@ -420,6 +477,18 @@ var _ = Describe("SentPacketHandler", func() {
 			handler.congestion = cong
 		})
 
+		It("uses Cubic / NewReno by default", func() {
+			h := newSentPacketHandler(0, &congestion.RTTStats{}, nil, perspective, false, nil, nil, utils.DefaultLogger)
+			Expect(h.congestion).ToNot(BeNil())
+		})
+
+		It("uses the congestion controller provided by the application", func() {
+			h := newSentPacketHandler(0, &congestion.RTTStats{}, cong, perspective, false, nil, nil, utils.DefaultLogger)
+			Expect(h.congestion).To(Equal(cong))
+			cong.EXPECT().OnPacketSent(gomock.Any(), protocol.ByteCount(42), protocol.PacketNumber(1), protocol.ByteCount(42), true)
+			h.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, Length: 42}))
+		})
+
 		It("should call OnSent", func() {
 			cong.EXPECT().OnPacketSent(
 				gomock.Any(),
@@ -451,6 +520,14 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, rcvTime)).To(Succeed())
 		})
 
+		It("resets the RTT estimate and the congestion controller on connection migration", func() {
+			updateRTT(time.Hour)
+			cong.EXPECT().OnConnectionMigration()
+			handler.OnConnectionMigration()
+			Expect(handler.rttStats.SmoothedRTT()).To(BeZero())
+			Expect(handler.rttStats.MinRTT()).To(BeZero())
+		})
+
 		It("doesn't call OnPacketAcked when a retransmitted packet is acked", func() {
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
@@ -468,6 +545,27 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
 		})
 
+		It("doesn't call OnPacketLost when a Path MTU probe packet is lost", func() {
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			var mtuPacketDeclaredLost bool
+			handler.SentPacket(ackElicitingPacket(&Packet{
+				PacketNumber:         1,
+				SendTime:             time.Now().Add(-time.Hour),
+				IsPathMTUProbePacket: true,
+				Frames:               []Frame{{Frame: &wire.PingFrame{}, OnLost: func(wire.Frame) { mtuPacketDeclaredLost = true }}},
+			}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
+			// lose packet 1, but don't EXPECT any call to OnPacketLost()
+			gomock.InOrder(
+				cong.EXPECT().MaybeExitSlowStart(),
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(2), protocol.ByteCount(1), protocol.ByteCount(2), gomock.Any()),
+			)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(mtuPacketDeclaredLost).To(BeTrue())
+			Expect(handler.bytesInFlight).To(BeZero())
+		})
+
 		It("calls OnPacketAcked and OnPacketLost with the right bytes_in_flight value", func() {
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(4)
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
@@ -875,6 +973,44 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("transmission statistics", func() {
+		It("counts sent packets", func() {
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, Length: 1000}))
+			handler.SentPacket(nonAckElicitingPacket(&Packet{PacketNumber: 2, Length: 50}))
+			stats := handler.TransmissionStats()
+			Expect(stats.PacketsSent).To(BeEquivalentTo(2))
+			Expect(stats.BytesSent).To(Equal(protocol.ByteCount(1050)))
+			Expect(stats.PacketsLost).To(BeZero())
+			Expect(stats.PacketsRetransmitted).To(BeZero())
+		})
+
+		It("counts lost packets", func() {
+			for i := protocol.PacketNumber(1); i <= 6; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, Length: protocol.ByteCount(i) * 100}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			stats := handler.TransmissionStats()
+			Expect(stats.PacketsSent).To(BeEquivalentTo(6))
+			Expect(stats.BytesSent).To(Equal(protocol.ByteCount(2100)))
+			Expect(stats.PacketsLost).To(BeEquivalentTo(3))
+			Expect(stats.BytesLost).To(Equal(protocol.ByteCount(600)))
+			Expect(stats.PacketsRetransmitted).To(BeEquivalentTo(3))
+			Expect(stats.BytesRetransmitted).To(Equal(protocol.ByteCount(600)))
+		})
+
+		It("counts packets retransmitted in probe packets", func() {
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 10, Length: 1200}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 11, Length: 1200}))
+			Expect(handler.QueueProbePacket(protocol.Encryption1RTT)).To(BeTrue())
+			stats := handler.TransmissionStats()
+			Expect(stats.PacketsLost).To(BeZero())
+			Expect(stats.PacketsRetransmitted).To(BeEquivalentTo(1))
+			Expect(stats.BytesRetransmitted).To(Equal(protocol.ByteCount(1200)))
+		})
+	})
+
 	Context("Delay-based loss detection", func() {
 		It("immediately detects old packets as lost when receiving an ACK", func() {
 			now := time.Now()
@@ -0,0 +1,134 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// sentPacketState is the state of the bandwidthSampler at the time a packet was sent.
+type sentPacketState struct {
+	sentTime time.Time
+	size     protocol.ByteCount
+
+	delivered     protocol.ByteCount
+	deliveredTime time.Time
+	firstSentTime time.Time
+	isAppLimited  bool
+}
+
+// A bandwidthSample is generated every time a packet is acknowledged.
+type bandwidthSample struct {
+	// The delivery rate. It is 0 if no valid sample could be taken.
+	bandwidth Bandwidth
+	// The time it took to acknowledge the packet.
+	rtt time.Duration
+	// The interval the delivery rate was measured over.
+	interval time.Duration
+	// The number of bytes delivered when the packet was sent.
+	// Used to count round trips.
+	priorDelivered protocol.ByteCount
+	// Set if the packet was sent while the application didn't have enough data to fill the congestion window.
+	isAppLimited bool
+}
+
+// The bandwidthSampler estimates the delivery rate of a connection,
+// as described in draft-cheng-iccrg-delivery-rate-estimation.
+type bandwidthSampler struct {
+	packets map[protocol.PacketNumber]*sentPacketState
+
+	// the total number of bytes acknowledged
+	delivered protocol.ByteCount
+	// the time when delivered was last updated
+	deliveredTime time.Time
+	// the send time of the packet that was most recently acknowledged
+	firstSentTime time.Time
+
+	largestSent protocol.PacketNumber
+	// Samples are marked as app-limited until this packet is acknowledged.
+	endOfAppLimitedPhase protocol.PacketNumber
+}
+
+func newBandwidthSampler() *bandwidthSampler {
+	return &bandwidthSampler{
+		packets:              make(map[protocol.PacketNumber]*sentPacketState),
+		largestSent:          protocol.InvalidPacketNumber,
+		endOfAppLimitedPhase: protocol.InvalidPacketNumber,
+	}
+}
+
+// OnPacketSent is called for every packet that is counted towards bytes in flight.
+func (s *bandwidthSampler) OnPacketSent(sentTime time.Time, packetNumber protocol.PacketNumber, size, priorInFlight protocol.ByteCount) {
+	s.largestSent = packetNumber
+	// When starting to send after an idle period, measure the delivery rate from this point.
+	if priorInFlight == 0 {
+		s.firstSentTime = sentTime
+		s.deliveredTime = sentTime
+	}
+	s.packets[packetNumber] = &sentPacketState{
+		sentTime:      sentTime,
+		size:          size,
+		delivered:     s.delivered,
+		deliveredTime: s.deliveredTime,
+		firstSentTime: s.firstSentTime,
+		isAppLimited:  s.endOfAppLimitedPhase != protocol.InvalidPacketNumber,
+	}
+}
+
+// OnPacketAcked generates a bandwidth sample.
+// It returns false if the packet is not known to the sampler.
+func (s *bandwidthSampler) OnPacketAcked(packetNumber protocol.PacketNumber, ackTime time.Time) (bandwidthSample, bool) {
+	p, ok := s.packets[packetNumber]
+	if !ok {
+		return bandwidthSample{}, false
+	}
+	delete(s.packets, packetNumber)
+
+	s.delivered += p.size
+	s.deliveredTime = ackTime
+	s.firstSentTime = p.sentTime
+	if s.endOfAppLimitedPhase != protocol.InvalidPacketNumber && packetNumber > s.endOfAppLimitedPhase {
+		s.endOfAppLimitedPhase = protocol.InvalidPacketNumber
+	}
+
+	sample := bandwidthSample{
+		rtt:            ackTime.Sub(p.sentTime),
+		priorDelivered: p.delivered,
+		isAppLimited:   p.isAppLimited,
+	}
+	// Use the longer of the send and the ACK interval.
+	// This avoids overestimating the delivery rate due to ACK compression.
+	sendElapsed := p.sentTime.Sub(p.firstSentTime)
+	ackElapsed := ackTime.Sub(p.deliveredTime)
+	sample.interval = sendElapsed
+	if ackElapsed > sendElapsed {
+		sample.interval = ackElapsed
+	}
+	if sample.interval > 0 {
+		sample.bandwidth = BandwidthFromDelta(s.delivered-p.delivered, sample.interval)
+	}
+	return sample, true
+}
+
+// OnPacketLost is called when a packet is declared lost.
+func (s *bandwidthSampler) OnPacketLost(packetNumber protocol.PacketNumber) {
+	delete(s.packets, packetNumber)
+}
+
+// OnAppLimited is called when the application doesn't have enough data to fill the congestion window.
+// Samples taken from packets sent from now on are marked as app-limited,
+// until a packet sent after this call is acknowledged.
+func (s *bandwidthSampler) OnAppLimited() {
+	s.endOfAppLimitedPhase = s.largestSent
+}
+
+// RemoveObsoletePackets removes all packets sent before the given time.
+// The sent packet handler doesn't report packets that are dropped when the keys for an
+// encryption level are dropped. This makes sure these packets don't stay in the map forever.
+func (s *bandwidthSampler) RemoveObsoletePackets(before time.Time) {
+	for pn, p := range s.packets {
+		if p.sentTime.Before(before) {
+			delete(s.packets, pn)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bandwidth Sampler", func() {
+	var (
+		sampler *bandwidthSampler
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		sampler = newBandwidthSampler()
+		now = time.Now()
+	})
+
+	It("ignores unknown packets", func() {
+		_, ok := sampler.OnPacketAcked(1, now)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("measures the delivery rate of paced packets", func() {
+		// send one packet every millisecond, the ACKs arrive 10ms later
+		var inFlight protocol.ByteCount
+		var sample bandwidthSample
+		for t := protocol.PacketNumber(1); t <= 30; t++ {
+			if pn := t - 10; pn >= 1 {
+				var ok bool
+				sample, ok = sampler.OnPacketAcked(pn, now.Add(time.Duration(t)*time.Millisecond))
+				Expect(ok).To(BeTrue())
+				Expect(sample.rtt).To(Equal(10 * time.Millisecond))
+				inFlight -= 1000
+			}
+			if t <= 20 {
+				sampler.OnPacketSent(now.Add(time.Duration(t)*time.Millisecond), t, 1000, inFlight)
+				inFlight += 1000
+			}
+		}
+		Expect(sample.bandwidth).To(Equal(1000 * 1000 * BytesPerSecond))
+		Expect(sample.interval).To(Equal(10 * time.Millisecond))
+		Expect(sample.priorDelivered).To(Equal(protocol.ByteCount(10 * 1000)))
+		Expect(sample.isAppLimited).To(BeFalse())
+		Expect(sampler.delivered).To(Equal(protocol.ByteCount(20 * 1000)))
+	})
+
+	It("uses the ACK interval for packets sent in a burst", func() {
+		// send a burst of 10 packets, the ACKs arrive one every 2ms
+		var inFlight protocol.ByteCount
+		for pn := protocol.PacketNumber(1); pn <= 10; pn++ {
+			sampler.OnPacketSent(now, pn, 1000, inFlight)
+			inFlight += 1000
+		}
+		var sample bandwidthSample
+		for pn := protocol.PacketNumber(1); pn <= 10; pn++ {
+			sample, _ = sampler.OnPacketAcked(pn, now.Add(10*time.Millisecond+time.Duration(2*pn)*time.Millisecond))
+		}
+		Expect(sample.interval).To(Equal(30 * time.Millisecond))
+		Expect(sample.bandwidth).To(Equal(BandwidthFromDelta(10*1000, 30*time.Millisecond)))
+	})
+
+	It("marks samples as app-limited", func() {
+		sampler.OnPacketSent(now, 1, 1000, 0)
+		sampler.OnAppLimited()
+		sampler.OnPacketSent(now.Add(time.Millisecond), 2, 1000, 1000)
+		sampler.OnPacketSent(now.Add(2*time.Millisecond), 3, 1000, 2000)
+		sample, _ := sampler.OnPacketAcked(1, now.Add(10*time.Millisecond))
+		Expect(sample.isAppLimited).To(BeFalse())
+		sample, _ = sampler.OnPacketAcked(2, now.Add(11*time.Millisecond))
+		Expect(sample.isAppLimited).To(BeTrue())
+		sample, _ = sampler.OnPacketAcked(3, now.Add(12*time.Millisecond))
+		Expect(sample.isAppLimited).To(BeTrue())
+		sampler.OnPacketSent(now.Add(13*time.Millisecond), 4, 1000, 0)
+		sample, _ = sampler.OnPacketAcked(4, now.Add(23*time.Millisecond))
+		Expect(sample.isAppLimited).To(BeFalse())
+	})
+
+	It("forgets lost packets", func() {
+		sampler.OnPacketSent(now, 1, 1000, 0)
+		sampler.OnPacketLost(1)
+		_, ok := sampler.OnPacketAcked(1, now.Add(10*time.Millisecond))
+		Expect(ok).To(BeFalse())
+		Expect(sampler.delivered).To(BeZero())
+	})
+
+	It("removes obsolete packets", func() {
+		sampler.OnPacketSent(now, 1, 1000, 0)
+		sampler.OnPacketSent(now.Add(time.Second), 2, 1000, 1000)
+		sampler.RemoveObsoletePackets(now.Add(time.Millisecond))
+		Expect(sampler.packets).To(HaveLen(1))
+		Expect(sampler.packets).To(HaveKey(protocol.PacketNumber(2)))
+	})
+})
@@ -0,0 +1,516 @@
+package congestion
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const (
+	// In Startup, BBR uses a gain of 2/ln(2), which allows doubling the sending rate every round trip.
+	bbrHighGain = 2.885
+	// In Drain, BBR uses the inverse gain to drain the queue created in Startup.
+	bbrDrainGain = 1 / bbrHighGain
+	// In ProbeBW, the congestion window is set to twice the bandwidth-delay product.
+	bbrCwndGain = 2
+	// The number of round trips the maximum bandwidth filter covers.
+	bbrBandwidthWindow = 10
+	// The min RTT expires after this time, at which point BBR enters ProbeRTT.
+	bbrMinRTTExpiry = 10 * time.Second
+	// The minimum time spent in ProbeRTT.
+	bbrProbeRTTDuration = 200 * time.Millisecond
+	// Startup ends when the bandwidth estimate grows less than 25% for 3 round trips.
+	bbrStartupGrowthTarget   = 1.25
+	bbrStartupRoundsNoGrowth = 3
+	// The congestion window used in ProbeRTT.
+	bbrMinCongestionWindow = 4 * maxDatagramSize
+	// Allow for some extra data in flight, to make up for delayed and stretched ACKs.
+	bbrQuantizationAllowance = 3 * maxDatagramSize
+)
+
+// In ProbeBW, BBR cycles through these pacing gains, spending one min RTT in each phase.
+var bbrPacingGainCycle = [...]float64{1.25, 0.75, 1, 1, 1, 1, 1, 1}
+
+type bbrMode uint8
+
+const (
+	// Startup: exponentially grow the sending rate, until the bandwidth estimate stops growing.
+	bbrModeStartup bbrMode = iota
+	// Drain: drain the queue created during Startup.
+	bbrModeDrain
+	// ProbeBW: cycle the sending rate around the estimated bandwidth, to probe for more bandwidth.
+	bbrModeProbeBW
+	// ProbeRTT: reduce the data in flight to measure the min RTT.
+	bbrModeProbeRTT
+)
+
+func (m bbrMode) String() string {
+	switch m {
+	case bbrModeStartup:
+		return "Startup"
+	case bbrModeDrain:
+		return "Drain"
+	case bbrModeProbeBW:
+		return "ProbeBW"
+	case bbrModeProbeRTT:
+		return "ProbeRTT"
+	default:
+		return "unknown BBR mode"
+	}
+}
+
+// The bbrSender implements BBR (version 1) congestion control,
+// as described in draft-cardwell-iccrg-bbr-congestion-control-00.
+// It builds a model of the path from the maximum delivery rate and the minimum RTT,
+// and paces packets at the estimated bottleneck bandwidth.
+type bbrSender struct {
+	clock    Clock
+	rttStats *RTTStats
+	pacer    *Pacer
+	sampler  *bandwidthSampler
+
+	mode bbrMode
+
+	maxBandwidth *maxBandwidthFilter
+	pacingRate   Bandwidth
+	pacingGain   float64
+	cwndGain     float64
+
+	// round trip counting
+	roundCount         uint64
+	nextRoundDelivered protocol.ByteCount
+	roundStart         bool
+
+	minRTT          time.Duration
+	minRTTTimestamp time.Time
+
+	congestionWindow        protocol.ByteCount
+	priorCongestionWindow   protocol.ByteCount
+	initialCongestionWindow protocol.ByteCount
+	maxCongestionWindow     protocol.ByteCount
+
+	// Startup
+	filledPipe         bool
+	fullBandwidth      Bandwidth
+	fullBandwidthCount int
+
+	// ProbeBW
+	cycleIndex     int
+	cycleStart     time.Time
+	lostSinceCycle bool
+
+	// ProbeRTT
+	probeRTTDoneTime  time.Time
+	probeRTTRoundDone bool
+
+	// loss recovery
+	inRecovery     bool
+	endOfRecovery  protocol.PacketNumber
+	recoveryWindow protocol.ByteCount
+
+	largestSentPacketNumber protocol.PacketNumber
+
+	// used to estimate the bytes in flight while processing an ACK frame
+	lastAckTime        time.Time
+	bytesAckedInAckEvt protocol.ByteCount
+}
+
+var _ SendAlgorithm = &bbrSender{}
+var _ SendAlgorithmWithDebugInfos = &bbrSender{}
+
+// NewBBRSender makes a new BBR sender
+func NewBBRSender(clock Clock, rttStats *RTTStats) *bbrSender {
+	return newBBRSender(clock, rttStats, initialCongestionWindow, maxCongestionWindow)
+}
+
+func newBBRSender(clock Clock, rttStats *RTTStats, initialCongestionWindow, maxCongestionWindow protocol.ByteCount) *bbrSender {
+	b := &bbrSender{
+		clock:                   clock,
+		rttStats:                rttStats,
+		initialCongestionWindow: initialCongestionWindow,
+		maxCongestionWindow:     maxCongestionWindow,
+	}
+	b.pacer = NewPacer(b.pacerBandwidth)
+	b.reset()
+	return b
+}
+
+func (b *bbrSender) reset() {
+	b.sampler = newBandwidthSampler()
+	b.maxBandwidth = newMaxBandwidthFilter(bbrBandwidthWindow)
+	b.pacingRate = 0
+	b.roundCount = 0
+	b.nextRoundDelivered = 0
+	b.roundStart = false
+	b.minRTT = 0
+	b.minRTTTimestamp = time.Time{}
+	b.congestionWindow = b.initialCongestionWindow
+	b.priorCongestionWindow = 0
+	b.filledPipe = false
+	b.fullBandwidth = 0
+	b.fullBandwidthCount = 0
+	b.probeRTTDoneTime = time.Time{}
+	b.inRecovery = false
+	b.largestSentPacketNumber = protocol.InvalidPacketNumber
+	b.endOfRecovery = protocol.InvalidPacketNumber
+	b.enterStartup()
+}
+
+// TimeUntilSend returns when the next packet should be sent.
+func (b *bbrSender) TimeUntilSend(_ protocol.ByteCount) time.Time {
+	return b.pacer.TimeUntilSend()
+}
+
+func (b *bbrSender) HasPacingBudget() bool {
+	return b.pacer.Budget(b.clock.Now()) >= maxDatagramSize
+}
+
+func (b *bbrSender) OnPacketSent(
+	sentTime time.Time,
+	bytesInFlight protocol.ByteCount,
+	packetNumber protocol.PacketNumber,
+	bytes protocol.ByteCount,
+	isRetransmittable bool,
+) {
+	b.pacer.SentPacket(sentTime, bytes)
+	if !isRetransmittable {
+		return
+	}
+	b.largestSentPacketNumber = packetNumber
+	b.sampler.OnPacketSent(sentTime, packetNumber, bytes, bytesInFlight-bytes)
+}
+
+func (b *bbrSender) CanSend(bytesInFlight protocol.ByteCount) bool {
+	return bytesInFlight < b.GetCongestionWindow()
+}
+
+// MaybeExitSlowStart is a no-op.
+// BBR leaves Startup based on the growth of the bandwidth estimate, not based on RTT increases.
+func (b *bbrSender) MaybeExitSlowStart() {}
+
+func (b *bbrSender) OnPacketAcked(
+	packetNumber protocol.PacketNumber,
+	ackedBytes protocol.ByteCount,
+	priorInFlight protocol.ByteCount,
+	eventTime time.Time,
+) {
+	// All packets acknowledged by the same ACK frame share the same priorInFlight.
+	if eventTime != b.lastAckTime {
+		b.lastAckTime = eventTime
+		b.bytesAckedInAckEvt = 0
+	}
+	b.bytesAckedInAckEvt += ackedBytes
+	var bytesInFlight protocol.ByteCount
+	if priorInFlight > b.bytesAckedInAckEvt {
+		bytesInFlight = priorInFlight - b.bytesAckedInAckEvt
+	}
+
+	sample, ok := b.sampler.OnPacketAcked(packetNumber, eventTime)
+	if !ok {
+		return
+	}
+	if b.inRecovery && packetNumber > b.endOfRecovery {
+		b.inRecovery = false
+		b.congestionWindow = utils.MaxByteCount(b.congestionWindow, b.priorCongestionWindow)
+	}
+	b.updateRound(sample, eventTime)
+	b.updateBandwidth(sample)
+	b.updateMinRTT(sample.rtt, eventTime)
+	b.checkFullPipe(sample)
+	b.updateMode(priorInFlight, bytesInFlight, eventTime)
+	b.updatePacingRate()
+	b.updateCongestionWindow(ackedBytes, bytesInFlight)
+
+	// We can't know if the application has more data to send.
+	// If the data in flight is well below the bandwidth-delay product,
+	// the connection is likely limited by the application, not by the network.
+	if b.mode != bbrModeProbeRTT && b.maxBandwidth.Get() > 0 && priorInFlight < b.bdp()/2 {
+		b.sampler.OnAppLimited()
+	}
+}
+
+func (b *bbrSender) updateRound(sample bandwidthSample, now time.Time) {
+	b.roundStart = false
+	if sample.priorDelivered < b.nextRoundDelivered {
+		return
+	}
+	b.nextRoundDelivered = b.sampler.delivered
+	b.roundCount++
+	b.roundStart = true
+	if srtt := b.rttStats.SmoothedRTT(); srtt > 0 {
+		b.sampler.RemoveObsoletePackets(now.Add(-4 * srtt))
+	}
+}
+
+func (b *bbrSender) updateBandwidth(sample bandwidthSample) {
+	if sample.bandwidth == 0 {
+		return
+	}
+	// Samples taken over less than the min RTT are likely inflated by ACK compression.
+	if b.minRTT > 0 && sample.interval < b.minRTT {
+		return
+	}
+	// App-limited samples underestimate the bandwidth.
+	if sample.isAppLimited && sample.bandwidth < b.maxBandwidth.Get() {
+		return
+	}
+	b.maxBandwidth.Update(sample.bandwidth, b.roundCount)
+}
+
+func (b *bbrSender) updateMinRTT(rtt time.Duration, now time.Time) {
+	expired := !b.minRTTTimestamp.IsZero() && now.Sub(b.minRTTTimestamp) > bbrMinRTTExpiry
+	if rtt > 0 && (b.minRTT == 0 || rtt <= b.minRTT || expired) {
+		b.minRTT = rtt
+		b.minRTTTimestamp = now
+	}
+	if expired && b.mode != bbrModeProbeRTT {
+		b.enterProbeRTT()
+	}
+}
+
+func (b *bbrSender) checkFullPipe(sample bandwidthSample) {
+	if b.filledPipe || !b.roundStart || sample.isAppLimited {
+		return
+	}
+	if float64(b.maxBandwidth.Get()) >= bbrStartupGrowthTarget*float64(b.fullBandwidth) {
+		b.fullBandwidth = b.maxBandwidth.Get()
+		b.fullBandwidthCount = 0
+		return
+	}
+	b.fullBandwidthCount++
+	if b.fullBandwidthCount >= bbrStartupRoundsNoGrowth {
+		b.filledPipe = true
+	}
+}
+
+func (b *bbrSender) updateMode(priorInFlight, bytesInFlight protocol.ByteCount, now time.Time) {
+	switch b.mode {
+	case bbrModeStartup:
+		if b.filledPipe {
+			b.mode = bbrModeDrain
+			b.pacingGain = bbrDrainGain
+			b.cwndGain = bbrHighGain
+		}
+	case bbrModeProbeBW:
+		if b.isNextCyclePhase(priorInFlight, bytesInFlight, now) {
+			b.advanceCyclePhase(now)
+		}
+	case bbrModeProbeRTT:
+		b.handleProbeRTT(bytesInFlight, now)
+	}
+	if b.mode == bbrModeDrain && bytesInFlight <= b.targetInFlight(1) {
+		b.enterProbeBW(now)
+	}
+}
+
+func (b *bbrSender) enterStartup() {
+	b.mode = bbrModeStartup
+	b.pacingGain = bbrHighGain
+	b.cwndGain = bbrHighGain
+}
+
+func (b *bbrSender) enterProbeBW(now time.Time) {
+	b.mode = bbrModeProbeBW
+	b.cwndGain = bbrCwndGain
+	// Start at a random phase, but never in the phase that drains the queue.
+	b.cycleIndex = rand.Intn(len(bbrPacingGainCycle) - 1)
+	if b.cycleIndex >= 1 {
+		b.cycleIndex++
+	}
+	b.cycleStart = now
+	b.lostSinceCycle = false
+	b.pacingGain = bbrPacingGainCycle[b.cycleIndex]
+}
+
+func (b *bbrSender) isNextCyclePhase(priorInFlight, bytesInFlight protocol.ByteCount, now time.Time) bool {
+	isFullLength := now.Sub(b.cycleStart) > b.minRTT
+	switch {
+	case b.pacingGain > 1:
+		// Stay in the probing phase until the queue was actually filled, or packets were lost.
+		return isFullLength && (b.lostSinceCycle || priorInFlight >= b.targetInFlight(b.pacingGain))
+	case b.pacingGain < 1:
+		// Leave the draining phase early when the queue has drained.
+		return isFullLength || bytesInFlight <= b.targetInFlight(1)
+	default:
+		return isFullLength
+	}
+}
+
+func (b *bbrSender) advanceCyclePhase(now time.Time) {
+	b.cycleIndex = (b.cycleIndex + 1) % len(bbrPacingGainCycle)
+	b.cycleStart = now
+	b.lostSinceCycle = false
+	b.pacingGain = bbrPacingGainCycle[b.cycleIndex]
+}
+
+func (b *bbrSender) enterProbeRTT() {
+	b.mode = bbrModeProbeRTT
+	b.pacingGain = 1
+	b.cwndGain = 1
+	b.saveCongestionWindow()
+	b.probeRTTDoneTime = time.Time{}
+}
+
+func (b *bbrSender) handleProbeRTT(bytesInFlight protocol.ByteCount, now time.Time) {
+	if b.probeRTTDoneTime.IsZero() {
+		if bytesInFlight <= bbrMinCongestionWindow {
+			b.probeRTTDoneTime = now.Add(bbrProbeRTTDuration)
+			b.probeRTTRoundDone = false
+			b.nextRoundDelivered = b.sampler.delivered
+		}
+		return
+	}
+	if b.roundStart {
+		b.probeRTTRoundDone = true
+	}
+	if b.probeRTTRoundDone && now.After(b.probeRTTDoneTime) {
+		b.minRTTTimestamp = now
+		b.congestionWindow = utils.MaxByteCount(b.congestionWindow, b.priorCongestionWindow)
+		if b.filledPipe {
+			b.enterProbeBW(now)
+		} else {
+			b.enterStartup()
+		}
+	}
+}
+
+func (b *bbrSender) saveCongestionWindow() {
+	if b.inRecovery || b.mode == bbrModeProbeRTT {
+		b.priorCongestionWindow = utils.MaxByteCount(b.priorCongestionWindow, b.congestionWindow)
+	} else {
+		b.priorCongestionWindow = b.congestionWindow
+	}
+}
+
+func (b *bbrSender) updatePacingRate() {
+	bw := b.maxBandwidth.Get()
+	if bw == 0 {
+		return
+	}
+	rate := Bandwidth(b.pacingGain * float64(bw))
+	// Don't reduce the pacing rate in Startup.
+	if b.filledPipe || rate > b.pacingRate {
+		b.pacingRate = rate
+	}
+}
+
+func (b *bbrSender) updateCongestionWindow(ackedBytes, bytesInFlight protocol.ByteCount) {
+	if b.inRecovery {
+		// packet conservation: send at most as much data as was acknowledged
+		b.recoveryWindow = utils.MaxByteCount(b.recoveryWindow, bytesInFlight+ackedBytes)
+	}
+	if b.mode == bbrModeProbeRTT {
+		return
+	}
+	target := b.targetInFlight(b.cwndGain)
+	if b.filledPipe {
+		b.congestionWindow = utils.MinByteCount(b.congestionWindow+ackedBytes, target)
+	} else if b.congestionWindow < target || b.sampler.delivered < b.initialCongestionWindow {
+		b.congestionWindow += ackedBytes
+	}
+	b.congestionWindow = utils.MaxByteCount(b.congestionWindow, bbrMinCongestionWindow)
+	b.congestionWindow = utils.MinByteCount(b.congestionWindow, b.maxCongestionWindow)
+}
+
+// bdp returns the estimated bandwidth-delay product.
+func (b *bbrSender) bdp() protocol.ByteCount {
+	return protocol.ByteCount(float64(b.maxBandwidth.Get()/BytesPerSecond) * b.minRTT.Seconds())
+}
+
+// targetInFlight returns the amount of data that should be in flight for the given gain.
+func (b *bbrSender) targetInFlight(gain float64) protocol.ByteCount {
+	if b.maxBandwidth.Get() == 0 || b.minRTT == 0 {
+		return b.initialCongestionWindow
+	}
+	return protocol.ByteCount(gain*float64(b.bdp())) + bbrQuantizationAllowance
+}
+
+// pacerBandwidth is the bandwidth used by the pacer.
+func (b *bbrSender) pacerBandwidth() Bandwidth {
+	rate := b.pacingRate
+	if rate == 0 {
+		// Before the first bandwidth sample, pace the initial window over the RTT.
+		srtt := b.rttStats.SmoothedRTT()
+		if srtt == 0 {
+			return infBandwidth
+		}
+		rate = Bandwidth(b.pacingGain * float64(BandwidthFromDelta(b.congestionWindow, srtt)))
+	}
+	// The pacer uses a rate 25% higher than the bandwidth passed to it.
+	// BBR's pacing gains already take care of probing for more bandwidth.
+	return rate / 5 * 4
+}
+
+func (b *bbrSender) OnPacketLost(
+	packetNumber protocol.PacketNumber,
+	lostBytes protocol.ByteCount,
+	priorInFlight protocol.ByteCount,
+) {
+	b.sampler.OnPacketLost(packetNumber)
+	b.lostSinceCycle = true
+	if b.inRecovery {
+		if b.recoveryWindow > lostBytes {
+			b.recoveryWindow -= lostBytes
+		}
+		b.recoveryWindow = utils.MaxByteCount(b.recoveryWindow, bbrMinCongestionWindow)
+		return
+	}
+	b.enterRecovery()
+	var inFlight protocol.ByteCount
+	if priorInFlight > lostBytes {
+		inFlight = priorInFlight - lostBytes
+	}
+	b.recoveryWindow = utils.MaxByteCount(inFlight, bbrMinCongestionWindow)
+}
+
+func (b *bbrSender) enterRecovery() {
+	b.saveCongestionWindow()
+	b.inRecovery = true
+	b.endOfRecovery = b.largestSentPacketNumber
+}
+
+// OnCongestionEvent is a no-op.
+// BBR (version 1) doesn't react to ECN-CE marks.
+func (b *bbrSender) OnCongestionEvent(protocol.PacketNumber, protocol.ByteCount) {}
+
+// OnRetransmissionTimeout is called on an retransmission timeout
+func (b *bbrSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	if !packetsRetransmitted {
+		return
+	}
+	if !b.inRecovery {
+		b.enterRecovery()
+	}
+	b.recoveryWindow = bbrMinCongestionWindow
+}
+
+// OnConnectionMigration is called when the connection is migrated to a new path.
+// The path model is not valid for the new path, so BBR starts over in Startup.
+func (b *bbrSender) OnConnectionMigration() {
+	b.reset()
+}
+
+func (b *bbrSender) InSlowStart() bool {
+	return b.mode == bbrModeStartup
+}
+
+func (b *bbrSender) InRecovery() bool {
+	return b.inRecovery
+}
+
+func (b *bbrSender) GetCongestionWindow() protocol.ByteCount {
+	if b.mode == bbrModeProbeRTT {
+		return bbrMinCongestionWindow
+	}
+	if b.inRecovery {
+		return utils.MinByteCount(b.congestionWindow, b.recoveryWindow)
+	}
+	return b.congestionWindow
+}
+
+// BandwidthEstimate returns the current bandwidth estimate
+func (b *bbrSender) BandwidthEstimate() Bandwidth {
+	return b.maxBandwidth.Get()
+}
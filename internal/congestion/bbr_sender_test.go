@@ -0,0 +1,235 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BBR Sender", func() {
+	const (
+		linkBandwidth = 10 * 1000 * 1000 * BitsPerSecond
+		linkRTT       = 40 * time.Millisecond
+	)
+
+	type simPacket struct {
+		packetNumber protocol.PacketNumber
+		sentTime     time.Time
+		ackTime      time.Time
+	}
+
+	var (
+		sender        *bbrSender
+		clock         mockClock
+		rttStats      *RTTStats
+		bytesInFlight protocol.ByteCount
+		packetNumber  protocol.PacketNumber
+		inFlight      []simPacket
+		linkFreeAt    time.Time
+		// can be increased to simulate a change of the path
+		propagationDelay time.Duration
+		// if set, packets are only sent when the test calls sendPacket
+		appLimited bool
+	)
+
+	BeforeEach(func() {
+		clock = mockClock(time.Now())
+		rttStats = NewRTTStats()
+		sender = NewBBRSender(&clock, rttStats)
+		bytesInFlight = 0
+		packetNumber = 1
+		inFlight = nil
+		linkFreeAt = time.Time{}
+		propagationDelay = linkRTT
+		appLimited = false
+	})
+
+	sendPacket := func() {
+		now := clock.Now()
+		bytesInFlight += maxDatagramSize
+		sender.OnPacketSent(now, bytesInFlight, packetNumber, maxDatagramSize, true)
+		// the packet is queued at the bottleneck link
+		if linkFreeAt.Before(now) {
+			linkFreeAt = now
+		}
+		linkFreeAt = linkFreeAt.Add(time.Duration(uint64(maxDatagramSize) * uint64(time.Second) / uint64(linkBandwidth/BytesPerSecond)))
+		inFlight = append(inFlight, simPacket{
+			packetNumber: packetNumber,
+			sentTime:     now,
+			ackTime:      linkFreeAt.Add(propagationDelay),
+		})
+		packetNumber++
+	}
+
+	// simulate a connection over a single bottleneck link
+	// Unless appLimited is set, the application always has data to send.
+	simulate := func(d time.Duration) {
+		end := clock.Now().Add(d)
+		for clock.Now().Before(end) {
+			now := clock.Now()
+			for !appLimited && sender.CanSend(bytesInFlight) && !sender.TimeUntilSend(bytesInFlight).After(now) {
+				sendPacket()
+			}
+			next := end
+			if len(inFlight) > 0 && inFlight[0].ackTime.Before(next) {
+				next = inFlight[0].ackTime
+			}
+			if !appLimited && sender.CanSend(bytesInFlight) {
+				if t := sender.TimeUntilSend(bytesInFlight); t.After(now) && t.Before(next) {
+					next = t
+				}
+			}
+			clock.Advance(next.Sub(now))
+			now = clock.Now()
+			priorInFlight := bytesInFlight
+			for len(inFlight) > 0 && !inFlight[0].ackTime.After(now) {
+				p := inFlight[0]
+				inFlight = inFlight[1:]
+				rttStats.UpdateRTT(now.Sub(p.sentTime), 0, now)
+				sender.OnPacketAcked(p.packetNumber, maxDatagramSize, priorInFlight, now)
+				bytesInFlight -= maxDatagramSize
+			}
+		}
+	}
+
+	It("starts in Startup", func() {
+		Expect(sender.mode).To(Equal(bbrModeStartup))
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(Equal(initialCongestionWindow))
+		Expect(sender.BandwidthEstimate()).To(BeZero())
+	})
+
+	It("doesn't pace before an RTT was measured", func() {
+		sender.OnPacketSent(clock.Now(), maxDatagramSize, 1, maxDatagramSize, true)
+		Expect(sender.HasPacingBudget()).To(BeTrue())
+		Expect(sender.TimeUntilSend(maxDatagramSize)).To(BeZero())
+	})
+
+	It("estimates the bandwidth and the min RTT, and enters ProbeBW", func() {
+		simulate(3 * time.Second)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		Expect(sender.InSlowStart()).To(BeFalse())
+		Expect(sender.filledPipe).To(BeTrue())
+		Expect(sender.minRTT).To(BeNumerically("~", linkRTT, 2*time.Millisecond))
+		Expect(sender.BandwidthEstimate()).To(BeNumerically("~", linkBandwidth, linkBandwidth/10))
+		// the congestion window is twice the bandwidth-delay product
+		bdp := protocol.ByteCount(uint64(linkBandwidth/BytesPerSecond) * uint64(linkRTT) / uint64(time.Second))
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("~", 2*bdp, bdp/5))
+	})
+
+	It("doesn't build a standing queue", func() {
+		simulate(3 * time.Second)
+		rttStats = NewRTTStats()
+		simulate(time.Second)
+		// In ProbeBW, BBR only briefly probes for more bandwidth, and drains the queue right afterwards.
+		Expect(rttStats.SmoothedRTT()).To(BeNumerically("<", linkRTT*3/2))
+	})
+
+	It("cycles through the pacing gains in ProbeBW", func() {
+		simulate(3 * time.Second)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		gains := make(map[float64]struct{})
+		for i := 0; i < 2*len(bbrPacingGainCycle); i++ {
+			simulate(linkRTT)
+			gains[sender.pacingGain] = struct{}{}
+		}
+		Expect(gains).To(HaveKey(1.25))
+		Expect(gains).To(HaveKey(0.75))
+		Expect(gains).To(HaveKey(1.0))
+	})
+
+	It("enters ProbeRTT when the min RTT expires", func() {
+		simulate(3 * time.Second)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		minRTTTimestamp := sender.minRTTTimestamp
+		// The RTT increases, so the min RTT won't be updated by new samples.
+		propagationDelay = linkRTT + 10*time.Millisecond
+		var enteredProbeRTT time.Time
+		for i := 0; i < 1200; i++ {
+			simulate(10 * time.Millisecond)
+			if sender.mode == bbrModeProbeRTT {
+				enteredProbeRTT = clock.Now()
+				break
+			}
+		}
+		Expect(enteredProbeRTT.Sub(minRTTTimestamp)).To(BeNumerically(">", bbrMinRTTExpiry))
+		Expect(sender.GetCongestionWindow()).To(Equal(bbrMinCongestionWindow))
+		Expect(sender.minRTT).To(BeNumerically(">=", propagationDelay))
+		// BBR stays in ProbeRTT for at least 200ms, after the data in flight was reduced
+		simulate(bbrProbeRTTDuration - 10*time.Millisecond)
+		Expect(sender.mode).To(Equal(bbrModeProbeRTT))
+		simulate(bbrProbeRTTDuration)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">", bbrMinCongestionWindow))
+	})
+
+	It("uses packet conservation when packets are lost", func() {
+		simulate(3 * time.Second)
+		cwnd := sender.GetCongestionWindow()
+		Expect(bytesInFlight).To(BeNumerically(">", 10*maxDatagramSize))
+		lost := inFlight[0]
+		inFlight = inFlight[1:]
+		sender.OnPacketLost(lost.packetNumber, maxDatagramSize, bytesInFlight)
+		bytesInFlight -= maxDatagramSize
+		Expect(sender.InRecovery()).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(Equal(bytesInFlight))
+		Expect(sender.CanSend(bytesInFlight)).To(BeFalse())
+		// once a packet sent after the loss is acknowledged, recovery ends
+		simulate(3 * linkRTT)
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", cwnd*9/10))
+	})
+
+	It("uses the minimum congestion window on a retransmission timeout", func() {
+		simulate(3 * time.Second)
+		sender.OnRetransmissionTimeout(false)
+		Expect(sender.InRecovery()).To(BeFalse())
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.InRecovery()).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(Equal(bbrMinCongestionWindow))
+	})
+
+	It("doesn't react to ECN-CE marks", func() {
+		simulate(3 * time.Second)
+		cwnd := sender.GetCongestionWindow()
+		sender.OnCongestionEvent(packetNumber-1, bytesInFlight)
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+	})
+
+	It("resets the path model on connection migration", func() {
+		simulate(3 * time.Second)
+		Expect(sender.BandwidthEstimate()).ToNot(BeZero())
+		sender.OnConnectionMigration()
+		Expect(sender.mode).To(Equal(bbrModeStartup))
+		Expect(sender.BandwidthEstimate()).To(BeZero())
+		Expect(sender.minRTT).To(BeZero())
+		Expect(sender.GetCongestionWindow()).To(Equal(initialCongestionWindow))
+	})
+
+	It("doesn't overestimate the bandwidth when the application doesn't send enough data", func() {
+		// send one packet every 10ms, much less than the link capacity
+		appLimited = true
+		for i := 0; i < 200; i++ {
+			sendPacket()
+			simulate(10 * time.Millisecond)
+		}
+		rate := BandwidthFromDelta(maxDatagramSize, 10*time.Millisecond)
+		Expect(sender.BandwidthEstimate()).To(BeNumerically("~", rate, rate/10))
+	})
+
+	It("doesn't reduce the bandwidth estimate when the application doesn't send enough data", func() {
+		simulate(3 * time.Second)
+		Expect(sender.BandwidthEstimate()).To(BeNumerically("~", linkBandwidth, linkBandwidth/10))
+		appLimited = true
+		for i := 0; i < 200; i++ {
+			sendPacket()
+			simulate(10 * time.Millisecond)
+		}
+		Expect(sender.sampler.endOfAppLimitedPhase).ToNot(Equal(protocol.InvalidPacketNumber))
+		Expect(sender.BandwidthEstimate()).To(BeNumerically("~", linkBandwidth, linkBandwidth/10))
+	})
+})
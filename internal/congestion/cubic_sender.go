@@ -23,7 +23,7 @@ type cubicSender struct {
 	rttStats        *RTTStats
 	stats           connectionStats
 	cubic           *Cubic
-	pacer           *pacer
+	pacer           *Pacer
 	clock           Clock
 
 	reno bool
@@ -93,7 +93,7 @@ func newCubicSender(clock Clock, rttStats *RTTStats, reno bool, initialCongestio
 		clock:                      clock,
 		reno:                       reno,
 	}
-	c.pacer = newPacer(c.BandwidthEstimate)
+	c.pacer = NewPacer(c.BandwidthEstimate)
 	return c
 }
 
@@ -194,7 +194,24 @@ func (c *cubicSender) OnPacketLost(
 	if c.InSlowStart() {
 		c.stats.slowstartPacketsLost++
 	}
+	c.reduceCongestionWindow()
+}
+
+// OnCongestionEvent is called when the peer reports an increase of the ECN-CE counter.
+// packetNumber is the largest packet number acknowledged by the ACK frame.
+// Like a packet loss, this reduces the congestion window at most once per round trip.
+func (c *cubicSender) OnCongestionEvent(
+	packetNumber protocol.PacketNumber,
+	priorInFlight protocol.ByteCount,
+) {
+	if packetNumber <= c.largestSentAtLastCutback {
+		return
+	}
+	c.lastCutbackExitedSlowstart = c.InSlowStart()
+	c.reduceCongestionWindow()
+}
 
+func (c *cubicSender) reduceCongestionWindow() {
 	// TODO(chromium): Separate out all of slow start into a separate class.
 	if c.slowStartLargeReduction && c.InSlowStart() {
 		if c.congestionWindow >= 2*c.initialCongestionWindow {
@@ -304,7 +321,8 @@ func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 	c.congestionWindow = c.minCongestionWindow
 }
 
-// OnConnectionMigration is called when the connection is migrated (?)
+// OnConnectionMigration is called when the connection is migrated to a new path.
+// It resets the congestion window and the slow start state.
 func (c *cubicSender) OnConnectionMigration() {
 	c.hybridSlowStart.Restart()
 	c.largestSentPacketNumber = protocol.InvalidPacketNumber
@@ -442,6 +442,23 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(postLossWindow).To(BeNumerically(">", sender.GetCongestionWindow()))
 	})
 
+	It("reduces the congestion window on a congestion event", func() {
+		SendAvailableSendWindow()
+		initialWindow := sender.GetCongestionWindow()
+		sender.OnCongestionEvent(ackedPacketNumber+1, bytesInFlight)
+		postEventWindow := sender.GetCongestionWindow()
+		Expect(postEventWindow).To(BeNumerically("<", initialWindow))
+		Expect(sender.GetSlowStartThreshold()).To(Equal(postEventWindow))
+		// only reduce the window once per round trip
+		sender.OnCongestionEvent(packetNumber-1, bytesInFlight)
+		Expect(sender.GetCongestionWindow()).To(Equal(postEventWindow))
+		// a loss in the same round trip doesn't reduce the window either
+		LosePacket(packetNumber - 1)
+		Expect(sender.GetCongestionWindow()).To(Equal(postEventWindow))
+		sender.OnCongestionEvent(packetNumber, bytesInFlight)
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<", postEventWindow))
+	})
+
 	It("2 connection congestion avoidance at end of recovery", func() {
 		sender.SetNumEmulatedConnections(2)
 		// Ack 10 packets in 5 acks to raise the CWND to 20.
@@ -8,14 +8,26 @@ import (
 
 // A SendAlgorithm performs congestion control
 type SendAlgorithm interface {
+	// TimeUntilSend returns when the next packet may be sent, taking pacing into account.
 	TimeUntilSend(bytesInFlight protocol.ByteCount) time.Time
+	// HasPacingBudget says if the pacer allows sending a packet right now.
 	HasPacingBudget() bool
+	// OnPacketSent is called for every packet sent, after bytesInFlight was increased.
 	OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool)
+	// CanSend says if the congestion window allows sending more data.
 	CanSend(bytesInFlight protocol.ByteCount) bool
+	// MaybeExitSlowStart is called after the RTT estimate was updated.
 	MaybeExitSlowStart()
+	// OnPacketAcked is called for every packet newly acknowledged.
+	// priorInFlight is the number of bytes in flight before the ACK was processed.
 	OnPacketAcked(number protocol.PacketNumber, ackedBytes protocol.ByteCount, priorInFlight protocol.ByteCount, eventTime time.Time)
+	// OnPacketLost is called for every packet declared lost.
 	OnPacketLost(number protocol.PacketNumber, lostBytes protocol.ByteCount, priorInFlight protocol.ByteCount)
+	// OnCongestionEvent is called when the peer reports new ECN-CE marks.
+	OnCongestionEvent(number protocol.PacketNumber, priorInFlight protocol.ByteCount)
 	OnRetransmissionTimeout(packetsRetransmitted bool)
+	// OnConnectionMigration is called when the connection switches to a new path.
+	OnConnectionMigration()
 }
 
 // A SendAlgorithmWithDebugInfos is a SendAlgorithm that exposes some debug infos
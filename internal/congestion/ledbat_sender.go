@@ -0,0 +1,266 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const (
+	// The maximum queuing delay LEDBAT introduces. RFC 6817 requires this to be at most 100ms.
+	ledbatTarget = 100 * time.Millisecond
+	// The rate at which the congestion window responds to changes in the queuing delay.
+	ledbatGain = 1
+	// The base delay is the minimum delay over the last 10 minutes.
+	ledbatBaseHistory         = 10
+	ledbatBaseHistoryInterval = time.Minute
+	// The current delay is the minimum of the last 4 delay samples.
+	ledbatCurrentFilter = 4
+	// The congestion window may grow to at most one packet more than the data in flight.
+	ledbatAllowedIncrease            = maxDatagramSize
+	ledbatMinCongestionWindow        = 2 * maxDatagramSize
+	ledbatInitialCongestionWindow    = 10 * maxDatagramSize
+	ledbatSlowStartQueuingDelayLimit = ledbatTarget / 2
+)
+
+// The ledbatSender implements LEDBAT (RFC 6817), a "less than best effort" congestion controller.
+// It estimates the queuing delay from the growth of the RTT over the minimum RTT,
+// and reduces its congestion window when the queuing delay exceeds the target,
+// yielding to other traffic on the bottleneck before packets are lost.
+// Since QUIC doesn't provide one-way delay measurements, the RTT is used instead.
+type ledbatSender struct {
+	clock    Clock
+	rttStats *RTTStats
+	pacer    *Pacer
+
+	congestionWindow    protocol.ByteCount
+	maxCongestionWindow protocol.ByteCount
+	inSlowStart         bool
+
+	largestSentPacketNumber  protocol.PacketNumber
+	largestAckedPacketNumber protocol.PacketNumber
+	largestSentAtLastCutback protocol.PacketNumber
+
+	// the minimum delay for each interval, the most recent interval last
+	baseDelays       []time.Duration
+	lastBaseRollover time.Time
+	// the most recent delay samples
+	currentDelays []time.Duration
+}
+
+var _ SendAlgorithm = &ledbatSender{}
+var _ SendAlgorithmWithDebugInfos = &ledbatSender{}
+
+// NewLEDBATSender makes a new LEDBAT sender
+func NewLEDBATSender(clock Clock, rttStats *RTTStats) *ledbatSender {
+	return newLEDBATSender(clock, rttStats, maxCongestionWindow)
+}
+
+func newLEDBATSender(clock Clock, rttStats *RTTStats, maxCongestionWindow protocol.ByteCount) *ledbatSender {
+	l := &ledbatSender{
+		clock:               clock,
+		rttStats:            rttStats,
+		maxCongestionWindow: maxCongestionWindow,
+	}
+	l.pacer = NewPacer(l.BandwidthEstimate)
+	l.reset()
+	return l
+}
+
+func (l *ledbatSender) reset() {
+	l.congestionWindow = ledbatInitialCongestionWindow
+	l.inSlowStart = true
+	l.largestSentPacketNumber = protocol.InvalidPacketNumber
+	l.largestAckedPacketNumber = protocol.InvalidPacketNumber
+	l.largestSentAtLastCutback = protocol.InvalidPacketNumber
+	l.baseDelays = nil
+	l.lastBaseRollover = time.Time{}
+	l.currentDelays = nil
+}
+
+// TimeUntilSend returns when the next packet should be sent.
+func (l *ledbatSender) TimeUntilSend(_ protocol.ByteCount) time.Time {
+	return l.pacer.TimeUntilSend()
+}
+
+func (l *ledbatSender) HasPacingBudget() bool {
+	return l.pacer.Budget(l.clock.Now()) >= maxDatagramSize
+}
+
+func (l *ledbatSender) OnPacketSent(
+	sentTime time.Time,
+	_ protocol.ByteCount,
+	packetNumber protocol.PacketNumber,
+	bytes protocol.ByteCount,
+	isRetransmittable bool,
+) {
+	l.pacer.SentPacket(sentTime, bytes)
+	if !isRetransmittable {
+		return
+	}
+	l.largestSentPacketNumber = packetNumber
+}
+
+func (l *ledbatSender) CanSend(bytesInFlight protocol.ByteCount) bool {
+	return bytesInFlight < l.GetCongestionWindow()
+}
+
+// MaybeExitSlowStart is called after the RTT estimate was updated.
+// LEDBAT uses it to take a new delay sample.
+func (l *ledbatSender) MaybeExitSlowStart() {
+	delay := l.rttStats.LatestRTT()
+	if delay <= 0 {
+		return
+	}
+	l.updateBaseDelay(delay)
+	l.currentDelays = append(l.currentDelays, delay)
+	if len(l.currentDelays) > ledbatCurrentFilter {
+		l.currentDelays = l.currentDelays[1:]
+	}
+	if l.inSlowStart && l.queuingDelay() > ledbatSlowStartQueuingDelayLimit {
+		l.inSlowStart = false
+	}
+}
+
+func (l *ledbatSender) updateBaseDelay(delay time.Duration) {
+	now := l.clock.Now()
+	if len(l.baseDelays) == 0 || now.Sub(l.lastBaseRollover) >= ledbatBaseHistoryInterval {
+		l.lastBaseRollover = now
+		l.baseDelays = append(l.baseDelays, delay)
+		if len(l.baseDelays) > ledbatBaseHistory {
+			l.baseDelays = l.baseDelays[1:]
+		}
+		return
+	}
+	last := len(l.baseDelays) - 1
+	l.baseDelays[last] = utils.MinDuration(l.baseDelays[last], delay)
+}
+
+func (l *ledbatSender) baseDelay() time.Duration {
+	var min time.Duration
+	for i, d := range l.baseDelays {
+		if i == 0 || d < min {
+			min = d
+		}
+	}
+	return min
+}
+
+// queuingDelay estimates the queuing delay from the difference between the current and the base delay.
+func (l *ledbatSender) queuingDelay() time.Duration {
+	if len(l.currentDelays) == 0 {
+		return 0
+	}
+	current := l.currentDelays[0]
+	for _, d := range l.currentDelays[1:] {
+		current = utils.MinDuration(current, d)
+	}
+	return current - l.baseDelay()
+}
+
+func (l *ledbatSender) OnPacketAcked(
+	ackedPacketNumber protocol.PacketNumber,
+	ackedBytes protocol.ByteCount,
+	priorInFlight protocol.ByteCount,
+	_ time.Time,
+) {
+	l.largestAckedPacketNumber = utils.MaxPacketNumber(ackedPacketNumber, l.largestAckedPacketNumber)
+	if l.InRecovery() {
+		return
+	}
+	if l.inSlowStart {
+		// Only grow the congestion window if it is actually used.
+		if priorInFlight >= l.congestionWindow/2 {
+			l.congestionWindow = utils.MinByteCount(l.congestionWindow+ackedBytes, l.maxCongestionWindow)
+		}
+		return
+	}
+	offTarget := float64(ledbatTarget-l.queuingDelay()) / float64(ledbatTarget)
+	// Don't back off faster than Reno would grow.
+	if offTarget < -1 {
+		offTarget = -1
+	}
+	delta := ledbatGain * offTarget * float64(ackedBytes) * float64(maxDatagramSize) / float64(l.congestionWindow)
+	if delta >= 0 {
+		// Don't grow the congestion window beyond what the connection is actually using.
+		maxAllowed := utils.MaxByteCount(l.congestionWindow, priorInFlight+ledbatAllowedIncrease)
+		l.congestionWindow = utils.MinByteCount(l.congestionWindow+protocol.ByteCount(delta), maxAllowed)
+		l.congestionWindow = utils.MinByteCount(l.congestionWindow, l.maxCongestionWindow)
+		return
+	}
+	decrease := protocol.ByteCount(-delta)
+	if l.congestionWindow < ledbatMinCongestionWindow+decrease {
+		l.congestionWindow = ledbatMinCongestionWindow
+		return
+	}
+	l.congestionWindow -= decrease
+}
+
+func (l *ledbatSender) OnPacketLost(
+	packetNumber protocol.PacketNumber,
+	_ protocol.ByteCount,
+	_ protocol.ByteCount,
+) {
+	// Treat all packets lost in the same round trip as a single loss event.
+	if packetNumber <= l.largestSentAtLastCutback {
+		return
+	}
+	l.reduceCongestionWindow()
+}
+
+// OnCongestionEvent is called when the peer reports an increase of the ECN-CE counter.
+// LEDBAT reacts to ECN-CE marks the same way it reacts to packet loss.
+func (l *ledbatSender) OnCongestionEvent(
+	packetNumber protocol.PacketNumber,
+	_ protocol.ByteCount,
+) {
+	if packetNumber <= l.largestSentAtLastCutback {
+		return
+	}
+	l.reduceCongestionWindow()
+}
+
+func (l *ledbatSender) reduceCongestionWindow() {
+	l.inSlowStart = false
+	l.congestionWindow = utils.MaxByteCount(l.congestionWindow/2, ledbatMinCongestionWindow)
+	l.largestSentAtLastCutback = l.largestSentPacketNumber
+}
+
+// OnRetransmissionTimeout is called on an retransmission timeout
+func (l *ledbatSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	l.largestSentAtLastCutback = protocol.InvalidPacketNumber
+	if !packetsRetransmitted {
+		return
+	}
+	l.inSlowStart = false
+	l.congestionWindow = ledbatMinCongestionWindow
+}
+
+// OnConnectionMigration is called when the connection is migrated to a new path.
+// The delay history is not valid for the new path.
+func (l *ledbatSender) OnConnectionMigration() {
+	l.reset()
+}
+
+func (l *ledbatSender) InSlowStart() bool {
+	return l.inSlowStart
+}
+
+func (l *ledbatSender) InRecovery() bool {
+	return l.largestAckedPacketNumber != protocol.InvalidPacketNumber && l.largestAckedPacketNumber <= l.largestSentAtLastCutback
+}
+
+func (l *ledbatSender) GetCongestionWindow() protocol.ByteCount {
+	return l.congestionWindow
+}
+
+// BandwidthEstimate returns the current bandwidth estimate
+func (l *ledbatSender) BandwidthEstimate() Bandwidth {
+	srtt := l.rttStats.SmoothedRTT()
+	if srtt == 0 {
+		// If we haven't measured an rtt, the bandwidth estimate is unknown.
+		return infBandwidth
+	}
+	return BandwidthFromDelta(l.GetCongestionWindow(), srtt)
+}
@@ -0,0 +1,230 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LEDBAT Sender", func() {
+	const baseRTT = 50 * time.Millisecond
+
+	var (
+		sender            *ledbatSender
+		clock             mockClock
+		rttStats          *RTTStats
+		bytesInFlight     protocol.ByteCount
+		packetNumber      protocol.PacketNumber
+		ackedPacketNumber protocol.PacketNumber
+	)
+
+	BeforeEach(func() {
+		clock = mockClock{}
+		rttStats = NewRTTStats()
+		sender = newLEDBATSender(&clock, rttStats, MaxCongestionWindow)
+		bytesInFlight = 0
+		packetNumber = 1
+		ackedPacketNumber = 0
+	})
+
+	sendAvailableSendWindow := func() int {
+		var packetsSent int
+		for sender.CanSend(bytesInFlight) {
+			bytesInFlight += maxDatagramSize
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+			packetNumber++
+			packetsSent++
+		}
+		return packetsSent
+	}
+
+	ackNPackets := func(n int, rtt time.Duration) {
+		for i := 0; i < n; i++ {
+			rttStats.UpdateRTT(rtt, 0, clock.Now())
+			sender.MaybeExitSlowStart()
+			ackedPacketNumber++
+			sender.OnPacketAcked(ackedPacketNumber, maxDatagramSize, bytesInFlight, clock.Now())
+		}
+		bytesInFlight -= protocol.ByteCount(n) * maxDatagramSize
+		clock.Advance(time.Millisecond)
+	}
+
+	// send a full congestion window, and receive the acknowledgements for all packets
+	runRoundTrip := func(rtt time.Duration) {
+		ackNPackets(sendAvailableSendWindow(), rtt)
+	}
+
+	It("starts in slow start", func() {
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(Equal(ledbatInitialCongestionWindow))
+	})
+
+	It("paces packets", func() {
+		Expect(sender.BandwidthEstimate()).To(Equal(infBandwidth))
+		rttStats.UpdateRTT(baseRTT, 0, clock.Now())
+		Expect(sender.BandwidthEstimate()).To(Equal(BandwidthFromDelta(ledbatInitialCongestionWindow, baseRTT)))
+		Expect(sender.HasPacingBudget()).To(BeTrue())
+	})
+
+	It("doubles the congestion window every round trip in slow start", func() {
+		runRoundTrip(baseRTT)
+		Expect(sender.GetCongestionWindow()).To(Equal(2 * ledbatInitialCongestionWindow))
+		runRoundTrip(baseRTT)
+		Expect(sender.GetCongestionWindow()).To(Equal(4 * ledbatInitialCongestionWindow))
+		Expect(sender.InSlowStart()).To(BeTrue())
+	})
+
+	It("doesn't grow the congestion window in slow start if it isn't used", func() {
+		bytesInFlight += maxDatagramSize
+		sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+		ackNPackets(1, baseRTT)
+		Expect(sender.GetCongestionWindow()).To(Equal(ledbatInitialCongestionWindow))
+	})
+
+	It("exits slow start when the queuing delay grows", func() {
+		runRoundTrip(baseRTT)
+		Expect(sender.InSlowStart()).To(BeTrue())
+		runRoundTrip(baseRTT + ledbatTarget/4)
+		Expect(sender.InSlowStart()).To(BeTrue())
+		cwnd := sender.GetCongestionWindow()
+		ackNPackets(ledbatCurrentFilter, baseRTT+ledbatTarget*3/4)
+		Expect(sender.InSlowStart()).To(BeFalse())
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("~", cwnd, maxDatagramSize))
+	})
+
+	Context("in congestion avoidance", func() {
+		BeforeEach(func() {
+			runRoundTrip(baseRTT)
+			ackNPackets(sendAvailableSendWindow(), baseRTT+ledbatTarget)
+			Expect(sender.InSlowStart()).To(BeFalse())
+		})
+
+		It("grows the congestion window by one packet per round trip when there's no queuing delay", func() {
+			for i := 0; i < ledbatCurrentFilter; i++ {
+				runRoundTrip(baseRTT)
+			}
+			cwnd := sender.GetCongestionWindow()
+			runRoundTrip(baseRTT)
+			Expect(sender.GetCongestionWindow()).To(BeNumerically("~", cwnd+maxDatagramSize, maxDatagramSize/10))
+		})
+
+		It("grows the congestion window more slowly when the queuing delay approaches the target", func() {
+			for i := 0; i < ledbatCurrentFilter; i++ {
+				runRoundTrip(baseRTT + ledbatTarget/2)
+			}
+			cwnd := sender.GetCongestionWindow()
+			runRoundTrip(baseRTT + ledbatTarget/2)
+			Expect(sender.GetCongestionWindow()).To(BeNumerically("~", cwnd+maxDatagramSize/2, maxDatagramSize/10))
+		})
+
+		It("keeps the congestion window constant at the target", func() {
+			cwnd := sender.GetCongestionWindow()
+			runRoundTrip(baseRTT + ledbatTarget)
+			Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+		})
+
+		It("reduces the congestion window when the queuing delay exceeds the target, before any loss", func() {
+			cwnd := sender.GetCongestionWindow()
+			runRoundTrip(baseRTT + 3*ledbatTarget/2)
+			Expect(sender.GetCongestionWindow()).To(BeNumerically("~", cwnd-maxDatagramSize/2, maxDatagramSize/10))
+			Expect(sender.InRecovery()).To(BeFalse())
+		})
+
+		It("reduces the congestion window by at most one packet per round trip", func() {
+			cwnd := sender.GetCongestionWindow()
+			runRoundTrip(baseRTT + 10*ledbatTarget)
+			Expect(sender.GetCongestionWindow()).To(BeNumerically("~", cwnd-maxDatagramSize, maxDatagramSize/5))
+		})
+
+		It("doesn't reduce the congestion window below the minimum", func() {
+			for i := 0; i < 100; i++ {
+				runRoundTrip(baseRTT + 10*ledbatTarget)
+			}
+			Expect(sender.GetCongestionWindow()).To(Equal(ledbatMinCongestionWindow))
+		})
+
+		It("doesn't grow the congestion window beyond the data in flight", func() {
+			for i := 0; i < ledbatCurrentFilter; i++ {
+				runRoundTrip(baseRTT)
+			}
+			cwnd := sender.GetCongestionWindow()
+			// only send a few packets
+			for i := 0; i < 3; i++ {
+				bytesInFlight += maxDatagramSize
+				sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+				packetNumber++
+			}
+			ackNPackets(3, baseRTT)
+			Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+		})
+
+		It("halves the congestion window on packet loss, at most once per round trip", func() {
+			cwnd := sender.GetCongestionWindow()
+			sendAvailableSendWindow()
+			sender.OnPacketLost(ackedPacketNumber+1, maxDatagramSize, bytesInFlight)
+			Expect(sender.GetCongestionWindow()).To(Equal(cwnd / 2))
+			sender.OnPacketLost(ackedPacketNumber+2, maxDatagramSize, bytesInFlight)
+			Expect(sender.GetCongestionWindow()).To(Equal(cwnd / 2))
+			ackedPacketNumber += 2
+			bytesInFlight -= 2 * maxDatagramSize
+			ackNPackets(1, baseRTT)
+			Expect(sender.InRecovery()).To(BeTrue())
+			// the congestion window doesn't grow during recovery
+			Expect(sender.GetCongestionWindow()).To(Equal(cwnd / 2))
+			ackNPackets(int(bytesInFlight/maxDatagramSize), baseRTT)
+			Expect(sender.InRecovery()).To(BeTrue())
+			// recovery ends when a packet sent after the loss is acknowledged
+			runRoundTrip(baseRTT)
+			Expect(sender.InRecovery()).To(BeFalse())
+			Expect(sender.GetCongestionWindow()).To(BeNumerically(">", cwnd/2))
+		})
+
+		It("halves the congestion window on ECN-CE marks", func() {
+			cwnd := sender.GetCongestionWindow()
+			sendAvailableSendWindow()
+			sender.OnCongestionEvent(ackedPacketNumber+1, bytesInFlight)
+			Expect(sender.GetCongestionWindow()).To(Equal(cwnd / 2))
+			sender.OnCongestionEvent(ackedPacketNumber+2, bytesInFlight)
+			Expect(sender.GetCongestionWindow()).To(Equal(cwnd / 2))
+		})
+	})
+
+	It("adapts to a longer base delay after 10 minutes", func() {
+		runRoundTrip(baseRTT)
+		// the path changes, the RTT is now higher
+		for i := 0; i < ledbatBaseHistory-1; i++ {
+			runRoundTrip(baseRTT + ledbatTarget)
+			clock.Advance(ledbatBaseHistoryInterval)
+			Expect(sender.queuingDelay()).To(Equal(ledbatTarget))
+		}
+		runRoundTrip(baseRTT + ledbatTarget)
+		clock.Advance(ledbatBaseHistoryInterval)
+		runRoundTrip(baseRTT + ledbatTarget)
+		Expect(sender.baseDelay()).To(Equal(baseRTT + ledbatTarget))
+		Expect(sender.queuingDelay()).To(BeZero())
+	})
+
+	It("uses the minimum congestion window on a retransmission timeout", func() {
+		runRoundTrip(baseRTT)
+		sender.OnRetransmissionTimeout(false)
+		Expect(sender.GetCongestionWindow()).To(Equal(2 * ledbatInitialCongestionWindow))
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(ledbatMinCongestionWindow))
+		Expect(sender.InSlowStart()).To(BeFalse())
+	})
+
+	It("resets the state on connection migration", func() {
+		runRoundTrip(baseRTT)
+		ackNPackets(sendAvailableSendWindow(), baseRTT+ledbatTarget)
+		Expect(sender.InSlowStart()).To(BeFalse())
+		sender.OnConnectionMigration()
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(Equal(ledbatInitialCongestionWindow))
+		Expect(sender.baseDelays).To(BeEmpty())
+		Expect(sender.queuingDelay()).To(BeZero())
+	})
+})
@@ -10,15 +10,18 @@ import (
 
 const maxBurstSize = 10 * maxDatagramSize
 
-// The pacer implements a token bucket pacing algorithm.
-type pacer struct {
+// The Pacer implements a token bucket pacing algorithm.
+// It can be used by SendAlgorithm implementations to spread out packets over the RTT.
+type Pacer struct {
 	budgetAtLastSent     protocol.ByteCount
 	lastSentTime         time.Time
 	getAdjustedBandwidth func() uint64 // in bytes/s
 }
 
-func newPacer(getBandwidth func() Bandwidth) *pacer {
-	p := &pacer{getAdjustedBandwidth: func() uint64 {
+// NewPacer creates a new Pacer.
+// getBandwidth returns the current bandwidth estimate of the congestion controller.
+func NewPacer(getBandwidth func() Bandwidth) *Pacer {
+	p := &Pacer{getAdjustedBandwidth: func() uint64 {
 		// Bandwidth is in bits/s. We need the value in bytes/s.
 		bw := uint64(getBandwidth() / BytesPerSecond)
 		// Use a slightly higher value than the actual measured bandwidth.
@@ -31,7 +34,8 @@ func newPacer(getBandwidth func() Bandwidth) *pacer {
 	return p
 }
 
-func (p *pacer) SentPacket(sendTime time.Time, size protocol.ByteCount) {
+// SentPacket is called when a packet is sent.
+func (p *Pacer) SentPacket(sendTime time.Time, size protocol.ByteCount) {
 	budget := p.Budget(sendTime)
 	if size > budget {
 		p.budgetAtLastSent = 0
@@ -41,7 +45,8 @@ func (p *pacer) SentPacket(sendTime time.Time, size protocol.ByteCount) {
 	p.lastSentTime = sendTime
 }
 
-func (p *pacer) Budget(now time.Time) protocol.ByteCount {
+// Budget returns the number of bytes that can be sent at the given time.
+func (p *Pacer) Budget(now time.Time) protocol.ByteCount {
 	if p.lastSentTime.IsZero() {
 		return p.maxBurstSize()
 	}
@@ -49,7 +54,7 @@ func (p *pacer) Budget(now time.Time) protocol.ByteCount {
 	return utils.MinByteCount(p.maxBurstSize(), budget)
 }
 
-func (p *pacer) maxBurstSize() protocol.ByteCount {
+func (p *Pacer) maxBurstSize() protocol.ByteCount {
 	return utils.MaxByteCount(
 		protocol.ByteCount(uint64((protocol.MinPacingDelay+protocol.TimerGranularity).Nanoseconds())*p.getAdjustedBandwidth())/1e9,
 		maxBurstSize,
@@ -57,7 +62,7 @@ func (p *pacer) maxBurstSize() protocol.ByteCount {
 }
 
 // TimeUntilSend returns when the next packet should be sent.
-func (p *pacer) TimeUntilSend() time.Time {
+func (p *Pacer) TimeUntilSend() time.Time {
 	if p.budgetAtLastSent >= maxDatagramSize {
 		return time.Time{}
 	}
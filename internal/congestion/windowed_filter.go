@@ -0,0 +1,69 @@
+package congestion
+
+type bandwidthEstimate struct {
+	bandwidth Bandwidth
+	round     uint64
+}
+
+// maxBandwidthFilter tracks the maximum bandwidth sample over a window of round trips.
+// It implements Kathleen Nichols' windowed max algorithm (as used in the Linux kernel),
+// keeping the best, second best and third best sample of the window.
+type maxBandwidthFilter struct {
+	window    uint64
+	estimates [3]bandwidthEstimate
+}
+
+func newMaxBandwidthFilter(window uint64) *maxBandwidthFilter {
+	return &maxBandwidthFilter{window: window}
+}
+
+// Get returns the maximum bandwidth sample in the window.
+func (f *maxBandwidthFilter) Get() Bandwidth {
+	return f.estimates[0].bandwidth
+}
+
+// Reset sets all estimates to the given sample.
+func (f *maxBandwidthFilter) Reset(bw Bandwidth, round uint64) {
+	e := bandwidthEstimate{bandwidth: bw, round: round}
+	f.estimates = [3]bandwidthEstimate{e, e, e}
+}
+
+// Update adds a new bandwidth sample, taken in the given round trip.
+func (f *maxBandwidthFilter) Update(bw Bandwidth, round uint64) {
+	e := bandwidthEstimate{bandwidth: bw, round: round}
+	// Reset all estimates if we have a new maximum, or if the whole window has passed.
+	if f.estimates[0].bandwidth == 0 || bw >= f.estimates[0].bandwidth || round-f.estimates[2].round > f.window {
+		f.Reset(bw, round)
+		return
+	}
+	if bw >= f.estimates[1].bandwidth {
+		f.estimates[1] = e
+		f.estimates[2] = e
+	} else if bw >= f.estimates[2].bandwidth {
+		f.estimates[2] = e
+	}
+
+	// Expire and update estimates as necessary.
+	elapsed := round - f.estimates[0].round
+	if elapsed > f.window {
+		// The best estimate hasn't been updated for an entire window, so promote the second and third best.
+		f.estimates[0] = f.estimates[1]
+		f.estimates[1] = f.estimates[2]
+		f.estimates[2] = e
+		if round-f.estimates[0].round > f.window {
+			f.estimates[0] = f.estimates[1]
+			f.estimates[1] = f.estimates[2]
+		}
+		return
+	}
+	if f.estimates[1].bandwidth == f.estimates[0].bandwidth && elapsed > f.window/4 {
+		// A quarter of the window has passed without a better sample, so take a second best estimate.
+		f.estimates[1] = e
+		f.estimates[2] = e
+		return
+	}
+	if f.estimates[2].bandwidth == f.estimates[1].bandwidth && elapsed > f.window/2 {
+		// Half of the window has passed without a better sample, so take a third best estimate.
+		f.estimates[2] = e
+	}
+}
@@ -0,0 +1,62 @@
+package congestion
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Max Bandwidth Filter", func() {
+	var f *maxBandwidthFilter
+
+	BeforeEach(func() {
+		f = newMaxBandwidthFilter(10)
+	})
+
+	It("is empty at the beginning", func() {
+		Expect(f.Get()).To(BeZero())
+	})
+
+	It("uses the first sample", func() {
+		f.Update(1000, 1)
+		Expect(f.Get()).To(Equal(Bandwidth(1000)))
+	})
+
+	It("uses a new maximum", func() {
+		f.Update(1000, 1)
+		f.Update(2000, 2)
+		Expect(f.Get()).To(Equal(Bandwidth(2000)))
+		f.Update(1500, 3)
+		Expect(f.Get()).To(Equal(Bandwidth(2000)))
+	})
+
+	It("expires the maximum after the window", func() {
+		f.Update(2000, 1)
+		for i := uint64(2); i <= 11; i++ {
+			f.Update(1000, i)
+			Expect(f.Get()).To(Equal(Bandwidth(2000)))
+		}
+		f.Update(1000, 12)
+		Expect(f.Get()).To(Equal(Bandwidth(1000)))
+	})
+
+	It("falls back to the second best estimate", func() {
+		f.Update(3000, 1)
+		f.Update(2000, 4) // more than a quarter of the window later
+		f.Update(1000, 7) // more than half of the window later
+		Expect(f.Get()).To(Equal(Bandwidth(3000)))
+		f.Update(500, 12)
+		Expect(f.Get()).To(Equal(Bandwidth(2000)))
+	})
+
+	It("resets all estimates when no sample was taken for a whole window", func() {
+		f.Update(3000, 1)
+		f.Update(1000, 20)
+		Expect(f.Get()).To(Equal(Bandwidth(1000)))
+	})
+
+	It("resets", func() {
+		f.Update(3000, 1)
+		f.Reset(1000, 2)
+		Expect(f.Get()).To(Equal(Bandwidth(1000)))
+	})
+})
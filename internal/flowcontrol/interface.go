@@ -24,6 +24,13 @@ type StreamFlowController interface {
 	// Abandon should be called when reading from the stream is aborted early,
 	// and there won't be any further calls to AddBytesRead.
 	Abandon()
+	// ReceiveWindowSize returns the number of bytes the peer is allowed to send on the stream,
+	// beyond the highest offset received so far.
+	ReceiveWindowSize() protocol.ByteCount
+	// for sending
+	// StreamSendWindowSize returns the send window of the stream,
+	// not taking into account connection-level flow control.
+	StreamSendWindowSize() protocol.ByteCount
 }
 
 // The ConnectionFlowController is the flow controller for the connection.
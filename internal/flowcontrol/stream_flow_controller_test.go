@@ -141,6 +141,15 @@ var _ = Describe("Stream Flow controller", func() {
 			})
 		})
 
+		It("gets the size of the receive window", func() {
+			controller.receiveWindow = 1000
+			Expect(controller.ReceiveWindowSize()).To(Equal(protocol.ByteCount(1000)))
+			Expect(controller.UpdateHighestReceived(300, false)).To(Succeed())
+			Expect(controller.ReceiveWindowSize()).To(Equal(protocol.ByteCount(700)))
+			Expect(controller.UpdateHighestReceived(1000, false)).To(Succeed())
+			Expect(controller.ReceiveWindowSize()).To(BeZero())
+		})
+
 		It("saves when data is read", func() {
 			controller.AddBytesRead(200)
 			Expect(controller.bytesRead).To(Equal(protocol.ByteCount(200)))
@@ -220,6 +229,14 @@ var _ = Describe("Stream Flow controller", func() {
 			Expect(controller.SendWindowSize()).To(Equal(protocol.ByteCount(2)))
 		})
 
+		It("gets the size of the stream-level send window", func() {
+			controller.connection.UpdateSendWindow(12)
+			controller.UpdateSendWindow(20)
+			controller.AddBytesSent(10)
+			Expect(controller.SendWindowSize()).To(Equal(protocol.ByteCount(2)))
+			Expect(controller.StreamSendWindowSize()).To(Equal(protocol.ByteCount(10)))
+		})
+
 		It("doesn't say that it's blocked, if only the connection is blocked", func() {
 			controller.connection.UpdateSendWindow(50)
 			controller.UpdateSendWindow(100)
@@ -29,8 +29,8 @@ var _ = Describe("Long Header AEAD", func() {
 				aead, err := cipher.NewGCM(block)
 				Expect(err).ToNot(HaveOccurred())
 
-				return newLongHeaderSealer(aead, newHeaderProtector(cs, hpKey, true)),
-					newLongHeaderOpener(aead, newHeaderProtector(cs, hpKey, true))
+				return newLongHeaderSealer(aead, newHeaderProtector(cs, hpKey, true, protocol.VersionTLS)),
+					newLongHeaderOpener(aead, newHeaderProtector(cs, hpKey, true, protocol.VersionTLS))
 			}
 
 			Context("message encryption", func() {
@@ -134,7 +134,7 @@ var _ = Describe("Long Header AEAD", func() {
 		Expect(err).ToNot(HaveOccurred())
 		aead, err = cipher.NewGCM(block)
 		Expect(err).ToNot(HaveOccurred())
-		hp = newHeaderProtector(cipherSuites[0], hpKey, true)
+		hp = newHeaderProtector(cipherSuites[0], hpKey, true, protocol.VersionTLS)
 	})
 
 	Context("for the server", func() {
@@ -0,0 +1,87 @@
+package handshake
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// An AntiReplayStore protects against replays of 0-RTT data.
+// Every session ticket issued by the server carries a unique ID,
+// and 0-RTT is only accepted the first time a client uses a session ticket.
+type AntiReplayStore interface {
+	// CheckAndStore is called when a client attempts 0-RTT with the session ticket with the given ID.
+	// It returns true the first time it is called for an ID, and false for all subsequent calls.
+	// The session ticket can't be used for 0-RTT after the expiry time, so the ID can be removed afterwards.
+	// It may be called concurrently.
+	CheckAndStore(id []byte, expiry time.Time) bool
+}
+
+type inMemoryAntiReplayStore struct {
+	mutex sync.Mutex
+
+	maxEntries int
+	entries    map[string]time.Time // ID -> expiry
+	// the IDs in the order of their expiry, so that expired IDs can be removed without iterating over all entries
+	expiries antiReplayHeap
+}
+
+var _ AntiReplayStore = &inMemoryAntiReplayStore{}
+
+// NewInMemoryAntiReplayStore creates an AntiReplayStore that keeps the IDs of used session tickets in memory.
+// It remembers up to maxEntries IDs. Once that number is reached, 0-RTT is rejected until IDs expire.
+func NewInMemoryAntiReplayStore(maxEntries int) AntiReplayStore {
+	return &inMemoryAntiReplayStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]time.Time),
+	}
+}
+
+func (s *inMemoryAntiReplayStore) CheckAndStore(id []byte, expiry time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.removeExpired(time.Now())
+	if _, ok := s.entries[string(id)]; ok {
+		return false
+	}
+	if len(s.entries) >= s.maxEntries {
+		return false
+	}
+	s.entries[string(id)] = expiry
+	heap.Push(&s.expiries, antiReplayEntry{id: string(id), expiry: expiry})
+	return true
+}
+
+// removeExpired removes all expired IDs.
+// Every ID is only removed once, so the amortized cost per call is O(log n).
+func (s *inMemoryAntiReplayStore) removeExpired(now time.Time) {
+	for len(s.expiries) > 0 && !now.Before(s.expiries[0].expiry) {
+		e := heap.Pop(&s.expiries).(antiReplayEntry)
+		delete(s.entries, e.id)
+	}
+}
+
+type antiReplayEntry struct {
+	id     string
+	expiry time.Time
+}
+
+// antiReplayHeap is a min-heap of IDs, ordered by their expiry.
+type antiReplayHeap []antiReplayEntry
+
+var _ heap.Interface = &antiReplayHeap{}
+
+func (h antiReplayHeap) Len() int           { return len(h) }
+func (h antiReplayHeap) Less(i, j int) bool { return h[i].expiry.Before(h[j].expiry) }
+func (h antiReplayHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *antiReplayHeap) Push(x interface{}) { *h = append(*h, x.(antiReplayEntry)) }
+
+func (h *antiReplayHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	*h = old[:n-1]
+	return e
+}
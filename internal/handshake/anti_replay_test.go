@@ -0,0 +1,56 @@
+package handshake
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("In-memory anti-replay store", func() {
+	It("accepts every ID only once", func() {
+		s := NewInMemoryAntiReplayStore(10)
+		expiry := time.Now().Add(time.Hour)
+		Expect(s.CheckAndStore([]byte("foo"), expiry)).To(BeTrue())
+		Expect(s.CheckAndStore([]byte("bar"), expiry)).To(BeTrue())
+		Expect(s.CheckAndStore([]byte("foo"), expiry)).To(BeFalse())
+		Expect(s.CheckAndStore([]byte("bar"), expiry)).To(BeFalse())
+	})
+
+	It("accepts an ID again once it expired", func() {
+		s := NewInMemoryAntiReplayStore(10)
+		Expect(s.CheckAndStore([]byte("foo"), time.Now().Add(-time.Second))).To(BeTrue())
+		Expect(s.CheckAndStore([]byte("foo"), time.Now().Add(time.Hour))).To(BeTrue())
+		Expect(s.CheckAndStore([]byte("foo"), time.Now().Add(time.Hour))).To(BeFalse())
+	})
+
+	It("rejects IDs when it is full", func() {
+		s := NewInMemoryAntiReplayStore(2)
+		Expect(s.CheckAndStore([]byte("foo"), time.Now().Add(time.Hour))).To(BeTrue())
+		Expect(s.CheckAndStore([]byte("bar"), time.Now().Add(time.Hour))).To(BeTrue())
+		Expect(s.CheckAndStore([]byte("baz"), time.Now().Add(time.Hour))).To(BeFalse())
+	})
+
+	It("removes expired IDs when it is full", func() {
+		s := NewInMemoryAntiReplayStore(2)
+		Expect(s.CheckAndStore([]byte("foo"), time.Now().Add(-time.Second))).To(BeTrue())
+		Expect(s.CheckAndStore([]byte("bar"), time.Now().Add(time.Hour))).To(BeTrue())
+		Expect(s.CheckAndStore([]byte("baz"), time.Now().Add(time.Hour))).To(BeTrue())
+		Expect(s.CheckAndStore([]byte("qux"), time.Now().Add(time.Hour))).To(BeFalse())
+	})
+
+	It("removes expired IDs in the order of their expiry", func() {
+		s := NewInMemoryAntiReplayStore(3).(*inMemoryAntiReplayStore)
+		now := time.Now()
+		Expect(s.CheckAndStore([]byte("foo"), now.Add(time.Hour))).To(BeTrue())
+		Expect(s.CheckAndStore([]byte("bar"), now.Add(time.Minute))).To(BeTrue())
+		Expect(s.CheckAndStore([]byte("baz"), now.Add(2*time.Minute))).To(BeTrue())
+		s.removeExpired(now.Add(90 * time.Second))
+		Expect(s.entries).To(HaveLen(2))
+		Expect(s.entries).ToNot(HaveKey("bar"))
+		Expect(s.expiries).To(HaveLen(2))
+		s.removeExpired(now.Add(time.Hour))
+		Expect(s.entries).To(BeEmpty())
+		Expect(s.expiries).To(BeEmpty())
+	})
+})
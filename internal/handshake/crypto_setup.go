@@ -2,6 +2,7 @@ package handshake
 
 import (
 	"bytes"
+	"crypto/rand"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -83,6 +84,10 @@ type cryptoSetup struct {
 	clientHelloWritten     bool
 	clientHelloWrittenChan chan *wire.TransportParameters
 
+	// only set for the server
+	allow0RTT       func() bool
+	antiReplayStore AntiReplayStore
+
 	receivedWriteKey chan struct{}
 	receivedReadKey  chan struct{}
 	// WriteRecord does a non-blocking send on this channel.
@@ -98,6 +103,13 @@ type cryptoSetup struct {
 	logger utils.Logger
 
 	perspective protocol.Perspective
+	// The version used on the connection.
+	// This can change during the handshake, if we switch to a compatible version.
+	version protocol.VersionNumber
+	// The version the connection was started with, used for 0-RTT.
+	origVersion protocol.VersionNumber
+	// The connection ID used to derive the Initial keys.
+	initialConnID protocol.ConnectionID
 
 	mutex sync.Mutex // protects all members below
 
@@ -136,9 +148,11 @@ func NewCryptoSetupClient(
 	runner handshakeRunner,
 	tlsConf *tls.Config,
 	enable0RTT bool,
+	keyUpdateInterval uint64,
 	rttStats *congestion.RTTStats,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
+	version protocol.VersionNumber,
 ) (CryptoSetup, <-chan *wire.TransportParameters /* ClientHello written. Receive nil for non-0-RTT */) {
 	cs, clientHelloWritten := newCryptoSetup(
 		initialStream,
@@ -148,10 +162,12 @@ func NewCryptoSetupClient(
 		runner,
 		tlsConf,
 		enable0RTT,
+		keyUpdateInterval,
 		rttStats,
 		tracer,
 		logger,
 		protocol.PerspectiveClient,
+		version,
 	)
 	cs.conn = qtls.Client(newConn(localAddr, remoteAddr), cs.tlsConf)
 	return cs, clientHelloWritten
@@ -168,9 +184,13 @@ func NewCryptoSetupServer(
 	runner handshakeRunner,
 	tlsConf *tls.Config,
 	enable0RTT bool,
+	allow0RTT func(net.Addr) bool,
+	antiReplayStore AntiReplayStore,
+	keyUpdateInterval uint64,
 	rttStats *congestion.RTTStats,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
+	version protocol.VersionNumber,
 ) CryptoSetup {
 	cs, _ := newCryptoSetup(
 		initialStream,
@@ -180,11 +200,17 @@ func NewCryptoSetupServer(
 		runner,
 		tlsConf,
 		enable0RTT,
+		keyUpdateInterval,
 		rttStats,
 		tracer,
 		logger,
 		protocol.PerspectiveServer,
+		version,
 	)
+	if allow0RTT != nil {
+		cs.allow0RTT = func() bool { return allow0RTT(remoteAddr) }
+	}
+	cs.antiReplayStore = antiReplayStore
 	cs.conn = qtls.Server(newConn(localAddr, remoteAddr), cs.tlsConf)
 	return cs
 }
@@ -197,23 +223,25 @@ func newCryptoSetup(
 	runner handshakeRunner,
 	tlsConf *tls.Config,
 	enable0RTT bool,
+	keyUpdateInterval uint64,
 	rttStats *congestion.RTTStats,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 	perspective protocol.Perspective,
+	version protocol.VersionNumber,
 ) (*cryptoSetup, <-chan *wire.TransportParameters /* ClientHello written. Receive nil for non-0-RTT */) {
-	initialSealer, initialOpener := NewInitialAEAD(connID, perspective)
+	initialSealer, initialOpener := NewInitialAEAD(connID, perspective, version)
 	if tracer != nil {
 		tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveClient)
 		tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveServer)
 	}
-	extHandler := newExtensionHandler(tp.Marshal(perspective), perspective)
+	extHandler := newExtensionHandler(tp.Marshal(perspective), perspective, version)
 	cs := &cryptoSetup{
 		initialStream:          initialStream,
 		initialSealer:          initialSealer,
 		initialOpener:          initialOpener,
 		handshakeStream:        handshakeStream,
-		aead:                   newUpdatableAEAD(rttStats, tracer, logger),
+		aead:                   newUpdatableAEAD(rttStats, keyUpdateInterval, tracer, logger, version),
 		readEncLevel:           protocol.EncryptionInitial,
 		writeEncLevel:          protocol.EncryptionInitial,
 		runner:                 runner,
@@ -223,6 +251,9 @@ func newCryptoSetup(
 		tracer:                 tracer,
 		logger:                 logger,
 		perspective:            perspective,
+		version:                version,
+		origVersion:            version,
+		initialConnID:          connID,
 		handshakeDone:          make(chan struct{}),
 		alertChan:              make(chan uint8),
 		clientHelloWrittenChan: make(chan *wire.TransportParameters, 1),
@@ -232,25 +263,85 @@ func newCryptoSetup(
 		writeRecord:            make(chan struct{}, 1),
 		closeChan:              make(chan struct{}),
 	}
+	if perspective == protocol.PerspectiveServer {
+		extHandler.updateParams = cs.negotiateVersion
+	}
 	qtlsConf := tlsConfigToQtlsConfig(tlsConf, cs, extHandler, rttStats, cs.marshalDataForSessionState, cs.handleDataFromSessionState, cs.accept0RTT, cs.rejected0RTT, enable0RTT)
 	cs.tlsConf = qtlsConf
 	return cs, cs.clientHelloWrittenChan
 }
 
 func (h *cryptoSetup) ChangeConnectionID(id protocol.ConnectionID) {
-	initialSealer, initialOpener := NewInitialAEAD(id, h.perspective)
-	h.initialSealer = initialSealer
-	h.initialOpener = initialOpener
+	h.mutex.Lock()
+	h.initialConnID = id
+	h.initialSealer, h.initialOpener = NewInitialAEAD(id, h.perspective, h.version)
+	h.mutex.Unlock()
+	if h.tracer != nil {
+		h.tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveClient)
+		h.tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveServer)
+	}
+}
+
+// ChangeVersion switches to a compatible version during the handshake, see RFC 9368.
+// The Initial keys are derived again, and all keys derived later use the new version.
+// It must be called before the Handshake keys are available.
+// The client also uses it to switch back, if a packet using the new version couldn't be decrypted.
+func (h *cryptoSetup) ChangeVersion(v protocol.VersionNumber) {
+	h.logger.Debugf("Switching to compatible version %s", v)
+	h.mutex.Lock()
+	h.version = v
+	h.aead.version = v
+	h.initialSealer, h.initialOpener = NewInitialAEAD(h.initialConnID, h.perspective, v)
+	h.mutex.Unlock()
 	if h.tracer != nil {
 		h.tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveClient)
 		h.tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveServer)
 	}
 }
 
+// negotiateVersion is called for the server when the client's transport parameters are received.
+// It runs on the qtls go routine, before the ServerHello is written,
+// such that the Handshake keys are derived using the new version.
+// It returns our updated transport parameters, or nil if the version wasn't changed.
+func (h *cryptoSetup) negotiateVersion(data []byte) []byte {
+	if h.ourParams.VersionInformation == nil {
+		return nil
+	}
+	var tp wire.TransportParameters
+	// Invalid transport parameters are rejected when handling them later.
+	if err := tp.Unmarshal(data, protocol.PerspectiveClient); err != nil {
+		return nil
+	}
+	if tp.VersionInformation == nil || tp.VersionInformation.ChosenVersion != h.version {
+		return nil
+	}
+	v := protocol.ChooseCompatibleVersion(h.version, h.ourParams.VersionInformation.AvailableVersions, tp.VersionInformation.AvailableVersions)
+	if v == h.version {
+		return nil
+	}
+	h.ChangeVersion(v)
+	h.ourParams.VersionInformation = &wire.VersionInformation{
+		ChosenVersion:     v,
+		AvailableVersions: h.ourParams.VersionInformation.AvailableVersions,
+	}
+	return h.ourParams.Marshal(h.perspective)
+}
+
 func (h *cryptoSetup) SetLargest1RTTAcked(pn protocol.PacketNumber) {
 	h.aead.SetLargestAcked(pn)
 }
 
+func (h *cryptoSetup) InitiateKeyUpdate() error {
+	h.mutex.Lock()
+	has1RTTSealer := h.has1RTTSealer
+	h.mutex.Unlock()
+
+	if !has1RTTSealer {
+		return ErrKeysNotYetAvailable
+	}
+	return h.aead.InitiateKeyUpdate()
+}
+
 func (h *cryptoSetup) RunHandshake() {
 	// Handle errors that might occur when HandleData() is called.
 	handshakeComplete := make(chan struct{})
@@ -501,10 +592,15 @@ func (h *cryptoSetup) GetSessionTicket() ([]byte, error) {
 	var appData []byte
 	// Save transport parameters to the session ticket if we're allowing 0-RTT.
 	if h.tlsConf.MaxEarlyData > 0 {
-		appData = (&sessionTicket{
+		t := &sessionTicket{
 			Parameters: h.ourParams,
 			RTT:        h.rttStats.SmoothedRTT(),
-		}).Marshal()
+			IssueTime:  time.Now(),
+		}
+		if _, err := rand.Read(t.ID[:]); err != nil {
+			return nil, err
+		}
+		appData = t.Marshal()
 	}
 	return h.conn.GetSessionTicket(appData)
 }
@@ -517,14 +613,26 @@ func (h *cryptoSetup) accept0RTT(sessionTicketData []byte) bool {
 		h.logger.Debugf("Unmarshaling transport parameters from session ticket failed: %s", err.Error())
 		return false
 	}
-	valid := h.ourParams.ValidFor0RTT(t.Parameters)
-	if valid {
-		h.logger.Debugf("Accepting 0-RTT. Restoring RTT from session ticket: %s", t.RTT)
-		h.rttStats.SetInitialRTT(t.RTT)
-	} else {
+	if !h.ourParams.ValidFor0RTT(t.Parameters) {
 		h.logger.Debugf("Transport parameters changed. Rejecting 0-RTT.")
+		return false
+	}
+	if h.allow0RTT != nil && !h.allow0RTT() {
+		h.logger.Debugf("0-RTT not allowed for this connection. Rejecting 0-RTT.")
+		return false
+	}
+	// Check the anti-replay store last, since this uses up the session ticket.
+	if time.Since(t.IssueTime) > protocol.Max0RTTTicketAge {
+		h.logger.Debugf("Session ticket issued at %s is too old. Rejecting 0-RTT.", t.IssueTime)
+		return false
+	}
+	if h.antiReplayStore != nil && !h.antiReplayStore.CheckAndStore(t.ID[:], t.IssueTime.Add(protocol.Max0RTTTicketAge)) {
+		h.logger.Debugf("Session ticket was already used for 0-RTT. Rejecting 0-RTT.")
+		return false
 	}
-	return valid
+	h.logger.Debugf("Accepting 0-RTT. Restoring RTT from session ticket: %s", t.RTT)
+	h.rttStats.SetInitialRTT(t.RTT)
+	return true
 }
 
 // rejected0RTT is called for the client when the server rejects 0-RTT.
@@ -584,8 +692,8 @@ func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.Ciph
 			panic("Received 0-RTT read key for the client")
 		}
 		h.zeroRTTOpener = newLongHeaderOpener(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.origVersion),
+			newHeaderProtector(suite, trafficSecret, true, h.origVersion),
 		)
 		h.mutex.Unlock()
 		h.logger.Debugf("Installed 0-RTT Read keys (using %s)", qtls.CipherSuiteName(suite.ID))
@@ -596,8 +704,8 @@ func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.Ciph
 	case qtls.EncryptionHandshake:
 		h.readEncLevel = protocol.EncryptionHandshake
 		h.handshakeOpener = newHandshakeOpener(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 			h.dropInitialKeys,
 			h.perspective,
 		)
@@ -625,8 +733,8 @@ func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.Cip
 			panic("Received 0-RTT write key for the server")
 		}
 		h.zeroRTTSealer = newLongHeaderSealer(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.origVersion),
+			newHeaderProtector(suite, trafficSecret, true, h.origVersion),
 		)
 		h.mutex.Unlock()
 		h.logger.Debugf("Installed 0-RTT Write keys (using %s)", qtls.CipherSuiteName(suite.ID))
@@ -637,8 +745,8 @@ func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.Cip
 	case qtls.EncryptionHandshake:
 		h.writeEncLevel = protocol.EncryptionHandshake
 		h.handshakeSealer = newHandshakeSealer(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 			h.dropInitialKeys,
 			h.perspective,
 		)
@@ -99,9 +99,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 			NewMockHandshakeRunner(mockCtrl),
 			tlsConf,
 			false,
+			nil,
+			nil,
+			0,
 			&congestion.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 		qtlsConf := server.(*cryptoSetup).tlsConf
 		Expect(qtlsConf.ServerName).To(Equal(tlsConf.ServerName))
@@ -133,9 +137,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			testdata.GetTLSConfig(),
 			false,
+			nil,
+			nil,
+			0,
 			&congestion.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 
 		done := make(chan struct{})
@@ -173,9 +181,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			testdata.GetTLSConfig(),
 			false,
+			nil,
+			nil,
+			0,
 			&congestion.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 
 		done := make(chan struct{})
@@ -216,9 +228,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			serverConf,
 			false,
+			nil,
+			nil,
+			0,
 			&congestion.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 
 		done := make(chan struct{})
@@ -252,9 +268,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 			NewMockHandshakeRunner(mockCtrl),
 			serverConf,
 			false,
+			nil,
+			nil,
+			0,
 			&congestion.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 
 		done := make(chan struct{})
@@ -267,6 +287,134 @@ var _ = Describe("Crypto Setup TLS", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	Context("accepting 0-RTT", func() {
+		var (
+			server *cryptoSetup
+			params *wire.TransportParameters
+		)
+
+		BeforeEach(func() {
+			params = &wire.TransportParameters{InitialMaxData: 1337}
+			server = &cryptoSetup{
+				ourParams:       params,
+				antiReplayStore: NewInMemoryAntiReplayStore(10),
+				rttStats:        &congestion.RTTStats{},
+				logger:          utils.DefaultLogger,
+			}
+		})
+
+		getTicket := func(issueTime time.Time) []byte {
+			t := &sessionTicket{
+				Parameters: params,
+				RTT:        10 * time.Millisecond,
+				IssueTime:  issueTime,
+			}
+			rand.Read(t.ID[:])
+			return t.Marshal()
+		}
+
+		It("accepts 0-RTT only once per session ticket", func() {
+			ticket := getTicket(time.Now())
+			Expect(server.accept0RTT(ticket)).To(BeTrue())
+			Expect(server.rttStats.SmoothedRTT()).To(Equal(10 * time.Millisecond))
+			Expect(server.accept0RTT(ticket)).To(BeFalse())
+			Expect(server.accept0RTT(getTicket(time.Now()))).To(BeTrue())
+		})
+
+		It("rejects 0-RTT when the session ticket is too old", func() {
+			Expect(server.accept0RTT(getTicket(time.Now().Add(-protocol.Max0RTTTicketAge - time.Second)))).To(BeFalse())
+		})
+
+		It("rejects 0-RTT when the transport parameters changed", func() {
+			ticket := getTicket(time.Now())
+			server.ourParams = &wire.TransportParameters{InitialMaxData: 42}
+			Expect(server.accept0RTT(ticket)).To(BeFalse())
+		})
+
+		It("rejects 0-RTT when the application doesn't allow it", func() {
+			ticket := getTicket(time.Now())
+			var allow bool
+			server.allow0RTT = func() bool { return allow }
+			Expect(server.accept0RTT(ticket)).To(BeFalse())
+			// the session ticket can still be used for 0-RTT
+			allow = true
+			Expect(server.accept0RTT(ticket)).To(BeTrue())
+		})
+	})
+
+	Context("compatible version negotiation", func() {
+		connID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
+
+		newServer := func(available []protocol.VersionNumber) *cryptoSetup {
+			_, sInitialStream, sHandshakeStream := initStreams()
+			var token [16]byte
+			return NewCryptoSetupServer(
+				sInitialStream,
+				sHandshakeStream,
+				connID,
+				nil,
+				nil,
+				&wire.TransportParameters{
+					StatelessResetToken: &token,
+					VersionInformation:  &wire.VersionInformation{ChosenVersion: protocol.Version1, AvailableVersions: available},
+				},
+				NewMockHandshakeRunner(mockCtrl),
+				testdata.GetTLSConfig(),
+				false,
+				nil,
+				nil,
+				0,
+				&congestion.RTTStats{},
+				nil,
+				utils.DefaultLogger.WithPrefix("server"),
+				protocol.Version1,
+			).(*cryptoSetup)
+		}
+
+		getClientParams := func(chosen protocol.VersionNumber, available []protocol.VersionNumber) []byte {
+			return (&wire.TransportParameters{
+				InitialSourceConnectionID: protocol.ConnectionID{1, 2, 3, 4},
+				VersionInformation:        &wire.VersionInformation{ChosenVersion: chosen, AvailableVersions: available},
+			}).Marshal(protocol.PerspectiveClient)
+		}
+
+		It("switches to a compatible version that the server prefers", func() {
+			server := newServer([]protocol.VersionNumber{protocol.Version2, protocol.Version1})
+			data := server.negotiateVersion(getClientParams(protocol.Version1, []protocol.VersionNumber{protocol.Version1, protocol.Version2}))
+			Expect(data).ToNot(BeNil())
+			Expect(server.version).To(Equal(protocol.Version2))
+			var tp wire.TransportParameters
+			Expect(tp.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+			Expect(tp.VersionInformation.ChosenVersion).To(Equal(protocol.Version2))
+			// the Initial keys were derived using QUIC v2
+			clientSealer, _ := NewInitialAEAD(connID, protocol.PerspectiveClient, protocol.Version2)
+			opener, err := server.GetInitialOpener()
+			Expect(err).ToNot(HaveOccurred())
+			msg := clientSealer.Seal(nil, []byte("foobar"), 42, []byte("aad"))
+			decrypted, err := opener.Open(nil, msg, 42, []byte("aad"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decrypted).To(Equal([]byte("foobar")))
+		})
+
+		It("doesn't switch if the server prefers the current version", func() {
+			server := newServer([]protocol.VersionNumber{protocol.Version1, protocol.Version2})
+			Expect(server.negotiateVersion(getClientParams(protocol.Version1, []protocol.VersionNumber{protocol.Version1, protocol.Version2}))).To(BeNil())
+			Expect(server.version).To(Equal(protocol.Version1))
+		})
+
+		It("doesn't switch if the client doesn't support the version", func() {
+			server := newServer([]protocol.VersionNumber{protocol.Version2, protocol.Version1})
+			Expect(server.negotiateVersion(getClientParams(protocol.Version1, []protocol.VersionNumber{protocol.Version1}))).To(BeNil())
+			Expect(server.version).To(Equal(protocol.Version1))
+		})
+
+		It("doesn't switch if the client's chosen version doesn't match", func() {
+			server := newServer([]protocol.VersionNumber{protocol.Version2, protocol.Version1})
+			Expect(server.negotiateVersion(getClientParams(protocol.Version2, []protocol.VersionNumber{protocol.Version1, protocol.Version2}))).To(BeNil())
+			Expect(server.version).To(Equal(protocol.Version1))
+		})
+	})
+
 	Context("doing the handshake", func() {
 		generateCert := func() tls.Certificate {
 			priv, err := rsa.GenerateKey(rand.Reader, 2048)
@@ -350,9 +498,11 @@ var _ = Describe("Crypto Setup TLS", func() {
 				cRunner,
 				clientConf,
 				enable0RTT,
+				0,
 				clientRTTStats,
 				nil,
 				utils.DefaultLogger.WithPrefix("client"),
+				protocol.VersionTLS,
 			)
 
 			var sHandshakeComplete bool
@@ -376,9 +526,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sRunner,
 				serverConf,
 				enable0RTT,
+				nil,
+				NewInMemoryAntiReplayStore(10),
+				0,
 				serverRTTStats,
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
+				protocol.VersionTLS,
 			)
 
 			handshake(client, cChunkChan, server, sChunkChan)
@@ -445,9 +599,11 @@ var _ = Describe("Crypto Setup TLS", func() {
 				runner,
 				&tls.Config{InsecureSkipVerify: true},
 				false,
+				0,
 				&congestion.RTTStats{},
 				nil,
 				utils.DefaultLogger.WithPrefix("client"),
+				protocol.VersionTLS,
 			)
 
 			done := make(chan struct{})
@@ -487,9 +643,11 @@ var _ = Describe("Crypto Setup TLS", func() {
 				cRunner,
 				clientConf,
 				false,
+				0,
 				&congestion.RTTStats{},
 				nil,
 				utils.DefaultLogger.WithPrefix("client"),
+				protocol.VersionTLS,
 			)
 
 			sChunkChan, sInitialStream, sHandshakeStream := initStreams()
@@ -511,9 +669,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sRunner,
 				serverConf,
 				false,
+				nil,
+				nil,
+				0,
 				&congestion.RTTStats{},
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
+				protocol.VersionTLS,
 			)
 
 			done := make(chan struct{})
@@ -544,9 +706,11 @@ var _ = Describe("Crypto Setup TLS", func() {
 					cRunner,
 					clientConf,
 					false,
+					0,
 					&congestion.RTTStats{},
 					nil,
 					utils.DefaultLogger.WithPrefix("client"),
+					protocol.VersionTLS,
 				)
 
 				sChunkChan, sInitialStream, sHandshakeStream := initStreams()
@@ -564,9 +728,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					false,
+					nil,
+					nil,
+					0,
 					&congestion.RTTStats{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
+					protocol.VersionTLS,
 				)
 
 				done := make(chan struct{})
@@ -604,9 +772,11 @@ var _ = Describe("Crypto Setup TLS", func() {
 					cRunner,
 					clientConf,
 					false,
+					0,
 					&congestion.RTTStats{},
 					nil,
 					utils.DefaultLogger.WithPrefix("client"),
+					protocol.VersionTLS,
 				)
 
 				sChunkChan, sInitialStream, sHandshakeStream := initStreams()
@@ -624,9 +794,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					false,
+					nil,
+					nil,
+					0,
 					&congestion.RTTStats{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
+					protocol.VersionTLS,
 				)
 
 				done := make(chan struct{})
@@ -73,10 +73,12 @@ type CryptoSetup interface {
 	RunHandshake()
 	io.Closer
 	ChangeConnectionID(protocol.ConnectionID)
+	ChangeVersion(protocol.VersionNumber)
 	GetSessionTicket() ([]byte, error)
 
 	HandleMessage([]byte, protocol.EncryptionLevel) bool
 	SetLargest1RTTAcked(protocol.PacketNumber)
+	InitiateKeyUpdate() error
 	DropHandshakeKeys()
 	ConnectionState() ConnectionState
 
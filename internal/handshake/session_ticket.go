@@ -4,23 +4,33 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
-const sessionTicketRevision = 2
+const sessionTicketRevision = 3
+
+// the length of the session ticket ID used for anti-replay protection
+const sessionTicketIDLen = 16
 
 type sessionTicket struct {
 	Parameters *wire.TransportParameters
 	RTT        time.Duration // to be encoded in mus
+	// ID uniquely identifies the session ticket.
+	// It is used to make sure that a session ticket is only used once for 0-RTT.
+	ID        [sessionTicketIDLen]byte
+	IssueTime time.Time // to be encoded in mus since the Unix epoch
 }
 
 func (t *sessionTicket) Marshal() []byte {
 	b := &bytes.Buffer{}
 	utils.WriteVarInt(b, sessionTicketRevision)
 	utils.WriteVarInt(b, uint64(t.RTT.Microseconds()))
+	b.Write(t.ID[:])
+	utils.WriteVarInt(b, uint64(t.IssueTime.UnixNano()/1000))
 	t.Parameters.MarshalForSessionTicket(b)
 	return b.Bytes()
 }
@@ -38,11 +48,21 @@ func (t *sessionTicket) Unmarshal(b []byte) error {
 	if err != nil {
 		return errors.New("failed to read RTT")
 	}
+	var id [sessionTicketIDLen]byte
+	if _, err := io.ReadFull(r, id[:]); err != nil {
+		return errors.New("failed to read ID")
+	}
+	issueTime, err := utils.ReadVarInt(r)
+	if err != nil {
+		return errors.New("failed to read issue time")
+	}
 	var tp wire.TransportParameters
 	if err := tp.UnmarshalFromSessionTicket(r); err != nil {
 		return fmt.Errorf("unmarshaling transport parameters from session ticket failed: %s", err.Error())
 	}
 	t.Parameters = &tp
 	t.RTT = time.Duration(rtt) * time.Microsecond
+	t.ID = id
+	t.IssueTime = time.Unix(0, int64(issueTime)*1000)
 	return nil
 }
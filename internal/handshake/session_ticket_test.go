@@ -18,13 +18,17 @@ var _ = Describe("Session Ticket", func() {
 				InitialMaxStreamDataBidiLocal:  1,
 				InitialMaxStreamDataBidiRemote: 2,
 			},
-			RTT: 1337 * time.Microsecond,
+			RTT:       1337 * time.Microsecond,
+			ID:        [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			IssueTime: time.Unix(1600000000, 123456000),
 		}
 		var t sessionTicket
 		Expect(t.Unmarshal(ticket.Marshal())).To(Succeed())
 		Expect(t.Parameters.InitialMaxStreamDataBidiLocal).To(BeEquivalentTo(1))
 		Expect(t.Parameters.InitialMaxStreamDataBidiRemote).To(BeEquivalentTo(2))
 		Expect(t.RTT).To(Equal(1337 * time.Microsecond))
+		Expect(t.ID).To(Equal([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}))
+		Expect(t.IssueTime).To(BeTemporally("==", time.Unix(1600000000, 123456000)))
 	})
 
 	It("refuses to unmarshal if the ticket is too short for the revision", func() {
@@ -43,9 +47,28 @@ var _ = Describe("Session Ticket", func() {
 		Expect((&sessionTicket{}).Unmarshal(b.Bytes())).To(MatchError("failed to read RTT"))
 	})
 
+	It("refuses to unmarshal if the ID cannot be read", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, sessionTicketRevision)
+		utils.WriteVarInt(b, 1337)
+		b.Write([]byte("foobar"))
+		Expect((&sessionTicket{}).Unmarshal(b.Bytes())).To(MatchError("failed to read ID"))
+	})
+
+	It("refuses to unmarshal if the issue time cannot be read", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, sessionTicketRevision)
+		utils.WriteVarInt(b, 1337)
+		b.Write(make([]byte, 16))
+		Expect((&sessionTicket{}).Unmarshal(b.Bytes())).To(MatchError("failed to read issue time"))
+	})
+
 	It("refuses to unmarshal if unmarshaling the transport parameters fails", func() {
 		b := &bytes.Buffer{}
 		utils.WriteVarInt(b, sessionTicketRevision)
+		utils.WriteVarInt(b, 1337)
+		b.Write(make([]byte, 16))
+		utils.WriteVarInt(b, 42)
 		b.Write([]byte("foobar"))
 		err := (&sessionTicket{}).Unmarshal(b.Bytes())
 		Expect(err).To(HaveOccurred())
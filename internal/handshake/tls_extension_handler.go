@@ -5,23 +5,38 @@ import (
 	"github.com/marten-seemann/qtls"
 )
 
-const quicTLSExtensionType = 0xffa5
+const (
+	quicTLSExtensionTypeOldDrafts = 0xffa5
+	quicTLSExtensionType          = 0x39
+)
 
 type extensionHandler struct {
 	ourParams  []byte
 	paramsChan chan []byte
 
+	extensionType uint16
+
 	perspective protocol.Perspective
+
+	// only used by the server
+	// Called with the client's transport parameters, before they are passed on.
+	// If it returns a non-nil value, we send these transport parameters instead of our original ones.
+	updateParams func(clientParams []byte) []byte
 }
 
 var _ tlsExtensionHandler = &extensionHandler{}
 
 // newExtensionHandler creates a new extension handler
-func newExtensionHandler(params []byte, pers protocol.Perspective) tlsExtensionHandler {
+func newExtensionHandler(params []byte, pers protocol.Perspective, v protocol.VersionNumber) *extensionHandler {
+	et := uint16(quicTLSExtensionType)
+	if v == protocol.VersionDraft29 {
+		et = quicTLSExtensionTypeOldDrafts
+	}
 	return &extensionHandler{
-		ourParams:   params,
-		paramsChan:  make(chan []byte),
-		perspective: pers,
+		ourParams:     params,
+		paramsChan:    make(chan []byte),
+		perspective:   pers,
+		extensionType: et,
 	}
 }
 
@@ -31,7 +46,7 @@ func (h *extensionHandler) GetExtensions(msgType uint8) []qtls.Extension {
 		return nil
 	}
 	return []qtls.Extension{{
-		Type: quicTLSExtensionType,
+		Type: h.extensionType,
 		Data: h.ourParams,
 	}}
 }
@@ -44,12 +59,17 @@ func (h *extensionHandler) ReceivedExtensions(msgType uint8, exts []qtls.Extensi
 
 	var data []byte
 	for _, ext := range exts {
-		if ext.Type == quicTLSExtensionType {
+		if ext.Type == h.extensionType {
 			data = ext.Data
 			break
 		}
 	}
 
+	if h.updateParams != nil {
+		if params := h.updateParams(data); params != nil {
+			h.ourParams = params
+		}
+	}
 	h.paramsChan <- data
 }
 
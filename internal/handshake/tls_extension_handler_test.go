@@ -10,18 +10,20 @@ import (
 
 var _ = Describe("TLS Extension Handler, for the server", func() {
 	var (
-		handlerServer tlsExtensionHandler
-		handlerClient tlsExtensionHandler
+		handlerServer *extensionHandler
+		handlerClient *extensionHandler
 	)
 
 	BeforeEach(func() {
 		handlerServer = newExtensionHandler(
 			[]byte("foobar"),
 			protocol.PerspectiveServer,
+			protocol.VersionTLS,
 		)
 		handlerClient = newExtensionHandler(
 			[]byte("raboof"),
 			protocol.PerspectiveClient,
+			protocol.VersionTLS,
 		)
 	})
 
@@ -83,6 +85,22 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 				Expect(data).To(BeEmpty())
 			})
 
+			It("updates the transport parameters it sends", func() {
+				handlerServer.updateParams = func(clientParams []byte) []byte {
+					Expect(clientParams).To(Equal([]byte("raboof")))
+					return []byte("updated")
+				}
+				go func() {
+					defer GinkgoRecover()
+					handlerServer.ReceivedExtensions(uint8(typeClientHello), chExts)
+				}()
+
+				Eventually(handlerServer.TransportParameters()).Should(Receive())
+				exts := handlerServer.GetExtensions(uint8(typeEncryptedExtensions))
+				Expect(exts).To(HaveLen(1))
+				Expect(exts[0].Data).To(Equal([]byte("updated")))
+			})
+
 			It("ignores extensions that are not sent with the ClientHello", func() {
 				done := make(chan struct{})
 				go func() {
@@ -168,4 +186,26 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 			})
 		})
 	})
+
+	It("uses the extension codepoint of the old drafts for draft-29", func() {
+		server := newExtensionHandler([]byte("foobar"), protocol.PerspectiveServer, protocol.VersionDraft29)
+		exts := server.GetExtensions(uint8(typeEncryptedExtensions))
+		Expect(exts).To(HaveLen(1))
+		Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionTypeOldDrafts))
+
+		client := newExtensionHandler([]byte("raboof"), protocol.PerspectiveClient, protocol.VersionDraft29)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			client.ReceivedExtensions(uint8(typeEncryptedExtensions), []qtls.Extension{
+				{Type: quicTLSExtensionType, Data: []byte("foo")},
+				{Type: quicTLSExtensionTypeOldDrafts, Data: []byte("bar")},
+			})
+			close(done)
+		}()
+		var data []byte
+		Eventually(client.TransportParameters()).Should(Receive(&data))
+		Expect(data).To(Equal([]byte("bar")))
+		Eventually(done).Should(BeClosed())
+	})
 })
@@ -22,6 +22,8 @@ type Token struct {
 	// only set for retry tokens
 	OriginalDestConnectionID protocol.ConnectionID
 	RetrySrcConnectionID     protocol.ConnectionID
+	// only set for tokens sent in NEW_TOKEN frames
+	ApplicationData []byte
 }
 
 // token is the struct that is used for ASN1 serialization and deserialization
@@ -31,6 +33,7 @@ type token struct {
 	Timestamp                int64
 	OriginalDestConnectionID []byte
 	RetrySrcConnectionID     []byte
+	ApplicationData          []byte `asn1:"optional"`
 }
 
 // A TokenGenerator generates tokens
@@ -38,9 +41,11 @@ type TokenGenerator struct {
 	tokenProtector tokenProtector
 }
 
-// NewTokenGenerator initializes a new TookenGenerator
-func NewTokenGenerator() (*TokenGenerator, error) {
-	tokenProtector, err := newTokenProtector()
+// NewTokenGenerator initializes a new TookenGenerator.
+// getKeys returns the keys used to protect tokens, the newest key first.
+// If getKeys is nil, a random key is used.
+func NewTokenGenerator(getKeys func() [][]byte) (*TokenGenerator, error) {
+	tokenProtector, err := newTokenProtector(getKeys)
 	if err != nil {
 		return nil, err
 	}
@@ -69,10 +74,11 @@ func (g *TokenGenerator) NewRetryToken(
 }
 
 // NewToken generates a new token to be sent in a NEW_TOKEN frame
-func (g *TokenGenerator) NewToken(raddr net.Addr) ([]byte, error) {
+func (g *TokenGenerator) NewToken(raddr net.Addr, appData []byte) ([]byte, error) {
 	data, err := asn1.Marshal(token{
-		RemoteAddr: encodeRemoteAddr(raddr),
-		Timestamp:  time.Now().UnixNano(),
+		RemoteAddr:      encodeRemoteAddr(raddr),
+		Timestamp:       time.Now().UnixNano(),
+		ApplicationData: appData,
 	})
 	if err != nil {
 		return nil, err
@@ -107,6 +113,8 @@ func (g *TokenGenerator) DecodeToken(encrypted []byte) (*Token, error) {
 	if t.IsRetryToken {
 		token.OriginalDestConnectionID = protocol.ConnectionID(t.OriginalDestConnectionID)
 		token.RetrySrcConnectionID = protocol.ConnectionID(t.RetrySrcConnectionID)
+	} else if len(t.ApplicationData) > 0 {
+		token.ApplicationData = t.ApplicationData
 	}
 	return token, nil
 }
@@ -16,7 +16,7 @@ var _ = Describe("Token Generator", func() {
 
 	BeforeEach(func() {
 		var err error
-		tokenGen, err = NewTokenGenerator()
+		tokenGen, err = NewTokenGenerator(nil)
 		Expect(err).ToNot(HaveOccurred())
 	})
 
@@ -62,6 +62,46 @@ var _ = Describe("Token Generator", func() {
 		Expect(token.RetrySrcConnectionID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xc0, 0xde}))
 	})
 
+	It("saves application data in tokens sent in NEW_TOKEN frames", func() {
+		tokenEnc, err := tokenGen.NewToken(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}, []byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		token, err := tokenGen.DecodeToken(tokenEnc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token.IsRetryToken).To(BeFalse())
+		Expect(token.RemoteAddr).To(Equal("192.168.0.1"))
+		Expect(token.ApplicationData).To(Equal([]byte("foobar")))
+	})
+
+	It("decodes tokens that don't contain application data", func() {
+		t, err := asn1.Marshal(struct {
+			IsRetryToken             bool
+			RemoteAddr               []byte
+			Timestamp                int64
+			OriginalDestConnectionID []byte
+			RetrySrcConnectionID     []byte
+		}{RemoteAddr: encodeRemoteAddr(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1)})})
+		Expect(err).ToNot(HaveOccurred())
+		enc, err := tokenGen.tokenProtector.NewToken(t)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := tokenGen.DecodeToken(enc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token.RemoteAddr).To(Equal("192.168.0.1"))
+		Expect(token.ApplicationData).To(BeNil())
+	})
+
+	It("accepts tokens generated by another generator using the same keys", func() {
+		getKeys := func() [][]byte { return [][]byte{[]byte("foobar")} }
+		gen1, err := NewTokenGenerator(getKeys)
+		Expect(err).ToNot(HaveOccurred())
+		gen2, err := NewTokenGenerator(getKeys)
+		Expect(err).ToNot(HaveOccurred())
+		tokenEnc, err := gen1.NewToken(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := gen2.DecodeToken(tokenEnc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token.RemoteAddr).To(Equal("192.168.0.1"))
+	})
+
 	It("rejects invalid tokens", func() {
 		_, err := tokenGen.DecodeToken([]byte("invalid token"))
 		Expect(err).To(HaveOccurred())
@@ -5,6 +5,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 
@@ -24,27 +25,39 @@ const (
 	tokenNonceSize  = 32
 )
 
+var errNoTokenKeys = errors.New("no token keys")
+
 // tokenProtector is used to create and verify a token
 type tokenProtectorImpl struct {
-	secret []byte
+	getKeys func() [][]byte
 }
 
-// newTokenProtector creates a source for source address tokens
-func newTokenProtector() (tokenProtector, error) {
-	secret := make([]byte, tokenSecretSize)
-	if _, err := rand.Read(secret); err != nil {
-		return nil, err
+// newTokenProtector creates a source for source address tokens.
+// The first key returned by getKeys is used to protect new tokens,
+// all keys are tried when decoding a token.
+// If getKeys is nil, a random key is generated.
+func newTokenProtector(getKeys func() [][]byte) (tokenProtector, error) {
+	if getKeys == nil {
+		secret := make([]byte, tokenSecretSize)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		getKeys = func() [][]byte { return [][]byte{secret} }
 	}
-	return &tokenProtectorImpl{secret: secret}, nil
+	return &tokenProtectorImpl{getKeys: getKeys}, nil
 }
 
 // NewToken encodes data into a new token.
 func (s *tokenProtectorImpl) NewToken(data []byte) ([]byte, error) {
+	keys := s.getKeys()
+	if len(keys) == 0 {
+		return nil, errNoTokenKeys
+	}
 	nonce := make([]byte, tokenNonceSize)
 	if _, err := rand.Read(nonce); err != nil {
 		return nil, err
 	}
-	aead, aeadNonce, err := s.createAEAD(nonce)
+	aead, aeadNonce, err := s.createAEAD(keys[0], nonce)
 	if err != nil {
 		return nil, err
 	}
@@ -56,16 +69,28 @@ func (s *tokenProtectorImpl) DecodeToken(p []byte) ([]byte, error) {
 	if len(p) < tokenNonceSize {
 		return nil, fmt.Errorf("token too short: %d", len(p))
 	}
+	keys := s.getKeys()
+	if len(keys) == 0 {
+		return nil, errNoTokenKeys
+	}
 	nonce := p[:tokenNonceSize]
-	aead, aeadNonce, err := s.createAEAD(nonce)
-	if err != nil {
-		return nil, err
+	var lastErr error
+	for _, key := range keys {
+		aead, aeadNonce, err := s.createAEAD(key, nonce)
+		if err != nil {
+			return nil, err
+		}
+		data, err := aead.Open(nil, aeadNonce, p[tokenNonceSize:], nil)
+		if err == nil {
+			return data, nil
+		}
+		lastErr = err
 	}
-	return aead.Open(nil, aeadNonce, p[tokenNonceSize:], nil)
+	return nil, lastErr
 }
 
-func (s *tokenProtectorImpl) createAEAD(nonce []byte) (cipher.AEAD, []byte, error) {
-	h := hkdf.New(sha256.New, s.secret, nonce, []byte("quic-go token source"))
+func (s *tokenProtectorImpl) createAEAD(secret, nonce []byte) (cipher.AEAD, []byte, error) {
+	h := hkdf.New(sha256.New, secret, nonce, []byte("quic-go token source"))
 	key := make([]byte, 32) // use a 32 byte key, in order to select AES-256
 	if _, err := io.ReadFull(h, key); err != nil {
 		return nil, nil, err
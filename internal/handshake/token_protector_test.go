@@ -10,7 +10,7 @@ var _ = Describe("Token Protector", func() {
 
 	BeforeEach(func() {
 		var err error
-		tp, err = newTokenProtector()
+		tp, err = newTokenProtector(nil)
 		Expect(err).ToNot(HaveOccurred())
 	})
 
@@ -32,6 +32,53 @@ var _ = Describe("Token Protector", func() {
 		Expect(err.Error()).To(ContainSubstring("message authentication failed"))
 	})
 
+	Context("using keys", func() {
+		var keys [][]byte
+
+		BeforeEach(func() {
+			keys = [][]byte{[]byte("key 1")}
+			var err error
+			tp, err = newTokenProtector(func() [][]byte { return keys })
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("decodes tokens encoded with an older key", func() {
+			token, err := tp.NewToken([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			keys = [][]byte{[]byte("key 2"), []byte("key 1")}
+			decoded, err := tp.DecodeToken(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal([]byte("foobar")))
+		})
+
+		It("encodes tokens using the first key", func() {
+			keys = [][]byte{[]byte("key 2"), []byte("key 1")}
+			token, err := tp.NewToken([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			keys = [][]byte{[]byte("key 2")}
+			decoded, err := tp.DecodeToken(token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal([]byte("foobar")))
+		})
+
+		It("rejects tokens encoded with a key that was removed", func() {
+			token, err := tp.NewToken([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			keys = [][]byte{[]byte("key 2")}
+			_, err = tp.DecodeToken(token)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("message authentication failed"))
+		})
+
+		It("errors when there are no keys", func() {
+			keys = nil
+			_, err := tp.NewToken([]byte("foobar"))
+			Expect(err).To(MatchError(errNoTokenKeys))
+			_, err = tp.DecodeToken(make([]byte, 100))
+			Expect(err).To(MatchError(errNoTokenKeys))
+		})
+	})
+
 	It("errors when decoding too short tokens", func() {
 		_, err := tp.DecodeToken([]byte("foobar"))
 		Expect(err).To(MatchError("token too short: 6"))
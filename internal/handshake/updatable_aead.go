@@ -4,6 +4,7 @@ import (
 	"crypto"
 	"crypto/cipher"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -22,8 +23,11 @@ import (
 // This is not needed in production, but useful for integration and interop testing.
 // Note that no mattter what value is set, a key update is only initiated once it is
 // permitted (i.e. once an ACK for a packet sent at the current key phase has been received).
+// The key update interval configured in the quic.Config takes precedence.
 const keyUpdateEnv = "QUIC_GO_KEY_UPDATE_INTERVAL"
 
+var errKeyUpdateNotAllowed = errors.New("key update not yet allowed")
+
 var keyUpdateInterval uint64
 
 func init() {
@@ -44,13 +48,18 @@ func setKeyUpdateInterval() {
 }
 
 type updatableAEAD struct {
-	suite *qtls.CipherSuiteTLS13
+	suite   *qtls.CipherSuiteTLS13
+	version protocol.VersionNumber
 
 	keyPhase          protocol.KeyPhase
 	largestAcked      protocol.PacketNumber
 	firstPacketNumber protocol.PacketNumber
 	keyUpdateInterval uint64
 
+	// the number of packets that failed authentication, across all keys
+	invalidPacketCount uint64
+	invalidPacketLimit uint64
+
 	// Time when the keys should be dropped. Keys are dropped on the next call to Open().
 	prevRcvAEADExpiry time.Time
 	prevRcvAEAD       cipher.AEAD
@@ -84,16 +93,22 @@ type updatableAEAD struct {
 var _ ShortHeaderOpener = &updatableAEAD{}
 var _ ShortHeaderSealer = &updatableAEAD{}
 
-func newUpdatableAEAD(rttStats *congestion.RTTStats, tracer logging.ConnectionTracer, logger utils.Logger) *updatableAEAD {
+// newUpdatableAEAD creates a new updatableAEAD.
+// If interval is 0, the default key update interval is used.
+func newUpdatableAEAD(rttStats *congestion.RTTStats, interval uint64, tracer logging.ConnectionTracer, logger utils.Logger, version protocol.VersionNumber) *updatableAEAD {
+	if interval == 0 {
+		interval = keyUpdateInterval
+	}
 	return &updatableAEAD{
 		firstPacketNumber:       protocol.InvalidPacketNumber,
 		largestAcked:            protocol.InvalidPacketNumber,
 		firstRcvdWithCurrentKey: protocol.InvalidPacketNumber,
 		firstSentWithCurrentKey: protocol.InvalidPacketNumber,
-		keyUpdateInterval:       keyUpdateInterval,
+		keyUpdateInterval:       interval,
 		rttStats:                rttStats,
 		tracer:                  tracer,
 		logger:                  logger,
+		version:                 version,
 	}
 }
 
@@ -110,45 +125,75 @@ func (a *updatableAEAD) rollKeys(now time.Time) {
 
 	a.nextRcvTrafficSecret = a.getNextTrafficSecret(a.suite.Hash, a.nextRcvTrafficSecret)
 	a.nextSendTrafficSecret = a.getNextTrafficSecret(a.suite.Hash, a.nextSendTrafficSecret)
-	a.nextRcvAEAD = createAEAD(a.suite, a.nextRcvTrafficSecret)
-	a.nextSendAEAD = createAEAD(a.suite, a.nextSendTrafficSecret)
+	a.nextRcvAEAD = createAEAD(a.suite, a.nextRcvTrafficSecret, a.version)
+	a.nextSendAEAD = createAEAD(a.suite, a.nextSendTrafficSecret, a.version)
 }
 
 func (a *updatableAEAD) getNextTrafficSecret(hash crypto.Hash, ts []byte) []byte {
-	return hkdfExpandLabel(hash, ts, []byte{}, "quic ku", hash.Size())
+	label := "quic ku"
+	if a.version == protocol.Version2 {
+		label = "quicv2 ku"
+	}
+	return hkdfExpandLabel(hash, ts, []byte{}, label, hash.Size())
 }
 
 // For the client, this function is called before SetWriteKey.
 // For the server, this function is called after SetWriteKey.
 func (a *updatableAEAD) SetReadKey(suite *qtls.CipherSuiteTLS13, trafficSecret []byte) {
-	a.rcvAEAD = createAEAD(suite, trafficSecret)
-	a.headerDecrypter = newHeaderProtector(suite, trafficSecret, false)
+	a.rcvAEAD = createAEAD(suite, trafficSecret, a.version)
+	a.headerDecrypter = newHeaderProtector(suite, trafficSecret, false, a.version)
 	if a.suite == nil {
-		a.nonceBuf = make([]byte, a.rcvAEAD.NonceSize())
-		a.aeadOverhead = a.rcvAEAD.Overhead()
-		a.suite = suite
+		a.setAEADParameters(a.rcvAEAD, suite)
 	}
 
 	a.nextRcvTrafficSecret = a.getNextTrafficSecret(suite.Hash, trafficSecret)
-	a.nextRcvAEAD = createAEAD(suite, a.nextRcvTrafficSecret)
+	a.nextRcvAEAD = createAEAD(suite, a.nextRcvTrafficSecret, a.version)
 }
 
 // For the client, this function is called after SetReadKey.
 // For the server, this function is called before SetWriteKey.
 func (a *updatableAEAD) SetWriteKey(suite *qtls.CipherSuiteTLS13, trafficSecret []byte) {
-	a.sendAEAD = createAEAD(suite, trafficSecret)
-	a.headerEncrypter = newHeaderProtector(suite, trafficSecret, false)
+	a.sendAEAD = createAEAD(suite, trafficSecret, a.version)
+	a.headerEncrypter = newHeaderProtector(suite, trafficSecret, false, a.version)
 	if a.suite == nil {
-		a.nonceBuf = make([]byte, a.sendAEAD.NonceSize())
-		a.aeadOverhead = a.sendAEAD.Overhead()
-		a.suite = suite
+		a.setAEADParameters(a.sendAEAD, suite)
 	}
 
 	a.nextSendTrafficSecret = a.getNextTrafficSecret(suite.Hash, trafficSecret)
-	a.nextSendAEAD = createAEAD(suite, a.nextSendTrafficSecret)
+	a.nextSendAEAD = createAEAD(suite, a.nextSendTrafficSecret, a.version)
+}
+
+func (a *updatableAEAD) setAEADParameters(aead cipher.AEAD, suite *qtls.CipherSuiteTLS13) {
+	a.nonceBuf = make([]byte, aead.NonceSize())
+	a.aeadOverhead = aead.Overhead()
+	a.suite = suite
+	// Apply the AEAD limits from section 6.6 of RFC 9001.
+	switch suite.ID {
+	case qtls.TLS_AES_128_GCM_SHA256, qtls.TLS_AES_256_GCM_SHA384:
+		// Update keys before the confidentiality limit is reached.
+		if a.keyUpdateInterval > protocol.ConfidentialityLimitAES {
+			a.keyUpdateInterval = protocol.ConfidentialityLimitAES
+		}
+		a.invalidPacketLimit = protocol.IntegrityLimitAES
+	case qtls.TLS_CHACHA20_POLY1305_SHA256:
+		a.invalidPacketLimit = protocol.IntegrityLimitChaCha
+	default:
+		panic(fmt.Sprintf("unknown cipher suite %d", suite.ID))
+	}
 }
 
 func (a *updatableAEAD) Open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+	dec, err := a.open(dst, src, rcvTime, pn, kp, ad)
+	if err == ErrDecryptionFailed {
+		a.invalidPacketCount++
+		if a.invalidPacketCount > a.invalidPacketLimit {
+			return nil, qerr.NewError(qerr.AEADLimitReached, fmt.Sprintf("%d packets failed authentication", a.invalidPacketCount))
+		}
+	}
+	return dec, err
+}
+
+func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
 	if a.prevRcvAEAD != nil && rcvTime.After(a.prevRcvAEADExpiry) {
 		a.prevRcvAEAD = nil
 		a.prevRcvAEADExpiry = time.Time{}
@@ -244,14 +289,29 @@ func (a *updatableAEAD) shouldInitiateKeyUpdate() bool {
 
 func (a *updatableAEAD) KeyPhase() protocol.KeyPhaseBit {
 	if a.shouldInitiateKeyUpdate() {
-		if a.tracer != nil {
-			a.tracer.UpdatedKey(a.keyPhase, false)
-		}
-		a.rollKeys(time.Now())
+		a.initiateKeyUpdate()
 	}
 	return a.keyPhase.Bit()
 }
 
+// InitiateKeyUpdate updates the keys, independent of the key update interval.
+// Packets sent after this call use the next key phase.
+func (a *updatableAEAD) InitiateKeyUpdate() error {
+	if !a.updateAllowed() {
+		return errKeyUpdateNotAllowed
+	}
+	a.logger.Debugf("Initiating key update to the next key phase: %s", a.keyPhase+1)
+	a.initiateKeyUpdate()
+	return nil
+}
+
+func (a *updatableAEAD) initiateKeyUpdate() {
+	if a.tracer != nil {
+		a.tracer.UpdatedKey(a.keyPhase, false)
+	}
+	a.rollKeys(time.Now())
+}
+
 func (a *updatableAEAD) Overhead() int {
 	return a.aeadOverhead
 }
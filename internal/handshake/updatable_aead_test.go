@@ -18,7 +18,7 @@ import (
 var _ = Describe("Updatable AEAD", func() {
 	It("ChaCha test vector from the draft", func() {
 		secret := splitHexString("9ac312a7f877468ebe69422748ad00a1 5443f18203a07d6060f688f30f21632b")
-		aead := newUpdatableAEAD(&congestion.RTTStats{}, nil, nil)
+		aead := newUpdatableAEAD(&congestion.RTTStats{}, 0, nil, nil, protocol.VersionTLS)
 		chacha := cipherSuites[2]
 		Expect(chacha.ID).To(Equal(qtls.TLS_CHACHA20_POLY1305_SHA256))
 		aead.SetWriteKey(chacha, secret)
@@ -43,8 +43,8 @@ var _ = Describe("Updatable AEAD", func() {
 				rand.Read(trafficSecret1)
 				rand.Read(trafficSecret2)
 
-				client = newUpdatableAEAD(rttStats, nil, utils.DefaultLogger)
-				server = newUpdatableAEAD(rttStats, nil, utils.DefaultLogger)
+				client = newUpdatableAEAD(rttStats, 0, nil, utils.DefaultLogger, protocol.VersionTLS)
+				server = newUpdatableAEAD(rttStats, 0, nil, utils.DefaultLogger, protocol.VersionTLS)
 				client.SetReadKey(cs, trafficSecret2)
 				client.SetWriteKey(cs, trafficSecret1)
 				server.SetReadKey(cs, trafficSecret1)
@@ -250,6 +250,31 @@ var _ = Describe("Updatable AEAD", func() {
 							server.SetLargestAcked(1)
 							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
 						})
+
+						It("initiates a key update when requested by the application", func() {
+							Expect(server.InitiateKeyUpdate()).To(MatchError(errKeyUpdateNotAllowed))
+							encrypted0 := server.Seal(nil, msg, 1, ad)
+							Expect(server.InitiateKeyUpdate()).To(MatchError(errKeyUpdateNotAllowed))
+							server.SetLargestAcked(1)
+							Expect(server.InitiateKeyUpdate()).To(Succeed())
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+							encrypted1 := server.Seal(nil, msg, 2, ad)
+							// the previous key update needs to be acknowledged first
+							Expect(server.InitiateKeyUpdate()).To(MatchError(errKeyUpdateNotAllowed))
+							// the client updates its keys when receiving the packet
+							_, err := client.Open(nil, encrypted0, time.Now(), 1, protocol.KeyPhaseZero, ad)
+							Expect(err).ToNot(HaveOccurred())
+							client.Seal(nil, msg, 1, ad)
+							decrypted, err := client.Open(nil, encrypted1, time.Now(), 2, protocol.KeyPhaseOne, ad)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(decrypted).To(Equal(msg))
+							Expect(client.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+						})
+					})
+
+					It("uses the configured key update interval", func() {
+						aead := newUpdatableAEAD(rttStats, 42, nil, utils.DefaultLogger, protocol.VersionTLS)
+						Expect(aead.keyUpdateInterval).To(BeEquivalentTo(42))
 					})
 
 					Context("reading the key update env", func() {
@@ -275,6 +300,41 @@ var _ = Describe("Updatable AEAD", func() {
 						})
 					})
 				})
+
+				Context("AEAD limits", func() {
+					It("updates keys before reaching the confidentiality limit", func() {
+						aead := newUpdatableAEAD(rttStats, 1<<40, nil, utils.DefaultLogger, protocol.VersionTLS)
+						aead.SetWriteKey(cs, make([]byte, 16))
+						if cs.ID == qtls.TLS_CHACHA20_POLY1305_SHA256 {
+							Expect(aead.keyUpdateInterval).To(BeEquivalentTo(1 << 40))
+						} else {
+							Expect(aead.keyUpdateInterval).To(BeEquivalentTo(protocol.ConfidentialityLimitAES))
+						}
+					})
+
+					It("sets the integrity limit", func() {
+						if cs.ID == qtls.TLS_CHACHA20_POLY1305_SHA256 {
+							Expect(server.invalidPacketLimit).To(BeEquivalentTo(protocol.IntegrityLimitChaCha))
+						} else {
+							Expect(server.invalidPacketLimit).To(BeEquivalentTo(protocol.IntegrityLimitAES))
+						}
+					})
+
+					It("errors when the integrity limit is exceeded", func() {
+						const limit = 10
+						server.invalidPacketLimit = limit
+						encrypted := client.Seal(nil, msg, 0x1337, ad)
+						for i := 0; i < limit; i++ {
+							_, err := server.Open(nil, encrypted, time.Now(), 0x42, protocol.KeyPhaseZero, ad)
+							Expect(err).To(MatchError(ErrDecryptionFailed))
+						}
+						// packets that are successfully opened don't count
+						_, err := server.Open(nil, encrypted, time.Now(), 0x1337, protocol.KeyPhaseZero, ad)
+						Expect(err).ToNot(HaveOccurred())
+						_, err = server.Open(nil, encrypted, time.Now(), 0x42, protocol.KeyPhaseZero, ad)
+						Expect(err).To(MatchError("AEAD_LIMIT_REACHED: 11 packets failed authentication"))
+					})
+				})
 			})
 		})
 	}
@@ -106,6 +106,18 @@ func (mr *MockSentPacketHandlerMockRecorder) HasPacingBudget() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasPacingBudget", reflect.TypeOf((*MockSentPacketHandler)(nil).HasPacingBudget))
 }
 
+// OnConnectionMigration mocks base method
+func (m *MockSentPacketHandler) OnConnectionMigration() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnConnectionMigration")
+}
+
+// OnConnectionMigration indicates an expected call of OnConnectionMigration
+func (mr *MockSentPacketHandlerMockRecorder) OnConnectionMigration() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnConnectionMigration", reflect.TypeOf((*MockSentPacketHandler)(nil).OnConnectionMigration))
+}
+
 // OnLossDetectionTimeout mocks base method
 func (m *MockSentPacketHandler) OnLossDetectionTimeout() error {
 	m.ctrl.T.Helper()
@@ -254,3 +266,17 @@ func (mr *MockSentPacketHandlerMockRecorder) TimeUntilSend() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TimeUntilSend", reflect.TypeOf((*MockSentPacketHandler)(nil).TimeUntilSend))
 }
+
+// TransmissionStats mocks base method
+func (m *MockSentPacketHandler) TransmissionStats() ackhandler.TransmissionStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransmissionStats")
+	ret0, _ := ret[0].(ackhandler.TransmissionStats)
+	return ret0
+}
+
+// TransmissionStats indicates an expected call of TransmissionStats
+func (mr *MockSentPacketHandlerMockRecorder) TransmissionStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransmissionStats", reflect.TypeOf((*MockSentPacketHandler)(nil).TransmissionStats))
+}
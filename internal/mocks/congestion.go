@@ -117,6 +117,30 @@ func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) MaybeExitSlowStart() *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaybeExitSlowStart", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).MaybeExitSlowStart))
 }
 
+// OnCongestionEvent mocks base method
+func (m *MockSendAlgorithmWithDebugInfos) OnCongestionEvent(arg0 protocol.PacketNumber, arg1 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnCongestionEvent", arg0, arg1)
+}
+
+// OnCongestionEvent indicates an expected call of OnCongestionEvent
+func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) OnCongestionEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnCongestionEvent", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).OnCongestionEvent), arg0, arg1)
+}
+
+// OnConnectionMigration mocks base method
+func (m *MockSendAlgorithmWithDebugInfos) OnConnectionMigration() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnConnectionMigration")
+}
+
+// OnConnectionMigration indicates an expected call of OnConnectionMigration
+func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) OnConnectionMigration() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnConnectionMigration", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).OnConnectionMigration))
+}
+
 // OnPacketAcked mocks base method
 func (m *MockSendAlgorithmWithDebugInfos) OnPacketAcked(arg0 protocol.PacketNumber, arg1, arg2 protocol.ByteCount, arg3 time.Time) {
 	m.ctrl.T.Helper()
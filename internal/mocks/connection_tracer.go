@@ -243,6 +243,18 @@ func (mr *MockConnectionTracerMockRecorder) StartedConnection(arg0, arg1, arg2,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartedConnection", reflect.TypeOf((*MockConnectionTracer)(nil).StartedConnection), arg0, arg1, arg2, arg3, arg4)
 }
 
+// StartedPathValidation mocks base method
+func (m *MockConnectionTracer) StartedPathValidation(arg0 net.Addr) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "StartedPathValidation", arg0)
+}
+
+// StartedPathValidation indicates an expected call of StartedPathValidation
+func (mr *MockConnectionTracerMockRecorder) StartedPathValidation(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartedPathValidation", reflect.TypeOf((*MockConnectionTracer)(nil).StartedPathValidation), arg0)
+}
+
 // UpdatedKey mocks base method
 func (m *MockConnectionTracer) UpdatedKey(arg0 protocol.KeyPhase, arg1 bool) {
 	m.ctrl.T.Helper()
@@ -267,6 +279,18 @@ func (mr *MockConnectionTracerMockRecorder) UpdatedKeyFromTLS(arg0, arg1 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedKeyFromTLS", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedKeyFromTLS), arg0, arg1)
 }
 
+// UpdatedMTU mocks base method
+func (m *MockConnectionTracer) UpdatedMTU(arg0 protocol.ByteCount, arg1 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatedMTU", arg0, arg1)
+}
+
+// UpdatedMTU indicates an expected call of UpdatedMTU
+func (mr *MockConnectionTracerMockRecorder) UpdatedMTU(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedMTU", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedMTU), arg0, arg1)
+}
+
 // UpdatedMetrics mocks base method
 func (m *MockConnectionTracer) UpdatedMetrics(arg0 *congestion.RTTStats, arg1, arg2 protocol.ByteCount, arg3 int) {
 	m.ctrl.T.Helper()
@@ -290,3 +314,15 @@ func (mr *MockConnectionTracerMockRecorder) UpdatedPTOCount(arg0 interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedPTOCount", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedPTOCount), arg0)
 }
+
+// UpdatedPath mocks base method
+func (m *MockConnectionTracer) UpdatedPath(arg0 net.Addr) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatedPath", arg0)
+}
+
+// UpdatedPath indicates an expected call of UpdatedPath
+func (mr *MockConnectionTracerMockRecorder) UpdatedPath(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedPath", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedPath), arg0)
+}
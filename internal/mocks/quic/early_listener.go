@@ -11,6 +11,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	quic "github.com/lucas-clemente/quic-go"
+	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
 // MockEarlyListener is a mock of EarlyListener interface
@@ -78,3 +79,31 @@ func (mr *MockEarlyListenerMockRecorder) Close() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockEarlyListener)(nil).Close))
 }
+
+// Shutdown mocks base method
+func (m *MockEarlyListener) Shutdown(arg0 context.Context, arg1 protocol.ApplicationErrorCode, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Shutdown", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Shutdown indicates an expected call of Shutdown
+func (mr *MockEarlyListenerMockRecorder) Shutdown(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Shutdown", reflect.TypeOf((*MockEarlyListener)(nil).Shutdown), arg0, arg1, arg2)
+}
+
+// Stats mocks base method
+func (m *MockEarlyListener) Stats() quic.ListenerStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(quic.ListenerStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats
+func (mr *MockEarlyListenerMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockEarlyListener)(nil).Stats))
+}
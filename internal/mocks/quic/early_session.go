@@ -124,6 +124,20 @@ func (mr *MockEarlySessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockEarlySession)(nil).HandshakeComplete))
 }
 
+// InitiateKeyUpdate mocks base method
+func (m *MockEarlySession) InitiateKeyUpdate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitiateKeyUpdate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitiateKeyUpdate indicates an expected call of InitiateKeyUpdate
+func (mr *MockEarlySessionMockRecorder) InitiateKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateKeyUpdate", reflect.TypeOf((*MockEarlySession)(nil).InitiateKeyUpdate))
+}
+
 // LocalAddr mocks base method
 func (m *MockEarlySession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -138,6 +152,20 @@ func (mr *MockEarlySessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockEarlySession)(nil).LocalAddr))
 }
 
+// MigrateTo mocks base method
+func (m *MockEarlySession) MigrateTo(arg0 net.PacketConn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateTo", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateTo indicates an expected call of MigrateTo
+func (mr *MockEarlySessionMockRecorder) MigrateTo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateTo", reflect.TypeOf((*MockEarlySession)(nil).MigrateTo), arg0)
+}
+
 // OpenStream mocks base method
 func (m *MockEarlySession) OpenStream() (quic.Stream, error) {
 	m.ctrl.T.Helper()
@@ -198,6 +226,35 @@ func (mr *MockEarlySessionMockRecorder) OpenUniStreamSync(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockEarlySession)(nil).OpenUniStreamSync), arg0)
 }
 
+// ReceiveMessage mocks base method
+func (m *MockEarlySession) ReceiveMessage(arg0 context.Context) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReceiveMessage", arg0)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReceiveMessage indicates an expected call of ReceiveMessage
+func (mr *MockEarlySessionMockRecorder) ReceiveMessage(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveMessage", reflect.TypeOf((*MockEarlySession)(nil).ReceiveMessage), arg0)
+}
+
+// Received0RTT mocks base method
+func (m *MockEarlySession) Received0RTT() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Received0RTT")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Received0RTT indicates an expected call of Received0RTT
+func (mr *MockEarlySessionMockRecorder) Received0RTT() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Received0RTT", reflect.TypeOf((*MockEarlySession)(nil).Received0RTT))
+}
+
 // RemoteAddr mocks base method
 func (m *MockEarlySession) RemoteAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -211,3 +268,31 @@ func (mr *MockEarlySessionMockRecorder) RemoteAddr() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockEarlySession)(nil).RemoteAddr))
 }
+
+// SendMessage mocks base method
+func (m *MockEarlySession) SendMessage(arg0 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMessage", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMessage indicates an expected call of SendMessage
+func (mr *MockEarlySessionMockRecorder) SendMessage(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockEarlySession)(nil).SendMessage), arg0)
+}
+
+// Stats mocks base method
+func (m *MockEarlySession) Stats() quic.ConnectionStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(quic.ConnectionStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats
+func (mr *MockEarlySessionMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockEarlySession)(nil).Stats))
+}
@@ -10,6 +10,7 @@ import (
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	quic "github.com/lucas-clemente/quic-go"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -117,6 +118,18 @@ func (mr *MockStreamMockRecorder) SetDeadline(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStream)(nil).SetDeadline), arg0)
 }
 
+// SetPriority mocks base method
+func (m *MockStream) SetPriority(arg0 quic.StreamPriority) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPriority", arg0)
+}
+
+// SetPriority indicates an expected call of SetPriority
+func (mr *MockStreamMockRecorder) SetPriority(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPriority", reflect.TypeOf((*MockStream)(nil).SetPriority), arg0)
+}
+
 // SetReadDeadline mocks base method
 func (m *MockStream) SetReadDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -145,6 +158,20 @@ func (mr *MockStreamMockRecorder) SetWriteDeadline(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteDeadline", reflect.TypeOf((*MockStream)(nil).SetWriteDeadline), arg0)
 }
 
+// Stats mocks base method
+func (m *MockStream) Stats() quic.StreamStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(quic.StreamStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats
+func (mr *MockStreamMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockStream)(nil).Stats))
+}
+
 // StreamID mocks base method
 func (m *MockStream) StreamID() protocol.StreamID {
 	m.ctrl.T.Helper()
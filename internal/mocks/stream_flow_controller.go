@@ -99,6 +99,20 @@ func (mr *MockStreamFlowControllerMockRecorder) IsNewlyBlocked() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsNewlyBlocked", reflect.TypeOf((*MockStreamFlowController)(nil).IsNewlyBlocked))
 }
 
+// ReceiveWindowSize mocks base method
+func (m *MockStreamFlowController) ReceiveWindowSize() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReceiveWindowSize")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// ReceiveWindowSize indicates an expected call of ReceiveWindowSize
+func (mr *MockStreamFlowControllerMockRecorder) ReceiveWindowSize() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).ReceiveWindowSize))
+}
+
 // SendWindowSize mocks base method
 func (m *MockStreamFlowController) SendWindowSize() protocol.ByteCount {
 	m.ctrl.T.Helper()
@@ -113,6 +127,20 @@ func (mr *MockStreamFlowControllerMockRecorder) SendWindowSize() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).SendWindowSize))
 }
 
+// StreamSendWindowSize mocks base method
+func (m *MockStreamFlowController) StreamSendWindowSize() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamSendWindowSize")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// StreamSendWindowSize indicates an expected call of StreamSendWindowSize
+func (mr *MockStreamFlowControllerMockRecorder) StreamSendWindowSize() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamSendWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).StreamSendWindowSize))
+}
+
 // UpdateHighestReceived mocks base method
 func (m *MockStreamFlowController) UpdateHighestReceived(arg0 protocol.ByteCount, arg1 bool) error {
 	m.ctrl.T.Helper()
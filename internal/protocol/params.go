@@ -55,6 +55,12 @@ const MaxTrackedSkippedPackets = 10
 // If the queue is full, new connection attempts will be rejected.
 const MaxAcceptQueueSize = 32
 
+// DefaultHandshakeRateLimitIPv4PrefixLen is the length of the IPv4 prefix that handshake rate limits are applied to.
+const DefaultHandshakeRateLimitIPv4PrefixLen = 24
+
+// DefaultHandshakeRateLimitIPv6PrefixLen is the length of the IPv6 prefix that handshake rate limits are applied to.
+const DefaultHandshakeRateLimitIPv6PrefixLen = 56
+
 // TokenValidity is the duration that a (non-retry) token is considered valid
 const TokenValidity = 24 * time.Hour
 
@@ -84,6 +90,11 @@ const MaxStreamFrameSorterGaps = 1000
 // very small STREAM frames to consume a lot of memory.
 const MinStreamFrameBufferSize = 128
 
+// MaxStreamFrameBufferSize is the size of the buffer of STREAM frames taken from the buffer pool.
+// It is based on Ethernet's max size of 1500 bytes, minus 40 bytes for the IPv6 header and 8 bytes for the UDP header.
+// STREAM frames with more data are allocated separately.
+const MaxStreamFrameBufferSize ByteCount = 1452
+
 // MinCoalescedPacketSize is the minimum size of a coalesced packet that we pack.
 // If a packet has less than this number of bytes, we won't coalesce any more packets onto it.
 const MinCoalescedPacketSize = 128
@@ -166,6 +177,24 @@ const MaxAckDelayInclGranularity = MaxAckDelay + TimerGranularity
 // KeyUpdateInterval is the maximum number of packets we send or receive before initiating a key udpate.
 const KeyUpdateInterval = 100 * 1000
 
+// ConfidentialityLimitAES is the maximum number of packets that may be encrypted with a single AES-GCM key,
+// see section 6.6 of RFC 9001.
+const ConfidentialityLimitAES = 1 << 23
+
+// IntegrityLimitAES is the maximum number of packets that may fail authentication when using AES-GCM.
+const IntegrityLimitAES = 1 << 52
+
+// IntegrityLimitChaCha is the maximum number of packets that may fail authentication when using ChaCha20-Poly1305.
+// There's no confidentiality limit for ChaCha20-Poly1305 that could be reached in practice.
+const IntegrityLimitChaCha = 1 << 36
+
+// Max0RTTTicketAge is the maximum age of a session ticket that is accepted for 0-RTT.
+// The IDs of session tickets used for 0-RTT need to be remembered for this time to prevent replays.
+const Max0RTTTicketAge = 24 * time.Hour
+
+// DefaultMaxAntiReplayEntries is the number of session ticket IDs the default anti-replay store remembers.
+const DefaultMaxAntiReplayEntries = 100 * 1000
+
 // Max0RTTQueueingDuration is the maximum time that we store 0-RTT packets in order to wait for the corresponding Initial to be received.
 const Max0RTTQueueingDuration = 100 * time.Millisecond
 
@@ -177,3 +206,25 @@ const Max0RTTQueues = 32
 // To avoid blocking, this value has to be smaller than MaxSessionUnprocessedPackets.
 // To avoid packets being dropped as undecryptable by the session, this value has to be smaller than MaxUndecryptablePackets.
 const Max0RTTQueueLen = 32
+
+// MaxDatagramFrameSize is the maximum size of a DATAGRAM frame as defined in
+// https://datatracker.ietf.org/doc/draft-pauly-quic-datagram/.
+// The size is chosen such that a DATAGRAM frame fits into a QUIC packet.
+const MaxDatagramFrameSize ByteCount = 1220
+
+// DatagramRcvQueueLen is the length of the receive queue for DATAGRAM frames.
+// If the application doesn't read received datagrams fast enough, excess datagrams are dropped.
+const DatagramRcvQueueLen = 128
+
+// MaxPathChallenges is the maximum number of PATH_CHALLENGE frames sent when validating a new path.
+// If no matching PATH_RESPONSE is received for any of them, path validation fails.
+const MaxPathChallenges = 3
+
+// MaxPathChallengePostponements is the maximum number of times a PATH_CHALLENGE is postponed
+// because the anti-amplification limit doesn't allow sending it.
+// If the peer doesn't send enough data on the new path in that time, path validation fails.
+const MaxPathChallengePostponements = 3
+
+// AmplificationFactor is the anti-amplification factor.
+// Before an address is validated, no more than AmplificationFactor times the bytes received from that address are sent to it.
+const AmplificationFactor = 3
@@ -34,6 +34,35 @@ func (t PacketType) String() string {
 	}
 }
 
+// ECN is the ECN value
+type ECN uint8
+
+const (
+	// ECNNon is Not-ECT (not ECN-capable transport)
+	ECNNon ECN = iota // 00
+	// ECT1 is ECN-capable transport, codepoint ECT(1)
+	ECT1 // 01
+	// ECT0 is ECN-capable transport, codepoint ECT(0)
+	ECT0 // 10
+	// ECNCE signals Congestion Experienced
+	ECNCE // 11
+)
+
+func (e ECN) String() string {
+	switch e {
+	case ECNNon:
+		return "Not-ECT"
+	case ECT1:
+		return "ECT(1)"
+	case ECT0:
+		return "ECT(0)"
+	case ECNCE:
+		return "CE"
+	default:
+		return fmt.Sprintf("invalid ECN value: %d", e)
+	}
+}
+
 // A ByteCount in QUIC
 type ByteCount uint64
 
@@ -47,8 +76,14 @@ type ApplicationErrorCode uint64
 // ethernet's max size, minus the IP and UDP headers. IPv6 has a 40 byte header,
 // UDP adds an additional 8 bytes.  This is a total overhead of 48 bytes.
 // Ethernet's max packet size is 1500 bytes,  1500 - 48 = 1452.
+// Larger packets are only used if Path MTU Discovery finds a larger MTU (for sending),
+// or if configured in the quic.Config (for receiving).
 const MaxReceivePacketSize ByteCount = 1452
 
+// MaxJumboPacketSize is the maximum size of a QUIC packet on paths that support Ethernet jumbo frames:
+// 9000 bytes, minus 20 bytes for the IPv4 header and 8 bytes for the UDP header.
+const MaxJumboPacketSize ByteCount = 8972
+
 // MinInitialPacketSize is the minimum size an Initial packet is required to have.
 const MinInitialPacketSize = 1200
 
@@ -15,4 +15,19 @@ var _ = Describe("Protocol", func() {
 			Expect(PacketType(10).String()).To(Equal("unknown packet type: 10"))
 		})
 	})
+
+	It("converts ECN bits from the IP header to the ECN codepoint", func() {
+		Expect(ECN(0b00)).To(Equal(ECNNon))
+		Expect(ECN(0b01)).To(Equal(ECT1))
+		Expect(ECN(0b10)).To(Equal(ECT0))
+		Expect(ECN(0b11)).To(Equal(ECNCE))
+	})
+
+	It("has a string representation for ECN", func() {
+		Expect(ECNNon.String()).To(Equal("Not-ECT"))
+		Expect(ECT0.String()).To(Equal("ECT(0)"))
+		Expect(ECT1.String()).To(Equal("ECT(1)"))
+		Expect(ECNCE.String()).To(Equal("CE"))
+		Expect(ECN(42).String()).To(Equal("invalid ECN value: 42"))
+	})
 })
@@ -18,14 +18,17 @@ const (
 
 // The version numbers, making grepping easier
 const (
-	VersionTLS      VersionNumber = 0x51474fff
-	VersionWhatever VersionNumber = 1 // for when the version doesn't matter
+	VersionTLS      VersionNumber = Version1
+	VersionWhatever VersionNumber = math.MaxUint32 - 1 // for when the version doesn't matter
 	VersionUnknown  VersionNumber = math.MaxUint32
+	VersionDraft29  VersionNumber = 0xff00001d
+	Version1        VersionNumber = 0x1
+	Version2        VersionNumber = 0x6b3343cf
 )
 
 // SupportedVersions lists the versions that the server supports
-// must be in sorted descending order
-var SupportedVersions = []VersionNumber{VersionTLS}
+// must be in order of preference
+var SupportedVersions = []VersionNumber{Version1, Version2, VersionDraft29}
 
 // IsValidVersion says if the version is known to quic-go
 func IsValidVersion(v VersionNumber) bool {
@@ -38,8 +41,12 @@ func (vn VersionNumber) String() string {
 		return "whatever"
 	case VersionUnknown:
 		return "unknown"
-	case VersionTLS:
-		return "TLS dev version (WIP)"
+	case VersionDraft29:
+		return "draft-29"
+	case Version1:
+		return "v1"
+	case Version2:
+		return "v2"
 	default:
 		if vn.isGQUIC() {
 			return fmt.Sprintf("gQUIC %d", vn.toGQUICVersion())
@@ -66,6 +73,33 @@ func IsSupportedVersion(supported []VersionNumber, v VersionNumber) bool {
 	return false
 }
 
+// AreCompatibleVersions says if a connection using version v1 can be upgraded to version v2
+// during the handshake, using compatible version negotiation.
+// This is only possible between QUIC v1 and QUIC v2, see section 4 of RFC 9369.
+func AreCompatibleVersions(v1, v2 VersionNumber) bool {
+	if v1 == v2 {
+		return true
+	}
+	return (v1 == Version1 && v2 == Version2) || (v1 == Version2 && v2 == Version1)
+}
+
+// ChooseCompatibleVersion chooses the version to switch to using compatible version negotiation.
+// current is the version currently used on the connection.
+// ours is a slice of versions that we support, sorted by our preference (descending)
+// theirs is a slice of versions offered by the peer. The order does not matter.
+// If we don't prefer any version compatible with the current version, current is returned.
+func ChooseCompatibleVersion(current VersionNumber, ours, theirs []VersionNumber) VersionNumber {
+	for _, v := range ours {
+		if v == current {
+			return current
+		}
+		if AreCompatibleVersions(current, v) && IsSupportedVersion(theirs, v) {
+			return v
+		}
+	}
+	return current
+}
+
 // ChooseSupportedVersion finds the best version in the overlap of ours and theirs
 // ours is a slice of versions that we support, sorted by our preference (descending)
 // theirs is a slice of versions offered by the peer. The order does not matter.
@@ -12,6 +12,9 @@ var _ = Describe("Version", func() {
 
 	It("says if a version is valid", func() {
 		Expect(IsValidVersion(VersionTLS)).To(BeTrue())
+		Expect(IsValidVersion(Version1)).To(BeTrue())
+		Expect(IsValidVersion(Version2)).To(BeTrue())
+		Expect(IsValidVersion(VersionDraft29)).To(BeTrue())
 		Expect(IsValidVersion(VersionWhatever)).To(BeFalse())
 		Expect(IsValidVersion(VersionUnknown)).To(BeFalse())
 		Expect(IsValidVersion(1234)).To(BeFalse())
@@ -19,10 +22,15 @@ var _ = Describe("Version", func() {
 
 	It("versions don't have reserved version numbers", func() {
 		Expect(isReservedVersion(VersionTLS)).To(BeFalse())
+		Expect(isReservedVersion(Version1)).To(BeFalse())
+		Expect(isReservedVersion(Version2)).To(BeFalse())
+		Expect(isReservedVersion(VersionDraft29)).To(BeFalse())
 	})
 
 	It("has the right string representation", func() {
-		Expect(VersionTLS.String()).To(ContainSubstring("TLS"))
+		Expect(Version1.String()).To(Equal("v1"))
+		Expect(Version2.String()).To(Equal("v2"))
+		Expect(VersionDraft29.String()).To(Equal("draft-29"))
 		Expect(VersionWhatever.String()).To(Equal("whatever"))
 		Expect(VersionUnknown.String()).To(Equal("unknown"))
 		// check with unsupported version numbers from the wiki
@@ -39,10 +47,25 @@ var _ = Describe("Version", func() {
 		Expect(IsSupportedVersion(SupportedVersions, SupportedVersions[len(SupportedVersions)-1])).To(BeTrue())
 	})
 
-	It("has supported versions in sorted order", func() {
-		for i := 0; i < len(SupportedVersions)-1; i++ {
-			Expect(SupportedVersions[i]).To(BeNumerically(">", SupportedVersions[i+1]))
-		}
+	It("prefers QUIC v1", func() {
+		Expect(SupportedVersions[0]).To(Equal(Version1))
+		Expect(SupportedVersions).To(ContainElement(Version2))
+		Expect(SupportedVersions).To(ContainElement(VersionDraft29))
+	})
+
+	It("chooses a compatible version", func() {
+		Expect(ChooseCompatibleVersion(Version1, []VersionNumber{Version2, Version1}, []VersionNumber{Version1, Version2})).To(Equal(Version2))
+		Expect(ChooseCompatibleVersion(Version1, []VersionNumber{Version1, Version2}, []VersionNumber{Version1, Version2})).To(Equal(Version1))
+		Expect(ChooseCompatibleVersion(Version1, []VersionNumber{Version2, Version1}, []VersionNumber{Version1})).To(Equal(Version1))
+		Expect(ChooseCompatibleVersion(VersionDraft29, []VersionNumber{Version2, VersionDraft29}, []VersionNumber{VersionDraft29, Version2})).To(Equal(VersionDraft29))
+	})
+
+	It("says which versions are compatible", func() {
+		Expect(AreCompatibleVersions(Version1, Version2)).To(BeTrue())
+		Expect(AreCompatibleVersions(Version2, Version1)).To(BeTrue())
+		Expect(AreCompatibleVersions(Version1, Version1)).To(BeTrue())
+		Expect(AreCompatibleVersions(Version1, VersionDraft29)).To(BeFalse())
+		Expect(AreCompatibleVersions(VersionDraft29, Version2)).To(BeFalse())
 	})
 
 	Context("highest supported version", func() {
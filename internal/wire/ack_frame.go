@@ -16,6 +16,9 @@ var errInvalidAckRanges = errors.New("AckFrame: ACK frame contains invalid ACK r
 type AckFrame struct {
 	AckRanges []AckRange // has to be ordered. The highest ACK range goes first, the lowest ACK range goes last
 	DelayTime time.Duration
+
+	// ECN counts, only set if the ACK frame has the ECN section
+	ECT0, ECT1, ECNCE uint64
 }
 
 // parseAckFrame reads an ACK frame
@@ -91,13 +94,23 @@ func parseAckFrame(r *bytes.Reader, ackDelayExponent uint8, _ protocol.VersionNu
 		return nil, errInvalidAckRanges
 	}
 
-	// parse (and skip) the ECN section
+	// parse the ECN section
 	if ecn {
-		for i := 0; i < 3; i++ {
-			if _, err := utils.ReadVarInt(r); err != nil {
-				return nil, err
-			}
+		ect0, err := utils.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		frame.ECT0 = ect0
+		ect1, err := utils.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		frame.ECT1 = ect1
+		ecnce, err := utils.ReadVarInt(r)
+		if err != nil {
+			return nil, err
 		}
+		frame.ECNCE = ecnce
 	}
 
 	return frame, nil
@@ -105,7 +118,12 @@ func parseAckFrame(r *bytes.Reader, ackDelayExponent uint8, _ protocol.VersionNu
 
 // Write writes an ACK frame.
 func (f *AckFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error {
-	b.WriteByte(0x2)
+	hasECN := f.HasECN()
+	if hasECN {
+		b.WriteByte(0x3)
+	} else {
+		b.WriteByte(0x2)
+	}
 	utils.WriteVarInt(b, uint64(f.LargestAcked()))
 	utils.WriteVarInt(b, encodeAckDelay(f.DelayTime))
 
@@ -122,6 +140,12 @@ func (f *AckFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error
 		utils.WriteVarInt(b, gap)
 		utils.WriteVarInt(b, len)
 	}
+
+	if hasECN {
+		utils.WriteVarInt(b, f.ECT0)
+		utils.WriteVarInt(b, f.ECT1)
+		utils.WriteVarInt(b, f.ECNCE)
+	}
 	return nil
 }
 
@@ -141,6 +165,9 @@ func (f *AckFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
 		length += utils.VarIntLen(gap)
 		length += utils.VarIntLen(len)
 	}
+	if f.HasECN() {
+		length += utils.VarIntLen(f.ECT0) + utils.VarIntLen(f.ECT1) + utils.VarIntLen(f.ECNCE)
+	}
 	return length
 }
 
@@ -149,6 +176,9 @@ func (f *AckFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
 func (f *AckFrame) numEncodableAckRanges() int {
 	length := 1 + utils.VarIntLen(uint64(f.LargestAcked())) + utils.VarIntLen(encodeAckDelay(f.DelayTime))
 	length += 2 // assume that the number of ranges will consume 2 bytes
+	if f.HasECN() {
+		length += utils.VarIntLen(f.ECT0) + utils.VarIntLen(f.ECT1) + utils.VarIntLen(f.ECNCE)
+	}
 	for i := 1; i < len(f.AckRanges); i++ {
 		gap, len := f.encodeAckRange(i)
 		rangeLen := utils.VarIntLen(gap) + utils.VarIntLen(len)
@@ -170,6 +200,12 @@ func (f *AckFrame) encodeAckRange(i int) (uint64 /* gap */, uint64 /* length */)
 		uint64(f.AckRanges[i].Largest - f.AckRanges[i].Smallest)
 }
 
+// HasECN says if the frame contains ECN counts.
+// An ACK frame with all ECN counts set to 0 is sent without the ECN section.
+func (f *AckFrame) HasECN() bool {
+	return f.ECT0 > 0 || f.ECT1 > 0 || f.ECNCE > 0
+}
+
 // HasMissingRanges returns if this frame reports any missing packets
 func (f *AckFrame) HasMissingRanges() bool {
 	return len(f.AckRanges) > 1
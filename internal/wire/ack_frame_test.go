@@ -176,6 +176,10 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 				Expect(frame.LargestAcked()).To(Equal(protocol.PacketNumber(100)))
 				Expect(frame.LowestAcked()).To(Equal(protocol.PacketNumber(90)))
 				Expect(frame.HasMissingRanges()).To(BeFalse())
+				Expect(frame.HasECN()).To(BeTrue())
+				Expect(frame.ECT0).To(BeEquivalentTo(0x42))
+				Expect(frame.ECT1).To(BeEquivalentTo(0x12345))
+				Expect(frame.ECNCE).To(BeEquivalentTo(0x12345678))
 				Expect(b.Len()).To(BeZero())
 			})
 
@@ -202,6 +206,32 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 	})
 
 	Context("when writing", func() {
+		It("writes a frame with ECN counts", func() {
+			buf := &bytes.Buffer{}
+			f := &AckFrame{
+				AckRanges: []AckRange{{Smallest: 10, Largest: 2000}},
+				ECT0:      13,
+				ECT1:      37,
+				ECNCE:     12345,
+			}
+			Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+			Expect(f.Length(versionIETFFrames)).To(BeEquivalentTo(buf.Len()))
+			expected := []byte{0x3}
+			expected = append(expected, encodeVarInt(2000)...) // largest acked
+			expected = append(expected, 0)                     // delay
+			expected = append(expected, encodeVarInt(0)...)    // num ranges
+			expected = append(expected, encodeVarInt(2000-10)...)
+			expected = append(expected, encodeVarInt(13)...)
+			expected = append(expected, encodeVarInt(37)...)
+			expected = append(expected, encodeVarInt(12345)...)
+			Expect(buf.Bytes()).To(Equal(expected))
+			b := bytes.NewReader(buf.Bytes())
+			frame, err := parseAckFrame(b, protocol.AckDelayExponent, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(f))
+			Expect(b.Len()).To(BeZero())
+		})
+
 		It("writes a simple frame", func() {
 			buf := &bytes.Buffer{}
 			f := &AckFrame{
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -24,6 +25,10 @@ type ExtendedHeader struct {
 
 	KeyPhase protocol.KeyPhaseBit
 
+	// GreaseQUICBit randomizes the QUIC bit when writing the header.
+	// It may only be set if the peer sent the grease_quic_bit transport parameter.
+	GreaseQUICBit bool
+
 	PacketNumberLen protocol.PacketNumberLen
 	PacketNumber    protocol.PacketNumber
 
@@ -127,22 +132,14 @@ func (h *ExtendedHeader) Write(b *bytes.Buffer, ver protocol.VersionNumber) erro
 }
 
 func (h *ExtendedHeader) writeLongHeader(b *bytes.Buffer, _ protocol.VersionNumber) error {
-	var packetType uint8
-	switch h.Type {
-	case protocol.PacketTypeInitial:
-		packetType = 0x0
-	case protocol.PacketType0RTT:
-		packetType = 0x1
-	case protocol.PacketTypeHandshake:
-		packetType = 0x2
-	case protocol.PacketTypeRetry:
-		packetType = 0x3
-	}
-	firstByte := 0xc0 | packetType<<4
+	firstByte := 0xc0 | encodeLongHeaderPacketType(h.Type, h.Version)<<4
 	if h.Type != protocol.PacketTypeRetry {
 		// Retry packets don't have a packet number
 		firstByte |= uint8(h.PacketNumberLen - 1)
 	}
+	if h.GreaseQUICBit {
+		firstByte = greaseQUICBit(firstByte)
+	}
 
 	b.WriteByte(firstByte)
 	utils.BigEndian.WriteUint32(b, uint32(h.Version))
@@ -169,12 +166,23 @@ func (h *ExtendedHeader) writeShortHeader(b *bytes.Buffer, _ protocol.VersionNum
 	if h.KeyPhase == protocol.KeyPhaseOne {
 		typeByte |= byte(1 << 2)
 	}
+	if h.GreaseQUICBit {
+		typeByte = greaseQUICBit(typeByte)
+	}
 
 	b.WriteByte(typeByte)
 	b.Write(h.DestConnectionID.Bytes())
 	return h.writePacketNumber(b)
 }
 
+// greaseQUICBit sets the QUIC bit to a random value, see RFC 9287.
+func greaseQUICBit(b byte) byte {
+	if rand.Intn(2) == 0 {
+		return b &^ 0x40
+	}
+	return b | 0x40
+}
+
 func (h *ExtendedHeader) writePacketNumber(b *bytes.Buffer) error {
 	switch h.PacketNumberLen {
 	case protocol.PacketNumberLen1:
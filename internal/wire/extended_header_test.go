@@ -114,6 +114,46 @@ var _ = Describe("Header", func() {
 				expected = append(expected, token...)
 				Expect(buf.Bytes()).To(Equal(expected))
 			})
+
+			It("uses the QUIC v2 packet type encoding", func() {
+				for t, expected := range map[protocol.PacketType]uint8{
+					protocol.PacketTypeInitial:   0x1,
+					protocol.PacketType0RTT:      0x2,
+					protocol.PacketTypeHandshake: 0x3,
+					protocol.PacketTypeRetry:     0x0,
+				} {
+					buf.Reset()
+					Expect((&ExtendedHeader{Header: Header{
+						IsLongHeader: true,
+						Version:      protocol.Version2,
+						Type:         t,
+					},
+						PacketNumberLen: protocol.PacketNumberLen1,
+					}).Write(buf, protocol.Version2)).To(Succeed())
+					Expect(buf.Bytes()[0] & 0x30 >> 4).To(Equal(expected))
+				}
+			})
+
+			It("greases the QUIC bit", func() {
+				var numCleared int
+				for i := 0; i < 100; i++ {
+					buf := &bytes.Buffer{}
+					Expect((&ExtendedHeader{
+						Header: Header{
+							IsLongHeader: true,
+							Type:         protocol.PacketTypeHandshake,
+							Version:      versionIETFHeader,
+						},
+						PacketNumberLen: protocol.PacketNumberLen1,
+						GreaseQUICBit:   true,
+					}).Write(buf, versionIETFHeader)).To(Succeed())
+					Expect(buf.Bytes()[0] & 0xbf).To(Equal(byte(0x80 | 0x2<<4)))
+					if buf.Bytes()[0]&0x40 == 0 {
+						numCleared++
+					}
+				}
+				Expect(numCleared).To(And(BeNumerically(">", 25), BeNumerically("<", 75)))
+			})
 		})
 
 		Context("short header", func() {
@@ -182,6 +222,25 @@ var _ = Describe("Header", func() {
 					0x42, // packet number
 				}))
 			})
+
+			It("greases the QUIC bit", func() {
+				var numCleared int
+				for i := 0; i < 100; i++ {
+					buf := &bytes.Buffer{}
+					Expect((&ExtendedHeader{
+						KeyPhase:        protocol.KeyPhaseOne,
+						PacketNumberLen: protocol.PacketNumberLen1,
+						PacketNumber:    0x42,
+						GreaseQUICBit:   true,
+					}).Write(buf, versionIETFHeader)).To(Succeed())
+					Expect(buf.Bytes()[0] & 0xbf).To(Equal(byte(0x4)))
+					Expect(buf.Bytes()[1:]).To(Equal([]byte{0x42}))
+					if buf.Bytes()[0]&0x40 == 0 {
+						numCleared++
+					}
+				}
+				Expect(numCleared).To(And(BeNumerically(">", 25), BeNumerically("<", 75)))
+			})
 		})
 	})
 
@@ -66,8 +66,20 @@ type Header struct {
 // If the packet has a long header, the packet is cut according to the length field.
 // If we understand the version, the packet is header up unto the packet number.
 // Otherwise, only the invariant part of the header is parsed.
+// Packets that have the QUIC bit cleared are rejected.
 func ParsePacket(data []byte, shortHeaderConnIDLen int) (*Header, []byte /* packet data */, []byte /* rest */, error) {
-	hdr, err := parseHeader(bytes.NewReader(data), shortHeaderConnIDLen)
+	return parsePacket(data, shortHeaderConnIDLen, false)
+}
+
+// ParseGreasedPacket parses a packet, just like ParsePacket.
+// It also accepts packets that have the QUIC bit cleared.
+// This must only be used after sending the grease_quic_bit transport parameter, see RFC 9287.
+func ParseGreasedPacket(data []byte, shortHeaderConnIDLen int) (*Header, []byte /* packet data */, []byte /* rest */, error) {
+	return parsePacket(data, shortHeaderConnIDLen, true)
+}
+
+func parsePacket(data []byte, shortHeaderConnIDLen int, acceptGreasedQUICBit bool) (*Header, []byte, []byte, error) {
+	hdr, err := parseHeader(bytes.NewReader(data), shortHeaderConnIDLen, acceptGreasedQUICBit)
 	if err != nil {
 		if err == ErrUnsupportedVersion {
 			return hdr, nil, nil, ErrUnsupportedVersion
@@ -91,9 +103,9 @@ func ParsePacket(data []byte, shortHeaderConnIDLen int) (*Header, []byte /* pack
 // For long header packets:
 // * if we understand the version: up to the packet number
 // * if not, only the invariant part of the header
-func parseHeader(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error) {
+func parseHeader(b *bytes.Reader, shortHeaderConnIDLen int, acceptGreasedQUICBit bool) (*Header, error) {
 	startLen := b.Len()
-	h, err := parseHeaderImpl(b, shortHeaderConnIDLen)
+	h, err := parseHeaderImpl(b, shortHeaderConnIDLen, acceptGreasedQUICBit)
 	if err != nil {
 		return h, err
 	}
@@ -101,7 +113,7 @@ func parseHeader(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error) {
 	return h, err
 }
 
-func parseHeaderImpl(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error) {
+func parseHeaderImpl(b *bytes.Reader, shortHeaderConnIDLen int, acceptGreasedQUICBit bool) (*Header, error) {
 	typeByte, err := b.ReadByte()
 	if err != nil {
 		return nil, err
@@ -113,7 +125,7 @@ func parseHeaderImpl(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error)
 	}
 
 	if !h.IsLongHeader {
-		if h.typeByte&0x40 == 0 {
+		if !acceptGreasedQUICBit && h.typeByte&0x40 == 0 {
 			return nil, errors.New("not a QUIC packet")
 		}
 		if err := h.parseShortHeader(b, shortHeaderConnIDLen); err != nil {
@@ -121,7 +133,7 @@ func parseHeaderImpl(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error)
 		}
 		return h, nil
 	}
-	return h, h.parseLongHeader(b)
+	return h, h.parseLongHeader(b, acceptGreasedQUICBit)
 }
 
 func (h *Header) parseShortHeader(b *bytes.Reader, shortHeaderConnIDLen int) error {
@@ -130,13 +142,13 @@ func (h *Header) parseShortHeader(b *bytes.Reader, shortHeaderConnIDLen int) err
 	return err
 }
 
-func (h *Header) parseLongHeader(b *bytes.Reader) error {
+func (h *Header) parseLongHeader(b *bytes.Reader, acceptGreasedQUICBit bool) error {
 	v, err := utils.BigEndian.ReadUint32(b)
 	if err != nil {
 		return err
 	}
 	h.Version = protocol.VersionNumber(v)
-	if h.Version != 0 && h.typeByte&0x40 == 0 {
+	if !acceptGreasedQUICBit && h.Version != 0 && h.typeByte&0x40 == 0 {
 		return errors.New("not a QUIC packet")
 	}
 	destConnIDLen, err := b.ReadByte()
@@ -163,16 +175,7 @@ func (h *Header) parseLongHeader(b *bytes.Reader) error {
 		return ErrUnsupportedVersion
 	}
 
-	switch (h.typeByte & 0x30) >> 4 {
-	case 0x0:
-		h.Type = protocol.PacketTypeInitial
-	case 0x1:
-		h.Type = protocol.PacketType0RTT
-	case 0x2:
-		h.Type = protocol.PacketTypeHandshake
-	case 0x3:
-		h.Type = protocol.PacketTypeRetry
-	}
+	h.Type = parseLongHeaderPacketType(h.typeByte, h.Version)
 
 	if h.Type == protocol.PacketTypeRetry {
 		tokenLen := b.Len() - 16
@@ -209,6 +212,51 @@ func (h *Header) parseLongHeader(b *bytes.Reader) error {
 	return nil
 }
 
+// QUIC v2 uses a different encoding of the long header packet types, see section 3.2 of RFC 9369.
+func parseLongHeaderPacketType(typeByte byte, v protocol.VersionNumber) protocol.PacketType {
+	packetType := (typeByte & 0x30) >> 4
+	if v == protocol.Version2 {
+		switch packetType {
+		case 0x1:
+			return protocol.PacketTypeInitial
+		case 0x2:
+			return protocol.PacketType0RTT
+		case 0x3:
+			return protocol.PacketTypeHandshake
+		default:
+			return protocol.PacketTypeRetry
+		}
+	}
+	switch packetType {
+	case 0x0:
+		return protocol.PacketTypeInitial
+	case 0x1:
+		return protocol.PacketType0RTT
+	case 0x2:
+		return protocol.PacketTypeHandshake
+	default:
+		return protocol.PacketTypeRetry
+	}
+}
+
+func encodeLongHeaderPacketType(t protocol.PacketType, v protocol.VersionNumber) uint8 {
+	var packetType uint8
+	switch t {
+	case protocol.PacketTypeInitial:
+		packetType = 0x0
+	case protocol.PacketType0RTT:
+		packetType = 0x1
+	case protocol.PacketTypeHandshake:
+		packetType = 0x2
+	case protocol.PacketTypeRetry:
+		packetType = 0x3
+	}
+	if v == protocol.Version2 {
+		packetType = (packetType + 1) % 4
+	}
+	return packetType
+}
+
 func (h *Header) parseVersionNegotiationPacket(b *bytes.Reader) error {
 	if b.Len() == 0 {
 		//nolint:stylecheck
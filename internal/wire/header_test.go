@@ -191,6 +191,39 @@ var _ = Describe("Header Parsing", func() {
 			Expect(extHdr.ParsedLen()).To(Equal(hdr.ParsedLen() + 4))
 		})
 
+		It("parses the packet type of QUIC v2 packets", func() {
+			for typeBits, expected := range map[uint8]protocol.PacketType{
+				0x1: protocol.PacketTypeInitial,
+				0x2: protocol.PacketType0RTT,
+				0x3: protocol.PacketTypeHandshake,
+			} {
+				data := []byte{0xc0 | typeBits<<4}
+				data = appendVersion(data, protocol.Version2)
+				data = append(data, []byte{0x0, 0x0}...) // connection ID lengths
+				if expected == protocol.PacketTypeInitial {
+					data = append(data, encodeVarInt(0)...) // token length
+				}
+				data = append(data, encodeVarInt(1)...) // length
+				data = append(data, 0x42)               // packet number
+				hdr, _, _, err := ParsePacket(data, 0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hdr.Type).To(Equal(expected))
+				Expect(hdr.Version).To(Equal(protocol.Version2))
+			}
+		})
+
+		It("parses a QUIC v2 Retry packet", func() {
+			data := []byte{0xc0}
+			data = appendVersion(data, protocol.Version2)
+			data = append(data, []byte{0x0, 0x0}...)                     // connection ID lengths
+			data = append(data, []byte{'f', 'o', 'o', 'b', 'a', 'r'}...) // token
+			data = append(data, []byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}...)
+			hdr, _, _, err := ParsePacket(data, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hdr.Type).To(Equal(protocol.PacketTypeRetry))
+			Expect(hdr.Token).To(Equal([]byte("foobar")))
+		})
+
 		It("errors if 0x40 is not set", func() {
 			data := []byte{
 				0x80 | 0x2<<4,
@@ -202,6 +235,22 @@ var _ = Describe("Header Parsing", func() {
 			Expect(err).To(MatchError("not a QUIC packet"))
 		})
 
+		It("accepts packets that have the QUIC bit cleared, if greasing is enabled", func() {
+			data := []byte{0x80 | 0x2<<4}
+			data = appendVersion(data, versionIETFFrames)
+			data = append(data, 0x4)                         // dest conn ID len
+			data = append(data, []byte{1, 2, 3, 4}...)       // dest conn ID
+			data = append(data, 0x0)                         // src conn ID len
+			data = append(data, encodeVarInt(3)...)          // length
+			data = append(data, []byte{0xde, 0xca, 0xfb}...) // packet number and payload
+			_, _, _, err := ParsePacket(data, 0)
+			Expect(err).To(MatchError("not a QUIC packet"))
+			hdr, _, _, err := ParseGreasedPacket(data, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hdr.Type).To(Equal(protocol.PacketTypeHandshake))
+			Expect(hdr.DestConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		})
+
 		It("stops parsing when encountering an unsupported version", func() {
 			data := []byte{
 				0xc0,
@@ -467,6 +516,16 @@ var _ = Describe("Header Parsing", func() {
 			Expect(err).To(MatchError("not a QUIC packet"))
 		})
 
+		It("accepts packets that have the QUIC bit cleared, if greasing is enabled", func() {
+			connID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37}
+			data := append([]byte{0x0}, connID...)
+			data = append(data, 0x42) // packet number
+			hdr, _, _, err := ParseGreasedPacket(data, 8)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hdr.IsLongHeader).To(BeFalse())
+			Expect(hdr.DestConnectionID).To(Equal(connID))
+		})
+
 		It("errors if the 4th or 5th bit are set", func() {
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5}
 			data := append([]byte{0x40 | 0x10 /* set the 4th bit */}, connID...)
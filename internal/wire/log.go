@@ -35,6 +35,9 @@ func LogFrame(logger utils.Logger, frame Frame, sent bool) {
 		} else {
 			logger.Debugf("\t%s &wire.AckFrame{LargestAcked: %d, LowestAcked: %d, DelayTime: %s}", dir, f.LargestAcked(), f.LowestAcked(), f.DelayTime.String())
 		}
+		if f.HasECN() {
+			logger.Debugf("\t\tECT(0): %d, ECT(1): %d, CE: %d", f.ECT0, f.ECT1, f.ECNCE)
+		}
 	case *MaxDataFrame:
 		logger.Debugf("\t%s &wire.MaxDataFrame{ByteOffset: %d}", dir, f.ByteOffset)
 	case *MaxStreamDataFrame:
@@ -61,6 +64,8 @@ func LogFrame(logger utils.Logger, frame Frame, sent bool) {
 		logger.Debugf("\t%s &wire.NewConnectionIDFrame{SequenceNumber: %d, ConnectionID: %s, StatelessResetToken: %#x}", dir, f.SequenceNumber, f.ConnectionID, f.StatelessResetToken)
 	case *NewTokenFrame:
 		logger.Debugf("\t%s &wire.NewTokenFrame{Token: %#x}", dir, f.Token)
+	case *DatagramFrame:
+		logger.Debugf("\t%s &wire.DatagramFrame{Length: %d}", dir, len(f.Data))
 	default:
 		logger.Debugf("\t%s %#v", dir, frame)
 	}
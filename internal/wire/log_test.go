@@ -154,4 +154,11 @@ var _ = Describe("Frame logging", func() {
 		}, true)
 		Expect(buf.String()).To(ContainSubstring("\t-> &wire.NewTokenFrame{Token: 0xdeadbeef"))
 	})
+
+	It("logs DATAGRAM frames", func() {
+		LogFrame(logger, &DatagramFrame{
+			Data: []byte("foobar"),
+		}, true)
+		Expect(buf.String()).To(ContainSubstring("\t-> &wire.DatagramFrame{Length: 6}"))
+	})
 })
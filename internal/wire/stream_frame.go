@@ -55,16 +55,15 @@ func parseStreamFrame(r *bytes.Reader, _ protocol.VersionNumber) (*StreamFrame,
 		dataLen = uint64(r.Len())
 	}
 
+	// The STREAM frame can't be larger than the rest of the packet.
+	if dataLen > uint64(r.Len()) {
+		return nil, io.EOF
+	}
 	var frame *StreamFrame
-	if dataLen < protocol.MinStreamFrameBufferSize {
+	if dataLen < protocol.MinStreamFrameBufferSize || dataLen > uint64(protocol.MaxStreamFrameBufferSize) {
 		frame = &StreamFrame{Data: make([]byte, dataLen)}
 	} else {
 		frame = GetStreamFrame()
-		// The STREAM frame can't be larger than the StreamFrame we obtained from the buffer,
-		// since those StreamFrames have a buffer length of the maximum packet size.
-		if dataLen > uint64(cap(frame.Data)) {
-			return nil, io.EOF
-		}
 		frame.Data = frame.Data[:dataLen]
 	}
 
@@ -79,11 +79,11 @@ var _ = Describe("STREAM frame", func() {
 			Expect(err).To(MatchError("FRAME_ENCODING_ERROR: stream data overflows maximum offset"))
 		})
 
-		It("rejects frames that claim to be longer than the packet size", func() {
+		It("rejects frames that claim to be longer than the packet", func() {
 			data := []byte{0x8 ^ 0x2}
-			data = append(data, encodeVarInt(0x12345)...)                                 // stream ID
-			data = append(data, encodeVarInt(uint64(protocol.MaxReceivePacketSize)+1)...) // data length
-			data = append(data, make([]byte, protocol.MaxReceivePacketSize+1)...)
+			data = append(data, encodeVarInt(0x12345)...)  // stream ID
+			data = append(data, encodeVarInt(0x1337+1)...) // data length
+			data = append(data, make([]byte, 0x1337)...)
 			r := bytes.NewReader(data)
 			_, err := parseStreamFrame(r, versionIETFFrames)
 			Expect(err).To(Equal(io.EOF))
@@ -136,6 +136,19 @@ var _ = Describe("STREAM frame", func() {
 			Expect(r.Len()).To(BeZero())
 			Expect(frame.PutBack).ToNot(Panic())
 		})
+
+		It("doesn't use the buffer for STREAM frames larger than the buffer", func() {
+			data := []byte{0x8}
+			data = append(data, encodeVarInt(0x12345)...) // stream ID
+			data = append(data, bytes.Repeat([]byte{'f'}, int(protocol.MaxStreamFrameBufferSize)+1)...)
+			r := bytes.NewReader(data)
+			frame, err := parseStreamFrame(r, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.Data).To(Equal(bytes.Repeat([]byte{'f'}, int(protocol.MaxStreamFrameBufferSize)+1)))
+			Expect(frame.fromPool).To(BeFalse())
+			Expect(r.Len()).To(BeZero())
+			Expect(frame.PutBack).ToNot(Panic())
+		})
 	})
 
 	Context("when writing", func() {
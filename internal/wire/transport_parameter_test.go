@@ -79,6 +79,7 @@ var _ = Describe("Transport Parameters", func() {
 			InitialMaxStreamDataUni:         protocol.ByteCount(getRandomValue()),
 			InitialMaxData:                  protocol.ByteCount(getRandomValue()),
 			MaxIdleTimeout:                  0xcafe * time.Second,
+			MaxUDPPayloadSize:               1500,
 			MaxBidiStreamNum:                protocol.StreamNum(getRandomValue()),
 			MaxUniStreamNum:                 protocol.StreamNum(getRandomValue()),
 			DisableActiveMigration:          true,
@@ -89,6 +90,8 @@ var _ = Describe("Transport Parameters", func() {
 			AckDelayExponent:                13,
 			MaxAckDelay:                     42 * time.Millisecond,
 			ActiveConnectionIDLimit:         getRandomValue(),
+			MaxDatagramFrameSize:            protocol.ByteCount(getRandomValue()),
+			GreaseQUICBit:                   true,
 		}
 		data := params.Marshal(protocol.PerspectiveServer)
 
@@ -101,6 +104,7 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.MaxUniStreamNum).To(Equal(params.MaxUniStreamNum))
 		Expect(p.MaxBidiStreamNum).To(Equal(params.MaxBidiStreamNum))
 		Expect(p.MaxIdleTimeout).To(Equal(params.MaxIdleTimeout))
+		Expect(p.MaxUDPPayloadSize).To(Equal(params.MaxUDPPayloadSize))
 		Expect(p.DisableActiveMigration).To(Equal(params.DisableActiveMigration))
 		Expect(p.StatelessResetToken).To(Equal(params.StatelessResetToken))
 		Expect(p.OriginalDestinationConnectionID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}))
@@ -109,6 +113,27 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.AckDelayExponent).To(Equal(uint8(13)))
 		Expect(p.MaxAckDelay).To(Equal(42 * time.Millisecond))
 		Expect(p.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
+		Expect(p.MaxDatagramFrameSize).To(Equal(params.MaxDatagramFrameSize))
+		Expect(p.GreaseQUICBit).To(BeTrue())
+		Expect(p.String()).To(HaveSuffix(", GreaseQUICBit: true}"))
+	})
+
+	It("doesn't marshal the grease_quic_bit, if greasing the QUIC bit is not supported", func() {
+		data := (&TransportParameters{
+			StatelessResetToken: &token,
+		}).Marshal(protocol.PerspectiveServer)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.GreaseQUICBit).To(BeFalse())
+	})
+
+	It("doesn't marshal the max_datagram_frame_size, if DATAGRAM frames are not supported", func() {
+		data := (&TransportParameters{
+			StatelessResetToken: &token,
+		}).Marshal(protocol.PerspectiveServer)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MaxDatagramFrameSize).To(BeZero())
 	})
 
 	It("doesn't marshal a retry_source_connection_id, if no Retry was performed", func() {
@@ -155,6 +180,14 @@ var _ = Describe("Transport Parameters", func() {
 		Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for disable_active_migration: 6 (expected empty)"))
 	})
 
+	It("errors when grease_quic_bit has content", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, uint64(greaseQUICBitParameterID))
+		utils.WriteVarInt(b, 6)
+		b.Write([]byte("foobar"))
+		Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for grease_quic_bit: 6 (expected empty)"))
+	})
+
 	It("errors when the server doesn't set the original_destination_connection_id", func() {
 		b := &bytes.Buffer{}
 		utils.WriteVarInt(b, uint64(statelessResetTokenParameterID))
@@ -407,6 +440,62 @@ var _ = Describe("Transport Parameters", func() {
 		})
 	})
 
+	Context("version information", func() {
+		It("marshals and unmarshals", func() {
+			data := (&TransportParameters{
+				VersionInformation: &VersionInformation{
+					ChosenVersion:     protocol.Version1,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version1, protocol.Version2},
+				},
+			}).Marshal(protocol.PerspectiveClient)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+			Expect(p.VersionInformation).ToNot(BeNil())
+			Expect(p.VersionInformation.ChosenVersion).To(Equal(protocol.Version1))
+			Expect(p.VersionInformation.AvailableVersions).To(Equal([]protocol.VersionNumber{protocol.Version1, protocol.Version2}))
+		})
+
+		It("doesn't marshal the version information, if not set", func() {
+			data := (&TransportParameters{}).Marshal(protocol.PerspectiveClient)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+			Expect(p.VersionInformation).To(BeNil())
+		})
+
+		It("has a string representation", func() {
+			p := &TransportParameters{
+				VersionInformation: &VersionInformation{
+					ChosenVersion:     protocol.Version2,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version2, protocol.Version1},
+				},
+			}
+			Expect(p.String()).To(ContainSubstring("VersionInformation: {ChosenVersion: v2, AvailableVersions: [v2 v1]}"))
+		})
+
+		It("errors when the length is not a multiple of 4", func() {
+			b := &bytes.Buffer{}
+			utils.WriteVarInt(b, uint64(versionInformationParameterID))
+			utils.WriteVarInt(b, 6)
+			b.Write([]byte{0, 0, 0, 1, 0, 0})
+			Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid length for version_information: 6"))
+		})
+
+		It("errors when the chosen version is missing", func() {
+			b := &bytes.Buffer{}
+			utils.WriteVarInt(b, uint64(versionInformationParameterID))
+			utils.WriteVarInt(b, 0)
+			Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid length for version_information: 0"))
+		})
+
+		It("errors when it contains version 0", func() {
+			b := &bytes.Buffer{}
+			utils.WriteVarInt(b, uint64(versionInformationParameterID))
+			utils.WriteVarInt(b, 8)
+			b.Write([]byte{0, 0, 0, 1, 0, 0, 0, 0})
+			Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: version_information contains version 0"))
+		})
+	})
+
 	Context("saving and retrieving from a session ticket", func() {
 		It("saves and retrieves the parameters", func() {
 			params := &TransportParameters{
@@ -417,6 +506,7 @@ var _ = Describe("Transport Parameters", func() {
 				MaxBidiStreamNum:               protocol.StreamNum(getRandomValue()),
 				MaxUniStreamNum:                protocol.StreamNum(getRandomValue()),
 				ActiveConnectionIDLimit:        getRandomValue(),
+				MaxDatagramFrameSize:           protocol.ByteCount(getRandomValue()),
 			}
 			Expect(params.ValidFor0RTT(params)).To(BeTrue())
 			b := &bytes.Buffer{}
@@ -430,6 +520,7 @@ var _ = Describe("Transport Parameters", func() {
 			Expect(tp.MaxBidiStreamNum).To(Equal(params.MaxBidiStreamNum))
 			Expect(tp.MaxUniStreamNum).To(Equal(params.MaxUniStreamNum))
 			Expect(tp.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
+			Expect(tp.MaxDatagramFrameSize).To(Equal(params.MaxDatagramFrameSize))
 		})
 
 		It("rejects the parameters if it can't parse them", func() {
@@ -500,6 +591,11 @@ var _ = Describe("Transport Parameters", func() {
 				p.MaxUniStreamNum = 0
 				Expect(params.ValidFor0RTT(p)).To(BeFalse())
 			})
+
+			It("rejects the parameters if the MaxDatagramFrameSize changed", func() {
+				p.MaxDatagramFrameSize = 1000
+				Expect(params.ValidFor0RTT(p)).To(BeFalse())
+			})
 		})
 	})
 })
@@ -42,6 +42,12 @@ const (
 	activeConnectionIDLimitParameterID         transportParameterID = 0xe
 	initialSourceConnectionIDParameterID       transportParameterID = 0xf
 	retrySourceConnectionIDParameterID         transportParameterID = 0x10
+	// RFC 9368
+	versionInformationParameterID transportParameterID = 0x11
+	// https://datatracker.ietf.org/doc/draft-pauly-quic-datagram/
+	maxDatagramFrameSizeParameterID transportParameterID = 0x20
+	// RFC 9287
+	greaseQUICBitParameterID transportParameterID = 0x2ab2
 )
 
 // PreferredAddress is the value encoding in the preferred_address transport parameter
@@ -54,6 +60,13 @@ type PreferredAddress struct {
 	StatelessResetToken [16]byte
 }
 
+// VersionInformation is the value of the version_information transport parameter.
+// It is used for compatible version negotiation, see RFC 9368.
+type VersionInformation struct {
+	ChosenVersion     protocol.VersionNumber
+	AvailableVersions []protocol.VersionNumber // in order of preference
+}
+
 // TransportParameters are parameters sent to the peer during the handshake
 type TransportParameters struct {
 	InitialMaxStreamDataBidiLocal  protocol.ByteCount
@@ -81,6 +94,12 @@ type TransportParameters struct {
 
 	StatelessResetToken     *[16]byte
 	ActiveConnectionIDLimit uint64
+
+	MaxDatagramFrameSize protocol.ByteCount // 0 if DATAGRAM frames are not supported
+
+	VersionInformation *VersionInformation
+
+	GreaseQUICBit bool // the peer accepts packets with the QUIC bit cleared
 }
 
 // Unmarshal the transport parameters
@@ -132,7 +151,8 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 			initialMaxStreamsUniParameterID,
 			maxIdleTimeoutParameterID,
 			maxUDPPayloadSizeParameterID,
-			activeConnectionIDLimitParameterID:
+			activeConnectionIDLimitParameterID,
+			maxDatagramFrameSizeParameterID:
 			if err := p.readNumericTransportParameter(r, paramID, int(paramLen)); err != nil {
 				return err
 			}
@@ -153,6 +173,11 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 					return fmt.Errorf("wrong length for disable_active_migration: %d (expected empty)", paramLen)
 				}
 				p.DisableActiveMigration = true
+			case greaseQUICBitParameterID:
+				if paramLen != 0 {
+					return fmt.Errorf("wrong length for grease_quic_bit: %d (expected empty)", paramLen)
+				}
+				p.GreaseQUICBit = true
 			case statelessResetTokenParameterID:
 				if sentBy == protocol.PerspectiveClient {
 					return errors.New("client sent a stateless_reset_token")
@@ -178,6 +203,10 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 				}
 				connID, _ := protocol.ReadConnectionID(r, int(paramLen))
 				p.RetrySourceConnectionID = &connID
+			case versionInformationParameterID:
+				if err := p.readVersionInformation(r, int(paramLen)); err != nil {
+					return err
+				}
 			default:
 				r.Seek(int64(paramLen), io.SeekCurrent)
 			}
@@ -258,6 +287,29 @@ func (p *TransportParameters) readPreferredAddress(r *bytes.Reader, expectedLen
 	return nil
 }
 
+func (p *TransportParameters) readVersionInformation(r *bytes.Reader, length int) error {
+	if length < 4 || length%4 != 0 {
+		return fmt.Errorf("invalid length for version_information: %d", length)
+	}
+	vi := &VersionInformation{AvailableVersions: make([]protocol.VersionNumber, 0, length/4-1)}
+	for i := 0; i < length/4; i++ {
+		v, err := utils.BigEndian.ReadUint32(r)
+		if err != nil {
+			return err
+		}
+		if v == 0 {
+			return errors.New("version_information contains version 0")
+		}
+		if i == 0 {
+			vi.ChosenVersion = protocol.VersionNumber(v)
+			continue
+		}
+		vi.AvailableVersions = append(vi.AvailableVersions, protocol.VersionNumber(v))
+	}
+	p.VersionInformation = vi
+	return nil
+}
+
 func (p *TransportParameters) readNumericTransportParameter(
 	r *bytes.Reader,
 	paramID transportParameterID,
@@ -307,6 +359,8 @@ func (p *TransportParameters) readNumericTransportParameter(
 		p.MaxAckDelay = maxAckDelay
 	case activeConnectionIDLimitParameterID:
 		p.ActiveConnectionIDLimit = val
+	case maxDatagramFrameSizeParameterID:
+		p.MaxDatagramFrameSize = protocol.ByteCount(val)
 	default:
 		return fmt.Errorf("TransportParameter BUG: transport parameter %d not found", paramID)
 	}
@@ -339,8 +393,11 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	p.marshalVarintParam(b, initialMaxStreamsUniParameterID, uint64(p.MaxUniStreamNum))
 	// idle_timeout
 	p.marshalVarintParam(b, maxIdleTimeoutParameterID, uint64(p.MaxIdleTimeout/time.Millisecond))
-	// max_packet_size
-	p.marshalVarintParam(b, maxUDPPayloadSizeParameterID, uint64(protocol.MaxReceivePacketSize))
+	// max_udp_payload_size
+	// Only send it if it is set. Otherwise, the default value of 65527 bytes applies.
+	if p.MaxUDPPayloadSize != 0 {
+		p.marshalVarintParam(b, maxUDPPayloadSizeParameterID, uint64(p.MaxUDPPayloadSize))
+	}
 	// max_ack_delay
 	// Only send it if is different from the default value.
 	if p.MaxAckDelay != protocol.DefaultMaxAckDelay {
@@ -356,6 +413,11 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 		utils.WriteVarInt(b, uint64(disableActiveMigrationParameterID))
 		utils.WriteVarInt(b, 0)
 	}
+	// grease_quic_bit
+	if p.GreaseQUICBit {
+		utils.WriteVarInt(b, uint64(greaseQUICBitParameterID))
+		utils.WriteVarInt(b, 0)
+	}
 	if pers == protocol.PerspectiveServer {
 		// stateless_reset_token
 		utils.WriteVarInt(b, uint64(statelessResetTokenParameterID))
@@ -381,6 +443,10 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	}
 	// active_connection_id_limit
 	p.marshalVarintParam(b, activeConnectionIDLimitParameterID, p.ActiveConnectionIDLimit)
+	// max_datagram_frame_size
+	if p.MaxDatagramFrameSize > 0 {
+		p.marshalVarintParam(b, maxDatagramFrameSizeParameterID, uint64(p.MaxDatagramFrameSize))
+	}
 	// initial_source_connection_id
 	utils.WriteVarInt(b, uint64(initialSourceConnectionIDParameterID))
 	utils.WriteVarInt(b, uint64(p.InitialSourceConnectionID.Len()))
@@ -391,6 +457,15 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 		utils.WriteVarInt(b, uint64(p.RetrySourceConnectionID.Len()))
 		b.Write(p.RetrySourceConnectionID.Bytes())
 	}
+	// version_information
+	if p.VersionInformation != nil {
+		utils.WriteVarInt(b, uint64(versionInformationParameterID))
+		utils.WriteVarInt(b, uint64(4*(1+len(p.VersionInformation.AvailableVersions))))
+		utils.BigEndian.WriteUint32(b, uint32(p.VersionInformation.ChosenVersion))
+		for _, v := range p.VersionInformation.AvailableVersions {
+			utils.BigEndian.WriteUint32(b, uint32(v))
+		}
+	}
 	return b.Bytes()
 }
 
@@ -425,6 +500,10 @@ func (p *TransportParameters) MarshalForSessionTicket(b *bytes.Buffer) {
 	p.marshalVarintParam(b, initialMaxStreamsUniParameterID, uint64(p.MaxUniStreamNum))
 	// active_connection_id_limit
 	p.marshalVarintParam(b, activeConnectionIDLimitParameterID, p.ActiveConnectionIDLimit)
+	// max_datagram_frame_size
+	if p.MaxDatagramFrameSize > 0 {
+		p.marshalVarintParam(b, maxDatagramFrameSizeParameterID, uint64(p.MaxDatagramFrameSize))
+	}
 }
 
 // UnmarshalFromSessionTicket unmarshals transport parameters from a session ticket.
@@ -446,7 +525,8 @@ func (p *TransportParameters) ValidFor0RTT(tp *TransportParameters) bool {
 		p.InitialMaxStreamDataUni == tp.InitialMaxStreamDataUni &&
 		p.InitialMaxData == tp.InitialMaxData &&
 		p.MaxBidiStreamNum == tp.MaxBidiStreamNum &&
-		p.MaxUniStreamNum == tp.MaxUniStreamNum
+		p.MaxUniStreamNum == tp.MaxUniStreamNum &&
+		p.MaxDatagramFrameSize == tp.MaxDatagramFrameSize
 }
 
 // String returns a string representation, intended for logging.
@@ -463,6 +543,17 @@ func (p *TransportParameters) String() string {
 		logString += ", StatelessResetToken: %#x"
 		logParams = append(logParams, *p.StatelessResetToken)
 	}
+	if p.MaxDatagramFrameSize > 0 {
+		logString += ", MaxDatagramFrameSize: %d"
+		logParams = append(logParams, p.MaxDatagramFrameSize)
+	}
+	if p.VersionInformation != nil {
+		logString += ", VersionInformation: {ChosenVersion: %s, AvailableVersions: %s}"
+		logParams = append(logParams, p.VersionInformation.ChosenVersion, p.VersionInformation.AvailableVersions)
+	}
+	if p.GreaseQUICBit {
+		logString += ", GreaseQUICBit: true"
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
@@ -16,7 +16,8 @@ import (
 	"github.com/lucas-clemente/quic-go"
 )
 
-const h09alpn = "hq-29"
+// the ALPNs used for HTTP/0.9, for QUIC v1 and draft-29
+var h09alpns = []string{"hq-interop", "hq-29"}
 
 type responseWriter struct {
 	io.Writer
@@ -68,7 +69,7 @@ func (s *Server) ListenAndServe() error {
 	}
 
 	tlsConf := s.TLSConfig.Clone()
-	tlsConf.NextProtos = []string{h09alpn}
+	tlsConf.NextProtos = h09alpns
 	ln, err := quic.ListenEarly(conn, tlsConf, s.QuicConfig)
 	if err != nil {
 		return err
@@ -27,6 +27,13 @@ type ConnectionTracer interface {
 	ReceivedRetry(*wire.Header)
 	ReceivedPacket(hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, frames []wire.Frame)
 	ReceivedStatelessReset(token *[16]byte)
+	// StartedPathValidation is called when the peer starts sending from a new remote address.
+	StartedPathValidation(remote net.Addr)
+	// UpdatedPath is called when the new remote address was validated, and is used from now on.
+	UpdatedPath(remote net.Addr)
+	// UpdatedMTU is called when Path MTU Discovery changes the maximum packet size.
+	// done is set when the search has finished.
+	UpdatedMTU(mtu protocol.ByteCount, done bool)
 	BufferedPacket(PacketType)
 	DroppedPacket(PacketType, protocol.ByteCount, PacketDropReason)
 	UpdatedMetrics(rttStats *congestion.RTTStats, cwnd protocol.ByteCount, bytesInFLight protocol.ByteCount, packetsInFlight int)
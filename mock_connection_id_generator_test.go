@@ -0,0 +1,63 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/lucas-clemente/quic-go (interfaces: ConnectionIDGenerator)
+
+// Package quic is a generated GoMock package.
+package quic
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockConnectionIDGenerator is a mock of ConnectionIDGenerator interface
+type MockConnectionIDGenerator struct {
+	ctrl     *gomock.Controller
+	recorder *MockConnectionIDGeneratorMockRecorder
+}
+
+// MockConnectionIDGeneratorMockRecorder is the mock recorder for MockConnectionIDGenerator
+type MockConnectionIDGeneratorMockRecorder struct {
+	mock *MockConnectionIDGenerator
+}
+
+// NewMockConnectionIDGenerator creates a new mock instance
+func NewMockConnectionIDGenerator(ctrl *gomock.Controller) *MockConnectionIDGenerator {
+	mock := &MockConnectionIDGenerator{ctrl: ctrl}
+	mock.recorder = &MockConnectionIDGeneratorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockConnectionIDGenerator) EXPECT() *MockConnectionIDGeneratorMockRecorder {
+	return m.recorder
+}
+
+// ConnectionIDLen mocks base method
+func (m *MockConnectionIDGenerator) ConnectionIDLen() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectionIDLen")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// ConnectionIDLen indicates an expected call of ConnectionIDLen
+func (mr *MockConnectionIDGeneratorMockRecorder) ConnectionIDLen() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionIDLen", reflect.TypeOf((*MockConnectionIDGenerator)(nil).ConnectionIDLen))
+}
+
+// GenerateConnectionID mocks base method
+func (m *MockConnectionIDGenerator) GenerateConnectionID() ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateConnectionID")
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateConnectionID indicates an expected call of GenerateConnectionID
+func (mr *MockConnectionIDGeneratorMockRecorder) GenerateConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateConnectionID", reflect.TypeOf((*MockConnectionIDGenerator)(nil).GenerateConnectionID))
+}
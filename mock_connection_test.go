@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
 // MockConnection is a mock of Connection interface
@@ -104,16 +105,85 @@ func (mr *MockConnectionMockRecorder) SetCurrentRemoteAddr(arg0 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCurrentRemoteAddr", reflect.TypeOf((*MockConnection)(nil).SetCurrentRemoteAddr), arg0)
 }
 
+// SetPacketConn mocks base method
+func (m *MockConnection) SetPacketConn(arg0 net.PacketConn) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPacketConn", arg0)
+}
+
+// SetPacketConn indicates an expected call of SetPacketConn
+func (mr *MockConnectionMockRecorder) SetPacketConn(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPacketConn", reflect.TypeOf((*MockConnection)(nil).SetPacketConn), arg0)
+}
+
+// SupportsDF mocks base method
+func (m *MockConnection) SupportsDF() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SupportsDF")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SupportsDF indicates an expected call of SupportsDF
+func (mr *MockConnectionMockRecorder) SupportsDF() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SupportsDF", reflect.TypeOf((*MockConnection)(nil).SupportsDF))
+}
+
+// SupportsECN mocks base method
+func (m *MockConnection) SupportsECN() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SupportsECN")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SupportsECN indicates an expected call of SupportsECN
+func (mr *MockConnectionMockRecorder) SupportsECN() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SupportsECN", reflect.TypeOf((*MockConnection)(nil).SupportsECN))
+}
+
 // Write mocks base method
-func (m *MockConnection) Write(arg0 []byte) error {
+func (m *MockConnection) Write(arg0 []byte, arg1 protocol.ECN) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Write", arg0)
+	ret := m.ctrl.Call(m, "Write", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Write indicates an expected call of Write
-func (mr *MockConnectionMockRecorder) Write(arg0 interface{}) *gomock.Call {
+func (mr *MockConnectionMockRecorder) Write(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockConnection)(nil).Write), arg0, arg1)
+}
+
+// WritePackets mocks base method
+func (m *MockConnection) WritePackets(arg0 [][]byte, arg1 protocol.ECN) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WritePackets", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WritePackets indicates an expected call of WritePackets
+func (mr *MockConnectionMockRecorder) WritePackets(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WritePackets", reflect.TypeOf((*MockConnection)(nil).WritePackets), arg0, arg1)
+}
+
+// WriteTo mocks base method
+func (m *MockConnection) WriteTo(arg0 []byte, arg1 net.Addr, arg2 protocol.ECN) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteTo", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteTo indicates an expected call of WriteTo
+func (mr *MockConnectionMockRecorder) WriteTo(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockConnection)(nil).Write), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteTo", reflect.TypeOf((*MockConnection)(nil).WriteTo), arg0, arg1, arg2)
 }
@@ -137,6 +137,20 @@ func (mr *MockQuicSessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockQuicSession)(nil).HandshakeComplete))
 }
 
+// InitiateKeyUpdate mocks base method
+func (m *MockQuicSession) InitiateKeyUpdate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitiateKeyUpdate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitiateKeyUpdate indicates an expected call of InitiateKeyUpdate
+func (mr *MockQuicSessionMockRecorder) InitiateKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateKeyUpdate", reflect.TypeOf((*MockQuicSession)(nil).InitiateKeyUpdate))
+}
+
 // LocalAddr mocks base method
 func (m *MockQuicSession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -151,6 +165,20 @@ func (mr *MockQuicSessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockQuicSession)(nil).LocalAddr))
 }
 
+// MigrateTo mocks base method
+func (m *MockQuicSession) MigrateTo(arg0 net.PacketConn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateTo", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateTo indicates an expected call of MigrateTo
+func (mr *MockQuicSessionMockRecorder) MigrateTo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateTo", reflect.TypeOf((*MockQuicSession)(nil).MigrateTo), arg0)
+}
+
 // OpenStream mocks base method
 func (m *MockQuicSession) OpenStream() (Stream, error) {
 	m.ctrl.T.Helper()
@@ -211,6 +239,35 @@ func (mr *MockQuicSessionMockRecorder) OpenUniStreamSync(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockQuicSession)(nil).OpenUniStreamSync), arg0)
 }
 
+// ReceiveMessage mocks base method
+func (m *MockQuicSession) ReceiveMessage(arg0 context.Context) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReceiveMessage", arg0)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReceiveMessage indicates an expected call of ReceiveMessage
+func (mr *MockQuicSessionMockRecorder) ReceiveMessage(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveMessage", reflect.TypeOf((*MockQuicSession)(nil).ReceiveMessage), arg0)
+}
+
+// Received0RTT mocks base method
+func (m *MockQuicSession) Received0RTT() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Received0RTT")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Received0RTT indicates an expected call of Received0RTT
+func (mr *MockQuicSessionMockRecorder) Received0RTT() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Received0RTT", reflect.TypeOf((*MockQuicSession)(nil).Received0RTT))
+}
+
 // RemoteAddr mocks base method
 func (m *MockQuicSession) RemoteAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -225,6 +282,34 @@ func (mr *MockQuicSessionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockQuicSession)(nil).RemoteAddr))
 }
 
+// SendMessage mocks base method
+func (m *MockQuicSession) SendMessage(arg0 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMessage", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMessage indicates an expected call of SendMessage
+func (mr *MockQuicSessionMockRecorder) SendMessage(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockQuicSession)(nil).SendMessage), arg0)
+}
+
+// Stats mocks base method
+func (m *MockQuicSession) Stats() ConnectionStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(ConnectionStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats
+func (mr *MockQuicSessionMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockQuicSession)(nil).Stats))
+}
+
 // destroy mocks base method
 func (m *MockQuicSession) destroy(arg0 error) {
 	m.ctrl.T.Helper()
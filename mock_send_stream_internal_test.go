@@ -78,6 +78,18 @@ func (mr *MockSendStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSendStreamI)(nil).Context))
 }
 
+// SetPriority mocks base method
+func (m *MockSendStreamI) SetPriority(arg0 StreamPriority) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPriority", arg0)
+}
+
+// SetPriority indicates an expected call of SetPriority
+func (mr *MockSendStreamIMockRecorder) SetPriority(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPriority", reflect.TypeOf((*MockSendStreamI)(nil).SetPriority), arg0)
+}
+
 // SetWriteDeadline mocks base method
 func (m *MockSendStreamI) SetWriteDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -92,6 +104,20 @@ func (mr *MockSendStreamIMockRecorder) SetWriteDeadline(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteDeadline", reflect.TypeOf((*MockSendStreamI)(nil).SetWriteDeadline), arg0)
 }
 
+// Stats mocks base method
+func (m *MockSendStreamI) Stats() StreamStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(StreamStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats
+func (mr *MockSendStreamIMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockSendStreamI)(nil).Stats))
+}
+
 // StreamID mocks base method
 func (m *MockSendStreamI) StreamID() protocol.StreamID {
 	m.ctrl.T.Helper()
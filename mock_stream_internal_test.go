@@ -119,6 +119,18 @@ func (mr *MockStreamIMockRecorder) SetDeadline(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStreamI)(nil).SetDeadline), arg0)
 }
 
+// SetPriority mocks base method
+func (m *MockStreamI) SetPriority(arg0 StreamPriority) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPriority", arg0)
+}
+
+// SetPriority indicates an expected call of SetPriority
+func (mr *MockStreamIMockRecorder) SetPriority(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPriority", reflect.TypeOf((*MockStreamI)(nil).SetPriority), arg0)
+}
+
 // SetReadDeadline mocks base method
 func (m *MockStreamI) SetReadDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -147,6 +159,20 @@ func (mr *MockStreamIMockRecorder) SetWriteDeadline(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteDeadline", reflect.TypeOf((*MockStreamI)(nil).SetWriteDeadline), arg0)
 }
 
+// Stats mocks base method
+func (m *MockStreamI) Stats() StreamStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(StreamStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats
+func (mr *MockStreamIMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockStreamI)(nil).Stats))
+}
+
 // StreamID mocks base method
 func (m *MockStreamI) StreamID() protocol.StreamID {
 	m.ctrl.T.Helper()
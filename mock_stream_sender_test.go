@@ -36,15 +36,15 @@ func (m *MockStreamSender) EXPECT() *MockStreamSenderMockRecorder {
 }
 
 // onHasStreamData mocks base method
-func (m *MockStreamSender) onHasStreamData(arg0 protocol.StreamID) {
+func (m *MockStreamSender) onHasStreamData(arg0 protocol.StreamID, arg1 StreamPriority) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "onHasStreamData", arg0)
+	m.ctrl.Call(m, "onHasStreamData", arg0, arg1)
 }
 
 // onHasStreamData indicates an expected call of onHasStreamData
-func (mr *MockStreamSenderMockRecorder) onHasStreamData(arg0 interface{}) *gomock.Call {
+func (mr *MockStreamSenderMockRecorder) onHasStreamData(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onHasStreamData", reflect.TypeOf((*MockStreamSender)(nil).onHasStreamData), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onHasStreamData", reflect.TypeOf((*MockStreamSender)(nil).onHasStreamData), arg0, arg1)
 }
 
 // onStreamCompleted mocks base method
@@ -0,0 +1,133 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+const (
+	// At some point, we have to stop searching for a higher MTU.
+	// We're happy to send a packet that's 20 bytes smaller than the actual MTU.
+	maxMTUDiff = 20
+	// send a probe packet every mtuProbeDelay RTTs
+	mtuProbeDelay = 5
+	// If this many PTOs fire in a row, we assume that the path can't carry packets
+	// of the size we discovered any more, and drop back to the base size.
+	mtuBlackHolePTOs = 3
+)
+
+// The mtuDiscoverer implements Datagram Packetization Layer Path MTU Discovery (RFC 8899).
+// It probes for a larger MTU using PING frames padded to the probe size,
+// and performs a binary search between the largest acknowledged and the smallest lost probe size.
+type mtuDiscoverer struct {
+	rttStats *congestion.RTTStats
+	// called when the MTU changes, done is set when the search has finished
+	onMTUChange func(mtu protocol.ByteCount, done bool)
+
+	lastProbeTime time.Time
+	probeInFlight bool
+	numPTOs       int
+	// incremented on every Reset, so that probes sent on a previous path are ignored
+	epoch uint64
+
+	base    protocol.ByteCount // the size that is known to work on every path
+	current protocol.ByteCount // the largest size that was acknowledged
+	max     protocol.ByteCount // the smallest size that was lost (or the upper bound of the search)
+}
+
+func newMTUDiscoverer(rttStats *congestion.RTTStats, base, max protocol.ByteCount, onMTUChange func(protocol.ByteCount, bool)) *mtuDiscoverer {
+	return &mtuDiscoverer{
+		rttStats:      rttStats,
+		onMTUChange:   onMTUChange,
+		lastProbeTime: time.Now(), // to make sure the first probe packet is not sent immediately
+		base:          base,
+		current:       base,
+		max:           max,
+	}
+}
+
+func (d *mtuDiscoverer) done() bool {
+	return d.max-d.current <= maxMTUDiff+1
+}
+
+// CurrentSize returns the largest packet size that was confirmed to work on the path.
+func (d *mtuDiscoverer) CurrentSize() protocol.ByteCount {
+	return d.current
+}
+
+// ShouldSendProbe says if a new probe packet should be sent.
+func (d *mtuDiscoverer) ShouldSendProbe(now time.Time) bool {
+	if d.probeInFlight || d.done() {
+		return false
+	}
+	return !now.Before(d.lastProbeTime.Add(mtuProbeDelay * d.rttStats.SmoothedRTT()))
+}
+
+// GetPing returns the PING frame for the next probe packet, and the size the packet has to be padded to.
+func (d *mtuDiscoverer) GetPing() (ackhandler.Frame, protocol.ByteCount) {
+	size := (d.max + d.current) / 2
+	epoch := d.epoch
+	d.lastProbeTime = time.Now()
+	d.probeInFlight = true
+	return ackhandler.Frame{
+		Frame: &wire.PingFrame{},
+		OnLost: func(wire.Frame) {
+			if epoch != d.epoch {
+				return
+			}
+			d.probeInFlight = false
+			// The probe might have been sent before a black hole was detected.
+			if size > d.current && size < d.max {
+				d.max = size
+			}
+		},
+		OnAcked: func(wire.Frame) {
+			if epoch != d.epoch {
+				return
+			}
+			d.probeInFlight = false
+			if size <= d.current || size >= d.max {
+				return
+			}
+			d.current = size
+			d.onMTUChange(size, d.done())
+		},
+	}, size
+}
+
+// OnAckReceived is called when an ACK frame is received.
+func (d *mtuDiscoverer) OnAckReceived() {
+	d.numPTOs = 0
+}
+
+// OnPTO is called when a probe timeout fires.
+// Repeated PTOs without receiving any acknowledgement indicate a black hole:
+// packets of the current size are not delivered any more.
+func (d *mtuDiscoverer) OnPTO() {
+	d.numPTOs++
+	if d.numPTOs < mtuBlackHolePTOs || d.current == d.base {
+		return
+	}
+	d.numPTOs = 0
+	// The packet size that led to the black hole is an upper bound for the next search.
+	d.max = d.current
+	d.current = d.base
+	d.lastProbeTime = time.Now()
+	d.onMTUChange(d.base, d.done())
+}
+
+// Reset restarts the search.
+// It is used when the connection is migrated to a new path.
+func (d *mtuDiscoverer) Reset(base, max protocol.ByteCount) {
+	d.epoch++
+	d.base = base
+	d.current = base
+	d.max = max
+	d.numPTOs = 0
+	d.probeInFlight = false
+	d.lastProbeTime = time.Now()
+}
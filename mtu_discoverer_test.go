@@ -0,0 +1,121 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MTU Discoverer", func() {
+	const (
+		rtt      = 100 * time.Millisecond
+		startMTU = 1000
+		maxMTU   = 2000
+	)
+
+	var (
+		d        *mtuDiscoverer
+		rttStats *congestion.RTTStats
+		now      time.Time
+		updates  []protocol.ByteCount
+		done     bool
+	)
+
+	BeforeEach(func() {
+		rttStats = &congestion.RTTStats{}
+		rttStats.SetInitialRTT(rtt)
+		Expect(rttStats.SmoothedRTT()).To(Equal(rtt))
+		updates = nil
+		done = false
+		d = newMTUDiscoverer(rttStats, startMTU, maxMTU, func(s protocol.ByteCount, isDone bool) {
+			updates = append(updates, s)
+			done = isDone
+		})
+		now = time.Now()
+	})
+
+	It("only allows a probe 5 RTTs after the handshake completes", func() {
+		Expect(d.ShouldSendProbe(now)).To(BeFalse())
+		Expect(d.ShouldSendProbe(now.Add(rtt * 9 / 2))).To(BeFalse())
+		Expect(d.ShouldSendProbe(now.Add(rtt * 5))).To(BeTrue())
+	})
+
+	It("doesn't allow a probe if another probe is still in flight", func() {
+		ping, _ := d.GetPing()
+		Expect(d.ShouldSendProbe(now.Add(10 * rtt))).To(BeFalse())
+		ping.OnLost(ping.Frame)
+		Expect(d.ShouldSendProbe(now.Add(10 * rtt))).To(BeTrue())
+		ping, _ = d.GetPing()
+		ping.OnAcked(ping.Frame)
+		Expect(d.ShouldSendProbe(now.Add(10 * rtt))).To(BeTrue())
+	})
+
+	It("tries a lower size when a probe is lost", func() {
+		ping, size := d.GetPing()
+		Expect(size).To(Equal(protocol.ByteCount(1500)))
+		ping.OnLost(ping.Frame)
+		_, size = d.GetPing()
+		Expect(size).To(Equal(protocol.ByteCount(1250)))
+		Expect(updates).To(BeEmpty())
+	})
+
+	It("tries a higher size and calls the callback when a probe is acknowledged", func() {
+		ping, size := d.GetPing()
+		Expect(size).To(Equal(protocol.ByteCount(1500)))
+		ping.OnAcked(ping.Frame)
+		Expect(d.CurrentSize()).To(Equal(protocol.ByteCount(1500)))
+		Expect(updates).To(Equal([]protocol.ByteCount{1500}))
+		Expect(done).To(BeFalse())
+		_, size = d.GetPing()
+		Expect(size).To(Equal(protocol.ByteCount(1750)))
+	})
+
+	It("stops discovery after getting close enough to the MTU", func() {
+		var sizes []protocol.ByteCount
+		t := now.Add(5 * rtt)
+		for d.ShouldSendProbe(t) {
+			ping, size := d.GetPing()
+			sizes = append(sizes, size)
+			ping.OnAcked(ping.Frame)
+			t = t.Add(5 * rtt)
+		}
+		Expect(sizes).To(Equal([]protocol.ByteCount{1500, 1750, 1875, 1937, 1968, 1984}))
+		Expect(d.CurrentSize()).To(Equal(protocol.ByteCount(1984)))
+		Expect(done).To(BeTrue())
+		Expect(d.ShouldSendProbe(t.Add(10 * rtt))).To(BeFalse())
+	})
+
+	It("drops back to the base size when a black hole is detected", func() {
+		ping, _ := d.GetPing()
+		ping.OnAcked(ping.Frame)
+		Expect(updates).To(Equal([]protocol.ByteCount{1500}))
+		for i := 0; i < mtuBlackHolePTOs-1; i++ {
+			d.OnPTO()
+		}
+		d.OnAckReceived()
+		d.OnPTO()
+		Expect(d.CurrentSize()).To(Equal(protocol.ByteCount(1500)))
+		for i := 0; i < mtuBlackHolePTOs-1; i++ {
+			d.OnPTO()
+		}
+		Expect(d.CurrentSize()).To(Equal(protocol.ByteCount(startMTU)))
+		Expect(updates).To(Equal([]protocol.ByteCount{1500, startMTU}))
+		// the size that caused the black hole is the upper bound for the next search
+		_, size := d.GetPing()
+		Expect(size).To(Equal(protocol.ByteCount(1250)))
+	})
+
+	It("ignores probes sent before the search was reset", func() {
+		ping, _ := d.GetPing()
+		d.Reset(1200, 1400)
+		ping.OnAcked(ping.Frame)
+		Expect(d.CurrentSize()).To(Equal(protocol.ByteCount(1200)))
+		Expect(updates).To(BeEmpty())
+		_, size := d.GetPing()
+		Expect(size).To(Equal(protocol.ByteCount(1300)))
+	})
+})
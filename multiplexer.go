@@ -6,6 +6,7 @@ import (
 	"net"
 	"sync"
 
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
@@ -15,12 +16,13 @@ var (
 )
 
 type multiplexer interface {
-	AddConn(c net.PacketConn, connIDLen int, statelessResetKey []byte) (packetHandlerManager, error)
+	AddConn(c net.PacketConn, connIDLen int, maxPacketSize protocol.ByteCount, statelessResetKey []byte) (packetHandlerManager, error)
 	RemoveConn(net.PacketConn) error
 }
 
 type connManager struct {
 	connIDLen         int
+	maxPacketSize     protocol.ByteCount
 	statelessResetKey []byte
 	manager           packetHandlerManager
 }
@@ -31,7 +33,7 @@ type connMultiplexer struct {
 	mutex sync.Mutex
 
 	conns                   map[string] /* LocalAddr().String() */ connManager
-	newPacketHandlerManager func(net.PacketConn, int, []byte, utils.Logger) packetHandlerManager // so it can be replaced in the tests
+	newPacketHandlerManager func(net.PacketConn, int, protocol.ByteCount, []byte, utils.Logger) packetHandlerManager // so it can be replaced in the tests
 
 	logger utils.Logger
 }
@@ -52,6 +54,7 @@ func getMultiplexer() multiplexer {
 func (m *connMultiplexer) AddConn(
 	c net.PacketConn,
 	connIDLen int,
+	maxPacketSize protocol.ByteCount,
 	statelessResetKey []byte,
 ) (packetHandlerManager, error) {
 	m.mutex.Lock()
@@ -60,9 +63,10 @@ func (m *connMultiplexer) AddConn(
 	connIndex := c.LocalAddr().Network() + " " + c.LocalAddr().String()
 	p, ok := m.conns[connIndex]
 	if !ok {
-		manager := m.newPacketHandlerManager(c, connIDLen, statelessResetKey, m.logger)
+		manager := m.newPacketHandlerManager(c, connIDLen, maxPacketSize, statelessResetKey, m.logger)
 		p = connManager{
 			connIDLen:         connIDLen,
+			maxPacketSize:     maxPacketSize,
 			statelessResetKey: statelessResetKey,
 			manager:           manager,
 		}
@@ -71,6 +75,9 @@ func (m *connMultiplexer) AddConn(
 	if p.connIDLen != connIDLen {
 		return nil, fmt.Errorf("cannot use %d byte connection IDs on a connection that is already using %d byte connction IDs", connIDLen, p.connIDLen)
 	}
+	if p.maxPacketSize != maxPacketSize {
+		return nil, fmt.Errorf("cannot receive packets of up to %d bytes on a connection that is already receiving packets of up to %d bytes", maxPacketSize, p.maxPacketSize)
+	}
 	if statelessResetKey != nil && !bytes.Equal(p.statelessResetKey, statelessResetKey) {
 		return nil, fmt.Errorf("cannot use different stateless reset keys on the same packet conn")
 	}
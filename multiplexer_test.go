@@ -3,6 +3,8 @@ package quic
 import (
 	"net"
 
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -15,7 +17,7 @@ type testConn struct {
 var _ = Describe("Client Multiplexer", func() {
 	It("adds a new packet conn ", func() {
 		conn := newMockPacketConn()
-		_, err := getMultiplexer().AddConn(conn, 8, nil)
+		_, err := getMultiplexer().AddConn(conn, 8, protocol.MaxReceivePacketSize, nil)
 		Expect(err).ToNot(HaveOccurred())
 	})
 
@@ -23,27 +25,35 @@ var _ = Describe("Client Multiplexer", func() {
 		pconn := newMockPacketConn()
 		pconn.addr = &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 4321}
 		conn := testConn{PacketConn: pconn}
-		_, err := getMultiplexer().AddConn(conn, 8, nil)
+		_, err := getMultiplexer().AddConn(conn, 8, protocol.MaxReceivePacketSize, nil)
 		Expect(err).ToNot(HaveOccurred())
 		conn.counter++
-		_, err = getMultiplexer().AddConn(conn, 8, nil)
+		_, err = getMultiplexer().AddConn(conn, 8, protocol.MaxReceivePacketSize, nil)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(getMultiplexer().(*connMultiplexer).conns).To(HaveLen(1))
 	})
 
 	It("errors when adding an existing conn with a different connection ID length", func() {
 		conn := newMockPacketConn()
-		_, err := getMultiplexer().AddConn(conn, 5, nil)
+		_, err := getMultiplexer().AddConn(conn, 5, protocol.MaxReceivePacketSize, nil)
 		Expect(err).ToNot(HaveOccurred())
-		_, err = getMultiplexer().AddConn(conn, 6, nil)
+		_, err = getMultiplexer().AddConn(conn, 6, protocol.MaxReceivePacketSize, nil)
 		Expect(err).To(MatchError("cannot use 6 byte connection IDs on a connection that is already using 5 byte connction IDs"))
 	})
 
+	It("errors when adding an existing conn with a different max packet size", func() {
+		conn := newMockPacketConn()
+		_, err := getMultiplexer().AddConn(conn, 7, protocol.MaxReceivePacketSize, nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = getMultiplexer().AddConn(conn, 7, protocol.MaxJumboPacketSize, nil)
+		Expect(err).To(MatchError("cannot receive packets of up to 8972 bytes on a connection that is already receiving packets of up to 1452 bytes"))
+	})
+
 	It("errors when adding an existing conn with a different stateless rest key", func() {
 		conn := newMockPacketConn()
-		_, err := getMultiplexer().AddConn(conn, 7, []byte("foobar"))
+		_, err := getMultiplexer().AddConn(conn, 7, protocol.MaxReceivePacketSize, []byte("foobar"))
 		Expect(err).ToNot(HaveOccurred())
-		_, err = getMultiplexer().AddConn(conn, 7, []byte("raboof"))
+		_, err = getMultiplexer().AddConn(conn, 7, protocol.MaxReceivePacketSize, []byte("raboof"))
 		Expect(err).To(MatchError("cannot use different stateless reset keys on the same packet conn"))
 	})
 })
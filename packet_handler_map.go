@@ -34,6 +34,12 @@ type packetHandlerMap struct {
 
 	conn      net.PacketConn
 	connIDLen int
+	// the size of the largest packet that is received, see Config.MaxUDPPayloadSize
+	maxPacketSize protocol.ByteCount
+	// set if the ECN bits can be read on received packets
+	ecnConn ecnCapableConn
+	// set if multiple packets can be read in a single syscall
+	batchReader batchReader
 
 	handlers    map[string] /* string(ConnectionID)*/ packetHandler
 	resetTokens map[[16]byte] /* stateless reset token */ packetHandler
@@ -56,12 +62,14 @@ var _ packetHandlerManager = &packetHandlerMap{}
 func newPacketHandlerMap(
 	conn net.PacketConn,
 	connIDLen int,
+	maxPacketSize protocol.ByteCount,
 	statelessResetKey []byte,
 	logger utils.Logger,
 ) packetHandlerManager {
 	m := &packetHandlerMap{
 		conn:                       conn,
 		connIDLen:                  connIDLen,
+		maxPacketSize:              maxPacketSize,
 		listening:                  make(chan struct{}),
 		handlers:                   make(map[string]packetHandler),
 		resetTokens:                make(map[[16]byte]packetHandler),
@@ -70,6 +78,10 @@ func newPacketHandlerMap(
 		statelessResetHasher:       hmac.New(sha256.New, statelessResetKey),
 		logger:                     logger,
 	}
+	if setReceiveECN(conn) {
+		m.ecnConn = conn.(ecnCapableConn)
+	}
+	m.batchReader = newBatchReader(conn, maxPacketSize)
 	go m.listen()
 
 	if logger.Debug() {
@@ -250,22 +262,55 @@ func (h *packetHandlerMap) close(e error) error {
 
 func (h *packetHandlerMap) listen() {
 	defer close(h.listening)
+	if h.batchReader != nil {
+		h.listenBatched()
+		return
+	}
+	oob := make([]byte, ecnOOBSize)
 	for {
-		buffer := getPacketBuffer()
-		data := buffer.Data[:protocol.MaxReceivePacketSize]
-		// The packet size should not exceed protocol.MaxReceivePacketSize bytes
+		buffer := getPacketBufferWithSize(h.maxPacketSize)
+		data := buffer.Data[:h.maxPacketSize]
+		// The packet size should not exceed maxPacketSize bytes
 		// If it does, we only read a truncated packet, which will then end up undecryptable
-		n, addr, err := h.conn.ReadFrom(data)
+		var (
+			n    int
+			addr net.Addr
+			ecn  protocol.ECN
+			err  error
+		)
+		if h.ecnConn != nil {
+			var oobn int
+			var udpAddr *net.UDPAddr
+			n, oobn, _, udpAddr, err = h.ecnConn.ReadMsgUDP(data, oob)
+			addr = udpAddr
+			ecn = parseECN(oob[:oobn])
+		} else {
+			n, addr, err = h.conn.ReadFrom(data)
+		}
 		if err != nil {
 			h.close(err)
 			return
 		}
-		h.handlePacket(addr, buffer, data[:n])
+		h.handlePacket(addr, ecn, buffer, data[:n])
+	}
+}
+
+func (h *packetHandlerMap) listenBatched() {
+	for {
+		packets, err := h.batchReader.ReadBatch()
+		if err != nil {
+			h.close(err)
+			return
+		}
+		for _, p := range packets {
+			h.handlePacket(p.remoteAddr, p.ecn, p.buffer, p.data)
+		}
 	}
 }
 
 func (h *packetHandlerMap) handlePacket(
 	addr net.Addr,
+	ecn protocol.ECN,
 	buffer *packetBuffer,
 	data []byte,
 ) {
@@ -287,6 +332,8 @@ func (h *packetHandlerMap) handlePacket(
 
 	p := &receivedPacket{
 		remoteAddr: addr,
+		conn:       h.conn,
+		ecn:        ecn,
 		rcvTime:    rcvTime,
 		buffer:     buffer,
 		data:       data,
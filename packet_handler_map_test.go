@@ -50,7 +50,7 @@ var _ = Describe("Packet Handler Map", func() {
 
 	JustBeforeEach(func() {
 		conn = newMockPacketConn()
-		handler = newPacketHandlerMap(conn, connIDLen, statelessResetKey, utils.DefaultLogger).(*packetHandlerMap)
+		handler = newPacketHandlerMap(conn, connIDLen, protocol.MaxReceivePacketSize, statelessResetKey, utils.DefaultLogger).(*packetHandlerMap)
 	})
 
 	AfterEach(func() {
@@ -122,7 +122,7 @@ var _ = Describe("Packet Handler Map", func() {
 		})
 
 		It("drops unparseable packets", func() {
-			handler.handlePacket(nil, nil, []byte{0, 1, 2, 3})
+			handler.handlePacket(nil, protocol.ECNNon, nil, []byte{0, 1, 2, 3})
 		})
 
 		It("deletes removed sessions immediately", func() {
@@ -130,7 +130,7 @@ var _ = Describe("Packet Handler Map", func() {
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
 			handler.Add(connID, NewMockPacketHandler(mockCtrl))
 			handler.Remove(connID)
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, protocol.ECNNon, nil, getPacket(connID))
 			// don't EXPECT any calls to handlePacket of the MockPacketHandler
 		})
 
@@ -141,10 +141,23 @@ var _ = Describe("Packet Handler Map", func() {
 			handler.Add(connID, sess)
 			handler.Retire(connID)
 			time.Sleep(scaleDuration(30 * time.Millisecond))
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, protocol.ECNNon, nil, getPacket(connID))
 			// don't EXPECT any calls to handlePacket of the MockPacketHandler
 		})
 
+		It("passes the ECN marking to the session", func() {
+			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
+			packetHandler := NewMockPacketHandler(mockCtrl)
+			handled := make(chan struct{})
+			packetHandler.EXPECT().handlePacket(gomock.Any()).Do(func(p *receivedPacket) {
+				Expect(p.ecn).To(Equal(protocol.ECNCE))
+				close(handled)
+			})
+			handler.Add(connID, packetHandler)
+			handler.handlePacket(nil, protocol.ECNCE, nil, getPacket(connID))
+			Eventually(handled).Should(BeClosed())
+		})
+
 		It("passes packets arriving late for closed sessions to that session", func() {
 			handler.deleteRetiredSessionsAfter = time.Hour
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
@@ -155,13 +168,13 @@ var _ = Describe("Packet Handler Map", func() {
 			})
 			handler.Add(connID, packetHandler)
 			handler.Retire(connID)
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, protocol.ECNNon, nil, getPacket(connID))
 			Eventually(handled).Should(BeClosed())
 		})
 
 		It("drops packets for unknown receivers", func() {
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, protocol.ECNNon, nil, getPacket(connID))
 		})
 
 		It("closes the packet handlers when reading from the conn fails", func() {
@@ -202,7 +215,7 @@ var _ = Describe("Packet Handler Map", func() {
 				Expect(cid).To(Equal(connID))
 			})
 			handler.SetServer(server)
-			handler.handlePacket(nil, nil, p)
+			handler.handlePacket(nil, protocol.ECNNon, nil, p)
 		})
 
 		It("closes all server sessions", func() {
@@ -224,7 +237,7 @@ var _ = Describe("Packet Handler Map", func() {
 			// don't EXPECT any calls to server.handlePacket
 			handler.SetServer(server)
 			handler.CloseServer()
-			handler.handlePacket(nil, nil, p)
+			handler.handlePacket(nil, protocol.ECNNon, nil, p)
 		})
 	})
 
@@ -285,7 +298,7 @@ var _ = Describe("Packet Handler Map", func() {
 				p = append(p, token[:]...)
 
 				time.Sleep(scaleDuration(30 * time.Millisecond))
-				handler.handlePacket(nil, nil, p)
+				handler.handlePacket(nil, protocol.ECNNon, nil, p)
 			})
 
 			It("ignores packets too small to contain a stateless reset", func() {
@@ -320,7 +333,7 @@ var _ = Describe("Packet Handler Map", func() {
 			It("sends stateless resets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 				p := append([]byte{40}, make([]byte, 100)...)
-				handler.handlePacket(addr, getPacketBuffer(), p)
+				handler.handlePacket(addr, protocol.ECNNon, getPacketBuffer(), p)
 				var reset mockPacketConnWrite
 				Eventually(conn.dataWritten).Should(Receive(&reset))
 				Expect(reset.to).To(Equal(addr))
@@ -331,7 +344,7 @@ var _ = Describe("Packet Handler Map", func() {
 			It("doesn't send stateless resets for small packets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 				p := append([]byte{40}, make([]byte, protocol.MinStatelessResetSize-2)...)
-				handler.handlePacket(addr, getPacketBuffer(), p)
+				handler.handlePacket(addr, protocol.ECNNon, getPacketBuffer(), p)
 				Consistently(conn.dataWritten).ShouldNot(Receive())
 			})
 		})
@@ -340,7 +353,7 @@ var _ = Describe("Packet Handler Map", func() {
 			It("doesn't send stateless resets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 				p := append([]byte{40}, make([]byte, 100)...)
-				handler.handlePacket(addr, getPacketBuffer(), p)
+				handler.handlePacket(addr, protocol.ECNNon, getPacketBuffer(), p)
 				Consistently(conn.dataWritten).ShouldNot(Receive())
 			})
 		})
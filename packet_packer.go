@@ -21,10 +21,14 @@ type packer interface {
 	PackPacket() (*packedPacket, error)
 	MaybePackProbePacket(protocol.EncryptionLevel) (*packedPacket, error)
 	MaybePackAckPacket(handshakeConfirmed bool) (*packedPacket, error)
+	PackPathProbePacket(protocol.ConnectionID, []ackhandler.Frame, protocol.ByteCount) (*packedPacket, error)
+	PackMTUProbePacket(ping ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error)
 	PackConnectionClose(*qerr.QuicError) (*coalescedPacket, error)
 
 	HandleTransportParameters(*wire.TransportParameters)
 	SetToken([]byte)
+	SetVersion(protocol.VersionNumber)
+	SetMaxPacketSize(protocol.ByteCount)
 }
 
 type sealer interface {
@@ -48,6 +52,8 @@ type packetContents struct {
 	frames []ackhandler.Frame
 
 	length protocol.ByteCount
+
+	isMTUProbePacket bool
 }
 
 type coalescedPacket struct {
@@ -96,12 +102,13 @@ func (p *packetContents) ToAckHandlerPacket(now time.Time, q *retransmissionQueu
 		}
 	}
 	return &ackhandler.Packet{
-		PacketNumber:    p.header.PacketNumber,
-		LargestAcked:    largestAcked,
-		Frames:          p.frames,
-		Length:          p.length,
-		EncryptionLevel: encLevel,
-		SendTime:        now,
+		PacketNumber:         p.header.PacketNumber,
+		LargestAcked:         largestAcked,
+		Frames:               p.frames,
+		Length:               p.length,
+		EncryptionLevel:      encLevel,
+		SendTime:             now,
+		IsPathMTUProbePacket: p.isMTUProbePacket,
 	}
 }
 
@@ -150,6 +157,9 @@ type packetPacker struct {
 
 	perspective protocol.Perspective
 	version     protocol.VersionNumber
+	// The version the connection was started with.
+	// Only differs from version if we switched to a compatible version during the handshake.
+	origVersion protocol.VersionNumber
 	cryptoSetup sealingManager
 
 	initialStream   cryptoStream
@@ -160,9 +170,12 @@ type packetPacker struct {
 	pnManager           packetNumberManager
 	framer              frameSource
 	acks                ackFrameSource
+	datagramQueue       *datagramQueue
 	retransmissionQueue *retransmissionQueue
 
 	maxPacketSize          protocol.ByteCount
+	maxUDPPayloadSize      protocol.ByteCount // the peer's max_udp_payload_size, 0 if not yet known
+	greaseQUICBit          bool               // the peer sent the grease_quic_bit transport parameter
 	numNonAckElicitingAcks int
 }
 
@@ -179,6 +192,7 @@ func newPacketPacker(
 	cryptoSetup sealingManager,
 	framer frameSource,
 	acks ackFrameSource,
+	datagramQueue *datagramQueue,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) *packetPacker {
@@ -191,8 +205,10 @@ func newPacketPacker(
 		retransmissionQueue: retransmissionQueue,
 		perspective:         perspective,
 		version:             version,
+		origVersion:         version,
 		framer:              framer,
 		acks:                acks,
+		datagramQueue:       datagramQueue,
 		pnManager:           packetNumberManager,
 		maxPacketSize:       getMaxPacketSize(remoteAddr),
 	}
@@ -206,7 +222,7 @@ func (p *packetPacker) PackConnectionClose(quicErr *qerr.QuicError) (*coalescedP
 		reason = quicErr.ErrorMessage
 	}
 
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferWithSize(p.maxPacketSize)
 	contents := make([]*packetContents, 0, 1)
 	for _, encLevel := range []protocol.EncryptionLevel{protocol.EncryptionInitial, protocol.EncryptionHandshake, protocol.Encryption0RTT, protocol.Encryption1RTT} {
 		if p.perspective == protocol.PerspectiveServer && encLevel == protocol.Encryption0RTT {
@@ -262,7 +278,7 @@ func (p *packetPacker) PackConnectionClose(quicErr *qerr.QuicError) (*coalescedP
 		} else {
 			hdr = p.getLongHeader(encLevel)
 		}
-		c, err := p.appendPacket(buffer, hdr, payload, encLevel, sealer)
+		c, err := p.appendPacket(buffer, hdr, payload, 0, encLevel, sealer, false)
 		if err != nil {
 			return nil, err
 		}
@@ -325,7 +341,7 @@ func (p *packetPacker) padPacket(buffer *packetBuffer) {
 // It packs an Initial / Handshake if there is data to send in these packet number spaces.
 // It should only be called before the handshake is confirmed.
 func (p *packetPacker) PackCoalescedPacket(maxPacketSize protocol.ByteCount) (*coalescedPacket, error) {
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferWithSize(utils.MinByteCount(maxPacketSize, p.maxPacketSize))
 	packet, err := p.packCoalescedPacket(buffer, maxPacketSize)
 	if err != nil {
 		return nil, err
@@ -397,7 +413,7 @@ func (p *packetPacker) packCoalescedPacket(buffer *packetBuffer, maxPacketSize p
 // PackPacket packs a packet in the application data packet number space.
 // It should be called after the handshake is confirmed.
 func (p *packetPacker) PackPacket() (*packedPacket, error) {
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferWithSize(p.maxPacketSize)
 	contents, err := p.maybeAppendAppDataPacket(buffer, p.maxPacketSize)
 	if err != nil || contents == nil {
 		buffer.Release()
@@ -474,7 +490,7 @@ func (p *packetPacker) maybeAppendCryptoPacket(buffer *packetBuffer, maxPacketSi
 		payload.frames = []ackhandler.Frame{{Frame: cf}}
 		payload.length += cf.Length(p.version)
 	}
-	return p.appendPacket(buffer, hdr, payload, encLevel, sealer)
+	return p.appendPacket(buffer, hdr, payload, 0, encLevel, sealer, false)
 }
 
 func (p *packetPacker) maybeAppendAppDataPacket(buffer *packetBuffer, maxPacketSize protocol.ByteCount) (*packetContents, error) {
@@ -520,7 +536,7 @@ func (p *packetPacker) maybeAppendAppDataPacket(buffer *packetBuffer, maxPacketS
 		p.numNonAckElicitingAcks = 0
 	}
 
-	return p.appendPacket(buffer, header, payload, encLevel, sealer)
+	return p.appendPacket(buffer, header, payload, 0, encLevel, sealer, false)
 }
 
 func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, ackAllowed bool) payload {
@@ -528,18 +544,38 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, ackAll
 	var ack *wire.AckFrame
 	hasData := p.framer.HasData()
 	hasRetransmission := p.retransmissionQueue.HasAppData()
+	var datagram *wire.DatagramFrame
+	if p.datagramQueue != nil {
+		datagram = p.datagramQueue.Peek()
+	}
 	if ackAllowed {
-		ack = p.acks.GetAckFrame(protocol.Encryption1RTT, !hasRetransmission && !hasData)
+		ack = p.acks.GetAckFrame(protocol.Encryption1RTT, !hasRetransmission && !hasData && datagram == nil)
 		if ack != nil {
 			payload.ack = ack
 			payload.length += ack.Length(p.version)
 		}
 	}
 
-	if ack == nil && !hasData && !hasRetransmission {
+	if ack == nil && !hasData && !hasRetransmission && datagram == nil {
 		return payload
 	}
 
+	if datagram != nil {
+		if size := datagram.Length(p.version); size <= maxFrameSize-payload.length {
+			payload.frames = append(payload.frames, ackhandler.Frame{
+				Frame: datagram,
+				// DATAGRAM frames are never retransmitted.
+				// Setting a no-op callback prevents the default callback from being set.
+				OnLost: func(wire.Frame) {},
+			})
+			payload.length += size
+			p.datagramQueue.Pop()
+		} else if payload.length == 0 {
+			// The DATAGRAM frame doesn't even fit into an empty packet. Drop it.
+			p.datagramQueue.Pop()
+		}
+	}
+
 	if hasRetransmission {
 		for {
 			remainingLen := maxFrameSize - payload.length
@@ -569,7 +605,7 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, ackAll
 func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel) (*packedPacket, error) {
 	var contents *packetContents
 	var err error
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferWithSize(p.maxPacketSize)
 	switch encLevel {
 	case protocol.EncryptionInitial:
 		contents, err = p.maybeAppendCryptoPacket(buffer, p.maxPacketSize, protocol.EncryptionInitial)
@@ -592,6 +628,70 @@ func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel) (
 	}, nil
 }
 
+// PackPathProbePacket packs a 1-RTT packet that is sent on a new path.
+// It uses the given destination connection ID, and is padded to size bytes.
+// Packets that would be larger than size without padding are not truncated.
+func (p *packetPacker) PackPathProbePacket(connID protocol.ConnectionID, frames []ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error) {
+	sealer, err := p.cryptoSetup.Get1RTTSealer()
+	if err != nil {
+		return nil, err
+	}
+	hdr := p.getShortHeader(sealer.KeyPhase())
+	hdr.DestConnectionID = connID
+
+	var payload payload
+	for _, f := range frames {
+		payload.frames = append(payload.frames, f)
+		payload.length += f.Length(p.version)
+	}
+	var padding protocol.ByteCount
+	if l := hdr.GetLength(p.version) + payload.length + protocol.ByteCount(sealer.Overhead()); l < size {
+		padding = size - l
+	}
+	buffer := getPacketBuffer()
+	contents, err := p.appendPacket(buffer, hdr, payload, padding, protocol.Encryption1RTT, sealer, false)
+	if err != nil {
+		buffer.Release()
+		return nil, err
+	}
+	return &packedPacket{
+		buffer:         buffer,
+		packetContents: contents,
+	}, nil
+}
+
+// PackMTUProbePacket packs a 1-RTT packet containing only the given PING frame, padded to size bytes.
+// The size may exceed the current maximum packet size, but not the peer's max_udp_payload_size.
+func (p *packetPacker) PackMTUProbePacket(ping ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error) {
+	maxSize := protocol.MaxJumboPacketSize // the size of the largest packet buffers
+	if p.maxUDPPayloadSize != 0 {
+		maxSize = utils.MinByteCount(maxSize, p.maxUDPPayloadSize)
+	}
+	if size > maxSize {
+		return nil, fmt.Errorf("PacketPacker BUG: MTU probe packet too large (%d bytes, allowed %d bytes)", size, maxSize)
+	}
+	sealer, err := p.cryptoSetup.Get1RTTSealer()
+	if err != nil {
+		return nil, err
+	}
+	hdr := p.getShortHeader(sealer.KeyPhase())
+	payload := payload{
+		frames: []ackhandler.Frame{ping},
+		length: ping.Length(p.version),
+	}
+	padding := size - hdr.GetLength(p.version) - payload.length - protocol.ByteCount(sealer.Overhead())
+	buffer := getPacketBufferWithSize(size)
+	contents, err := p.appendPacket(buffer, hdr, payload, padding, protocol.Encryption1RTT, sealer, true)
+	if err != nil {
+		buffer.Release()
+		return nil, err
+	}
+	return &packedPacket{
+		buffer:         buffer,
+		packetContents: contents,
+	}, nil
+}
+
 func (p *packetPacker) getSealerAndHeader(encLevel protocol.EncryptionLevel) (sealer, *wire.ExtendedHeader, error) {
 	switch encLevel {
 	case protocol.EncryptionInitial:
@@ -634,6 +734,7 @@ func (p *packetPacker) getShortHeader(kp protocol.KeyPhaseBit) *wire.ExtendedHea
 	hdr.PacketNumberLen = pnLen
 	hdr.DestConnectionID = p.getDestConnID()
 	hdr.KeyPhase = kp
+	hdr.GreaseQUICBit = p.greaseQUICBit
 	return hdr
 }
 
@@ -651,6 +752,7 @@ func (p *packetPacker) getLongHeader(encLevel protocol.EncryptionLevel) *wire.Ex
 
 	hdr.PacketNumber = pn
 	hdr.PacketNumberLen = pnLen
+	hdr.GreaseQUICBit = p.greaseQUICBit
 
 	switch encLevel {
 	case protocol.EncryptionInitial:
@@ -660,6 +762,8 @@ func (p *packetPacker) getLongHeader(encLevel protocol.EncryptionLevel) *wire.Ex
 		hdr.Type = protocol.PacketTypeHandshake
 	case protocol.Encryption0RTT:
 		hdr.Type = protocol.PacketType0RTT
+		// 0-RTT keys are derived using the version the connection was started with.
+		hdr.Version = p.origVersion
 	}
 
 	return hdr
@@ -672,8 +776,8 @@ func (p *packetPacker) writeSinglePacket(
 	encLevel protocol.EncryptionLevel,
 	sealer sealer,
 ) (*packedPacket, error) {
-	buffer := getPacketBuffer()
-	contents, err := p.appendPacket(buffer, header, payload, encLevel, sealer)
+	buffer := getPacketBufferWithSize(p.maxPacketSize)
+	contents, err := p.appendPacket(buffer, header, payload, 0, encLevel, sealer, false)
 	if err != nil {
 		return nil, err
 	}
@@ -687,14 +791,18 @@ func (p *packetPacker) appendPacket(
 	buffer *packetBuffer,
 	header *wire.ExtendedHeader,
 	payload payload,
+	padding protocol.ByteCount,
 	encLevel protocol.EncryptionLevel,
 	sealer sealer,
+	isMTUProbePacket bool,
 ) (*packetContents, error) {
 	var paddingLen protocol.ByteCount
 	pnLen := protocol.ByteCount(header.PacketNumberLen)
 	if payload.length < 4-pnLen {
 		paddingLen = 4 - pnLen - payload.length
 	}
+	// padding already includes the padding needed for header protection
+	paddingLen = utils.MaxByteCount(paddingLen, padding)
 	if header.IsLongHeader {
 		header.Length = pnLen + protocol.ByteCount(sealer.Overhead()) + payload.length + paddingLen
 	}
@@ -723,7 +831,8 @@ func (p *packetPacker) appendPacket(
 	if payloadSize := protocol.ByteCount(buf.Len()-payloadOffset) - paddingLen; payloadSize != payload.length {
 		return nil, fmt.Errorf("PacketPacker BUG: payload size inconsistent (expected %d, got %d bytes)", payload.length, payloadSize)
 	}
-	if size := protocol.ByteCount(buf.Len() + sealer.Overhead()); size > p.maxPacketSize {
+	// MTU probe packets are larger than the currently allowed packet size by design.
+	if size := protocol.ByteCount(buf.Len() + sealer.Overhead()); !isMTUProbePacket && size > p.maxPacketSize {
 		return nil, fmt.Errorf("PacketPacker BUG: packet too large (%d bytes, allowed %d bytes)", size, p.maxPacketSize)
 	}
 
@@ -742,10 +851,11 @@ func (p *packetPacker) appendPacket(
 		return nil, errors.New("packetPacker BUG: Peeked and Popped packet numbers do not match")
 	}
 	return &packetContents{
-		header: header,
-		ack:    payload.ack,
-		frames: payload.frames,
-		length: buffer.Len() - hdrOffset,
+		header:           header,
+		ack:              payload.ack,
+		frames:           payload.frames,
+		length:           buffer.Len() - hdrOffset,
+		isMTUProbePacket: isMTUProbePacket,
 	}, nil
 }
 
@@ -753,8 +863,26 @@ func (p *packetPacker) SetToken(token []byte) {
 	p.token = token
 }
 
+// SetVersion sets the version used for long header packets.
+// It is called when switching to a compatible version during the handshake.
+func (p *packetPacker) SetVersion(v protocol.VersionNumber) {
+	p.version = v
+}
+
+// SetMaxPacketSize sets the maximum packet size.
+// It is called when Path MTU Discovery finds a new MTU, or when the MTU is reset.
+// The size is never increased beyond the peer's max_udp_payload_size.
+func (p *packetPacker) SetMaxPacketSize(s protocol.ByteCount) {
+	if p.maxUDPPayloadSize != 0 {
+		s = utils.MinByteCount(s, p.maxUDPPayloadSize)
+	}
+	p.maxPacketSize = utils.MinByteCount(s, protocol.MaxJumboPacketSize)
+}
+
 func (p *packetPacker) HandleTransportParameters(params *wire.TransportParameters) {
 	if params.MaxUDPPayloadSize != 0 {
+		p.maxUDPPayloadSize = params.MaxUDPPayloadSize
 		p.maxPacketSize = utils.MinByteCount(p.maxPacketSize, params.MaxUDPPayloadSize)
 	}
+	p.greaseQUICBit = params.GreaseQUICBit
 }
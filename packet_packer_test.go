@@ -2,6 +2,7 @@ package quic
 
 import (
 	"bytes"
+	"fmt"
 	"math/rand"
 	"net"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	mockackhandler "github.com/lucas-clemente/quic-go/internal/mocks/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -27,6 +29,7 @@ var _ = Describe("Packet packer", func() {
 	var (
 		packer              *packetPacker
 		retransmissionQueue *retransmissionQueue
+		datagramQueue       *datagramQueue
 		framer              *MockFrameSource
 		ackFramer           *MockAckFrameSource
 		initialStream       *MockCryptoStream
@@ -68,13 +71,14 @@ var _ = Describe("Packet packer", func() {
 		rand.Seed(GinkgoRandomSeed())
 		retransmissionQueue = newRetransmissionQueue(version)
 		mockSender := NewMockStreamSender(mockCtrl)
-		mockSender.EXPECT().onHasStreamData(gomock.Any()).AnyTimes()
+		mockSender.EXPECT().onHasStreamData(gomock.Any(), gomock.Any()).AnyTimes()
 		initialStream = NewMockCryptoStream(mockCtrl)
 		handshakeStream = NewMockCryptoStream(mockCtrl)
 		framer = NewMockFrameSource(mockCtrl)
 		ackFramer = NewMockAckFrameSource(mockCtrl)
 		sealingManager = NewMockSealingManager(mockCtrl)
 		pnManager = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		datagramQueue = newDatagramQueue(func() {}, utils.DefaultLogger)
 
 		packer = newPacketPacker(
 			protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
@@ -87,6 +91,7 @@ var _ = Describe("Packet packer", func() {
 			sealingManager,
 			framer,
 			ackFramer,
+			datagramQueue,
 			protocol.PerspectiveServer,
 			version,
 		)
@@ -132,6 +137,15 @@ var _ = Describe("Packet packer", func() {
 			Expect(h.DestConnectionID).To(Equal(destConnID))
 		})
 
+		It("uses the new version after switching to a compatible version", func() {
+			packer.SetVersion(protocol.Version2)
+			pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+			Expect(packer.getLongHeader(protocol.EncryptionHandshake).Version).To(Equal(protocol.Version2))
+			// 0-RTT packets are sent using the original version
+			pnManager.EXPECT().PeekPacketNumber(protocol.Encryption0RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+			Expect(packer.getLongHeader(protocol.Encryption0RTT).Version).To(Equal(version))
+		})
+
 		It("gets a short header", func() {
 			pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x1337), protocol.PacketNumberLen4)
 			h := packer.getShortHeader(protocol.KeyPhaseOne)
@@ -140,6 +154,15 @@ var _ = Describe("Packet packer", func() {
 			Expect(h.PacketNumberLen).To(Equal(protocol.PacketNumberLen4))
 			Expect(h.KeyPhase).To(Equal(protocol.KeyPhaseOne))
 		})
+
+		It("greases the QUIC bit, if the peer allows it", func() {
+			pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x1337), protocol.PacketNumberLen4).Times(2)
+			pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+			Expect(packer.getShortHeader(protocol.KeyPhaseZero).GreaseQUICBit).To(BeFalse())
+			packer.HandleTransportParameters(&wire.TransportParameters{GreaseQUICBit: true})
+			Expect(packer.getShortHeader(protocol.KeyPhaseZero).GreaseQUICBit).To(BeTrue())
+			Expect(packer.getLongHeader(protocol.EncryptionHandshake).GreaseQUICBit).To(BeTrue())
+		})
 	})
 
 	Context("encrypting packets", func() {
@@ -505,6 +528,60 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.buffer.Len()).ToNot(BeZero())
 			})
 
+			It("packs DATAGRAM frames", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				f := &wire.DatagramFrame{
+					DataLenPresent: true,
+					Data:           []byte("foobar"),
+				}
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					datagramQueue.AddAndWait(f)
+				}()
+				// make sure the DATAGRAM has actually been queued
+				time.Sleep(scaleDuration(20 * time.Millisecond))
+
+				framer.EXPECT().HasData()
+				p, err := packer.PackPacket()
+				Expect(p).ToNot(BeNil())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame).To(Equal(f))
+				Expect(p.frames[0].OnLost).ToNot(BeNil())
+				Expect(p.buffer.Data).ToNot(BeEmpty())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("drops DATAGRAM frames that don't fit into a packet", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				f := &wire.DatagramFrame{
+					DataLenPresent: true,
+					Data:           make([]byte, maxPacketSize),
+				}
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					datagramQueue.AddAndWait(f)
+				}()
+				// make sure the DATAGRAM has actually been queued
+				time.Sleep(scaleDuration(20 * time.Millisecond))
+
+				framer.EXPECT().HasData()
+				p, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).To(BeNil())
+				Expect(datagramQueue.Peek()).To(BeNil())
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("accounts for the space consumed by control frames", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
@@ -556,7 +633,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(secondPayloadByte).To(Equal(byte(0)))
 				// ... followed by the PING
-				frameParser := wire.NewFrameParser(packer.version)
+				frameParser := wire.NewFrameParser(true, packer.version)
 				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
@@ -593,7 +670,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(firstPayloadByte).To(Equal(byte(0)))
 				// ... followed by the STREAM frame
-				frameParser := wire.NewFrameParser(packer.version)
+				frameParser := wire.NewFrameParser(true, packer.version)
 				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
@@ -777,6 +854,84 @@ var _ = Describe("Packet packer", func() {
 					_, err = packer.PackPacket()
 					Expect(err).ToNot(HaveOccurred())
 				})
+
+				It("increases the max packet size, but not beyond the peer's max_udp_payload_size", func() {
+					packer.HandleTransportParameters(&wire.TransportParameters{
+						MaxUDPPayloadSize: maxPacketSize + 100,
+					})
+					Expect(packer.maxPacketSize).To(Equal(maxPacketSize))
+					packer.SetMaxPacketSize(maxPacketSize + 50)
+					Expect(packer.maxPacketSize).To(Equal(maxPacketSize + 50))
+					packer.SetMaxPacketSize(maxPacketSize + 200)
+					Expect(packer.maxPacketSize).To(Equal(maxPacketSize + 100))
+					// reduce it again, e.g. after a black hole was detected
+					packer.SetMaxPacketSize(maxPacketSize - 10)
+					Expect(packer.maxPacketSize).To(Equal(maxPacketSize - 10))
+				})
+
+				It("uses large packet buffers only after the max packet size was increased", func() {
+					packer.HandleTransportParameters(&wire.TransportParameters{MaxUDPPayloadSize: protocol.MaxByteCount})
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2).Times(2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42)).Times(2)
+					sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil).Times(2)
+					framer.EXPECT().HasData().Return(true).Times(2)
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false).Times(2)
+					expectAppendControlFrames(ackhandler.Frame{Frame: &wire.PingFrame{}})
+					expectAppendStreamFrames()
+					p, err := packer.PackPacket()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p.buffer.Data).To(HaveCap(int(protocol.MaxReceivePacketSize)))
+					// Path MTU Discovery found a larger MTU
+					packer.SetMaxPacketSize(3000)
+					expectAppendControlFrames(ackhandler.Frame{Frame: &wire.PingFrame{}})
+					expectAppendStreamFrames()
+					p, err = packer.PackPacket()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p.buffer.Data).To(HaveCap(int(protocol.MaxJumboPacketSize)))
+					// the packet size is limited by the size of the largest packet buffers
+					packer.SetMaxPacketSize(protocol.MaxJumboPacketSize + 1)
+					Expect(packer.maxPacketSize).To(Equal(protocol.MaxJumboPacketSize))
+				})
+			})
+		})
+
+		Context("packing MTU probe packets", func() {
+			It("packs a PING frame padded to the probe size", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				ping := ackhandler.Frame{Frame: &wire.PingFrame{}}
+				const probeSize = maxPacketSize + 42
+				p, err := packer.PackMTUProbePacket(ping, probeSize)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
+				Expect(p.header.PacketNumber).To(Equal(protocol.PacketNumber(0x43)))
+				Expect(p.frames).To(Equal([]ackhandler.Frame{ping}))
+				Expect(p.buffer.Len()).To(BeEquivalentTo(probeSize))
+				Expect(p.length).To(BeEquivalentTo(probeSize))
+				Expect(p.ToAckHandlerPacket(time.Now(), nil).IsPathMTUProbePacket).To(BeTrue())
+			})
+
+			It("packs probe packets larger than the default packet buffers", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				p, err := packer.PackMTUProbePacket(ackhandler.Frame{Frame: &wire.PingFrame{}}, 8000)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.buffer.Len()).To(BeEquivalentTo(8000))
+				Expect(p.buffer.Data).To(HaveCap(int(protocol.MaxJumboPacketSize)))
+			})
+
+			It("refuses to pack a probe packet larger than the packet buffers", func() {
+				_, err := packer.PackMTUProbePacket(ackhandler.Frame{Frame: &wire.PingFrame{}}, protocol.MaxJumboPacketSize+1)
+				Expect(err).To(MatchError(fmt.Sprintf("PacketPacker BUG: MTU probe packet too large (%d bytes, allowed %d bytes)", protocol.MaxJumboPacketSize+1, protocol.MaxJumboPacketSize)))
+			})
+
+			It("refuses to pack a probe packet larger than the peer's max_udp_payload_size", func() {
+				packer.HandleTransportParameters(&wire.TransportParameters{MaxUDPPayloadSize: 4000})
+				_, err := packer.PackMTUProbePacket(ackhandler.Frame{Frame: &wire.PingFrame{}}, 4001)
+				Expect(err).To(MatchError("PacketPacker BUG: MTU probe packet too large (4001 bytes, allowed 4000 bytes)"))
 			})
 		})
 
@@ -1044,7 +1199,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(secondPayloadByte).To(Equal(byte(0)))
 				// ... followed by the PING
-				frameParser := wire.NewFrameParser(packer.version)
+				frameParser := wire.NewFrameParser(true, packer.version)
 				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
@@ -1239,6 +1394,34 @@ var _ = Describe("Packet packer", func() {
 				Expect(packet.frames[0].Frame).To(Equal(f))
 			})
 
+			It("packs a path probe packet", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				connID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
+				f := &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
+				packet, err := packer.PackPathProbePacket(connID, []ackhandler.Frame{{Frame: f}}, protocol.MinInitialPacketSize)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(packet).ToNot(BeNil())
+				Expect(packet.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
+				Expect(packet.header.DestConnectionID).To(Equal(connID))
+				Expect(packet.frames).To(Equal([]ackhandler.Frame{{Frame: f}}))
+				Expect(packet.buffer.Len()).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+				hdr, _, _, err := wire.ParsePacket(packet.buffer.Data, connID.Len())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hdr.DestConnectionID).To(Equal(connID))
+			})
+
+			It("packs a path probe packet of a given size", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				f := &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
+				packet, err := packer.PackPathProbePacket(protocol.ConnectionID{1, 2, 3, 4}, []ackhandler.Frame{{Frame: f}}, 100)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(packet.buffer.Len()).To(BeEquivalentTo(100))
+			})
+
 			It("returns nil if there's no probe data to send", func() {
 				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
 				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
@@ -0,0 +1,65 @@
+package quic
+
+import (
+	"crypto/rand"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// The pathValidator validates a new network path, as described in section 8.2 of the QUIC transport draft.
+// It generates the PATH_CHALLENGE frames to send on the path,
+// and checks if a PATH_RESPONSE frame matches any of them.
+type pathValidator struct {
+	challenges [][8]byte
+	postponed  int
+	deadline   time.Time
+}
+
+func newPathValidator() *pathValidator {
+	return &pathValidator{challenges: make([][8]byte, 0, protocol.MaxPathChallenges)}
+}
+
+// NextChallenge generates a new PATH_CHALLENGE frame.
+// The next PATH_CHALLENGE is due after timeout.
+// It returns nil if the maximum number of PATH_CHALLENGE frames was already sent,
+// which means that path validation failed.
+func (v *pathValidator) NextChallenge(now time.Time, timeout time.Duration) *wire.PathChallengeFrame {
+	if len(v.challenges) >= protocol.MaxPathChallenges {
+		return nil
+	}
+	f := &wire.PathChallengeFrame{}
+	_, _ = rand.Read(f.Data[:]) // ignore the error here. Nothing bad will happen if the challenge is not perfectly random.
+	v.challenges = append(v.challenges, f.Data)
+	v.deadline = now.Add(timeout)
+	return f
+}
+
+// Postpone delays the next PATH_CHALLENGE frame until after timeout,
+// without using up one of the PATH_CHALLENGE frames.
+// It returns false if the maximum number of postponements was already reached,
+// which means that path validation failed.
+func (v *pathValidator) Postpone(now time.Time, timeout time.Duration) bool {
+	if v.postponed >= protocol.MaxPathChallengePostponements {
+		return false
+	}
+	v.postponed++
+	v.deadline = now.Add(timeout)
+	return true
+}
+
+// Deadline returns the time when the next PATH_CHALLENGE frame should be sent.
+func (v *pathValidator) Deadline() time.Time {
+	return v.deadline
+}
+
+// HandlePathResponse says if the PATH_RESPONSE frame echoes one of the PATH_CHALLENGE frames sent.
+func (v *pathValidator) HandlePathResponse(f *wire.PathResponseFrame) bool {
+	for _, c := range v.challenges {
+		if c == f.Data {
+			return true
+		}
+	}
+	return false
+}
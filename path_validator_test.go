@@ -0,0 +1,69 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path Validator", func() {
+	var v *pathValidator
+
+	BeforeEach(func() {
+		v = newPathValidator()
+	})
+
+	It("generates PATH_CHALLENGE frames", func() {
+		now := time.Now()
+		f1 := v.NextChallenge(now, time.Second)
+		Expect(f1).ToNot(BeNil())
+		Expect(v.Deadline()).To(Equal(now.Add(time.Second)))
+		f2 := v.NextChallenge(now.Add(time.Second), time.Second)
+		Expect(f2).ToNot(BeNil())
+		Expect(f2.Data).ToNot(Equal(f1.Data))
+		Expect(v.Deadline()).To(Equal(now.Add(2 * time.Second)))
+	})
+
+	It("stops after the maximum number of PATH_CHALLENGE frames", func() {
+		for i := 0; i < protocol.MaxPathChallenges; i++ {
+			Expect(v.NextChallenge(time.Now(), time.Second)).ToNot(BeNil())
+		}
+		Expect(v.NextChallenge(time.Now(), time.Second)).To(BeNil())
+	})
+
+	It("postpones the next PATH_CHALLENGE", func() {
+		now := time.Now()
+		Expect(v.Postpone(now, time.Second)).To(BeTrue())
+		Expect(v.Deadline()).To(Equal(now.Add(time.Second)))
+		for i := 0; i < protocol.MaxPathChallenges; i++ {
+			Expect(v.NextChallenge(now, time.Second)).ToNot(BeNil())
+		}
+	})
+
+	It("stops after the maximum number of postponements", func() {
+		now := time.Now()
+		for i := 0; i < protocol.MaxPathChallengePostponements; i++ {
+			Expect(v.Postpone(now, time.Second)).To(BeTrue())
+		}
+		Expect(v.Postpone(now.Add(time.Hour), time.Second)).To(BeFalse())
+		Expect(v.Deadline()).To(Equal(now.Add(time.Second)))
+	})
+
+	It("accepts PATH_RESPONSE frames for any PATH_CHALLENGE sent", func() {
+		f1 := v.NextChallenge(time.Now(), time.Second)
+		f2 := v.NextChallenge(time.Now(), time.Second)
+		Expect(v.HandlePathResponse(&wire.PathResponseFrame{Data: f1.Data})).To(BeTrue())
+		Expect(v.HandlePathResponse(&wire.PathResponseFrame{Data: f2.Data})).To(BeTrue())
+	})
+
+	It("rejects PATH_RESPONSE frames that don't match", func() {
+		f := v.NextChallenge(time.Now(), time.Second)
+		data := f.Data
+		data[0]++
+		Expect(v.HandlePathResponse(&wire.PathResponseFrame{Data: data})).To(BeFalse())
+	})
+})
@@ -191,6 +191,46 @@ func (e eventStatelessResetReceived) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("stateless_reset_token", fmt.Sprintf("%x", *e.Token))
 }
 
+type eventPathValidationStarted struct {
+	DestAddr *net.UDPAddr
+}
+
+func (e eventPathValidationStarted) Category() category { return categoryConnectivity }
+func (e eventPathValidationStarted) Name() string       { return "path_validation_started" }
+func (e eventPathValidationStarted) IsNil() bool        { return false }
+
+func (e eventPathValidationStarted) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("dst_ip", e.DestAddr.IP.String())
+	enc.IntKey("dst_port", e.DestAddr.Port)
+}
+
+type eventPathUpdated struct {
+	DestAddr *net.UDPAddr
+}
+
+func (e eventPathUpdated) Category() category { return categoryConnectivity }
+func (e eventPathUpdated) Name() string       { return "path_updated" }
+func (e eventPathUpdated) IsNil() bool        { return false }
+
+func (e eventPathUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("dst_ip", e.DestAddr.IP.String())
+	enc.IntKey("dst_port", e.DestAddr.Port)
+}
+
+type eventMTUUpdated struct {
+	MTU  protocol.ByteCount
+	Done bool
+}
+
+func (e eventMTUUpdated) Category() category { return categoryConnectivity }
+func (e eventMTUUpdated) Name() string       { return "mtu_updated" }
+func (e eventMTUUpdated) IsNil() bool        { return false }
+
+func (e eventMTUUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Int64Key("new", int64(e.MTU))
+	enc.BoolKey("done", e.Done)
+}
+
 type eventPacketBuffered struct {
 	PacketType logging.PacketType
 }
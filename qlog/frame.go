@@ -31,6 +31,10 @@ type cryptoFrame struct {
 	Length protocol.ByteCount
 }
 
+type datagramFrame struct {
+	Length protocol.ByteCount
+}
+
 type streamFrame struct {
 	StreamID protocol.StreamID
 	Offset   protocol.ByteCount
@@ -39,8 +43,8 @@ type streamFrame struct {
 }
 
 func transformFrame(wf wire.Frame) *frame {
-	// We don't want to store CRYPTO and STREAM frames, for multiple reasons:
-	// * They both contain data, and we want to make this byte slice GC'able as soon as possible.
+	// We don't want to store CRYPTO, STREAM and DATAGRAM frames, for multiple reasons:
+	// * They all contain data, and we want to make this byte slice GC'able as soon as possible.
 	// * STREAM frames use a slice from the buffer pool, which is released as soon as the frame is processed.
 	switch f := wf.(type) {
 	case *wire.CryptoFrame:
@@ -55,6 +59,8 @@ func transformFrame(wf wire.Frame) *frame {
 			Length:   f.DataLen(),
 			FinBit:   f.FinBit,
 		}}
+	case *wire.DatagramFrame:
+		return &frame{Frame: &datagramFrame{Length: protocol.ByteCount(len(f.Data))}}
 	default:
 		return &frame{Frame: wf}
 	}
@@ -100,6 +106,8 @@ func (f frame) MarshalJSONObject(enc *gojay.Encoder) {
 		marshalConnectionCloseFrame(enc, frame)
 	case *wire.HandshakeDoneFrame:
 		marshalHandshakeDoneFrame(enc, frame)
+	case *datagramFrame:
+		marshalDatagramFrame(enc, frame)
 	default:
 		panic("unknown frame type")
 	}
@@ -136,6 +144,11 @@ func marshalAckFrame(enc *gojay.Encoder, f *wire.AckFrame) {
 	enc.StringKey("frame_type", "ack")
 	enc.FloatKeyOmitEmpty("ack_delay", milliseconds(f.DelayTime))
 	enc.ArrayKey("acked_ranges", ackRanges(f.AckRanges))
+	if f.HasECN() {
+		enc.Uint64Key("ect0", f.ECT0)
+		enc.Uint64Key("ect1", f.ECT1)
+		enc.Uint64Key("ce", f.ECNCE)
+	}
 }
 
 func marshalResetStreamFrame(enc *gojay.Encoder, f *wire.ResetStreamFrame) {
@@ -248,3 +261,8 @@ func marshalConnectionCloseFrame(enc *gojay.Encoder, f *wire.ConnectionCloseFram
 func marshalHandshakeDoneFrame(enc *gojay.Encoder, _ *wire.HandshakeDoneFrame) {
 	enc.StringKey("frame_type", "handshake_done")
 }
+
+func marshalDatagramFrame(enc *gojay.Encoder, f *datagramFrame) {
+	enc.StringKey("frame_type", "datagram")
+	enc.Int64Key("length", int64(f.Length))
+}
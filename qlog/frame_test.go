@@ -61,6 +61,24 @@ var _ = Describe("Frames", func() {
 		)
 	})
 
+	It("marshals ACK frames with ECN counts", func() {
+		check(
+			&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Smallest: 120, Largest: 120}},
+				ECT0:      10,
+				ECT1:      100,
+				ECNCE:     1000,
+			},
+			map[string]interface{}{
+				"frame_type":   "ack",
+				"acked_ranges": [][]float64{{120}},
+				"ect0":         10,
+				"ect1":         100,
+				"ce":           1000,
+			},
+		)
+	})
+
 	It("marshals ACK frames with a range acknowledging ranges of packets", func() {
 		check(
 			&wire.AckFrame{
@@ -348,4 +366,14 @@ var _ = Describe("Frames", func() {
 			},
 		)
 	})
+
+	It("marshals DATAGRAM frames", func() {
+		check(
+			&wire.DatagramFrame{Data: []byte("foobar")},
+			map[string]interface{}{
+				"frame_type": "datagram",
+				"length":     6,
+			},
+		)
+	})
 })
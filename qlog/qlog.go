@@ -268,6 +268,34 @@ func (t *connectionTracer) ReceivedStatelessReset(token *[16]byte) {
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) StartedPathValidation(remote net.Addr) {
+	// ignore this event if we're not dealing with UDP addresses here
+	remoteAddr, ok := remote.(*net.UDPAddr)
+	if !ok {
+		return
+	}
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventPathValidationStarted{DestAddr: remoteAddr})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) UpdatedPath(remote net.Addr) {
+	// ignore this event if we're not dealing with UDP addresses here
+	remoteAddr, ok := remote.(*net.UDPAddr)
+	if !ok {
+		return
+	}
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventPathUpdated{DestAddr: remoteAddr})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) UpdatedMTU(mtu protocol.ByteCount, done bool) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventMTUUpdated{MTU: mtu, Done: done})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) BufferedPacket(packetType logging.PacketType) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventPacketBuffered{PacketType: packetType})
@@ -409,6 +409,39 @@ var _ = Describe("Tracer", func() {
 			Expect(ev).To(HaveKeyWithValue("stateless_reset_token", "00112233445566778899aabbccddeeff"))
 		})
 
+		It("records the start of a path validation", func() {
+			tracer.StartedPathValidation(&net.UDPAddr{IP: net.IPv4(192, 168, 12, 34), Port: 24})
+			entry := exportAndParseSingle()
+			Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+			Expect(entry.Category).To(Equal("connectivity"))
+			Expect(entry.Name).To(Equal("path_validation_started"))
+			ev := entry.Event
+			Expect(ev).To(HaveKeyWithValue("dst_ip", "192.168.12.34"))
+			Expect(ev).To(HaveKeyWithValue("dst_port", float64(24)))
+		})
+
+		It("records path updates", func() {
+			tracer.UpdatedPath(&net.UDPAddr{IP: net.IPv4(192, 168, 12, 34), Port: 24})
+			entry := exportAndParseSingle()
+			Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+			Expect(entry.Category).To(Equal("connectivity"))
+			Expect(entry.Name).To(Equal("path_updated"))
+			ev := entry.Event
+			Expect(ev).To(HaveKeyWithValue("dst_ip", "192.168.12.34"))
+			Expect(ev).To(HaveKeyWithValue("dst_port", float64(24)))
+		})
+
+		It("records MTU updates", func() {
+			tracer.UpdatedMTU(1337, true)
+			entry := exportAndParseSingle()
+			Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+			Expect(entry.Category).To(Equal("connectivity"))
+			Expect(entry.Name).To(Equal("mtu_updated"))
+			ev := entry.Event
+			Expect(ev).To(HaveKeyWithValue("new", float64(1337)))
+			Expect(ev).To(HaveKeyWithValue("done", true))
+		})
+
 		It("records buffered packets", func() {
 			tracer.BufferedPacket(logging.PacketTypeHandshake)
 			entry := exportAndParseSingle()
@@ -0,0 +1,198 @@
+// Package quiclb implements connection ID generators that encode a server ID into the connection ID,
+// allowing a load balancer to route packets to the right server.
+// It implements the plaintext and the stream cipher algorithm of QUIC-LB,
+// see https://datatracker.ietf.org/doc/draft-ietf-quic-load-balancers/.
+// This package should not be considered stable.
+package quiclb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	maxConnIDLen = 20
+
+	minStreamCipherNonceLen = 8
+	maxStreamCipherNonceLen = 16
+)
+
+// A Config is the QUIC-LB configuration.
+// It has to be shared between the load balancer and the servers.
+type Config struct {
+	// ConfigRotation is the config rotation codepoint, encoded into the two most significant bits of the first octet.
+	// It can take values between 0 and 2. The codepoint 3 is reserved for unroutable connection IDs.
+	ConfigRotation uint8
+	// LengthSelfEncoding encodes the length of the connection ID (without the first octet)
+	// into the six least significant bits of the first octet.
+	// If false, these bits are random.
+	LengthSelfEncoding bool
+	// ServerID is the server ID encoded into the connection ID.
+	ServerID []byte
+	// NonceLen is the length of the nonce.
+	// For the stream cipher algorithm, it must be between 8 and 16 bytes.
+	NonceLen int
+}
+
+func (c *Config) connIDLen() int {
+	return 1 + len(c.ServerID) + c.NonceLen
+}
+
+func (c *Config) validate() error {
+	if c.ConfigRotation > 2 {
+		return fmt.Errorf("invalid config rotation codepoint: %d", c.ConfigRotation)
+	}
+	if len(c.ServerID) == 0 {
+		return errors.New("server ID not set")
+	}
+	if c.NonceLen < 0 {
+		return fmt.Errorf("invalid nonce length: %d", c.NonceLen)
+	}
+	if l := c.connIDLen(); l > maxConnIDLen {
+		return fmt.Errorf("connection ID too long: %d bytes", l)
+	}
+	return nil
+}
+
+// firstOctet calculates the first octet of the connection ID.
+// rnd is a random byte, used if length self-encoding is disabled.
+func (c *Config) firstOctet(rnd byte) byte {
+	if c.LengthSelfEncoding {
+		return c.ConfigRotation<<6 | byte(c.connIDLen()-1)
+	}
+	return c.ConfigRotation<<6 | rnd&0x3f
+}
+
+func (c *Config) checkConnectionID(connID []byte) error {
+	if len(connID) != c.connIDLen() {
+		return fmt.Errorf("invalid connection ID length: %d", len(connID))
+	}
+	if rotation := connID[0] >> 6; rotation != c.ConfigRotation {
+		return fmt.Errorf("connection ID uses config rotation codepoint %d, expected %d", rotation, c.ConfigRotation)
+	}
+	return nil
+}
+
+// A PlaintextGenerator generates connection IDs using the plaintext algorithm.
+// The connection ID consists of the first octet, the server ID, and a random nonce.
+type PlaintextGenerator struct {
+	config Config
+	rand   io.Reader
+}
+
+// NewPlaintextGenerator creates a new PlaintextGenerator.
+func NewPlaintextGenerator(config *Config) (*PlaintextGenerator, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &PlaintextGenerator{config: *config, rand: rand.Reader}, nil
+}
+
+// GenerateConnectionID generates a new connection ID.
+func (g *PlaintextGenerator) GenerateConnectionID() ([]byte, error) {
+	rnd := make([]byte, 1+g.config.NonceLen)
+	if _, err := io.ReadFull(g.rand, rnd); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, g.config.connIDLen())
+	b = append(b, g.config.firstOctet(rnd[0]))
+	b = append(b, g.config.ServerID...)
+	return append(b, rnd[1:]...), nil
+}
+
+// ConnectionIDLen returns the length of the connection IDs.
+func (g *PlaintextGenerator) ConnectionIDLen() int {
+	return g.config.connIDLen()
+}
+
+// ServerID decodes the server ID from a connection ID.
+func (g *PlaintextGenerator) ServerID(connID []byte) ([]byte, error) {
+	if err := g.config.checkConnectionID(connID); err != nil {
+		return nil, err
+	}
+	return connID[1 : 1+len(g.config.ServerID)], nil
+}
+
+// A StreamCipherGenerator generates connection IDs using the stream cipher algorithm.
+// The connection ID consists of the first octet, the encrypted nonce, and the encrypted server ID.
+// The nonce and the server ID are encrypted using the three-pass algorithm described in the draft.
+type StreamCipherGenerator struct {
+	config Config
+	block  cipher.Block
+	rand   io.Reader
+}
+
+// NewStreamCipherGenerator creates a new StreamCipherGenerator.
+// The key must be 16 bytes long.
+func NewStreamCipherGenerator(config *Config, key []byte) (*StreamCipherGenerator, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if config.NonceLen < minStreamCipherNonceLen || config.NonceLen > maxStreamCipherNonceLen {
+		return nil, fmt.Errorf("invalid nonce length for the stream cipher algorithm: %d", config.NonceLen)
+	}
+	if len(key) != 16 {
+		return nil, fmt.Errorf("invalid key length: %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamCipherGenerator{config: *config, block: block, rand: rand.Reader}, nil
+}
+
+// GenerateConnectionID generates a new connection ID.
+func (g *StreamCipherGenerator) GenerateConnectionID() ([]byte, error) {
+	rnd := make([]byte, 1+g.config.NonceLen)
+	if _, err := io.ReadFull(g.rand, rnd); err != nil {
+		return nil, err
+	}
+	b := make([]byte, g.config.connIDLen())
+	b[0] = g.config.firstOctet(rnd[0])
+	nonce := b[1 : 1+g.config.NonceLen]
+	serverID := b[1+g.config.NonceLen:]
+	copy(nonce, rnd[1:])
+	copy(serverID, g.config.ServerID)
+	// pass 1: encrypt the server ID using the nonce
+	g.xorMask(serverID, nonce)
+	// pass 2: encrypt the nonce using the intermediate server ID
+	g.xorMask(nonce, serverID)
+	// pass 3: encrypt the intermediate server ID using the encrypted nonce
+	g.xorMask(serverID, nonce)
+	return b, nil
+}
+
+// ConnectionIDLen returns the length of the connection IDs.
+func (g *StreamCipherGenerator) ConnectionIDLen() int {
+	return g.config.connIDLen()
+}
+
+// ServerID decodes the server ID from a connection ID.
+func (g *StreamCipherGenerator) ServerID(connID []byte) ([]byte, error) {
+	if err := g.config.checkConnectionID(connID); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, g.config.NonceLen)
+	copy(nonce, connID[1:])
+	serverID := make([]byte, len(g.config.ServerID))
+	copy(serverID, connID[1+g.config.NonceLen:])
+	// The passes of the encryption are undone in reverse order.
+	g.xorMask(serverID, nonce)
+	g.xorMask(nonce, serverID)
+	g.xorMask(serverID, nonce)
+	return serverID, nil
+}
+
+// xorMask XORs dst with the AES-128-ECB encryption of the zero-padded src.
+func (g *StreamCipherGenerator) xorMask(dst, src []byte) {
+	var mask [aes.BlockSize]byte
+	copy(mask[:], src)
+	g.block.Encrypt(mask[:], mask[:])
+	for i := range dst {
+		dst[i] ^= mask[i]
+	}
+}
@@ -0,0 +1,190 @@
+package quiclb
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var (
+	_ quic.ConnectionIDGenerator = &PlaintextGenerator{}
+	_ quic.ConnectionIDGenerator = &StreamCipherGenerator{}
+)
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var _ = Describe("QUIC-LB", func() {
+	Context("config validation", func() {
+		It("rejects invalid config rotation codepoints", func() {
+			_, err := NewPlaintextGenerator(&Config{ConfigRotation: 3, ServerID: []byte{1}, NonceLen: 4})
+			Expect(err).To(MatchError("invalid config rotation codepoint: 3"))
+		})
+
+		It("requires a server ID", func() {
+			_, err := NewPlaintextGenerator(&Config{NonceLen: 4})
+			Expect(err).To(MatchError("server ID not set"))
+		})
+
+		It("rejects connection IDs longer than 20 bytes", func() {
+			_, err := NewPlaintextGenerator(&Config{ServerID: make([]byte, 10), NonceLen: 10})
+			Expect(err).To(MatchError("connection ID too long: 21 bytes"))
+		})
+
+		It("rejects invalid nonce lengths for the stream cipher algorithm", func() {
+			_, err := NewStreamCipherGenerator(&Config{ServerID: []byte{1}, NonceLen: 7}, make([]byte, 16))
+			Expect(err).To(MatchError("invalid nonce length for the stream cipher algorithm: 7"))
+			_, err = NewStreamCipherGenerator(&Config{ServerID: []byte{1}, NonceLen: 17}, make([]byte, 16))
+			Expect(err).To(MatchError("invalid nonce length for the stream cipher algorithm: 17"))
+		})
+
+		It("rejects invalid keys for the stream cipher algorithm", func() {
+			_, err := NewStreamCipherGenerator(&Config{ServerID: []byte{1}, NonceLen: 8}, make([]byte, 32))
+			Expect(err).To(MatchError("invalid key length: 32"))
+		})
+	})
+
+	Context("plaintext algorithm", func() {
+		It("generates connection IDs", func() {
+			g, err := NewPlaintextGenerator(&Config{
+				LengthSelfEncoding: true,
+				ServerID:           mustDecodeHex("abcd"),
+				NonceLen:           5,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			g.rand = bytes.NewReader(mustDecodeHex("ff0102030405"))
+			Expect(g.ConnectionIDLen()).To(Equal(8))
+			connID, err := g.GenerateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID).To(Equal(mustDecodeHex("07abcd0102030405")))
+			serverID, err := g.ServerID(connID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serverID).To(Equal(mustDecodeHex("abcd")))
+		})
+
+		It("uses random bits in the first octet, if length self-encoding is disabled", func() {
+			g, err := NewPlaintextGenerator(&Config{
+				ConfigRotation: 1,
+				ServerID:       mustDecodeHex("abcd"),
+				NonceLen:       5,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			g.rand = bytes.NewReader(mustDecodeHex("ea0102030405"))
+			connID, err := g.GenerateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID).To(Equal(mustDecodeHex("6aabcd0102030405")))
+		})
+
+		It("generates different connection IDs", func() {
+			g, err := NewPlaintextGenerator(&Config{ServerID: []byte{1, 2, 3}, NonceLen: 8})
+			Expect(err).ToNot(HaveOccurred())
+			c1, err := g.GenerateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			c2, err := g.GenerateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c1).ToNot(Equal(c2))
+		})
+	})
+
+	Context("stream cipher algorithm", func() {
+		vectors := []struct {
+			config *Config
+			key    string
+			rand   string
+			connID string
+		}{
+			// This test vector is taken from the draft (section B.2), which uses a nonce of zero.
+			{
+				config: &Config{LengthSelfEncoding: true, ServerID: mustDecodeHex("c5"), NonceLen: 12},
+				key:    "4d9d0fd25a25e7f321ef464e13f9fa3d",
+				rand:   "00000000000000000000000000",
+				connID: "0d69fe8ab8293680395ae256e89c",
+			},
+			// These test vectors were computed with an independent AES-128-ECB implementation.
+			{
+				config: &Config{ConfigRotation: 1, LengthSelfEncoding: true, ServerID: mustDecodeHex("314159"), NonceLen: 8},
+				key:    "000102030405060708090a0b0c0d0e0f",
+				rand:   "ff1011121314151617",
+				connID: "4b3e4aca4b12f65bf43ddcd9",
+			},
+			{
+				config: &Config{ConfigRotation: 2, ServerID: mustDecodeHex("deadbeef"), NonceLen: 12},
+				key:    "8f95f09245765f80256934e50c66207f",
+				rand:   "ffa0a1a2a3a4a5a6a7a8a9aaab",
+				connID: "bff0c1d25c3af4ee4dc8db341c1d3daf45",
+			},
+		}
+
+		for i := range vectors {
+			v := vectors[i]
+
+			It("generates connection IDs", func() {
+				g, err := NewStreamCipherGenerator(v.config, mustDecodeHex(v.key))
+				Expect(err).ToNot(HaveOccurred())
+				g.rand = bytes.NewReader(mustDecodeHex(v.rand))
+				connID, err := g.GenerateConnectionID()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(connID).To(Equal(mustDecodeHex(v.connID)))
+				Expect(g.ConnectionIDLen()).To(Equal(len(connID)))
+			})
+
+			It("decodes the server ID", func() {
+				g, err := NewStreamCipherGenerator(v.config, mustDecodeHex(v.key))
+				Expect(err).ToNot(HaveOccurred())
+				serverID, err := g.ServerID(mustDecodeHex(v.connID))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(serverID).To(Equal(v.config.ServerID))
+			})
+		}
+
+		It("encrypts the nonce", func() {
+			g, err := NewStreamCipherGenerator(&Config{ServerID: []byte{1, 2, 3}, NonceLen: 8}, make([]byte, 16))
+			Expect(err).ToNot(HaveOccurred())
+			g.rand = bytes.NewReader(mustDecodeHex("000102030405060708"))
+			connID, err := g.GenerateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID[1:9]).ToNot(Equal(mustDecodeHex("0102030405060708")))
+		})
+
+		It("doesn't include the server ID in plaintext", func() {
+			serverID := mustDecodeHex("0123456789")
+			g, err := NewStreamCipherGenerator(&Config{ServerID: serverID, NonceLen: 8}, make([]byte, 16))
+			Expect(err).ToNot(HaveOccurred())
+			connID, err := g.GenerateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bytes.Contains(connID, serverID)).To(BeFalse())
+			decoded, err := g.ServerID(connID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal(serverID))
+		})
+	})
+
+	Context("decoding", func() {
+		var g *PlaintextGenerator
+
+		BeforeEach(func() {
+			var err error
+			g, err = NewPlaintextGenerator(&Config{ConfigRotation: 1, ServerID: []byte{1, 2}, NonceLen: 4})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("rejects connection IDs with the wrong length", func() {
+			_, err := g.ServerID(mustDecodeHex("400102030405"))
+			Expect(err).To(MatchError("invalid connection ID length: 6"))
+		})
+
+		It("rejects connection IDs with a different config rotation codepoint", func() {
+			_, err := g.ServerID(mustDecodeHex("80010203040506"))
+			Expect(err).To(MatchError("connection ID uses config rotation codepoint 2, expected 1"))
+		})
+	})
+})
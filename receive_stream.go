@@ -35,6 +35,7 @@ type receiveStream struct {
 	currentFrameDone   func()
 	currentFrameIsLast bool // is the currentFrame the last frame on this stream
 	readPosInFrame     int
+	bytesRead          protocol.ByteCount
 
 	closeForShutdownErr error
 	cancelReadErr       error
@@ -170,6 +171,7 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 		bytesRead += m
 
 		s.mutex.Lock()
+		s.bytesRead += protocol.ByteCount(m)
 		// when a RESET_STREAM was received, the was already informed about the final byteOffset for this stream
 		if !s.resetRemotely {
 			s.flowController.AddBytesRead(protocol.ByteCount(m))
@@ -310,6 +312,19 @@ func (s *receiveStream) closeForShutdown(err error) {
 	s.signalRead()
 }
 
+func (s *receiveStream) Stats() StreamStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, outOfOrder := s.frameQueue.QueuedBytes()
+	return StreamStats{
+		BytesReceived:           s.frameQueue.HighestContiguousOffset() + outOfOrder,
+		BytesRead:               s.bytesRead,
+		BytesBufferedOutOfOrder: outOfOrder,
+		ReceiveWindow:           s.flowController.ReceiveWindowSize(),
+	}
+}
+
 func (s *receiveStream) getWindowUpdate() protocol.ByteCount {
 	return s.flowController.GetWindowUpdate()
 }
@@ -655,4 +655,35 @@ var _ = Describe("Receive Stream", func() {
 			Expect(str.getWindowUpdate()).To(Equal(protocol.ByteCount(0x100)))
 		})
 	})
+
+	Context("statistics", func() {
+		It("counts received, read and out-of-order bytes", func() {
+			mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), false).AnyTimes()
+			mockFC.EXPECT().AddBytesRead(gomock.Any()).AnyTimes()
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 0, Data: []byte("foo")})).To(Succeed())
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 6, Data: []byte("baz")})).To(Succeed())
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 10, Data: []byte("qux")})).To(Succeed())
+			// duplicate data is not counted
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 0, Data: []byte("foo")})).To(Succeed())
+			mockFC.EXPECT().ReceiveWindowSize().Return(protocol.ByteCount(1000))
+			stats := str.Stats()
+			Expect(stats.BytesReceived).To(Equal(protocol.ByteCount(9)))
+			Expect(stats.BytesRead).To(BeZero())
+			Expect(stats.BytesBufferedOutOfOrder).To(Equal(protocol.ByteCount(6)))
+			Expect(stats.ReceiveWindow).To(Equal(protocol.ByteCount(1000)))
+
+			b := make([]byte, 10)
+			n, err := strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(3))
+			// fill the first gap
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 3, Data: []byte("bar")})).To(Succeed())
+			mockFC.EXPECT().ReceiveWindowSize().Return(protocol.ByteCount(990))
+			stats = str.Stats()
+			Expect(stats.BytesReceived).To(Equal(protocol.ByteCount(12)))
+			Expect(stats.BytesRead).To(Equal(protocol.ByteCount(3)))
+			Expect(stats.BytesBufferedOutOfOrder).To(Equal(protocol.ByteCount(3)))
+			Expect(stats.ReceiveWindow).To(Equal(protocol.ByteCount(990)))
+		})
+	})
 })
@@ -1,10 +1,24 @@
 package quic
 
+import "github.com/lucas-clemente/quic-go/internal/protocol"
+
+// The sendQueue holds at most this many packets.
+// Sending blocks when the queue is full, so the session can't get ahead of the socket.
+const sendQueueCapacity = 1
+
+type queuedPacket struct {
+	buffer  *packetBuffer
+	ecn     protocol.ECN
+	isProbe bool // Path MTU probe packets might exceed the MTU of the local interface
+}
+
 type sendQueue struct {
-	queue       chan *packetBuffer
+	queue       chan queuedPacket
 	closeCalled chan struct{} // runStopped when Close() is called
 	runStopped  chan struct{} // runStopped when the run loop returns
 	conn        connection
+
+	batch [][]byte
 }
 
 func newSendQueue(conn connection) *sendQueue {
@@ -12,18 +26,28 @@ func newSendQueue(conn connection) *sendQueue {
 		conn:        conn,
 		runStopped:  make(chan struct{}),
 		closeCalled: make(chan struct{}),
-		queue:       make(chan *packetBuffer, 1),
+		queue:       make(chan queuedPacket, sendQueueCapacity),
+		batch:       make([][]byte, 0, sendQueueCapacity+1),
 	}
 	return s
 }
 
-func (h *sendQueue) Send(p *packetBuffer) {
-	h.queue <- p
+// Send queues a packet for sending.
+// The ECN bits are set to ecn, if the connection supports it.
+func (h *sendQueue) Send(p *packetBuffer, ecn protocol.ECN) {
+	h.queue <- queuedPacket{buffer: p, ecn: ecn}
+}
+
+// SendProbe queues a Path MTU probe packet for sending.
+// Unlike for other packets, failing to send it because it exceeds the MTU of the local interface is not an error.
+func (h *sendQueue) SendProbe(p *packetBuffer, ecn protocol.ECN) {
+	h.queue <- queuedPacket{buffer: p, ecn: ecn, isProbe: true}
 }
 
 func (h *sendQueue) Run() error {
 	defer close(h.runStopped)
 	var shouldClose bool
+	packets := make([]queuedPacket, 0, sendQueueCapacity+1)
 	for {
 		if shouldClose && len(h.queue) == 0 {
 			return nil
@@ -34,14 +58,62 @@ func (h *sendQueue) Run() error {
 			// make sure that all queued packets are actually sent out
 			shouldClose = true
 		case p := <-h.queue:
-			if err := h.conn.Write(p.Data); err != nil {
+			packets = append(packets[:0], p)
+			// Send the packets that were queued while the last batch was written in the same batch.
+			// Packets queued after this point are sent in the next batch.
+			for n := len(h.queue); n > 0; n-- {
+				packets = append(packets, <-h.queue)
+			}
+			if err := h.send(packets); err != nil {
 				return err
 			}
-			p.Release()
 		}
 	}
 }
 
+// send writes the packets, grouping consecutive packets with the same ECN marking.
+func (h *sendQueue) send(packets []queuedPacket) error {
+	defer func() {
+		for _, p := range packets {
+			p.buffer.Release()
+		}
+	}()
+	for i := 0; i < len(packets); {
+		j := i + 1
+		for j < len(packets) && packets[j].ecn == packets[i].ecn {
+			j++
+		}
+		if err := h.write(packets[i:j]); err != nil {
+			return err
+		}
+		i = j
+	}
+	return nil
+}
+
+// write writes packets that all have the same ECN marking.
+func (h *sendQueue) write(packets []queuedPacket) error {
+	h.batch = h.batch[:0]
+	for _, p := range packets {
+		h.batch = append(h.batch, p.buffer.Data)
+	}
+	batch := h.batch
+	for len(batch) > 0 {
+		n, err := h.conn.WritePackets(batch, packets[0].ecn)
+		if err == nil {
+			return nil
+		}
+		// Failing to send a Path MTU probe packet because it exceeds the MTU of the local interface
+		// is treated like a packet loss.
+		if !isMsgSizeErr(err) || !packets[n].isProbe {
+			return err
+		}
+		batch = batch[n+1:]
+		packets = packets[n+1:]
+	}
+	return nil
+}
+
 func (h *sendQueue) Close() {
 	close(h.closeCalled)
 	// wait until the run loop returned
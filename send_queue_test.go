@@ -1,6 +1,13 @@
 package quic
 
 import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -22,63 +29,155 @@ var _ = Describe("Send Queue", func() {
 		return buf
 	}
 
-	It("sends a packet", func() {
-		p := getPacket([]byte("foobar"))
-		q.Send(p)
-
-		written := make(chan struct{})
-		c.EXPECT().Write([]byte("foobar")).Do(func([]byte) { close(written) })
+	runQueue := func() chan struct{} {
 		done := make(chan struct{})
 		go func() {
 			defer GinkgoRecover()
 			q.Run()
 			close(done)
 		}()
+		return done
+	}
+
+	It("sends a packet", func() {
+		p := getPacket([]byte("foobar"))
+		q.Send(p, protocol.ECNNon)
+
+		written := make(chan struct{})
+		c.EXPECT().WritePackets([][]byte{[]byte("foobar")}, protocol.ECNNon).Do(func([][]byte, protocol.ECN) { close(written) })
+		done := runQueue()
 
 		Eventually(written).Should(BeClosed())
 		q.Close()
 		Eventually(done).Should(BeClosed())
 	})
 
-	It("blocks sending when too many packets are queued", func() {
-		q.Send(getPacket([]byte("foobar")))
+	It("writes a batch of packets", func() {
+		c.EXPECT().WritePackets([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")}, protocol.ECT0)
+		Expect(q.send([]queuedPacket{
+			{buffer: getPacket([]byte("foo")), ecn: protocol.ECT0},
+			{buffer: getPacket([]byte("bar")), ecn: protocol.ECT0},
+			{buffer: getPacket([]byte("baz")), ecn: protocol.ECT0},
+		})).To(Succeed())
+	})
+
+	It("splits the batch when the ECN marking changes", func() {
+		gomock.InOrder(
+			c.EXPECT().WritePackets([][]byte{[]byte("foo")}, protocol.ECNNon),
+			c.EXPECT().WritePackets([][]byte{[]byte("bar"), []byte("baz")}, protocol.ECT0),
+		)
+		Expect(q.send([]queuedPacket{
+			{buffer: getPacket([]byte("foo")), ecn: protocol.ECNNon},
+			{buffer: getPacket([]byte("bar")), ecn: protocol.ECT0},
+			{buffer: getPacket([]byte("baz")), ecn: protocol.ECT0},
+		})).To(Succeed())
+	})
+
+	It("skips probe packets that exceed the MTU of the local interface", func() {
+		msgSizeErr := &net.OpError{Op: "write", Err: os.NewSyscallError("sendmmsg", syscall.EMSGSIZE)}
+		gomock.InOrder(
+			c.EXPECT().WritePackets([][]byte{[]byte("foo"), []byte("probe"), []byte("bar")}, protocol.ECNNon).Return(1, msgSizeErr),
+			c.EXPECT().WritePackets([][]byte{[]byte("bar")}, protocol.ECNNon),
+		)
+		Expect(q.send([]queuedPacket{
+			{buffer: getPacket([]byte("foo")), ecn: protocol.ECNNon},
+			{buffer: getPacket([]byte("probe")), ecn: protocol.ECNNon, isProbe: true},
+			{buffer: getPacket([]byte("bar")), ecn: protocol.ECNNon},
+		})).To(Succeed())
+	})
+
+	It("returns the error when a non-probe packet exceeds the MTU of the local interface", func() {
+		msgSizeErr := &net.OpError{Op: "write", Err: os.NewSyscallError("sendmmsg", syscall.EMSGSIZE)}
+		c.EXPECT().WritePackets([][]byte{[]byte("foo"), []byte("bar")}, protocol.ECNNon).Return(1, msgSizeErr)
+		Expect(q.send([]queuedPacket{
+			{buffer: getPacket([]byte("foo")), ecn: protocol.ECNNon},
+			{buffer: getPacket([]byte("bar")), ecn: protocol.ECNNon},
+		})).To(MatchError(msgSizeErr))
+	})
+
+	It("sends probe packets", func() {
+		q.SendProbe(getPacket([]byte("probe")), protocol.ECNNon)
+		Expect(<-q.queue).To(And(
+			WithTransform(func(p queuedPacket) bool { return p.isProbe }, BeTrue()),
+			WithTransform(func(p queuedPacket) []byte { return p.buffer.Data }, Equal([]byte("probe"))),
+		))
+	})
+
+	It("returns write errors", func() {
+		testErr := errors.New("test error")
+		c.EXPECT().WritePackets(gomock.Any(), gomock.Any()).Return(0, testErr)
+		errChan := make(chan error)
+		go func() {
+			defer GinkgoRecover()
+			errChan <- q.Run()
+		}()
+		q.Send(getPacket([]byte("foobar")), protocol.ECNNon)
+		Eventually(errChan).Should(Receive(MatchError(testErr)))
+	})
+
+	It("blocks sending when a packet is queued", func() {
+		Expect(sendQueueCapacity).To(Equal(1))
+		q.Send(getPacket([]byte("foobar")), protocol.ECNNon)
 
 		written := make(chan []byte, 2)
-		c.EXPECT().Write(gomock.Any()).Do(func(p []byte) { written <- p }).Times(2)
+		c.EXPECT().WritePackets(gomock.Any(), gomock.Any()).Do(func(p [][]byte, _ protocol.ECN) {
+			for _, b := range p {
+				written <- append([]byte{}, b...)
+			}
+		}).MinTimes(1).MaxTimes(2)
 
 		sent := make(chan struct{})
 		go func() {
 			defer GinkgoRecover()
-			q.Send(getPacket([]byte("raboof")))
+			q.Send(getPacket([]byte("raboof")), protocol.ECNNon)
 			close(sent)
 		}()
 
 		Consistently(sent).ShouldNot(BeClosed())
 
-		done := make(chan struct{})
-		go func() {
-			defer GinkgoRecover()
-			q.Run()
-			close(done)
-		}()
+		done := runQueue()
 
 		Eventually(written).Should(Receive(Equal([]byte("foobar"))))
+		Eventually(sent).Should(BeClosed())
 		Eventually(written).Should(Receive(Equal([]byte("raboof"))))
 		q.Close()
 		Eventually(done).Should(BeClosed())
 	})
 
-	It("blocks Close() until the packet has been sent out", func() {
-		written := make(chan []byte)
-		c.EXPECT().Write(gomock.Any()).Do(func(p []byte) { written <- p })
-		done := make(chan struct{})
+	It("doesn't dequeue packets while writing a batch", func() {
+		unblock := make(chan struct{})
+		written := make(chan [][]byte, 3)
+		c.EXPECT().WritePackets(gomock.Any(), gomock.Any()).Do(func(p [][]byte, _ protocol.ECN) {
+			b := make([][]byte, len(p))
+			copy(b, p)
+			written <- b
+			<-unblock
+		}).AnyTimes()
+		done := runQueue()
+
+		q.Send(getPacket([]byte("foo")), protocol.ECNNon)
+		Eventually(written).Should(Receive(Equal([][]byte{[]byte("foo")})))
+		// the socket is busy writing the first packet
+		q.Send(getPacket([]byte("bar")), protocol.ECNNon)
+		sent := make(chan struct{})
 		go func() {
 			defer GinkgoRecover()
-			q.Run()
-			close(done)
+			q.Send(getPacket([]byte("baz")), protocol.ECNNon)
+			close(sent)
 		}()
+		Consistently(sent).ShouldNot(BeClosed())
+		close(unblock)
+		Eventually(sent).Should(BeClosed())
+		q.Close()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("blocks Close() until the packet has been sent out", func() {
+		written := make(chan [][]byte)
+		c.EXPECT().WritePackets(gomock.Any(), gomock.Any()).Do(func(p [][]byte, _ protocol.ECN) { written <- p })
+		done := runQueue()
 
-		q.Send(getPacket([]byte("foobar")))
+		q.Send(getPacket([]byte("foobar")), protocol.ECNNon)
 
 		closed := make(chan struct{})
 		go func() {
@@ -37,6 +37,14 @@ type sendStream struct {
 
 	writeOffset protocol.ByteCount
 
+	// statistics
+	bytesWritten       protocol.ByteCount
+	bytesAcked         protocol.ByteCount
+	bytesRetransmitted protocol.ByteCount
+	// set when no data could be sent due to flow control
+	blockedOnStreamFlowControl     bool
+	blockedOnConnectionFlowControl bool
+
 	cancelWriteErr      error
 	closeForShutdownErr error
 
@@ -52,6 +60,8 @@ type sendStream struct {
 	writeChan chan struct{}
 	deadline  time.Time
 
+	priority StreamPriority
+
 	flowController flowcontrol.StreamFlowController
 
 	version protocol.VersionNumber
@@ -71,6 +81,7 @@ func newSendStream(
 		sender:         sender,
 		flowController: flowController,
 		writeChan:      make(chan struct{}, 1),
+		priority:       defaultStreamPriority(),
 		version:        version,
 	}
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
@@ -130,6 +141,7 @@ func (s *sendStream) Write(p []byte) (int, error) {
 				s.nextFrame.Data = s.nextFrame.Data[:l+len(s.dataForWriting)]
 				copy(s.nextFrame.Data[l:], s.dataForWriting)
 			}
+			s.bytesWritten += protocol.ByteCount(len(s.dataForWriting))
 			s.dataForWriting = nil
 			bytesWritten = len(p)
 			copied = true
@@ -152,9 +164,10 @@ func (s *sendStream) Write(p []byte) (int, error) {
 			}
 		}
 
+		priority := s.priority
 		s.mutex.Unlock()
 		if !notifiedSender {
-			s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+			s.sender.onHasStreamData(s.streamID, priority) // must be called without holding the mutex
 			notifiedSender = true
 		}
 		if copied {
@@ -189,7 +202,7 @@ func (s *sendStream) canBufferStreamFrame() bool {
 	if s.nextFrame != nil {
 		l = s.nextFrame.DataLen()
 	}
-	return l+protocol.ByteCount(len(s.dataForWriting)) <= protocol.MaxReceivePacketSize
+	return l+protocol.ByteCount(len(s.dataForWriting)) <= protocol.MaxStreamFrameBufferSize
 }
 
 // popStreamFrame returns the next STREAM frame that is supposed to be sent on this stream
@@ -239,6 +252,8 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 	}
 
 	sendWindow := s.flowController.SendWindowSize()
+	s.blockedOnStreamFlowControl = sendWindow == 0 && s.flowController.StreamSendWindowSize() == 0
+	s.blockedOnConnectionFlowControl = sendWindow == 0 && !s.blockedOnStreamFlowControl
 	if sendWindow == 0 {
 		if isBlocked, offset := s.flowController.IsNewlyBlocked(); isBlocked {
 			s.sender.queueControlFrame(&wire.StreamDataBlockedFrame{
@@ -289,7 +304,9 @@ func (s *sendStream) popNewStreamFrame(maxBytes, sendWindow protocol.ByteCount)
 	f.DataLenPresent = true
 	f.Data = f.Data[:0]
 
-	hasMoreData := s.popNewStreamFrameWithoutBuffer(f, maxBytes, sendWindow)
+	// The STREAM frame can't be larger than its buffer.
+	// If the packet is larger (after Path MTU Discovery), the framer adds more STREAM frames for this stream.
+	hasMoreData := s.popNewStreamFrameWithoutBuffer(f, maxBytes, utils.MinByteCount(sendWindow, protocol.ByteCount(cap(f.Data))))
 	if len(f.Data) == 0 && !f.FinBit {
 		f.PutBack()
 		return nil, hasMoreData
@@ -311,9 +328,13 @@ func (s *sendStream) maybeGetRetransmission(maxBytes protocol.ByteCount) (*wire.
 	f := s.retransmissionQueue[0]
 	newFrame, needsSplit := f.MaybeSplitOffFrame(maxBytes, s.version)
 	if needsSplit {
+		if newFrame != nil {
+			s.bytesRetransmitted += newFrame.DataLen()
+		}
 		return newFrame, true
 	}
 	s.retransmissionQueue = s.retransmissionQueue[1:]
+	s.bytesRetransmitted += f.DataLen()
 	return f, len(s.retransmissionQueue) > 0
 }
 
@@ -328,12 +349,14 @@ func (s *sendStream) getDataForWriting(f *wire.StreamFrame, maxBytes protocol.By
 	if protocol.ByteCount(len(s.dataForWriting)) <= maxBytes {
 		f.Data = f.Data[:len(s.dataForWriting)]
 		copy(f.Data, s.dataForWriting)
+		s.bytesWritten += protocol.ByteCount(len(s.dataForWriting))
 		s.dataForWriting = nil
 		s.signalWrite()
 		return
 	}
 	f.Data = f.Data[:maxBytes]
 	copy(f.Data, s.dataForWriting)
+	s.bytesWritten += maxBytes
 	s.dataForWriting = s.dataForWriting[maxBytes:]
 	if s.canBufferStreamFrame() {
 		s.signalWrite()
@@ -341,9 +364,12 @@ func (s *sendStream) getDataForWriting(f *wire.StreamFrame, maxBytes protocol.By
 }
 
 func (s *sendStream) frameAcked(f wire.Frame) {
-	f.(*wire.StreamFrame).PutBack()
+	sf := f.(*wire.StreamFrame)
+	dataLen := sf.DataLen()
+	sf.PutBack()
 
 	s.mutex.Lock()
+	s.bytesAcked += dataLen
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
@@ -374,9 +400,10 @@ func (s *sendStream) queueRetransmission(f wire.Frame) {
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
 	}
+	priority := s.priority
 	s.mutex.Unlock()
 
-	s.sender.onHasStreamData(s.streamID)
+	s.sender.onHasStreamData(s.streamID, priority)
 }
 
 func (s *sendStream) Close() error {
@@ -391,9 +418,10 @@ func (s *sendStream) Close() error {
 	}
 	s.ctxCancel()
 	s.finishedWriting = true
+	priority := s.priority
 	s.mutex.Unlock()
 
-	s.sender.onHasStreamData(s.streamID) // need to send the FIN, must be called without holding the mutex
+	s.sender.onHasStreamData(s.streamID, priority) // need to send the FIN, must be called without holding the mutex
 	return nil
 }
 
@@ -428,11 +456,39 @@ func (s *sendStream) cancelWriteImpl(errorCode protocol.ApplicationErrorCode, wr
 func (s *sendStream) handleMaxStreamDataFrame(frame *wire.MaxStreamDataFrame) {
 	s.mutex.Lock()
 	hasStreamData := s.dataForWriting != nil || s.nextFrame != nil
+	priority := s.priority
+	s.flowController.UpdateSendWindow(frame.ByteOffset)
 	s.mutex.Unlock()
 
-	s.flowController.UpdateSendWindow(frame.ByteOffset)
 	if hasStreamData {
-		s.sender.onHasStreamData(s.streamID)
+		s.sender.onHasStreamData(s.streamID, priority)
+	}
+}
+
+func (s *sendStream) SetPriority(priority StreamPriority) {
+	s.mutex.Lock()
+	s.priority = priority
+	hasStreamData := s.dataForWriting != nil || s.nextFrame != nil
+	s.mutex.Unlock()
+
+	// reschedule the stream with the new priority
+	if hasStreamData {
+		s.sender.onHasStreamData(s.streamID, priority)
+	}
+}
+
+func (s *sendStream) Stats() StreamStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return StreamStats{
+		BytesWritten:                   s.bytesWritten,
+		BytesSent:                      s.writeOffset,
+		BytesAcked:                     s.bytesAcked,
+		BytesRetransmitted:             s.bytesRetransmitted,
+		SendWindow:                     s.flowController.StreamSendWindowSize(),
+		BlockedOnStreamFlowControl:     s.blockedOnStreamFlowControl,
+		BlockedOnConnectionFlowControl: s.blockedOnConnectionFlowControl,
 	}
 }
 
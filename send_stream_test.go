@@ -77,7 +77,7 @@ var _ = Describe("Send Stream", func() {
 			go func() {
 				defer GinkgoRecover()
 				defer close(done)
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				n, err := strWithTimeout.Write([]byte("foobar"))
 				Expect(err).ToNot(HaveOccurred())
 				Expect(n).To(Equal(6))
@@ -100,7 +100,7 @@ var _ = Describe("Send Stream", func() {
 			done := make(chan struct{})
 			go func() {
 				defer GinkgoRecover()
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				n, err := strWithTimeout.Write([]byte("foobar"))
 				Expect(err).ToNot(HaveOccurred())
 				Expect(n).To(Equal(6))
@@ -129,7 +129,7 @@ var _ = Describe("Send Stream", func() {
 			done := make(chan struct{})
 			go func() {
 				defer GinkgoRecover()
-				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority()).Times(2)
 				n, err := strWithTimeout.Write([]byte("foo"))
 				Expect(err).ToNot(HaveOccurred())
 				Expect(n).To(Equal(3))
@@ -155,7 +155,7 @@ var _ = Describe("Send Stream", func() {
 			done := make(chan struct{})
 			go func() {
 				defer GinkgoRecover()
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				n, err := strWithTimeout.Write(getData(5000))
 				Expect(err).ToNot(HaveOccurred())
 				Expect(n).To(Equal(5000))
@@ -174,13 +174,38 @@ var _ = Describe("Send Stream", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("doesn't pop STREAM frames larger than the STREAM frame buffer", func() {
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
+				_, err := strWithTimeout.Write(getData(5000))
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			waitForWrite()
+			frame, hasMoreData := str.popStreamFrame(5000)
+			Expect(hasMoreData).To(BeTrue())
+			Expect(frame.Frame.(*wire.StreamFrame).DataLen()).To(Equal(protocol.MaxStreamFrameBufferSize))
+			dataLen := frame.Frame.(*wire.StreamFrame).DataLen()
+			for hasMoreData {
+				frame, hasMoreData = str.popStreamFrame(5000)
+				Expect(frame.Frame.(*wire.StreamFrame).DataLen()).To(BeNumerically("<=", protocol.MaxStreamFrameBufferSize))
+				dataLen += frame.Frame.(*wire.StreamFrame).DataLen()
+			}
+			Expect(dataLen).To(BeEquivalentTo(5000))
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("unblocks Write as soon as a STREAM frame can be buffered", func() {
 			done := make(chan struct{})
 			go func() {
 				defer GinkgoRecover()
 				defer close(done)
-				mockSender.EXPECT().onHasStreamData(streamID)
-				_, err := strWithTimeout.Write(getData(protocol.MaxReceivePacketSize + 3))
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
+				_, err := strWithTimeout.Write(getData(protocol.MaxStreamFrameBufferSize + 3))
 				Expect(err).ToNot(HaveOccurred())
 			}()
 			waitForWrite()
@@ -200,15 +225,15 @@ var _ = Describe("Send Stream", func() {
 		})
 
 		It("only unblocks Write once a previously buffered STREAM frame has been fully dequeued", func() {
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			_, err := strWithTimeout.Write([]byte("foobar"))
 			Expect(err).ToNot(HaveOccurred())
 			done := make(chan struct{})
 			go func() {
 				defer GinkgoRecover()
 				defer close(done)
-				mockSender.EXPECT().onHasStreamData(streamID)
-				_, err := str.Write(getData(protocol.MaxReceivePacketSize))
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
+				_, err := str.Write(getData(protocol.MaxStreamFrameBufferSize))
 				Expect(err).ToNot(HaveOccurred())
 			}()
 			waitForWrite()
@@ -238,7 +263,7 @@ var _ = Describe("Send Stream", func() {
 			go func() {
 				defer GinkgoRecover()
 				defer close(done)
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				n, err := strWithTimeout.Write(bytes.Repeat([]byte{0}, 100))
 				Expect(err).ToNot(HaveOccurred())
 				Expect(n).To(Equal(100))
@@ -269,7 +294,7 @@ var _ = Describe("Send Stream", func() {
 			go func() {
 				defer GinkgoRecover()
 				defer close(done)
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				n, err := strWithTimeout.Write(s)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(n).To(Equal(3))
@@ -300,7 +325,7 @@ var _ = Describe("Send Stream", func() {
 		})
 
 		It("cancels the context when Close is called", func() {
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			Expect(str.Context().Done()).ToNot(BeClosed())
 			Expect(str.Close()).To(Succeed())
 			Expect(str.Context().Done()).To(BeClosed())
@@ -309,6 +334,7 @@ var _ = Describe("Send Stream", func() {
 		Context("flow control blocking", func() {
 			It("queues a BLOCKED frame if the stream is flow control blocked", func() {
 				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(0))
+				mockFC.EXPECT().StreamSendWindowSize().Return(protocol.ByteCount(0)).Times(2)
 				mockFC.EXPECT().IsNewlyBlocked().Return(true, protocol.ByteCount(12))
 				mockSender.EXPECT().queueControlFrame(&wire.StreamDataBlockedFrame{
 					StreamID:  streamID,
@@ -318,7 +344,7 @@ var _ = Describe("Send Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					defer close(done)
-					mockSender.EXPECT().onHasStreamData(streamID)
+					mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 					_, err := str.Write([]byte("foobar"))
 					Expect(err).ToNot(HaveOccurred())
 				}()
@@ -326,6 +352,34 @@ var _ = Describe("Send Stream", func() {
 				f, hasMoreData := str.popStreamFrame(1000)
 				Expect(f).To(BeNil())
 				Expect(hasMoreData).To(BeFalse())
+				stats := str.Stats()
+				Expect(stats.BlockedOnStreamFlowControl).To(BeTrue())
+				Expect(stats.BlockedOnConnectionFlowControl).To(BeFalse())
+				// make the Write go routine return
+				str.closeForShutdown(nil)
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("notices when it is blocked by connection-level flow control", func() {
+				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(0))
+				mockFC.EXPECT().StreamSendWindowSize().Return(protocol.ByteCount(100)).Times(2)
+				mockFC.EXPECT().IsNewlyBlocked()
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
+					_, err := str.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				waitForWrite()
+				f, hasMoreData := str.popStreamFrame(1000)
+				Expect(f).To(BeNil())
+				Expect(hasMoreData).To(BeTrue())
+				stats := str.Stats()
+				Expect(stats.BlockedOnStreamFlowControl).To(BeFalse())
+				Expect(stats.BlockedOnConnectionFlowControl).To(BeTrue())
+				Expect(stats.SendWindow).To(Equal(protocol.ByteCount(100)))
 				// make the Write go routine return
 				str.closeForShutdown(nil)
 				Eventually(done).Should(BeClosed())
@@ -336,7 +390,7 @@ var _ = Describe("Send Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					defer close(done)
-					mockSender.EXPECT().onHasStreamData(streamID)
+					mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 					_, err := str.Write([]byte("foobar"))
 					Expect(err).ToNot(HaveOccurred())
 				}()
@@ -351,6 +405,7 @@ var _ = Describe("Send Stream", func() {
 
 				// try to pop again, this time noticing that we're blocked
 				mockFC.EXPECT().SendWindowSize()
+				mockFC.EXPECT().StreamSendWindowSize()
 				// don't use offset 3 here, to make sure the BLOCKED frame contains the number returned by the flow controller
 				mockFC.EXPECT().IsNewlyBlocked().Return(true, protocol.ByteCount(10))
 				mockSender.EXPECT().queueControlFrame(&wire.StreamDataBlockedFrame{
@@ -375,7 +430,7 @@ var _ = Describe("Send Stream", func() {
 			})
 
 			It("unblocks after the deadline", func() {
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
 				str.SetWriteDeadline(deadline)
 				n, err := strWithTimeout.Write(getData(5000))
@@ -385,7 +440,7 @@ var _ = Describe("Send Stream", func() {
 			})
 
 			It("unblocks when the deadline is changed to the past", func() {
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				str.SetWriteDeadline(time.Now().Add(time.Hour))
 				done := make(chan struct{})
 				go func() {
@@ -409,7 +464,7 @@ var _ = Describe("Send Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					defer close(writeReturned)
-					mockSender.EXPECT().onHasStreamData(streamID)
+					mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 					var err error
 					n, err = strWithTimeout.Write(getData(5000))
 					Expect(err).To(MatchError(errDeadline))
@@ -432,7 +487,7 @@ var _ = Describe("Send Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					defer close(writeReturned)
-					mockSender.EXPECT().onHasStreamData(streamID)
+					mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 					_, err := strWithTimeout.Write(getData(5000))
 					Expect(err).To(MatchError(errDeadline))
 				}()
@@ -447,7 +502,7 @@ var _ = Describe("Send Stream", func() {
 			})
 
 			It("doesn't unblock if the deadline is changed before the first one expires", func() {
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				deadline1 := time.Now().Add(scaleDuration(50 * time.Millisecond))
 				deadline2 := time.Now().Add(scaleDuration(100 * time.Millisecond))
 				str.SetWriteDeadline(deadline1)
@@ -469,7 +524,7 @@ var _ = Describe("Send Stream", func() {
 			})
 
 			It("unblocks earlier, when a new deadline is set", func() {
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				deadline1 := time.Now().Add(scaleDuration(200 * time.Millisecond))
 				deadline2 := time.Now().Add(scaleDuration(50 * time.Millisecond))
 				done := make(chan struct{})
@@ -490,7 +545,7 @@ var _ = Describe("Send Stream", func() {
 			})
 
 			It("doesn't unblock if the deadline is removed", func() {
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
 				str.SetWriteDeadline(deadline)
 				deadlineUnset := make(chan struct{})
@@ -520,14 +575,14 @@ var _ = Describe("Send Stream", func() {
 
 		Context("closing", func() {
 			It("doesn't allow writes after it has been closed", func() {
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				str.Close()
 				_, err := strWithTimeout.Write([]byte("foobar"))
 				Expect(err).To(MatchError("write on closed stream 1337"))
 			})
 
 			It("allows FIN", func() {
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				str.Close()
 				frame, hasMoreData := str.popStreamFrame(1000)
 				Expect(frame).ToNot(BeNil())
@@ -540,7 +595,7 @@ var _ = Describe("Send Stream", func() {
 
 			It("doesn't send a FIN when there's still data", func() {
 				const frameHeaderLen protocol.ByteCount = 4
-				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority()).Times(2)
 				_, err := strWithTimeout.Write([]byte("foobar"))
 				Expect(err).ToNot(HaveOccurred())
 				Expect(str.Close()).To(Succeed())
@@ -562,10 +617,10 @@ var _ = Describe("Send Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					defer close(done)
-					mockSender.EXPECT().onHasStreamData(streamID)
+					mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 					_, err := strWithTimeout.Write(getData(5000))
 					Expect(err).ToNot(HaveOccurred())
-					mockSender.EXPECT().onHasStreamData(streamID)
+					mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 					Expect(str.Close()).To(Succeed())
 				}()
 				waitForWrite()
@@ -596,7 +651,7 @@ var _ = Describe("Send Stream", func() {
 			})
 
 			It("doesn't allow FIN twice", func() {
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				str.Close()
 				frame, _ := str.popStreamFrame(1000)
 				Expect(frame).ToNot(BeNil())
@@ -622,7 +677,7 @@ var _ = Describe("Send Stream", func() {
 			It("doesn't get data for writing if an error occurred", func() {
 				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
 				mockFC.EXPECT().AddBytesSent(gomock.Any())
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				done := make(chan struct{})
 				go func() {
 					defer GinkgoRecover()
@@ -660,7 +715,7 @@ var _ = Describe("Send Stream", func() {
 
 		It("says when it has data for sending", func() {
 			mockFC.EXPECT().UpdateSendWindow(gomock.Any())
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			done := make(chan struct{})
 			go func() {
 				defer GinkgoRecover()
@@ -669,7 +724,7 @@ var _ = Describe("Send Stream", func() {
 				close(done)
 			}()
 			waitForWrite()
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			str.handleMaxStreamDataFrame(&wire.MaxStreamDataFrame{
 				StreamID:   streamID,
 				ByteOffset: 42,
@@ -680,6 +735,36 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("priorities", func() {
+		It("uses the priority when it has data for sending", func() {
+			prio := StreamPriority{Urgency: 1}
+			str.SetPriority(prio) // the stream doesn't have any data yet
+			mockSender.EXPECT().onHasStreamData(streamID, prio)
+			Expect(str.Close()).To(Succeed())
+			mockSender.EXPECT().onHasStreamData(streamID, prio)
+			str.numOutstandingFrames++
+			str.queueRetransmission(&wire.StreamFrame{StreamID: streamID, FinBit: true})
+		})
+
+		It("reschedules the stream when the priority changes while it has data for sending", func() {
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			waitForWrite()
+			prio := StreamPriority{Urgency: 5, Incremental: true}
+			mockSender.EXPECT().onHasStreamData(streamID, prio)
+			str.SetPriority(prio)
+			// make sure the Write go routine returns
+			str.closeForShutdown(nil)
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
 	Context("stream cancellations", func() {
 		Context("canceling writing", func() {
 			It("queues a RESET_STREAM frame", func() {
@@ -700,7 +785,7 @@ var _ = Describe("Send Stream", func() {
 			// for reliable results it has to be run many times.
 			It("returns a nil error when the whole slice has been sent out", func() {
 				mockSender.EXPECT().queueControlFrame(gomock.Any()).MaxTimes(1)
-				mockSender.EXPECT().onHasStreamData(streamID).MaxTimes(1)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority()).MaxTimes(1)
 				mockSender.EXPECT().onStreamCompleted(streamID).MaxTimes(1)
 				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).MaxTimes(1)
 				mockFC.EXPECT().AddBytesSent(gomock.Any()).MaxTimes(1)
@@ -723,7 +808,7 @@ var _ = Describe("Send Stream", func() {
 
 			It("unblocks Write", func() {
 				mockSender.EXPECT().queueControlFrame(gomock.Any())
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
 				mockFC.EXPECT().AddBytesSent(gomock.Any())
 				writeReturned := make(chan struct{})
@@ -745,7 +830,7 @@ var _ = Describe("Send Stream", func() {
 
 			It("doesn't pop STREAM frames after being canceled", func() {
 				mockSender.EXPECT().queueControlFrame(gomock.Any())
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
 				mockFC.EXPECT().AddBytesSent(gomock.Any())
 				writeReturned := make(chan struct{})
@@ -767,7 +852,7 @@ var _ = Describe("Send Stream", func() {
 
 			It("doesn't pop STREAM frames after being canceled, for large writes", func() {
 				mockSender.EXPECT().queueControlFrame(gomock.Any())
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
 				mockFC.EXPECT().AddBytesSent(gomock.Any())
 				writeReturned := make(chan struct{})
@@ -812,7 +897,7 @@ var _ = Describe("Send Stream", func() {
 			})
 
 			It("queues a RESET_STREAM frame, even if the stream was already closed", func() {
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
 					Expect(f).To(BeAssignableToTypeOf(&wire.ResetStreamFrame{}))
 				})
@@ -838,7 +923,7 @@ var _ = Describe("Send Stream", func() {
 			})
 
 			It("unblocks Write", func() {
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				mockSender.EXPECT().queueControlFrame(gomock.Any())
 				mockSender.EXPECT().onStreamCompleted(gomock.Any())
 				done := make(chan struct{})
@@ -883,7 +968,7 @@ var _ = Describe("Send Stream", func() {
 				Offset:         0x42,
 				DataLenPresent: false,
 			}
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			str.queueRetransmission(f)
 			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
 			Expect(frame).ToNot(BeNil())
@@ -900,7 +985,7 @@ var _ = Describe("Send Stream", func() {
 				Offset:         0x42,
 				DataLenPresent: false,
 			}
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			str.queueRetransmission(sf)
 			frame, hasMoreData := str.popStreamFrame(sf.Length(str.version) - 3)
 			Expect(frame).ToNot(BeNil())
@@ -924,7 +1009,7 @@ var _ = Describe("Send Stream", func() {
 				Offset:         0x42,
 				DataLenPresent: false,
 			}
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			str.queueRetransmission(f)
 			frame, hasMoreData := str.popStreamFrame(2)
 			Expect(hasMoreData).To(BeTrue())
@@ -932,7 +1017,7 @@ var _ = Describe("Send Stream", func() {
 		})
 
 		It("queues lost STREAM frames", func() {
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999))
 			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
 			done := make(chan struct{})
@@ -949,7 +1034,7 @@ var _ = Describe("Send Stream", func() {
 			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
 
 			// now lose the frame
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			frame.OnLost(frame.Frame)
 			newFrame, _ := str.popStreamFrame(protocol.MaxByteCount)
 			Expect(newFrame).ToNot(BeNil())
@@ -963,7 +1048,7 @@ var _ = Describe("Send Stream", func() {
 				Offset:         0x42,
 				DataLenPresent: false,
 			}
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			str.queueRetransmission(f)
 			mockSender.EXPECT().queueControlFrame(gomock.Any())
 			str.CancelWrite(0)
@@ -973,6 +1058,80 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("statistics", func() {
+		It("counts written, sent, acknowledged and retransmitted bytes", func() {
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			waitForWrite()
+			Eventually(done).Should(BeClosed())
+			mockFC.EXPECT().StreamSendWindowSize().Return(protocol.ByteCount(1000))
+			stats := str.Stats()
+			Expect(stats.BytesWritten).To(Equal(protocol.ByteCount(6)))
+			Expect(stats.BytesSent).To(BeZero())
+			Expect(stats.SendWindow).To(Equal(protocol.ByteCount(1000)))
+
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999)).Times(2)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3)).Times(2)
+			frame1, _ := str.popStreamFrame(expectedFrameHeaderLen(0) + 3)
+			Expect(frame1).ToNot(BeNil())
+			frame2, _ := str.popStreamFrame(expectedFrameHeaderLen(3) + 3)
+			Expect(frame2).ToNot(BeNil())
+			mockFC.EXPECT().StreamSendWindowSize().Return(protocol.ByteCount(994))
+			stats = str.Stats()
+			Expect(stats.BytesSent).To(Equal(protocol.ByteCount(6)))
+			Expect(stats.SendWindow).To(Equal(protocol.ByteCount(994)))
+
+			// lose the first frame, and acknowledge the second one
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
+			frame1.OnLost(frame1.Frame)
+			frame2.OnAcked(frame2.Frame)
+			retransmission, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(retransmission).ToNot(BeNil())
+			mockFC.EXPECT().StreamSendWindowSize()
+			stats = str.Stats()
+			Expect(stats.BytesSent).To(Equal(protocol.ByteCount(6)))
+			Expect(stats.BytesAcked).To(Equal(protocol.ByteCount(3)))
+			Expect(stats.BytesRetransmitted).To(Equal(protocol.ByteCount(3)))
+			Expect(stats.BlockedOnStreamFlowControl).To(BeFalse())
+			Expect(stats.BlockedOnConnectionFlowControl).To(BeFalse())
+		})
+
+		It("counts data that is sent directly from the Write call", func() {
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := strWithTimeout.Write(getData(5000))
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			waitForWrite()
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+			frame, _ := str.popStreamFrame(1000)
+			Expect(frame).ToNot(BeNil())
+			mockFC.EXPECT().StreamSendWindowSize().AnyTimes()
+			stats := str.Stats()
+			Expect(stats.BytesSent).To(Equal(frame.Frame.(*wire.StreamFrame).DataLen()))
+			// the rest of the data was either sent or copied into the stream
+			for {
+				if f, _ := str.popStreamFrame(1000); f == nil {
+					break
+				}
+			}
+			Eventually(done).Should(BeClosed())
+			stats = str.Stats()
+			Expect(stats.BytesWritten).To(Equal(protocol.ByteCount(5000)))
+			Expect(stats.BytesSent).To(Equal(protocol.ByteCount(5000)))
+		})
+	})
+
 	Context("determining when a stream is completed", func() {
 		BeforeEach(func() {
 			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
@@ -980,7 +1139,7 @@ var _ = Describe("Send Stream", func() {
 		})
 
 		It("says when a stream is completed", func() {
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			done := make(chan struct{})
 			go func() {
 				defer GinkgoRecover()
@@ -1011,7 +1170,7 @@ var _ = Describe("Send Stream", func() {
 			}
 
 			// Now close the stream and acknowledge the FIN.
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			Expect(str.Close()).To(Succeed())
 			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
 			Expect(frame).ToNot(BeNil())
@@ -1020,7 +1179,7 @@ var _ = Describe("Send Stream", func() {
 		})
 
 		It("says when a stream is completed, if Close() is called before popping the frame", func() {
-			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority()).Times(2)
 			done := make(chan struct{})
 			go func() {
 				defer GinkgoRecover()
@@ -1042,13 +1201,13 @@ var _ = Describe("Send Stream", func() {
 		})
 
 		It("doesn't say it's completed when there are frames waiting to be retransmitted", func() {
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			done := make(chan struct{})
 			go func() {
 				defer GinkgoRecover()
 				_, err := strWithTimeout.Write(getData(100))
 				Expect(err).ToNot(HaveOccurred())
-				mockSender.EXPECT().onHasStreamData(streamID)
+				mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 				Expect(str.Close()).To(Succeed())
 				close(done)
 			}()
@@ -1072,7 +1231,7 @@ var _ = Describe("Send Stream", func() {
 			for _, f := range frames[1:] {
 				f.OnAcked(f.Frame)
 			}
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority())
 			frames[0].OnLost(frames[0].Frame)
 
 			// get the retransmission and acknowledge it
@@ -1088,7 +1247,7 @@ var _ = Describe("Send Stream", func() {
 		// and has to be retransmitted.
 		It("retransmits data until everything has been acknowledged", func() {
 			const dataLen = 1 << 22 // 4 MB
-			mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+			mockSender.EXPECT().onHasStreamData(streamID, defaultStreamPriority()).AnyTimes()
 			mockFC.EXPECT().SendWindowSize().DoAndReturn(func() protocol.ByteCount {
 				return protocol.ByteCount(mrand.Intn(500)) + 50
 			}).AnyTimes()
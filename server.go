@@ -53,6 +53,12 @@ type quicSession interface {
 
 // A Listener of QUIC
 type baseServer struct {
+	// counters exposed by Stats, to be used as atomics
+	// They are placed first, so that they're 64 bit aligned on 32 bit platforms.
+	retriesSent uint64
+	refused     uint64
+	rateLimited uint64
+
 	mutex sync.Mutex
 
 	acceptEarlySessions bool
@@ -64,6 +70,10 @@ type baseServer struct {
 	// If the server is started with ListenAddr, we create a packet conn.
 	// If it is started with Listen, we take a packet conn as a parameter.
 	createdPacketConn bool
+	// set if the DF bit was set on the packet conn, which enables Path MTU Discovery
+	supportsDF bool
+	// set if the ECN bits can be set and read on the connection
+	supportsECN bool
 
 	tokenGenerator *handshake.TokenGenerator
 
@@ -99,6 +109,16 @@ type baseServer struct {
 	sessionQueue    chan quicSession
 	sessionQueueLen int32 // to be used as an atomic
 
+	numHandshakes int32 // to be used as an atomic
+
+	sessionsMutex sync.Mutex
+	// all sessions created by this server that haven't been closed yet
+	sessions     map[quicSession]struct{}
+	shuttingDown bool
+	drained      chan struct{} // closed when the server is shutting down and all sessions are closed
+	// nil if handshakes are not rate limited
+	rateLimiter *handshakeRateLimiter
+
 	logger utils.Logger
 }
 
@@ -172,22 +192,30 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 		return nil, errors.New("quic: tls.Config not set")
 	}
 	config = populateServerConfig(config)
+	if config.ConnectionIDGenerator != nil && config.ConnectionIDGenerator.ConnectionIDLen() != config.ConnectionIDLength {
+		return nil, fmt.Errorf("quic: ConnectionIDLength (%d) doesn't match the length of the ConnectionIDGenerator (%d)", config.ConnectionIDLength, config.ConnectionIDGenerator.ConnectionIDLen())
+	}
 	for _, v := range config.Versions {
 		if !protocol.IsValidVersion(v) {
 			return nil, fmt.Errorf("%s is not a valid QUIC version", v)
 		}
 	}
+	if config.HandshakeRateLimit != nil && config.HandshakeRateLimit.Rate <= 0 {
+		return nil, fmt.Errorf("quic: invalid handshake rate: %f", config.HandshakeRateLimit.Rate)
+	}
 
-	sessionHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDLength, config.StatelessResetKey)
+	sessionHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDLength, protocol.ByteCount(config.MaxUDPPayloadSize), config.StatelessResetKey)
 	if err != nil {
 		return nil, err
 	}
-	tokenGenerator, err := handshake.NewTokenGenerator()
+	tokenGenerator, err := handshake.NewTokenGenerator(config.TokenKeys)
 	if err != nil {
 		return nil, err
 	}
 	s := &baseServer{
 		conn:                conn,
+		supportsDF:          !config.DisablePathMTUDiscovery && setDF(conn),
+		supportsECN:         setReceiveECN(conn),
 		tlsConf:             tlsConf,
 		config:              config,
 		tokenGenerator:      tokenGenerator,
@@ -197,10 +225,15 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 		errorChan:           make(chan struct{}),
 		running:             make(chan struct{}),
 		receivedPackets:     make(chan *receivedPacket, protocol.MaxServerUnprocessedPackets),
+		sessions:            make(map[quicSession]struct{}),
+		drained:             make(chan struct{}),
 		newSession:          newSession,
 		logger:              utils.DefaultLogger.WithPrefix("server"),
 		acceptEarlySessions: acceptEarly,
 	}
+	if config.HandshakeRateLimit != nil {
+		s.rateLimiter = newHandshakeRateLimiter(config.HandshakeRateLimit)
+	}
 	go s.run()
 	sessionHandler.SetServer(s)
 	s.logger.Debugf("Listening for %s connections on %s", conn.LocalAddr().Network(), conn.LocalAddr().String())
@@ -264,6 +297,73 @@ func (s *baseServer) accept(ctx context.Context) (quicSession, error) {
 	}
 }
 
+// Stats returns counters that can be used for monitoring the server.
+func (s *baseServer) Stats() ListenerStats {
+	return ListenerStats{
+		Sessions:    s.numSessions(),
+		Handshakes:  int(atomic.LoadInt32(&s.numHandshakes)),
+		RetriesSent: atomic.LoadUint64(&s.retriesSent),
+		Refused:     atomic.LoadUint64(&s.refused),
+		RateLimited: atomic.LoadUint64(&s.rateLimited),
+	}
+}
+
+// Shutdown gracefully shuts down the server.
+// New connection attempts are refused, while existing sessions keep running.
+// Sessions that are still in the handshake are returned by Accept once they're ready.
+// Shutdown waits until all sessions have been closed, and then closes the server.
+// If the context expires first, the remaining sessions are closed with the given error code and reason,
+// and the context's error is returned.
+func (s *baseServer) Shutdown(ctx context.Context, code ErrorCode, desc string) error {
+	s.sessionsMutex.Lock()
+	if !s.shuttingDown {
+		s.logger.Debugf("Shutting down. Waiting for %d sessions to close.", len(s.sessions))
+		s.shuttingDown = true
+		if len(s.sessions) == 0 {
+			close(s.drained)
+		}
+	}
+	s.sessionsMutex.Unlock()
+
+	select {
+	case <-s.drained:
+		return s.Close()
+	case <-ctx.Done():
+	}
+
+	s.sessionsMutex.Lock()
+	sessions := make([]quicSession, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.sessionsMutex.Unlock()
+	s.logger.Debugf("Shutdown timed out. Closing %d sessions.", len(sessions))
+	var wg sync.WaitGroup
+	wg.Add(len(sessions))
+	for _, sess := range sessions {
+		go func(sess quicSession) {
+			defer wg.Done()
+			// blocks until the CONNECTION_CLOSE has been sent and the run-loop has stopped
+			sess.CloseWithError(code, desc)
+		}(sess)
+	}
+	wg.Wait()
+	s.Close()
+	return ctx.Err()
+}
+
+func (s *baseServer) isShuttingDown() bool {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+	return s.shuttingDown
+}
+
+func (s *baseServer) numSessions() int {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+	return len(s.sessions)
+}
+
 // Close the server
 func (s *baseServer) Close() error {
 	s.mutex.Lock()
@@ -314,7 +414,11 @@ func (s *baseServer) handlePacket(p *receivedPacket) {
 func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* should the buffer be released */ {
 	// If we're creating a new session, the packet will be passed to the session.
 	// The header will then be parsed again.
-	hdr, _, _, err := wire.ParsePacket(p.data, s.config.ConnectionIDLength)
+	parsePacket := wire.ParsePacket
+	if s.config.GreaseQUICBit {
+		parsePacket = wire.ParseGreasedPacket
+	}
+	hdr, _, _, err := parsePacket(p.data, s.config.ConnectionIDLength)
 	if err != nil && err != wire.ErrUnsupportedVersion {
 		s.logger.Debugf("Error parsing packet: %s", err)
 		return false
@@ -361,6 +465,12 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		return errors.New("too short connection ID")
 	}
 
+	if s.isShuttingDown() {
+		s.logger.Debugf("Rejecting new connection. Server is shutting down.")
+		s.refuseConnection(p, hdr, &s.refused)
+		return nil
+	}
+
 	var (
 		token                *Token
 		retrySrcConnectionID *protocol.ConnectionID
@@ -370,9 +480,10 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		c, err := s.tokenGenerator.DecodeToken(hdr.Token)
 		if err == nil {
 			token = &Token{
-				IsRetryToken: c.IsRetryToken,
-				RemoteAddr:   c.RemoteAddr,
-				SentTime:     c.SentTime,
+				IsRetryToken:    c.IsRetryToken,
+				RemoteAddr:      c.RemoteAddr,
+				SentTime:        c.SentTime,
+				ApplicationData: c.ApplicationData,
 			}
 			if token.IsRetryToken {
 				origDestConnectionID = c.OriginalDestConnectionID
@@ -380,7 +491,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 			}
 		}
 	}
-	if !s.config.AcceptToken(p.remoteAddr, token) {
+	if !s.config.AcceptToken(p.remoteAddr, token) || s.requireAddressValidation(p.remoteAddr, token) {
 		go func() {
 			defer p.buffer.Release()
 			if token != nil && token.IsRetryToken {
@@ -398,15 +509,26 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 
 	if queueLen := atomic.LoadInt32(&s.sessionQueueLen); queueLen >= protocol.MaxAcceptQueueSize {
 		s.logger.Debugf("Rejecting new connection. Server currently busy. Accept queue length: %d (max %d)", queueLen, protocol.MaxAcceptQueueSize)
-		go func() {
-			if err := s.sendConnectionRefused(p.remoteAddr, hdr); err != nil {
-				s.logger.Debugf("Error rejecting connection: %s", err)
-			}
-		}()
+		s.refuseConnection(p, hdr, &s.refused)
+		return nil
+	}
+	if numSessions := s.numSessions(); s.config.MaxSessions > 0 && numSessions >= s.config.MaxSessions {
+		s.logger.Debugf("Rejecting new connection. Too many sessions: %d (max %d)", numSessions, s.config.MaxSessions)
+		s.refuseConnection(p, hdr, &s.refused)
+		return nil
+	}
+	if numHandshakes := atomic.LoadInt32(&s.numHandshakes); s.config.MaxHandshakes > 0 && int(numHandshakes) >= s.config.MaxHandshakes {
+		s.logger.Debugf("Rejecting new connection. Too many handshakes in progress: %d (max %d)", numHandshakes, s.config.MaxHandshakes)
+		s.refuseConnection(p, hdr, &s.refused)
+		return nil
+	}
+	if s.rateLimiter != nil && !s.rateLimiter.Allow(p.remoteAddr, time.Now()) {
+		s.logger.Debugf("Rejecting new connection from %s. Handshake rate limit exceeded.", p.remoteAddr)
+		s.refuseConnection(p, hdr, &s.rateLimited)
 		return nil
 	}
 
-	connID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	connID, err := generateServerConnectionID(s.config.ConnectionIDGenerator, s.config.ConnectionIDLength)
 	if err != nil {
 		return err
 	}
@@ -444,6 +566,13 @@ func (s *baseServer) createNewSession(
 	srcConnID protocol.ConnectionID,
 	version protocol.VersionNumber,
 ) quicSession {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+	// Shutdown might have been called after handleInitialImpl checked.
+	// Drop the packet. The client will retransmit its Initial, and then be refused.
+	if s.shuttingDown {
+		return nil
+	}
 	var sess quicSession
 	if added := s.sessionHandler.AddWithConnID(clientDestConnID, srcConnID, func() packetHandler {
 		var tracer logging.ConnectionTracer
@@ -456,7 +585,7 @@ func (s *baseServer) createNewSession(
 			tracer = s.config.Tracer.TracerForServer(connID)
 		}
 		sess = s.newSession(
-			&conn{pconn: s.conn, currentAddr: remoteAddr},
+			&conn{pconn: s.conn, currentAddr: remoteAddr, supportsDF: s.supportsDF, supportsECN: s.supportsECN},
 			s.sessionHandler,
 			origDestConnID,
 			retrySrcConnID,
@@ -476,13 +605,57 @@ func (s *baseServer) createNewSession(
 	}); !added {
 		return nil
 	}
+	s.sessions[sess] = struct{}{}
+	atomic.AddInt32(&s.numHandshakes, 1)
 	go sess.run()
 	go s.handleNewSession(sess)
 	return sess
 }
 
+// requireAddressValidation says if a Retry needs to be sent because too many handshakes are in progress.
+// This is the case if the client didn't present a token that would be accepted by the default token validation.
+func (s *baseServer) requireAddressValidation(remoteAddr net.Addr, token *Token) bool {
+	if s.config.RetryThreshold <= 0 || int(atomic.LoadInt32(&s.numHandshakes)) < s.config.RetryThreshold {
+		return false
+	}
+	if defaultAcceptToken(remoteAddr, token) {
+		return false
+	}
+	s.logger.Debugf("Too many handshakes in progress. Validating the address of %s.", remoteAddr)
+	return true
+}
+
+func (s *baseServer) refuseConnection(p *receivedPacket, hdr *wire.Header, counter *uint64) {
+	atomic.AddUint64(counter, 1)
+	go func() {
+		defer p.buffer.Release()
+		if err := s.sendConnectionRefused(p.remoteAddr, hdr); err != nil {
+			s.logger.Debugf("Error rejecting connection: %s", err)
+		}
+	}()
+}
+
+// trackSession keeps the session and handshake counters up to date.
+func (s *baseServer) trackSession(sess quicSession, sessCtx, handshakeCtx context.Context) {
+	select {
+	case <-handshakeCtx.Done():
+	case <-sessCtx.Done():
+	}
+	atomic.AddInt32(&s.numHandshakes, -1)
+	<-sessCtx.Done()
+
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+	delete(s.sessions, sess)
+	if s.shuttingDown && len(s.sessions) == 0 {
+		close(s.drained)
+	}
+}
+
 func (s *baseServer) handleNewSession(sess quicSession) {
 	sessCtx := sess.Context()
+	handshakeCtx := sess.HandshakeComplete()
+	go s.trackSession(sess, sessCtx, handshakeCtx)
 	if s.acceptEarlySessions {
 		// wait until the early session is ready (or the handshake fails)
 		select {
@@ -493,7 +666,7 @@ func (s *baseServer) handleNewSession(sess quicSession) {
 	} else {
 		// wait until the handshake is complete (or fails)
 		select {
-		case <-sess.HandshakeComplete().Done():
+		case <-handshakeCtx.Done():
 		case <-sessCtx.Done():
 			return
 		}
@@ -513,7 +686,7 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 	// Log the Initial packet now.
 	// If no Retry is sent, the packet will be logged by the session.
 	(&wire.ExtendedHeader{Header: *hdr}).Log(s.logger)
-	srcConnID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	srcConnID, err := generateServerConnectionID(s.config.ConnectionIDGenerator, s.config.ConnectionIDLength)
 	if err != nil {
 		return err
 	}
@@ -536,16 +709,19 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 		return err
 	}
 	// append the Retry integrity tag
-	tag := handshake.GetRetryIntegrityTag(buf.Bytes(), hdr.DestConnectionID)
+	tag := handshake.GetRetryIntegrityTag(buf.Bytes(), hdr.DestConnectionID, hdr.Version)
 	buf.Write(tag[:])
-	_, err = s.conn.WriteTo(buf.Bytes(), remoteAddr)
-	return err
+	if _, err := s.conn.WriteTo(buf.Bytes(), remoteAddr); err != nil {
+		return err
+	}
+	atomic.AddUint64(&s.retriesSent, 1)
+	return nil
 }
 
 func (s *baseServer) maybeSendInvalidToken(p *receivedPacket, hdr *wire.Header) error {
 	// Only send INVALID_TOKEN if we can unprotect the packet.
 	// This makes sure that we won't send it for packets that were corrupted.
-	sealer, opener := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer)
+	sealer, opener := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, hdr.Version)
 	data := p.data[:hdr.ParsedLen()+hdr.Length]
 	extHdr, err := unpackHeader(opener, hdr, data, hdr.Version)
 	if err != nil {
@@ -564,7 +740,7 @@ func (s *baseServer) maybeSendInvalidToken(p *receivedPacket, hdr *wire.Header)
 }
 
 func (s *baseServer) sendConnectionRefused(remoteAddr net.Addr, hdr *wire.Header) error {
-	sealer, _ := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer)
+	sealer, _ := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, hdr.Version)
 	return s.sendError(remoteAddr, hdr, sealer, qerr.ConnectionRefused)
 }
 
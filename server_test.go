@@ -55,7 +55,7 @@ var _ = Describe("Server", func() {
 		n := buf.Len()
 		buf.Write(p)
 		data := buffer.Data[:buf.Len()]
-		sealer, _ := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveClient)
+		sealer, _ := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveClient, hdr.Version)
 		_ = sealer.Seal(data[n:n], data[n:], 0x42, data[:n])
 		data = data[:len(data)+16]
 		sealer.EncryptHeader(data[n:n+16], &data[0], data[n-4:n])
@@ -117,6 +117,18 @@ var _ = Describe("Server", func() {
 		Expect(err).To(MatchError("0x1234 is not a valid QUIC version"))
 	})
 
+	It("errors when the ConnectionIDLength doesn't match the ConnectionIDGenerator", func() {
+		gen := NewMockConnectionIDGenerator(mockCtrl)
+		gen.EXPECT().ConnectionIDLen().Return(8).AnyTimes()
+		_, err := Listen(nil, tlsConf, &Config{ConnectionIDLength: 6, ConnectionIDGenerator: gen})
+		Expect(err).To(MatchError("quic: ConnectionIDLength (6) doesn't match the length of the ConnectionIDGenerator (8)"))
+	})
+
+	It("errors when the HandshakeRateLimit has an invalid rate", func() {
+		_, err := Listen(nil, tlsConf, &Config{HandshakeRateLimit: &HandshakeRateLimit{Burst: 10}})
+		Expect(err).To(MatchError("quic: invalid handshake rate: 0.000000"))
+	})
+
 	It("fills in default values if options are not set in the Config", func() {
 		ln, err := Listen(conn, tlsConf, &Config{})
 		Expect(err).ToNot(HaveOccurred())
@@ -267,6 +279,33 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("passes the application data of a NEW_TOKEN token to the callback", func() {
+				raddr := &net.UDPAddr{
+					IP:   net.IPv4(192, 168, 13, 37),
+					Port: 1337,
+				}
+				done := make(chan struct{})
+				serv.config.AcceptToken = func(addr net.Addr, token *Token) bool {
+					Expect(addr).To(Equal(raddr))
+					Expect(token).ToNot(BeNil())
+					Expect(token.IsRetryToken).To(BeFalse())
+					Expect(token.ApplicationData).To(Equal([]byte("foobar")))
+					close(done)
+					return false
+				}
+				token, err := serv.tokenGenerator.NewToken(raddr, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				packet := getPacket(&wire.Header{
+					IsLongHeader: true,
+					Type:         protocol.PacketTypeInitial,
+					Token:        token,
+					Version:      serv.config.Versions[0],
+				}, make([]byte, protocol.MinInitialPacketSize))
+				packet.remoteAddr = raddr
+				serv.handlePacket(packet)
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("passes an empty token to the callback, if decoding fails", func() {
 				raddr := &net.UDPAddr{
 					IP:   net.IPv4(192, 168, 13, 37),
@@ -391,6 +430,37 @@ var _ = Describe("Server", func() {
 				Expect(hdr.SupportedVersions).ToNot(ContainElement(protocol.VersionNumber(0x42)))
 			})
 
+			It("drops packets that have the QUIC bit cleared", func() {
+				packet := getPacket(&wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeHandshake,
+					SrcConnectionID:  protocol.ConnectionID{1, 2, 3, 4, 5},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6},
+					Version:          0x42,
+				}, make([]byte, protocol.MinInitialPacketSize))
+				packet.data[0] ^= 0x40 // unset the QUIC bit
+				packet.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+				serv.handlePacket(packet)
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+			})
+
+			It("accepts packets that have the QUIC bit cleared, if greasing is enabled", func() {
+				serv.config.GreaseQUICBit = true
+				packet := getPacket(&wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeHandshake,
+					SrcConnectionID:  protocol.ConnectionID{1, 2, 3, 4, 5},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6},
+					Version:          0x42,
+				}, make([]byte, protocol.MinInitialPacketSize))
+				packet.data[0] ^= 0x40 // unset the QUIC bit
+				packet.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+				serv.handlePacket(packet)
+				var write mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&write))
+				Expect(wire.IsVersionNegotiationPacket(write.data)).To(BeTrue())
+			})
+
 			It("replies with a Retry packet, if a Token is required", func() {
 				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
 				hdr := &wire.Header{
@@ -411,7 +481,29 @@ var _ = Describe("Server", func() {
 				Expect(replyHdr.SrcConnectionID).ToNot(Equal(hdr.DestConnectionID))
 				Expect(replyHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
 				Expect(replyHdr.Token).ToNot(BeEmpty())
-				Expect(write.data[len(write.data)-16:]).To(Equal(handshake.GetRetryIntegrityTag(write.data[:len(write.data)-16], hdr.DestConnectionID)[:]))
+				Expect(write.data[len(write.data)-16:]).To(Equal(handshake.GetRetryIntegrityTag(write.data[:len(write.data)-16], hdr.DestConnectionID, hdr.Version)[:]))
+			})
+
+			It("uses the ConnectionIDGenerator for the Retry", func() {
+				gen := NewMockConnectionIDGenerator(mockCtrl)
+				gen.EXPECT().GenerateConnectionID().Return([]byte{0xde, 0xca, 0xfb, 0xad}, nil)
+				serv.config.ConnectionIDGenerator = gen
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+					Version:          protocol.VersionTLS,
+				}
+				packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				packet.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+				serv.handlePacket(packet)
+				var write mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&write))
+				replyHdr := parseHeader(write.data)
+				Expect(replyHdr.Type).To(Equal(protocol.PacketTypeRetry))
+				Expect(replyHdr.SrcConnectionID).To(Equal(protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}))
 			})
 
 			It("sends an INVALID_TOKEN error, if an invalid retry token is received", func() {
@@ -437,12 +529,12 @@ var _ = Describe("Server", func() {
 				Expect(replyHdr.Type).To(Equal(protocol.PacketTypeInitial))
 				Expect(replyHdr.SrcConnectionID).To(Equal(hdr.DestConnectionID))
 				Expect(replyHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
-				_, opener := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveClient)
+				_, opener := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveClient, hdr.Version)
 				extHdr, err := unpackHeader(opener, replyHdr, write.data, hdr.Version)
 				Expect(err).ToNot(HaveOccurred())
 				data, err := opener.Open(nil, write.data[extHdr.ParsedLen():], extHdr.PacketNumber, write.data[:extHdr.ParsedLen()])
 				Expect(err).ToNot(HaveOccurred())
-				f, err := wire.NewFrameParser(hdr.Version).ParseNext(bytes.NewReader(data), protocol.EncryptionInitial)
+				f, err := wire.NewFrameParser(false, hdr.Version).ParseNext(bytes.NewReader(data), protocol.EncryptionInitial)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(f).To(BeAssignableToTypeOf(&wire.ConnectionCloseFrame{}))
 				ccf := f.(*wire.ConnectionCloseFrame)
@@ -803,6 +895,250 @@ var _ = Describe("Server", func() {
 			})
 		})
 
+		Context("admission control", func() {
+			var sessCtx, handshakeCtx context.Context
+
+			expectConnectionRefused := func(p *receivedPacket) {
+				hdr := parseHeader(p.data)
+				var reject mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&reject))
+				Expect(reject.to).To(Equal(p.remoteAddr))
+				rejectHdr := parseHeader(reject.data)
+				Expect(rejectHdr.Type).To(Equal(protocol.PacketTypeInitial))
+				Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+				Expect(rejectHdr.SrcConnectionID).To(Equal(hdr.DestConnectionID))
+			}
+
+			BeforeEach(func() {
+				sessCtx = context.Background()
+				handshakeCtx = context.Background()
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+				serv.newSession = func(
+					_ connection,
+					runner sessionRunner,
+					_ protocol.ConnectionID,
+					_ *protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ [16]byte,
+					_ *Config,
+					_ *tls.Config,
+					_ *handshake.TokenGenerator,
+					_ bool,
+					_ logging.ConnectionTracer,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					sess := NewMockQuicSession(mockCtrl)
+					sess.EXPECT().handlePacket(gomock.Any())
+					sess.EXPECT().run()
+					sess.EXPECT().Context().Return(sessCtx)
+					sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+					return sess
+				}
+				phm.EXPECT().AddWithConnID(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, _ protocol.ConnectionID, fn func() packetHandler) bool {
+					phm.EXPECT().GetStatelessResetToken(gomock.Any())
+					fn()
+					return true
+				}).AnyTimes()
+			})
+
+			It("refuses new connection attempts if there are too many sessions", func() {
+				serv.config.MaxSessions = 2
+				var cancel context.CancelFunc
+				sessCtx, cancel = context.WithCancel(context.Background())
+				var cancelHandshake context.CancelFunc
+				handshakeCtx, cancelHandshake = context.WithCancel(context.Background())
+				cancelHandshake()
+				serv.handlePacket(getInitialWithRandomDestConnID())
+				Eventually(func() int { return serv.Stats().Sessions }).Should(Equal(1))
+				sessCtx = context.Background()
+				serv.handlePacket(getInitialWithRandomDestConnID())
+				Eventually(func() ListenerStats { return serv.Stats() }).Should(Equal(ListenerStats{Sessions: 2}))
+				p := getInitialWithRandomDestConnID()
+				serv.handlePacket(p)
+				expectConnectionRefused(p)
+				Expect(serv.Stats().Refused).To(BeEquivalentTo(1))
+				// close the first session
+				cancel()
+				Eventually(func() int { return serv.Stats().Sessions }).Should(Equal(1))
+				serv.handlePacket(getInitialWithRandomDestConnID())
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+				Expect(serv.Stats()).To(Equal(ListenerStats{Sessions: 2, Refused: 1}))
+			})
+
+			It("refuses new connection attempts if there are too many handshakes", func() {
+				serv.config.MaxHandshakes = 1
+				serv.handlePacket(getInitialWithRandomDestConnID())
+				Eventually(func() ListenerStats { return serv.Stats() }).Should(Equal(ListenerStats{Sessions: 1, Handshakes: 1}))
+				p := getInitialWithRandomDestConnID()
+				serv.handlePacket(p)
+				expectConnectionRefused(p)
+				Expect(serv.Stats()).To(Equal(ListenerStats{Sessions: 1, Handshakes: 1, Refused: 1}))
+			})
+
+			It("counts a handshake as done when the session is closed", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				sessCtx = ctx
+				serv.handlePacket(getInitialWithRandomDestConnID())
+				Eventually(func() ListenerStats { return serv.Stats() }).Should(Equal(ListenerStats{Sessions: 1, Handshakes: 1}))
+				cancel()
+				Eventually(func() ListenerStats { return serv.Stats() }).Should(Equal(ListenerStats{}))
+			})
+
+			It("rate limits handshakes", func() {
+				serv.rateLimiter = newHandshakeRateLimiter(&HandshakeRateLimit{Rate: 0.001, Burst: 1})
+				serv.handlePacket(getInitialWithRandomDestConnID())
+				Eventually(func() int { return serv.Stats().Sessions }).Should(Equal(1))
+				p := getInitialWithRandomDestConnID()
+				serv.handlePacket(p)
+				expectConnectionRefused(p)
+				Expect(serv.Stats()).To(Equal(ListenerStats{Sessions: 1, Handshakes: 1, RateLimited: 1}))
+				// clients from a different network are not affected
+				p = getInitialWithRandomDestConnID()
+				p.remoteAddr = &net.UDPAddr{IP: net.IPv4(10, 11, 12, 13), Port: 1234}
+				serv.handlePacket(p)
+				Eventually(func() int { return serv.Stats().Sessions }).Should(Equal(2))
+			})
+
+			It("sends a Retry if too many handshakes are in progress", func() {
+				serv.config.RetryThreshold = 1
+				serv.handlePacket(getInitialWithRandomDestConnID())
+				Eventually(func() int { return serv.Stats().Handshakes }).Should(Equal(1))
+				p := getInitialWithRandomDestConnID()
+				hdr := parseHeader(p.data)
+				serv.handlePacket(p)
+				var write mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&write))
+				replyHdr := parseHeader(write.data)
+				Expect(replyHdr.Type).To(Equal(protocol.PacketTypeRetry))
+				Expect(replyHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+				Eventually(func() uint64 { return serv.Stats().RetriesSent }).Should(BeEquivalentTo(1))
+				Expect(serv.Stats().Sessions).To(Equal(1))
+			})
+
+			It("doesn't send a Retry if the client presents a valid token", func() {
+				serv.config.RetryThreshold = 1
+				serv.handlePacket(getInitialWithRandomDestConnID())
+				Eventually(func() int { return serv.Stats().Handshakes }).Should(Equal(1))
+				token, err := serv.tokenGenerator.NewToken(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 42}, nil)
+				Expect(err).ToNot(HaveOccurred())
+				p := getPacket(&wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+					Token:            token,
+					Version:          protocol.VersionTLS,
+				}, make([]byte, protocol.MinInitialPacketSize))
+				p.remoteAddr = &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 42}
+				serv.handlePacket(p)
+				Eventually(func() int { return serv.Stats().Handshakes }).Should(Equal(2))
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+			})
+		})
+
+		Context("shutting down", func() {
+			// creates a session that stays open until the returned function is called
+			createSession := func() (*MockQuicSession, context.CancelFunc) {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+				sessCtx, cancel := context.WithCancel(context.Background())
+				sess := NewMockQuicSession(mockCtrl)
+				serv.newSession = func(
+					_ connection,
+					runner sessionRunner,
+					_ protocol.ConnectionID,
+					_ *protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ [16]byte,
+					_ *Config,
+					_ *tls.Config,
+					_ *handshake.TokenGenerator,
+					_ bool,
+					_ logging.ConnectionTracer,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					sess.EXPECT().handlePacket(gomock.Any())
+					sess.EXPECT().run()
+					sess.EXPECT().Context().Return(sessCtx)
+					sess.EXPECT().HandshakeComplete().Return(context.Background())
+					return sess
+				}
+				phm.EXPECT().AddWithConnID(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, _ protocol.ConnectionID, fn func() packetHandler) bool {
+					phm.EXPECT().GetStatelessResetToken(gomock.Any())
+					fn()
+					return true
+				})
+				serv.handlePacket(getInitialWithRandomDestConnID())
+				Eventually(func() int { return serv.Stats().Sessions }).Should(Equal(1))
+				return sess, cancel
+			}
+
+			It("closes the server right away, if there are no sessions", func() {
+				phm.EXPECT().CloseServer()
+				Expect(serv.Shutdown(context.Background(), 0, "")).To(Succeed())
+				_, err := serv.Accept(context.Background())
+				Expect(err).To(MatchError("server closed"))
+			})
+
+			It("waits for existing sessions to close, and refuses new connection attempts", func() {
+				_, closeSession := createSession()
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					Expect(serv.Shutdown(context.Background(), 0, "")).To(Succeed())
+				}()
+				Eventually(serv.isShuttingDown).Should(BeTrue())
+
+				p := getInitialWithRandomDestConnID()
+				hdr := parseHeader(p.data)
+				serv.handlePacket(p)
+				var reject mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&reject))
+				rejectHdr := parseHeader(reject.data)
+				Expect(rejectHdr.Type).To(Equal(protocol.PacketTypeInitial))
+				Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+				Expect(serv.Stats().Refused).To(BeEquivalentTo(1))
+				Consistently(done).ShouldNot(BeClosed())
+
+				phm.EXPECT().CloseServer()
+				closeSession()
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("closes the remaining sessions when the context expires", func() {
+				sess, closeSession := createSession()
+				sess.EXPECT().CloseWithError(ErrorCode(0x1337), "shutting down").Do(func(ErrorCode, string) { closeSession() })
+				phm.EXPECT().CloseServer()
+				ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(50*time.Millisecond))
+				defer cancel()
+				Expect(serv.Shutdown(ctx, 0x1337, "shutting down")).To(MatchError(context.DeadlineExceeded))
+				Eventually(func() int { return serv.Stats().Sessions }).Should(BeZero())
+			})
+
+			It("can be called multiple times", func() {
+				_, closeSession := createSession()
+				done := make(chan struct{}, 2)
+				for i := 0; i < 2; i++ {
+					go func() {
+						defer GinkgoRecover()
+						Expect(serv.Shutdown(context.Background(), 0, "")).To(Succeed())
+						done <- struct{}{}
+					}()
+				}
+				Consistently(done).ShouldNot(Receive())
+				phm.EXPECT().CloseServer()
+				closeSession()
+				Eventually(done).Should(Receive())
+				Eventually(done).Should(Receive())
+			})
+		})
+
 		Context("accepting sessions", func() {
 			It("returns Accept when an error occurs", func() {
 				testErr := errors.New("test err")
@@ -943,6 +1279,7 @@ var _ = Describe("Server", func() {
 				Expect(enable0RTT).To(BeTrue())
 				sess.EXPECT().run().Do(func() {})
 				sess.EXPECT().earlySessionReady().Return(ready)
+				sess.EXPECT().HandshakeComplete().Return(context.Background())
 				sess.EXPECT().Context().Return(context.Background())
 				return sess
 			}
@@ -984,6 +1321,7 @@ var _ = Describe("Server", func() {
 				sess.EXPECT().handlePacket(gomock.Any())
 				sess.EXPECT().run()
 				sess.EXPECT().earlySessionReady().Return(ready)
+				sess.EXPECT().HandshakeComplete().Return(context.Background())
 				sess.EXPECT().Context().Return(context.Background())
 				return sess
 			}
@@ -1040,6 +1378,7 @@ var _ = Describe("Server", func() {
 				sess.EXPECT().handlePacket(p)
 				sess.EXPECT().run()
 				sess.EXPECT().earlySessionReady()
+				sess.EXPECT().HandshakeComplete().Return(context.Background())
 				sess.EXPECT().Context().Return(ctx)
 				close(sessionCreated)
 				return sess
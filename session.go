@@ -51,7 +51,9 @@ type streamManager interface {
 type cryptoStreamHandler interface {
 	RunHandshake()
 	ChangeConnectionID(protocol.ConnectionID)
+	ChangeVersion(protocol.VersionNumber)
 	SetLargest1RTTAcked(protocol.PacketNumber)
+	InitiateKeyUpdate() error
 	DropHandshakeKeys()
 	GetSessionTicket() ([]byte, error)
 	io.Closer
@@ -60,6 +62,8 @@ type cryptoStreamHandler interface {
 
 type receivedPacket struct {
 	remoteAddr net.Addr
+	conn       net.PacketConn // the PacketConn the packet was received on, set by the packetHandlerMap
+	ecn        protocol.ECN
 	rcvTime    time.Time
 	data       []byte
 
@@ -69,6 +73,8 @@ type receivedPacket struct {
 func (p *receivedPacket) Clone() *receivedPacket {
 	return &receivedPacket{
 		remoteAddr: p.remoteAddr,
+		conn:       p.conn,
+		ecn:        p.ecn,
 		rcvTime:    p.rcvTime,
 		data:       p.data,
 		buffer:     p.buffer,
@@ -104,6 +110,41 @@ type closeError struct {
 	immediate bool
 }
 
+// A pathMigration is a migration of the connection to a new path.
+// Applications can migrate the connection to a new local address,
+// and the client migrates to the server's preferred address.
+type pathMigration struct {
+	// only set when migrating to a new local address
+	pconn  net.PacketConn
+	runner packetHandlerManager // the packet handler manager of the new PacketConn
+	// only set when migrating to the server's preferred address
+	remoteAddr net.Addr
+	// The connection ID used on the new path.
+	// Not set if the peer uses zero-length connection IDs.
+	connID    utils.NewConnectionID
+	validator *pathValidator
+	result    chan error
+}
+
+func (m *pathMigration) String() string {
+	if m.pconn != nil {
+		return fmt.Sprintf("local address %s", m.pconn.LocalAddr())
+	}
+	return fmt.Sprintf("remote address %s", m.remoteAddr)
+}
+
+// A peerPathValidation is started by the server when a client starts sending from a new remote address,
+// e.g. because of a NAT rebinding.
+// Until the new path is validated, the server keeps sending to the old address,
+// and only sends probe packets on the new path.
+type peerPathValidation struct {
+	remoteAddr net.Addr
+	validator  *pathValidator
+	// used to enforce the anti-amplification limit on the new path
+	bytesReceived protocol.ByteCount
+	bytesSent     protocol.ByteCount
+}
+
 type errCloseForRecreating struct {
 	nextPacketNumber protocol.PacketNumber
 	nextVersion      protocol.VersionNumber
@@ -129,13 +170,18 @@ type session struct {
 
 	srcConnIDLen int
 
-	perspective    protocol.Perspective
-	initialVersion protocol.VersionNumber // if version negotiation is performed, this is the version we initially tried
+	perspective protocol.Perspective
+	// For the client: if version negotiation is performed, this is the version we initially tried.
+	// For the server: the version of the client's first packet.
+	initialVersion protocol.VersionNumber
 	version        protocol.VersionNumber
-	config         *Config
+	// set if we switched to a compatible version during the handshake
+	switchedToCompatibleVersion bool
+	config                      *Config
 
 	conn      connection
 	sendQueue *sendQueue
+	runner    sessionRunner
 
 	streamsMap      streamManager
 	connIDManager   *connIDManager
@@ -150,6 +196,8 @@ type session struct {
 	framer                framer
 	windowUpdateQueue     *windowUpdateQueue
 	connFlowController    flowcontrol.ConnectionFlowController
+	datagramQueue         *datagramQueue            // only set if datagram support is enabled
+	mtuDiscoverer         *mtuDiscoverer            // only set once the handshake is confirmed, if the DF bit can be set
 	tokenStoreKey         string                    // only set for the client
 	tokenGenerator        *handshake.TokenGenerator // only set for the server
 
@@ -163,6 +211,17 @@ type session struct {
 	receivedPackets  chan *receivedPacket
 	sendingScheduled chan struct{}
 
+	statsRequests     chan chan<- ConnectionStats
+	keyUpdateRequests chan chan<- error
+	migrationRequests chan *pathMigration
+	migration         *pathMigration // only set while a new path is being validated
+	// The connection ID sent in the server's preferred_address.
+	// Only set on the client, until the client migrated to the preferred address.
+	preferredAddressConnID *utils.NewConnectionID
+
+	largestRcvdAppDataPacket protocol.PacketNumber // only used by the server to detect address changes
+	peerPathValidation       *peerPathValidation
+
 	closeOnce sync.Once
 	// closeChan is used to notify the run loop that it should terminate
 	closeChan chan closeError
@@ -195,6 +254,13 @@ type session struct {
 
 	peerParams *wire.TransportParameters
 
+	handshakeDuration time.Duration
+	packetsReceived   uint64
+	bytesReceived     protocol.ByteCount
+
+	// set when the first 0-RTT packet is received, read by Received0RTT
+	received0RTT utils.AtomicBool
+
 	timer *utils.Timer
 	// keepAlivePingSent stores whether a keep alive PING is in flight.
 	// It is reset as soon as we receive a packet from the peer.
@@ -231,6 +297,7 @@ var newSession = func(
 ) quicSession {
 	s := &session{
 		conn:                  conn,
+		runner:                runner,
 		config:                conf,
 		handshakeDestConnID:   destConnID,
 		srcConnIDLen:          srcConnID.Len(),
@@ -240,6 +307,7 @@ var newSession = func(
 		handshakeCompleteChan: make(chan struct{}),
 		tracer:                tracer,
 		logger:                logger,
+		initialVersion:        v,
 		version:               v,
 	}
 	if origDestConnID != nil {
@@ -249,26 +317,31 @@ var newSession = func(
 	}
 	s.connIDManager = newConnIDManager(
 		destConnID,
-		func(token [16]byte) { runner.AddResetToken(token, s) },
-		runner.RemoveResetToken,
-		runner.RetireResetToken,
+		func(token [16]byte) { s.runner.AddResetToken(token, s) },
+		func(token [16]byte) { s.runner.RemoveResetToken(token) },
+		func(token [16]byte) { s.runner.RetireResetToken(token) },
 		s.queueControlFrame,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		clientDestConnID,
-		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
-		runner.GetStatelessResetToken,
-		runner.Remove,
-		runner.Retire,
-		runner.ReplaceWithClosed,
+		func() (protocol.ConnectionID, error) {
+			return generateServerConnectionID(s.config.ConnectionIDGenerator, srcConnID.Len())
+		},
+		func(connID protocol.ConnectionID) { s.runner.Add(connID, s) },
+		func(connID protocol.ConnectionID) [16]byte { return s.runner.GetStatelessResetToken(connID) },
+		func(connID protocol.ConnectionID) { s.runner.Remove(connID) },
+		func(connID protocol.ConnectionID) { s.runner.Retire(connID) },
+		func(connID protocol.ConnectionID, h packetHandler) { s.runner.ReplaceWithClosed(connID, h) },
 		s.queueControlFrame,
 	)
 	s.preSetup()
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		0,
 		s.rttStats,
+		s.newCongestionController(),
 		s.perspective,
+		s.conn.SupportsECN(),
 		s.traceCallback,
 		s.tracer,
 		s.logger,
@@ -292,6 +365,23 @@ var newSession = func(
 		ActiveConnectionIDLimit:         protocol.MaxActiveConnectionIDs,
 		InitialSourceConnectionID:       srcConnID,
 		RetrySourceConnectionID:         retrySrcConnID,
+		VersionInformation: &wire.VersionInformation{
+			ChosenVersion:     s.version,
+			AvailableVersions: s.config.Versions,
+		},
+		GreaseQUICBit:     s.config.GreaseQUICBit,
+		MaxUDPPayloadSize: protocol.ByteCount(s.config.MaxUDPPayloadSize),
+	}
+	if s.config.EnableDatagrams {
+		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
+	}
+	if s.config.PreferredAddress != nil {
+		pa, err := s.newPreferredAddress(s.config.PreferredAddress)
+		if err != nil {
+			s.logger.Errorf("Not sending preferred_address: %s", err)
+		} else {
+			params.PreferredAddress = pa
+		}
 	}
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
@@ -314,9 +404,13 @@ var newSession = func(
 		},
 		tlsConf,
 		enable0RTT,
+		s.config.Allow0RTT,
+		s.config.AntiReplayStore,
+		s.config.KeyUpdateInterval,
 		s.rttStats,
 		tracer,
 		logger,
+		s.version,
 	)
 	s.cryptoStreamHandler = cs
 	s.packer = newPacketPacker(
@@ -330,6 +424,7 @@ var newSession = func(
 		cs,
 		s.framer,
 		s.receivedPacketHandler,
+		s.datagramQueue,
 		s.perspective,
 		s.version,
 	)
@@ -338,6 +433,29 @@ var newSession = func(
 	return s
 }
 
+// newPreferredAddress generates the preferred_address transport parameter.
+func (s *session) newPreferredAddress(addr *PreferredAddress) (*wire.PreferredAddress, error) {
+	connID, token, err := s.connIDGenerator.GenerateForPreferredAddress()
+	if err != nil {
+		return nil, err
+	}
+	pa := &wire.PreferredAddress{
+		IPv4:                net.IPv4zero,
+		IPv6:                net.IPv6zero,
+		ConnectionID:        connID,
+		StatelessResetToken: token,
+	}
+	if addr.IPv4 != nil {
+		pa.IPv4 = addr.IPv4.IP
+		pa.IPv4Port = uint16(addr.IPv4.Port)
+	}
+	if addr.IPv6 != nil {
+		pa.IPv6 = addr.IPv6.IP.To16()
+		pa.IPv6Port = uint16(addr.IPv6.Port)
+	}
+	return pa, nil
+}
+
 // declare this as a variable, such that we can it mock it in the tests
 var newClientSession = func(
 	conn connection,
@@ -356,6 +474,7 @@ var newClientSession = func(
 ) quicSession {
 	s := &session{
 		conn:                  conn,
+		runner:                runner,
 		config:                conf,
 		origDestConnID:        destConnID,
 		handshakeDestConnID:   destConnID,
@@ -371,26 +490,29 @@ var newClientSession = func(
 	}
 	s.connIDManager = newConnIDManager(
 		destConnID,
-		func(token [16]byte) { runner.AddResetToken(token, s) },
-		runner.RemoveResetToken,
-		runner.RetireResetToken,
+		func(token [16]byte) { s.runner.AddResetToken(token, s) },
+		func(token [16]byte) { s.runner.RemoveResetToken(token) },
+		func(token [16]byte) { s.runner.RetireResetToken(token) },
 		s.queueControlFrame,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		nil,
-		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
-		runner.GetStatelessResetToken,
-		runner.Remove,
-		runner.Retire,
-		runner.ReplaceWithClosed,
+		func() (protocol.ConnectionID, error) { return protocol.GenerateConnectionID(srcConnID.Len()) },
+		func(connID protocol.ConnectionID) { s.runner.Add(connID, s) },
+		func(connID protocol.ConnectionID) [16]byte { return s.runner.GetStatelessResetToken(connID) },
+		func(connID protocol.ConnectionID) { s.runner.Remove(connID) },
+		func(connID protocol.ConnectionID) { s.runner.Retire(connID) },
+		func(connID protocol.ConnectionID, h packetHandler) { s.runner.ReplaceWithClosed(connID, h) },
 		s.queueControlFrame,
 	)
 	s.preSetup()
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		initialPacketNumber,
 		s.rttStats,
+		s.newCongestionController(),
 		s.perspective,
+		s.conn.SupportsECN(),
 		s.traceCallback,
 		s.tracer,
 		s.logger,
@@ -411,6 +533,16 @@ var newClientSession = func(
 		DisableActiveMigration:         true,
 		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
 		InitialSourceConnectionID:      srcConnID,
+		VersionInformation: &wire.VersionInformation{
+			ChosenVersion: s.version,
+			// add a greased version, so that servers don't choke on unknown versions
+			AvailableVersions: protocol.GetGreasedVersions(s.config.Versions),
+		},
+		GreaseQUICBit:     s.config.GreaseQUICBit,
+		MaxUDPPayloadSize: protocol.ByteCount(s.config.MaxUDPPayloadSize),
+	}
+	if s.config.EnableDatagrams {
+		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
 	}
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
@@ -430,9 +562,11 @@ var newClientSession = func(
 		},
 		tlsConf,
 		enable0RTT,
+		s.config.KeyUpdateInterval,
 		s.rttStats,
 		tracer,
 		logger,
+		s.version,
 	)
 	s.clientHelloWritten = clientHelloWritten
 	s.cryptoStreamHandler = cs
@@ -449,6 +583,7 @@ var newClientSession = func(
 		cs,
 		s.framer,
 		s.receivedPacketHandler,
+		s.datagramQueue,
 		s.perspective,
 		s.version,
 	)
@@ -465,10 +600,26 @@ var newClientSession = func(
 	return s
 }
 
+// newCongestionController creates the congestion controller configured by the application.
+// It returns nil if the default congestion controller should be used.
+func (s *session) newCongestionController() congestion.SendAlgorithmWithDebugInfos {
+	if s.config.CongestionControl == nil {
+		return nil
+	}
+	return s.config.CongestionControl(s.rttStats)
+}
+
+func (s *session) newStreamScheduler() StreamScheduler {
+	if s.config.StreamScheduler == nil {
+		return NewStrictPriorityScheduler()
+	}
+	return s.config.StreamScheduler()
+}
+
 func (s *session) preSetup() {
 	s.sendQueue = newSendQueue(s.conn)
 	s.retransmissionQueue = newRetransmissionQueue(s.version)
-	s.frameParser = wire.NewFrameParser(s.version)
+	s.frameParser = wire.NewFrameParser(s.config.EnableDatagrams, s.version)
 	s.rttStats = &congestion.RTTStats{}
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.InitialMaxData,
@@ -486,10 +637,14 @@ func (s *session) preSetup() {
 		s.perspective,
 		s.version,
 	)
-	s.framer = newFramer(s.streamsMap, s.version)
+	s.framer = newFramer(s.streamsMap, s.newStreamScheduler(), s.version)
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
+	s.statsRequests = make(chan chan<- ConnectionStats)
+	s.keyUpdateRequests = make(chan chan<- error)
+	s.migrationRequests = make(chan *pathMigration)
+	s.largestRcvdAppDataPacket = protocol.InvalidPacketNumber
 	s.undecryptablePackets = make([]*receivedPacket, 0, protocol.MaxUndecryptablePackets)
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
@@ -499,6 +654,9 @@ func (s *session) preSetup() {
 	s.sessionCreationTime = now
 
 	s.windowUpdateQueue = newWindowUpdateQueue(s.streamsMap, s.connFlowController, s.framer.QueueControlFrame)
+	if s.config.EnableDatagrams {
+		s.datagramQueue = newDatagramQueue(s.scheduleSending, s.logger)
+	}
 
 	if s.config.QuicTracer != nil {
 		s.traceCallback = func(ev quictrace.Event) {
@@ -574,6 +732,13 @@ runLoop:
 			}
 		case <-s.handshakeCompleteChan:
 			s.handleHandshakeComplete()
+		case c := <-s.statsRequests:
+			c <- s.stats()
+			continue
+		case c := <-s.keyUpdateRequests:
+			c <- s.initiateKeyUpdate()
+		case m := <-s.migrationRequests:
+			s.startPathMigration(m)
 		}
 
 		now := time.Now()
@@ -583,6 +748,16 @@ runLoop:
 			if err := s.sentPacketHandler.OnLossDetectionTimeout(); err != nil {
 				s.closeLocal(err)
 			}
+			if s.mtuDiscoverer != nil && s.sentPacketHandler.SendMode() == ackhandler.SendPTOAppData {
+				s.mtuDiscoverer.OnPTO()
+			}
+		}
+
+		if s.migration != nil && !now.Before(s.migration.validator.Deadline()) {
+			s.sendPathChallenge()
+		}
+		if s.peerPathValidation != nil && !now.Before(s.peerPathValidation.validator.Deadline()) {
+			s.sendPeerPathChallenge()
 		}
 
 		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
@@ -629,6 +804,10 @@ func (s *session) HandshakeComplete() context.Context {
 	return s.handshakeCtx
 }
 
+func (s *session) Received0RTT() bool {
+	return s.received0RTT.Get()
+}
+
 func (s *session) Context() context.Context {
 	return s.ctx
 }
@@ -637,6 +816,62 @@ func (s *session) ConnectionState() ConnectionState {
 	return s.cryptoStreamHandler.ConnectionState()
 }
 
+func (s *session) Stats() ConnectionStats {
+	c := make(chan ConnectionStats, 1)
+	select {
+	case s.statsRequests <- c:
+		return <-c
+	case <-s.ctx.Done():
+		// The run loop has returned, so the state of the session won't change any more.
+		return s.stats()
+	}
+}
+
+func (s *session) InitiateKeyUpdate() error {
+	c := make(chan error, 1)
+	select {
+	case s.keyUpdateRequests <- c:
+		return <-c
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	}
+}
+
+func (s *session) initiateKeyUpdate() error {
+	if !s.handshakeConfirmed {
+		return errors.New("cannot update keys before the handshake is confirmed")
+	}
+	return s.cryptoStreamHandler.InitiateKeyUpdate()
+}
+
+// stats must only be called from the run loop, or after the run loop returned
+func (s *session) stats() ConnectionStats {
+	transmissionStats := s.sentPacketHandler.TransmissionStats()
+	stats := ConnectionStats{
+		MinRTT:               s.rttStats.MinRTT(),
+		LatestRTT:            s.rttStats.LatestRTT(),
+		SmoothedRTT:          s.rttStats.SmoothedRTT(),
+		RTTVariance:          s.rttStats.MeanDeviation(),
+		PacketsSent:          transmissionStats.PacketsSent,
+		BytesSent:            transmissionStats.BytesSent,
+		PacketsReceived:      s.packetsReceived,
+		BytesReceived:        s.bytesReceived,
+		PacketsLost:          transmissionStats.PacketsLost,
+		BytesLost:            transmissionStats.BytesLost,
+		PacketsRetransmitted: transmissionStats.PacketsRetransmitted,
+		BytesRetransmitted:   transmissionStats.BytesRetransmitted,
+		HandshakeDuration:    s.handshakeDuration,
+	}
+	congestionState := s.sentPacketHandler.GetStats()
+	stats.CongestionWindow = congestionState.CongestionWindow
+	stats.BytesInFlight = congestionState.BytesInFlight
+	if s.peerParams != nil {
+		params := *s.peerParams
+		stats.PeerTransportParameters = &params
+	}
+	return stats
+}
+
 // Time when the next keep-alive packet should be sent.
 // It returns a zero time if no keep-alive should be sent.
 func (s *session) nextKeepAliveTime() time.Time {
@@ -667,6 +902,12 @@ func (s *session) maybeResetTimer() {
 	if !s.pacingDeadline.IsZero() {
 		deadline = utils.MinTime(deadline, s.pacingDeadline)
 	}
+	if s.migration != nil {
+		deadline = utils.MinTime(deadline, s.migration.validator.Deadline())
+	}
+	if s.peerPathValidation != nil {
+		deadline = utils.MinTime(deadline, s.peerPathValidation.validator.Deadline())
+	}
 
 	s.timer.Reset(deadline)
 }
@@ -677,6 +918,7 @@ func (s *session) idleTimeoutStartTime() time.Time {
 
 func (s *session) handleHandshakeComplete() {
 	s.handshakeComplete = true
+	s.handshakeDuration = time.Since(s.sessionCreationTime)
 	s.handshakeCompleteChan = nil // prevent this case from ever being selected again
 	s.handshakeCtxCancel()
 
@@ -694,7 +936,11 @@ func (s *session) handleHandshakeComplete() {
 				s.queueControlFrame(s.oneRTTStream.PopCryptoFrame(protocol.MaxPostHandshakeCryptoFrameSize))
 			}
 		}
-		token, err := s.tokenGenerator.NewToken(s.conn.RemoteAddr())
+		var tokenData []byte
+		if s.config.GetTokenData != nil {
+			tokenData = s.config.GetTokenData(s.conn.RemoteAddr())
+		}
+		token, err := s.tokenGenerator.NewToken(s.conn.RemoteAddr(), tokenData)
 		if err != nil {
 			s.closeLocal(err)
 		}
@@ -716,13 +962,17 @@ func (s *session) handlePacketImpl(rp *receivedPacket) bool {
 	data := rp.data
 	p := rp
 	s.sentPacketHandler.ReceivedBytes(protocol.ByteCount(len(data)))
+	parsePacket := wire.ParsePacket
+	if s.config.GreaseQUICBit {
+		parsePacket = wire.ParseGreasedPacket
+	}
 	for len(data) > 0 {
 		if counter > 0 {
 			p = p.Clone()
 			p.data = data
 		}
 
-		hdr, packetData, rest, err := wire.ParsePacket(p.data, s.srcConnIDLen)
+		hdr, packetData, rest, err := parsePacket(p.data, s.srcConnIDLen)
 		if err != nil {
 			if s.tracer != nil {
 				dropReason := logging.PacketDropHeaderParseError
@@ -735,7 +985,7 @@ func (s *session) handlePacketImpl(rp *receivedPacket) bool {
 			break
 		}
 
-		if hdr.IsLongHeader && hdr.Version != s.version {
+		if hdr.IsLongHeader && hdr.Version != s.version && !s.acceptVersion(hdr) {
 			if s.tracer != nil {
 				s.tracer.DroppedPacket(logging.PacketTypeFromHeader(hdr), protocol.ByteCount(len(data)), logging.PacketDropUnexpectedVersion)
 			}
@@ -771,6 +1021,32 @@ func (s *session) handlePacketImpl(rp *receivedPacket) bool {
 	return processed
 }
 
+// acceptVersion is called for long header packets that don't use the version of the connection.
+// It returns true if the packet should be processed nonetheless.
+// For the client, the version is only switched once the packet was decrypted, see handleSinglePacket.
+func (s *session) acceptVersion(hdr *wire.Header) bool {
+	if s.perspective == protocol.PerspectiveServer {
+		// If we switched to a compatible version, the client continues sending 0-RTT packets using the original version.
+		return hdr.Type == protocol.PacketType0RTT && hdr.Version == s.initialVersion
+	}
+	// The server switched to a compatible version.
+	// We offered this version in the version_information transport parameter.
+	if hdr.Type != protocol.PacketTypeInitial || s.switchedToCompatibleVersion ||
+		!protocol.IsSupportedVersion(s.config.Versions, hdr.Version) ||
+		!protocol.AreCompatibleVersions(s.version, hdr.Version) {
+		return false
+	}
+	return true
+}
+
+// switchToCompatibleVersion switches to a compatible version during the handshake, see RFC 9368.
+func (s *session) switchToCompatibleVersion(v protocol.VersionNumber) {
+	s.logger.Infof("Switching to compatible QUIC version %s.", v)
+	s.switchedToCompatibleVersion = true
+	s.version = v
+	s.packer.SetVersion(v)
+}
+
 func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /* was the packet successfully processed */ {
 	var wasQueued bool
 
@@ -800,7 +1076,21 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		return false
 	}
 
+	// The server switched to a compatible version, see acceptVersion.
+	// Anyone can send us a packet using a different version, so we only commit to
+	// the new version if the packet can be decrypted using the new version's Initial keys.
+	isVersionSwitch := s.perspective == protocol.PerspectiveClient && hdr.Type == protocol.PacketTypeInitial && hdr.Version != s.version
+	if isVersionSwitch {
+		s.cryptoStreamHandler.ChangeVersion(hdr.Version)
+	}
 	packet, err := s.unpacker.Unpack(hdr, p.rcvTime, p.data)
+	if isVersionSwitch {
+		if err != nil {
+			s.cryptoStreamHandler.ChangeVersion(s.version)
+		} else {
+			s.switchToCompatibleVersion(hdr.Version)
+		}
+	}
 	if err != nil {
 		switch err {
 		case handshake.ErrKeysDropped:
@@ -816,6 +1106,10 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		case wire.ErrInvalidReservedBits:
 			s.closeLocal(qerr.NewError(qerr.ProtocolViolation, err.Error()))
 		default:
+			if qErr, ok := err.(*qerr.QuicError); ok && qErr.ErrorCode == qerr.AEADLimitReached {
+				s.closeLocal(qErr)
+				break
+			}
 			// This might be a packet injected by an attacker.
 			// Drop it.
 			if s.tracer != nil {
@@ -839,7 +1133,7 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		return false
 	}
 
-	if err := s.handleUnpackedPacket(packet, p.rcvTime, protocol.ByteCount(len(p.data))); err != nil {
+	if err := s.handleUnpackedPacket(packet, p); err != nil {
 		s.closeLocal(err)
 		return false
 	}
@@ -877,7 +1171,7 @@ func (s *session) handleRetryPacket(hdr *wire.Header, data []byte) bool /* was t
 		return false
 	}
 
-	tag := handshake.GetRetryIntegrityTag(data[:len(data)-16], destConnID)
+	tag := handshake.GetRetryIntegrityTag(data[:len(data)-16], destConnID, hdr.Version)
 	if !bytes.Equal(data[len(data)-16:], tag[:]) {
 		if s.tracer != nil {
 			s.tracer.DroppedPacket(logging.PacketTypeRetry, protocol.ByteCount(len(data)), logging.PacketDropPayloadDecryptError)
@@ -955,14 +1249,16 @@ func (s *session) handleVersionNegotiationPacket(p *receivedPacket) {
 	})
 }
 
-func (s *session) handleUnpackedPacket(
-	packet *unpackedPacket,
-	rcvTime time.Time,
-	packetSize protocol.ByteCount, // only for logging
-) error {
+func (s *session) handleUnpackedPacket(packet *unpackedPacket, p *receivedPacket) error {
 	if len(packet.data) == 0 {
 		return qerr.NewError(qerr.ProtocolViolation, "empty packet")
 	}
+	packetSize := protocol.ByteCount(len(p.data))
+	s.packetsReceived++
+	s.bytesReceived += packetSize
+	if packet.encryptionLevel == protocol.Encryption0RTT {
+		s.received0RTT.Set(true)
+	}
 
 	if !s.receivedFirstPacket {
 		s.receivedFirstPacket = true
@@ -995,7 +1291,7 @@ func (s *session) handleUnpackedPacket(
 		}
 	}
 
-	s.lastPacketReceivedTime = rcvTime
+	s.lastPacketReceivedTime = p.rcvTime
 	s.firstAckElicitingPacketAfterIdleSentTime = time.Time{}
 	s.keepAlivePingSent = false
 
@@ -1005,7 +1301,7 @@ func (s *session) handleUnpackedPacket(
 	var transportState *quictrace.TransportState
 
 	r := bytes.NewReader(packet.data)
-	var isAckEliciting bool
+	var isAckEliciting, isNonProbing bool
 	for {
 		frame, err := s.frameParser.ParseNext(r, packet.encryptionLevel)
 		if err != nil {
@@ -1017,13 +1313,16 @@ func (s *session) handleUnpackedPacket(
 		if ackhandler.IsFrameAckEliciting(frame) {
 			isAckEliciting = true
 		}
+		if !isProbingFrame(frame) {
+			isNonProbing = true
+		}
 		if s.traceCallback != nil || s.tracer != nil {
 			frames = append(frames, frame)
 		}
 		// Only process frames now if we're not logging.
 		// If we're logging, we need to make sure that the packet_received event is logged first.
 		if s.tracer == nil {
-			if err := s.handleFrame(frame, packet.encryptionLevel); err != nil {
+			if err := s.handleFrame(frame, packet.encryptionLevel, p); err != nil {
 				return err
 			}
 		}
@@ -1032,7 +1331,7 @@ func (s *session) handleUnpackedPacket(
 	if s.traceCallback != nil {
 		transportState = s.sentPacketHandler.GetStats()
 		s.traceCallback(quictrace.Event{
-			Time:            rcvTime,
+			Time:            p.rcvTime,
 			EventType:       quictrace.PacketReceived,
 			TransportState:  transportState,
 			EncryptionLevel: packet.encryptionLevel,
@@ -1044,16 +1343,31 @@ func (s *session) handleUnpackedPacket(
 	if s.tracer != nil {
 		s.tracer.ReceivedPacket(packet.hdr, packetSize, frames)
 		for _, frame := range frames {
-			if err := s.handleFrame(frame, packet.encryptionLevel); err != nil {
+			if err := s.handleFrame(frame, packet.encryptionLevel, p); err != nil {
 				return err
 			}
 		}
 	}
 
-	return s.receivedPacketHandler.ReceivedPacket(packet.packetNumber, packet.encryptionLevel, rcvTime, isAckEliciting)
+	if s.perspective == protocol.PerspectiveServer && packet.encryptionLevel == protocol.Encryption1RTT {
+		s.handlePeerAddress(p.remoteAddr, packet.packetNumber, packetSize, isNonProbing)
+	}
+
+	return s.receivedPacketHandler.ReceivedPacket(packet.packetNumber, p.ecn, packet.encryptionLevel, p.rcvTime, isAckEliciting)
 }
 
-func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel) error {
+// isProbingFrame says if a frame is a probing frame.
+// Packets that only contain probing frames (and PADDING) don't cause a path change.
+func isProbingFrame(f wire.Frame) bool {
+	switch f.(type) {
+	case *wire.PathChallengeFrame, *wire.PathResponseFrame, *wire.NewConnectionIDFrame:
+		return true
+	}
+	return false
+}
+
+// handleFrame handles a frame received in the packet p.
+func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, p *receivedPacket) error {
 	var err error
 	wire.LogFrame(s.logger, f, false)
 	switch frame := f.(type) {
@@ -1080,10 +1394,9 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel) e
 		err = s.handleStopSendingFrame(frame)
 	case *wire.PingFrame:
 	case *wire.PathChallengeFrame:
-		s.handlePathChallengeFrame(frame)
+		s.handlePathChallengeFrame(frame, p)
 	case *wire.PathResponseFrame:
-		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
-		err = errors.New("unexpected PATH_RESPONSE frame")
+		s.handlePathResponseFrame(frame)
 	case *wire.NewTokenFrame:
 		err = s.handleNewTokenFrame(frame)
 	case *wire.NewConnectionIDFrame:
@@ -1092,6 +1405,8 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel) e
 		err = s.handleRetireConnectionIDFrame(frame)
 	case *wire.HandshakeDoneFrame:
 		err = s.handleHandshakeDoneFrame()
+	case *wire.DatagramFrame:
+		err = s.handleDatagramFrame(frame)
 	default:
 		err = fmt.Errorf("unexpected frame type: %s", reflect.ValueOf(&frame).Elem().Type().Name())
 	}
@@ -1188,8 +1503,58 @@ func (s *session) handleStopSendingFrame(frame *wire.StopSendingFrame) error {
 	return nil
 }
 
-func (s *session) handlePathChallengeFrame(frame *wire.PathChallengeFrame) {
-	s.queueControlFrame(&wire.PathResponseFrame{Data: frame.Data})
+// handlePathChallengeFrame sends the PATH_RESPONSE on the path that the PATH_CHALLENGE was received on,
+// see section 8.2.2 of RFC 9000.
+// On the current path, the PATH_RESPONSE is sent with the next packet.
+// A PATH_CHALLENGE received on a different path (e.g. while the peer is migrating) is answered with a probe packet,
+// since the current path might not work any more.
+func (s *session) handlePathChallengeFrame(frame *wire.PathChallengeFrame, p *receivedPacket) {
+	response := &wire.PathResponseFrame{Data: frame.Data}
+	m := s.migration
+	onMigrationPath := m != nil && m.pconn != nil && p.conn == m.pconn
+	if !onMigrationPath && (p.remoteAddr == nil || equalAddr(p.remoteAddr, s.conn.RemoteAddr())) {
+		s.queueControlFrame(response)
+		return
+	}
+
+	connID := s.connIDManager.Get()
+	var pconn net.PacketConn
+	if m != nil && (onMigrationPath || (m.remoteAddr != nil && equalAddr(m.remoteAddr, p.remoteAddr))) {
+		connID = m.connID.ConnectionID
+		if onMigrationPath {
+			pconn = m.pconn
+		}
+	}
+	// The peer's address might not have been validated yet.
+	// Make sure to not exceed the anti-amplification limit.
+	size := utils.MinByteCount(protocol.MinInitialPacketSize, protocol.AmplificationFactor*protocol.ByteCount(len(p.data)))
+	packet, err := s.packer.PackPathProbePacket(connID, []ackhandler.Frame{{
+		Frame: response,
+		// PATH_RESPONSE frames are not retransmitted.
+		// The peer sends a new PATH_CHALLENGE if the PATH_RESPONSE is lost.
+		OnLost: func(wire.Frame) {},
+	}}, size)
+	if err != nil {
+		s.closeLocal(err)
+		return
+	}
+	if v := s.peerPathValidation; v != nil && equalAddr(v.remoteAddr, p.remoteAddr) {
+		v.bytesSent += packet.buffer.Len()
+	}
+	if err := s.sendPathProbePacket(packet, pconn, p.remoteAddr); err != nil {
+		s.logger.Debugf("Sending PATH_RESPONSE to %s failed: %s", p.remoteAddr, err)
+	}
+}
+
+func (s *session) handlePathResponseFrame(frame *wire.PathResponseFrame) {
+	switch {
+	case s.migration != nil && s.migration.validator.HandlePathResponse(frame):
+		s.completePathMigration()
+	case s.peerPathValidation != nil && s.peerPathValidation.validator.HandlePathResponse(frame):
+		s.completePeerPathValidation()
+	default:
+		s.logger.Debugf("Ignoring PATH_RESPONSE frame that doesn't match any PATH_CHALLENGE sent.")
+	}
 }
 
 func (s *session) handleNewTokenFrame(frame *wire.NewTokenFrame) error {
@@ -1197,7 +1562,7 @@ func (s *session) handleNewTokenFrame(frame *wire.NewTokenFrame) error {
 		return qerr.NewError(qerr.ProtocolViolation, "Received NEW_TOKEN frame from the client.")
 	}
 	if s.config.TokenStore != nil {
-		s.config.TokenStore.Put(s.tokenStoreKey, &ClientToken{data: frame.Token})
+		s.config.TokenStore.Put(s.tokenStoreKey, &ClientToken{data: frame.Token, rcvTime: time.Now()})
 	}
 	return nil
 }
@@ -1215,6 +1580,17 @@ func (s *session) handleHandshakeDoneFrame() error {
 		return qerr.NewError(qerr.ProtocolViolation, "received a HANDSHAKE_DONE frame")
 	}
 	s.cryptoStreamHandler.DropHandshakeKeys()
+	if s.preferredAddressConnID != nil {
+		s.migrateToPreferredAddress()
+	}
+	return nil
+}
+
+func (s *session) handleDatagramFrame(f *wire.DatagramFrame) error {
+	if f.Length(s.version) > protocol.MaxDatagramFrameSize {
+		return qerr.NewError(qerr.ProtocolViolation, "DATAGRAM frame too large")
+	}
+	s.datagramQueue.HandleDatagramFrame(f)
 	return nil
 }
 
@@ -1224,6 +1600,9 @@ func (s *session) handleAckFrame(frame *wire.AckFrame, encLevel protocol.Encrypt
 	}
 	if encLevel == protocol.Encryption1RTT {
 		s.cryptoStreamHandler.SetLargest1RTTAcked(frame.LargestAcked())
+		if s.mtuDiscoverer != nil {
+			s.mtuDiscoverer.OnAckReceived()
+		}
 	}
 	return nil
 }
@@ -1292,7 +1671,13 @@ func (s *session) handleCloseError(closeErr closeError) {
 	}
 
 	s.streamsMap.CloseWithError(quicErr)
+	if s.migration != nil {
+		s.failPathMigration(quicErr)
+	}
 	s.connIDManager.Close()
+	if s.datagramQueue != nil {
+		s.datagramQueue.CloseWithError(quicErr)
+	}
 
 	// If this is a remote close we're done here
 	if closeErr.remote {
@@ -1314,6 +1699,9 @@ func (s *session) handleCloseError(closeErr closeError) {
 func (s *session) dropEncryptionLevel(encLevel protocol.EncryptionLevel) {
 	if encLevel == protocol.EncryptionHandshake {
 		s.handshakeConfirmed = true
+		if s.conn.SupportsDF() {
+			s.startMTUDiscovery()
+		}
 	}
 	s.sentPacketHandler.DropPackets(encLevel)
 	s.receivedPacketHandler.DropPackets(encLevel)
@@ -1373,6 +1761,12 @@ func (s *session) processTransportParametersImpl(params *wire.TransportParameter
 		}
 	}
 
+	if params.VersionInformation != nil {
+		if err := s.handleVersionInformation(params.VersionInformation); err != nil {
+			return err
+		}
+	}
+
 	s.peerParams = params
 	// Our local idle timeout will always be > 0.
 	s.idleTimeout = utils.MinNonZeroDuration(s.config.MaxIdleTimeout, params.MaxIdleTimeout)
@@ -1388,11 +1782,14 @@ func (s *session) processTransportParametersImpl(params *wire.TransportParameter
 	if params.StatelessResetToken != nil {
 		s.connIDManager.SetStatelessResetToken(*params.StatelessResetToken)
 	}
-	// We don't support connection migration yet, so we don't have any use for the preferred_address.
+	// The client migrates to the preferred_address once the handshake is confirmed.
 	if params.PreferredAddress != nil {
-		s.logger.Debugf("Server sent preferred_address. Retiring the preferred_address connection ID.")
-		// Retire the connection ID.
-		s.connIDManager.AddFromPreferredAddress(params.PreferredAddress.ConnectionID, &params.PreferredAddress.StatelessResetToken)
+		s.logger.Debugf("Server sent preferred_address.")
+		s.preferredAddressConnID = &utils.NewConnectionID{
+			SequenceNumber:      1,
+			ConnectionID:        params.PreferredAddress.ConnectionID,
+			StatelessResetToken: &params.PreferredAddress.StatelessResetToken,
+		}
 	}
 	// On the server side, the early session is ready as soon as we processed
 	// the client's transport parameters.
@@ -1402,6 +1799,32 @@ func (s *session) processTransportParametersImpl(params *wire.TransportParameter
 	return nil
 }
 
+// handleVersionInformation performs the downgrade checks for compatible version negotiation, see RFC 9368.
+func (s *session) handleVersionInformation(vi *wire.VersionInformation) error {
+	if s.perspective == protocol.PerspectiveServer {
+		if vi.ChosenVersion != s.initialVersion {
+			return qerr.NewError(qerr.VersionNegotiationError, fmt.Sprintf("client's chosen version (%s) doesn't match the version of its Initial (%s)", vi.ChosenVersion, s.initialVersion))
+		}
+		// The crypto setup already switched to this version before sending the ServerHello.
+		if v := protocol.ChooseCompatibleVersion(s.version, s.config.Versions, vi.AvailableVersions); v != s.version {
+			s.switchToCompatibleVersion(v)
+		}
+		return nil
+	}
+	if vi.ChosenVersion != s.version {
+		return qerr.NewError(qerr.VersionNegotiationError, fmt.Sprintf("server's chosen version (%s) doesn't match the negotiated version (%s)", vi.ChosenVersion, s.version))
+	}
+	// If we received a Version Negotiation packet, make sure that we would have chosen the same version
+	// if that packet had contained the versions the server actually supports.
+	if s.versionNegotiated {
+		v, ok := protocol.ChooseSupportedVersion(s.config.Versions, vi.AvailableVersions)
+		if !ok || !protocol.AreCompatibleVersions(v, s.version) {
+			return qerr.NewError(qerr.VersionNegotiationError, fmt.Sprintf("version downgrade detected: server supports %s", vi.AvailableVersions))
+		}
+	}
+	return nil
+}
+
 func (s *session) sendPackets() error {
 	s.pacingDeadline = time.Time{}
 
@@ -1438,6 +1861,13 @@ func (s *session) sendPackets() error {
 				s.pacingDeadline = s.sentPacketHandler.TimeUntilSend()
 				return nil
 			}
+			if s.mtuDiscoverer != nil && s.mtuDiscoverer.ShouldSendProbe(time.Now()) {
+				if err := s.sendMTUProbePacket(); err != nil {
+					return err
+				}
+				sentPacket = true
+				continue
+			}
 			sent, err := s.sendPacket()
 			if err != nil || !sent {
 				return err
@@ -1502,6 +1932,17 @@ func (s *session) sendProbePacket(encLevel protocol.EncryptionLevel) error {
 	return nil
 }
 
+func (s *session) sendMTUProbePacket() error {
+	ping, size := s.mtuDiscoverer.GetPing()
+	packet, err := s.packer.PackMTUProbePacket(ping, size)
+	if err != nil {
+		return err
+	}
+	s.logger.Debugf("Sending Path MTU probe packet (%d bytes)", size)
+	s.sendPackedPacket(packet)
+	return nil
+}
+
 func (s *session) sendPacket() (bool, error) {
 	if isBlocked, offset := s.connFlowController.IsNewlyBlocked(); isBlocked {
 		s.framer.QueueControlFrame(&wire.DataBlockedFrame{DataLimit: offset})
@@ -1515,14 +1956,21 @@ func (s *session) sendPacket() (bool, error) {
 			return false, err
 		}
 		s.logCoalescedPacket(now, packet)
+		ecn := protocol.ECNNon
 		for _, p := range packet.packets {
 			if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && p.IsAckEliciting() {
 				s.firstAckElicitingPacketAfterIdleSentTime = now
 			}
-			s.sentPacketHandler.SentPacket(p.ToAckHandlerPacket(now, s.retransmissionQueue))
+			ackhandlerPacket := p.ToAckHandlerPacket(now, s.retransmissionQueue)
+			s.sentPacketHandler.SentPacket(ackhandlerPacket)
+			// Only 1-RTT packets are ECN marked.
+			// Since it is the last packet in a coalesced packet, it determines the ECN marking of the datagram.
+			if ackhandlerPacket.EncryptionLevel == protocol.Encryption1RTT {
+				ecn = ackhandlerPacket.ECN
+			}
 		}
 		s.connIDManager.SentPacket()
-		s.sendQueue.Send(packet.buffer)
+		s.sendQueue.Send(packet.buffer, ecn)
 		return true, nil
 	}
 	packet, err := s.packer.PackPacket()
@@ -1538,10 +1986,15 @@ func (s *session) sendPackedPacket(packet *packedPacket) {
 	if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && packet.IsAckEliciting() {
 		s.firstAckElicitingPacketAfterIdleSentTime = now
 	}
-	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(time.Now(), s.retransmissionQueue))
+	ackhandlerPacket := packet.ToAckHandlerPacket(time.Now(), s.retransmissionQueue)
+	s.sentPacketHandler.SentPacket(ackhandlerPacket)
 	s.connIDManager.SentPacket()
 	s.logPacket(now, packet)
-	s.sendQueue.Send(packet.buffer)
+	if packet.isMTUProbePacket {
+		s.sendQueue.SendProbe(packet.buffer, ackhandlerPacket.ECN)
+		return
+	}
+	s.sendQueue.Send(packet.buffer, ackhandlerPacket.ECN)
 }
 
 func (s *session) sendConnectionClose(quicErr *qerr.QuicError) ([]byte, error) {
@@ -1550,7 +2003,7 @@ func (s *session) sendConnectionClose(quicErr *qerr.QuicError) ([]byte, error) {
 		return nil, err
 	}
 	s.logCoalescedPacket(time.Now(), packet)
-	return packet.buffer.Data, s.conn.Write(packet.buffer.Data)
+	return packet.buffer.Data, s.conn.Write(packet.buffer.Data, protocol.ECNNon)
 }
 
 func (s *session) logPacketContents(now time.Time, p *packetContents) {
@@ -1709,8 +2162,8 @@ func (s *session) onHasConnectionWindowUpdate() {
 	s.scheduleSending()
 }
 
-func (s *session) onHasStreamData(id protocol.StreamID) {
-	s.framer.AddActiveStream(id)
+func (s *session) onHasStreamData(id protocol.StreamID, priority StreamPriority) {
+	s.framer.AddActiveStream(id, priority)
 	s.scheduleSending()
 }
 
@@ -1720,6 +2173,407 @@ func (s *session) onStreamCompleted(id protocol.StreamID) {
 	}
 }
 
+func (s *session) SendMessage(p []byte) error {
+	if !s.supportsDatagrams() {
+		return errors.New("datagram support disabled")
+	}
+	f := &wire.DatagramFrame{DataLenPresent: true}
+	if protocol.ByteCount(len(p)) > f.MaxDataLen(s.peerParams.MaxDatagramFrameSize, s.version) {
+		return errors.New("message too large")
+	}
+	f.Data = make([]byte, len(p))
+	copy(f.Data, p)
+	return s.datagramQueue.AddAndWait(f)
+}
+
+func (s *session) ReceiveMessage(ctx context.Context) ([]byte, error) {
+	if !s.config.EnableDatagrams {
+		return nil, errors.New("datagram support disabled")
+	}
+	return s.datagramQueue.Receive(ctx)
+}
+
+// supportsDatagrams says if both endpoints enabled datagram support
+func (s *session) supportsDatagrams() bool {
+	return s.config.EnableDatagrams && s.peerParams != nil && s.peerParams.MaxDatagramFrameSize > 0
+}
+
+func (s *session) MigrateTo(pconn net.PacketConn) error {
+	if s.perspective == protocol.PerspectiveServer {
+		return errors.New("only clients can migrate")
+	}
+	runner, err := getMultiplexer().AddConn(pconn, s.srcConnIDLen, protocol.ByteCount(s.config.MaxUDPPayloadSize), s.config.StatelessResetKey)
+	if err != nil {
+		return err
+	}
+	m := &pathMigration{
+		pconn:  pconn,
+		runner: runner,
+		result: make(chan error, 1),
+	}
+	select {
+	case s.migrationRequests <- m:
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	}
+	return <-m.result
+}
+
+func (s *session) startPathMigration(m *pathMigration) {
+	if m.runner == s.runner {
+		m.result <- errors.New("already using this PacketConn")
+		return
+	}
+	if err := s.canMigrate(); err != nil {
+		m.result <- err
+		return
+	}
+	if connID, ok := s.connIDManager.GetUnused(); ok {
+		m.connID = connID
+	} else if s.connIDManager.Get().Len() > 0 {
+		// If the peer uses zero-length connection IDs, there's no connection ID that could link the two paths.
+		m.result <- errors.New("no unused connection ID available")
+		return
+	}
+	// Receive packets arriving on the new path.
+	for _, connID := range s.connIDGenerator.ActiveConnectionIDs() {
+		m.runner.Add(connID, s)
+	}
+	m.validator = newPathValidator()
+	s.migration = m
+	s.logger.Infof("Probing new path from %s.", m.pconn.LocalAddr())
+	s.sendPathChallenge()
+}
+
+// canMigrate checks if the connection can be migrated to a new path.
+func (s *session) canMigrate() error {
+	if s.migration != nil {
+		return errors.New("migration already in progress")
+	}
+	if !s.handshakeConfirmed {
+		return errors.New("cannot migrate before the handshake is confirmed")
+	}
+	if s.peerParams.DisableActiveMigration {
+		return errors.New("peer disabled active migration")
+	}
+	return nil
+}
+
+// migrateToPreferredAddress is called by the client when the handshake is confirmed.
+func (s *session) migrateToPreferredAddress() {
+	connID := *s.preferredAddressConnID
+	s.preferredAddressConnID = nil
+	addr := selectPreferredAddress(s.peerParams.PreferredAddress, s.conn.RemoteAddr())
+	if addr == nil || s.migration != nil {
+		s.logger.Debugf("Not migrating to the server's preferred address.")
+		s.connIDManager.RetireUnused(connID)
+		return
+	}
+	s.migration = &pathMigration{
+		remoteAddr: addr,
+		connID:     connID,
+		validator:  newPathValidator(),
+		result:     make(chan error, 1),
+	}
+	s.logger.Infof("Probing the server's preferred address %s.", addr)
+	s.sendPathChallenge()
+}
+
+// selectPreferredAddress selects the address of the preferred_address that uses the same address family as remote.
+// It returns nil if the server didn't send a usable address for this address family.
+func selectPreferredAddress(pa *wire.PreferredAddress, remote net.Addr) *net.UDPAddr {
+	udpAddr, ok := remote.(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	var addr *net.UDPAddr
+	if udpAddr.IP.To4() != nil {
+		addr = &net.UDPAddr{IP: pa.IPv4, Port: int(pa.IPv4Port)}
+	} else {
+		addr = &net.UDPAddr{IP: pa.IPv6, Port: int(pa.IPv6Port)}
+	}
+	if addr.IP.IsUnspecified() || addr.Port == 0 {
+		return nil
+	}
+	return addr
+}
+
+// sendPathChallenge sends a PATH_CHALLENGE frame on the path that is being validated.
+// Path validation fails if too many PATH_CHALLENGE frames were sent.
+func (s *session) sendPathChallenge() {
+	m := s.migration
+	now := time.Now()
+	f := m.validator.NextChallenge(now, 3*s.rttStats.PTO(false))
+	if f == nil {
+		s.failPathMigration(errors.New("path validation failed"))
+		return
+	}
+	packet, err := s.packer.PackPathProbePacket(m.connID.ConnectionID, []ackhandler.Frame{{
+		Frame: f,
+		// PATH_CHALLENGE frames are not retransmitted.
+		// A new PATH_CHALLENGE is sent when the path validation timer fires.
+		OnLost: func(wire.Frame) {},
+	}}, protocol.MinInitialPacketSize)
+	if err != nil {
+		s.failPathMigration(err)
+		return
+	}
+	addr := m.remoteAddr
+	if m.pconn != nil {
+		addr = s.conn.RemoteAddr()
+	}
+	if err := s.sendPathProbePacket(packet, m.pconn, addr); err != nil {
+		s.failPathMigration(err)
+	}
+}
+
+// sendPathProbePacket sends a packet on a path that is not the current path.
+// If pconn is nil, the packet is sent from the current local address.
+func (s *session) sendPathProbePacket(packet *packedPacket, pconn net.PacketConn, addr net.Addr) error {
+	now := time.Now()
+	ackhandlerPacket := packet.ToAckHandlerPacket(now, s.retransmissionQueue)
+	s.sentPacketHandler.SentPacket(ackhandlerPacket)
+	s.logPacket(now, packet)
+	defer packet.buffer.Release()
+	if pconn != nil {
+		return writePacket(pconn, packet.buffer.Data, addr, ackhandlerPacket.ECN)
+	}
+	return s.conn.WriteTo(packet.buffer.Data, addr, ackhandlerPacket.ECN)
+}
+
+func (s *session) completePathMigration() {
+	m := s.migration
+	s.migration = nil
+	if m.runner != nil {
+		token := s.connIDManager.StatelessResetToken()
+		// Stop receiving packets on the old path.
+		// Connection IDs might have been issued while the new path was validated,
+		// so we need to make sure that all active connection IDs are added for the new path.
+		for _, connID := range s.connIDGenerator.ActiveConnectionIDs() {
+			m.runner.Add(connID, s)
+			s.runner.Retire(connID)
+		}
+		if token != nil {
+			s.runner.RetireResetToken(*token)
+		}
+		s.runner = m.runner
+		if m.connID.ConnectionID.Len() == 0 && token != nil {
+			s.runner.AddResetToken(*token, s)
+		}
+	}
+	if m.connID.ConnectionID.Len() > 0 {
+		// This adds the stateless reset token for the new connection ID.
+		s.connIDManager.SwitchTo(m.connID)
+	}
+	if m.pconn != nil {
+		s.conn.SetPacketConn(m.pconn)
+	}
+	if m.remoteAddr != nil {
+		s.conn.SetCurrentRemoteAddr(m.remoteAddr)
+	}
+	s.sentPacketHandler.OnConnectionMigration()
+	s.resetMTU()
+	s.logger.Infof("Migrated connection to %s.", m)
+	m.result <- nil
+}
+
+func (s *session) failPathMigration(e error) {
+	m := s.migration
+	s.migration = nil
+	if m.runner != nil {
+		// The PacketConn might be used by other sessions, so we only stop receiving packets for this session.
+		// It is closed by the application.
+		for _, connID := range s.connIDGenerator.ActiveConnectionIDs() {
+			m.runner.Remove(connID)
+		}
+	}
+	if m.connID.ConnectionID.Len() > 0 {
+		s.connIDManager.RetireUnused(m.connID)
+	}
+	s.logger.Infof("Migration to %s failed: %s", m, e)
+	m.result <- e
+}
+
+// handlePeerAddress is called by the server for every 1-RTT packet received.
+// If the client starts sending non-probing packets from a new address, the new path is validated.
+func (s *session) handlePeerAddress(addr net.Addr, pn protocol.PacketNumber, size protocol.ByteCount, isNonProbing bool) {
+	v := s.peerPathValidation
+	if v != nil && addr != nil && equalAddr(addr, v.remoteAddr) {
+		v.bytesReceived += size
+	}
+	isLargest := pn > s.largestRcvdAppDataPacket
+	if isLargest {
+		s.largestRcvdAppDataPacket = pn
+	}
+	if addr == nil || equalAddr(addr, s.conn.RemoteAddr()) {
+		return
+	}
+	// Only packets with the largest packet number received so far cause a path change.
+	// Reordered packets might still arrive from the old address.
+	if !isNonProbing || !isLargest || !s.handshakeConfirmed {
+		return
+	}
+	if v != nil && equalAddr(addr, v.remoteAddr) {
+		return
+	}
+	if v != nil {
+		s.logger.Debugf("Abandoning path validation for %s.", v.remoteAddr)
+	}
+	s.logger.Infof("Received packet from new remote address %s. Validating path.", addr)
+	if s.tracer != nil {
+		s.tracer.StartedPathValidation(addr)
+	}
+	s.peerPathValidation = &peerPathValidation{
+		remoteAddr:    addr,
+		validator:     newPathValidator(),
+		bytesReceived: size,
+	}
+	s.sendPeerPathChallenge()
+}
+
+// sendPeerPathChallenge sends a PATH_CHALLENGE frame to the new remote address of the client.
+// Since the address has not been validated yet, it respects the anti-amplification limit.
+func (s *session) sendPeerPathChallenge() {
+	v := s.peerPathValidation
+	now := time.Now()
+	timeout := 3 * s.rttStats.PTO(false)
+	connID := s.connIDManager.Get()
+	var window protocol.ByteCount
+	if v.bytesSent < protocol.AmplificationFactor*v.bytesReceived {
+		window = protocol.AmplificationFactor*v.bytesReceived - v.bytesSent
+	}
+	// Check the anti-amplification limit before generating the PATH_CHALLENGE,
+	// so that we don't use up one of the PATH_CHALLENGE frames allowed for this path validation.
+	if window < maxPathChallengePacketSize(connID, s.version) {
+		// We'll try again when the path validation timer fires.
+		s.logger.Debugf("Not sending PATH_CHALLENGE to %s, since it would exceed the anti-amplification limit.", v.remoteAddr)
+		if !v.validator.Postpone(now, timeout) {
+			s.logger.Infof("Path validation for %s failed. The peer didn't send enough data on the new path.", v.remoteAddr)
+			s.peerPathValidation = nil
+		}
+		return
+	}
+	f := v.validator.NextChallenge(now, timeout)
+	if f == nil {
+		s.logger.Infof("Path validation for %s failed.", v.remoteAddr)
+		s.peerPathValidation = nil
+		return
+	}
+	packet, err := s.packer.PackPathProbePacket(connID, []ackhandler.Frame{{
+		Frame: f,
+		// PATH_CHALLENGE frames are not retransmitted.
+		// A new PATH_CHALLENGE is sent when the path validation timer fires.
+		OnLost: func(wire.Frame) {},
+	}}, utils.MinByteCount(protocol.MinInitialPacketSize, window))
+	if err != nil {
+		s.closeLocal(err)
+		return
+	}
+	v.bytesSent += packet.buffer.Len()
+	if err := s.sendPathProbePacket(packet, nil, v.remoteAddr); err != nil {
+		s.logger.Debugf("Sending PATH_CHALLENGE to %s failed: %s", v.remoteAddr, err)
+	}
+}
+
+// maxPathChallengePacketSize is the maximum size of a 1-RTT packet that only contains a PATH_CHALLENGE frame.
+func maxPathChallengePacketSize(connID protocol.ConnectionID, v protocol.VersionNumber) protocol.ByteCount {
+	hdr := &wire.ExtendedHeader{
+		Header:          wire.Header{DestConnectionID: connID},
+		PacketNumberLen: protocol.PacketNumberLen4,
+	}
+	return hdr.GetLength(v) + (&wire.PathChallengeFrame{}).Length(v) + 16 /* AEAD tag */
+}
+
+func (s *session) completePeerPathValidation() {
+	v := s.peerPathValidation
+	s.peerPathValidation = nil
+	// If only the port changed, this is most likely a NAT rebinding,
+	// and there's no need to reset the congestion controller.
+	pathChanged := !onlyPortChanged(s.conn.RemoteAddr(), v.remoteAddr)
+	if pathChanged {
+		s.sentPacketHandler.OnConnectionMigration()
+	}
+	s.conn.SetCurrentRemoteAddr(v.remoteAddr)
+	if pathChanged {
+		s.resetMTU()
+	}
+	s.logger.Infof("Validated new remote address %s.", v.remoteAddr)
+	if s.tracer != nil {
+		s.tracer.UpdatedPath(v.remoteAddr)
+	}
+}
+
+// startMTUDiscovery starts probing for packet sizes larger than the size
+// derived from the remote address (see getMaxPacketSize).
+func (s *session) startMTUDiscovery() {
+	base := getMaxPacketSize(s.conn.RemoteAddr())
+	max := s.maxMTUProbeSize()
+	if max <= base {
+		return
+	}
+	s.mtuDiscoverer = newMTUDiscoverer(s.rttStats, base, max, s.onMTUChange)
+}
+
+// maxMTUProbeSize is the upper bound for Path MTU Discovery.
+// Packets are never larger than our largest packet buffers, or than the peer's max_udp_payload_size.
+func (s *session) maxMTUProbeSize() protocol.ByteCount {
+	max := protocol.MaxJumboPacketSize
+	if s.peerParams != nil && s.peerParams.MaxUDPPayloadSize != 0 {
+		max = utils.MinByteCount(max, s.peerParams.MaxUDPPayloadSize)
+	}
+	return max
+}
+
+func (s *session) onMTUChange(mtu protocol.ByteCount, done bool) {
+	s.logger.Debugf("Setting max packet size to %d bytes (Path MTU Discovery finished: %t)", mtu, done)
+	s.packer.SetMaxPacketSize(mtu)
+	if s.tracer != nil {
+		s.tracer.UpdatedMTU(mtu, done)
+	}
+}
+
+// resetMTU is called when the connection is migrated to a new path.
+// The MTU of the new path is unknown, so Path MTU Discovery starts from the base size again.
+func (s *session) resetMTU() {
+	if s.mtuDiscoverer == nil {
+		return
+	}
+	base := getMaxPacketSize(s.conn.RemoteAddr())
+	s.packer.SetMaxPacketSize(base)
+	if s.tracer != nil {
+		s.tracer.UpdatedMTU(base, false)
+	}
+	max := s.maxMTUProbeSize()
+	if !s.conn.SupportsDF() || max <= base {
+		s.mtuDiscoverer = nil
+		return
+	}
+	s.mtuDiscoverer.Reset(base, max)
+}
+
+// equalAddr says if two addresses are equal.
+// It is called for every packet received, and therefore avoids allocating for UDP addresses.
+func equalAddr(a, b net.Addr) bool {
+	ua, okA := a.(*net.UDPAddr)
+	ub, okB := b.(*net.UDPAddr)
+	if !okA || !okB {
+		return a.Network() == b.Network() && a.String() == b.String()
+	}
+	return ua.Port == ub.Port && ua.Zone == ub.Zone && ua.IP.Equal(ub.IP)
+}
+
+func onlyPortChanged(a, b net.Addr) bool {
+	ua, ok := a.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	ub, ok := b.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	return ua.IP.Equal(ub.IP)
+}
+
 func (s *session) LocalAddr() net.Addr {
 	return s.conn.LocalAddr()
 }
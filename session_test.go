@@ -22,6 +22,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	"github.com/lucas-clemente/quic-go/logging"
+	"github.com/lucas-clemente/quic-go/quictrace"
 
 	"github.com/golang/mock/gomock"
 
@@ -85,7 +86,8 @@ var _ = Describe("Session", func() {
 		mconn = NewMockConnection(mockCtrl)
 		mconn.EXPECT().RemoteAddr().Return(remoteAddr).AnyTimes()
 		mconn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
-		tokenGenerator, err := handshake.NewTokenGenerator()
+		mconn.EXPECT().SupportsECN()
+		tokenGenerator, err := handshake.NewTokenGenerator(nil)
 		Expect(err).ToNot(HaveOccurred())
 		tracer = mocks.NewMockConnectionTracer(mockCtrl)
 		tracer.EXPECT().SentTransportParameters(gomock.Any())
@@ -198,7 +200,7 @@ var _ = Describe("Session", func() {
 				Expect(sess.handleFrame(&wire.ResetStreamFrame{
 					StreamID:  3,
 					ErrorCode: 42,
-				}, protocol.EncryptionUnspecified)).To(Succeed())
+				}, protocol.EncryptionUnspecified, nil)).To(Succeed())
 			})
 		})
 
@@ -233,7 +235,7 @@ var _ = Describe("Session", func() {
 				Expect(sess.handleFrame(&wire.MaxStreamDataFrame{
 					StreamID:   10,
 					ByteOffset: 1337,
-				}, protocol.EncryptionUnspecified)).To(Succeed())
+				}, protocol.EncryptionUnspecified, nil)).To(Succeed())
 			})
 		})
 
@@ -275,7 +277,7 @@ var _ = Describe("Session", func() {
 				Expect(sess.handleFrame(&wire.StopSendingFrame{
 					StreamID:  3,
 					ErrorCode: 1337,
-				}, protocol.EncryptionUnspecified)).To(Succeed())
+				}, protocol.EncryptionUnspecified, nil)).To(Succeed())
 			})
 		})
 
@@ -283,23 +285,23 @@ var _ = Describe("Session", func() {
 			Expect(sess.handleFrame(&wire.NewConnectionIDFrame{
 				SequenceNumber: 10,
 				ConnectionID:   protocol.ConnectionID{1, 2, 3, 4},
-			}, protocol.Encryption1RTT)).To(Succeed())
+			}, protocol.Encryption1RTT, nil)).To(Succeed())
 			Expect(sess.connIDManager.queue.Back().Value.ConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
 		})
 
 		It("handles PING frames", func() {
-			err := sess.handleFrame(&wire.PingFrame{}, protocol.EncryptionUnspecified)
+			err := sess.handleFrame(&wire.PingFrame{}, protocol.EncryptionUnspecified, nil)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("rejects PATH_RESPONSE frames", func() {
-			err := sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.EncryptionUnspecified)
-			Expect(err).To(MatchError("unexpected PATH_RESPONSE frame"))
+		It("ignores PATH_RESPONSE frames that don't match a PATH_CHALLENGE", func() {
+			err := sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.EncryptionUnspecified, nil)
+			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("handles PATH_CHALLENGE frames", func() {
 			data := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
-			err := sess.handleFrame(&wire.PathChallengeFrame{Data: data}, protocol.EncryptionUnspecified)
+			err := sess.handleFrame(&wire.PathChallengeFrame{Data: data}, protocol.EncryptionUnspecified, &receivedPacket{remoteAddr: remoteAddr})
 			Expect(err).ToNot(HaveOccurred())
 			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
 			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.PathResponseFrame{Data: data}}}))
@@ -313,17 +315,17 @@ var _ = Describe("Session", func() {
 		})
 
 		It("handles BLOCKED frames", func() {
-			err := sess.handleFrame(&wire.DataBlockedFrame{}, protocol.EncryptionUnspecified)
+			err := sess.handleFrame(&wire.DataBlockedFrame{}, protocol.EncryptionUnspecified, nil)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("handles STREAM_BLOCKED frames", func() {
-			err := sess.handleFrame(&wire.StreamDataBlockedFrame{}, protocol.EncryptionUnspecified)
+			err := sess.handleFrame(&wire.StreamDataBlockedFrame{}, protocol.EncryptionUnspecified, nil)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("handles STREAM_ID_BLOCKED frames", func() {
-			err := sess.handleFrame(&wire.StreamsBlockedFrame{}, protocol.EncryptionUnspecified)
+			err := sess.handleFrame(&wire.StreamsBlockedFrame{}, protocol.EncryptionUnspecified, nil)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -347,7 +349,7 @@ var _ = Describe("Session", func() {
 			Expect(sess.handleFrame(&wire.ConnectionCloseFrame{
 				ErrorCode:    qerr.StreamLimitError,
 				ReasonPhrase: "foobar",
-			}, protocol.EncryptionUnspecified)).To(Succeed())
+			}, protocol.EncryptionUnspecified, nil)).To(Succeed())
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
@@ -373,7 +375,7 @@ var _ = Describe("Session", func() {
 				ReasonPhrase:       "foobar",
 				IsApplicationError: true,
 			}
-			Expect(sess.handleFrame(ccf, protocol.EncryptionUnspecified)).To(Succeed())
+			Expect(sess.handleFrame(ccf, protocol.EncryptionUnspecified, nil)).To(Succeed())
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
@@ -428,7 +430,7 @@ var _ = Describe("Session", func() {
 				Expect(quicErr.ErrorMessage).To(BeEmpty())
 				return &coalescedPacket{buffer: buffer}, nil
 			})
-			mconn.EXPECT().Write([]byte("connection close"))
+			mconn.EXPECT().Write([]byte("connection close"), protocol.ECNNon)
 			tracer.EXPECT().Close()
 			sess.shutdown()
 			Eventually(areSessionsRunning).Should(BeFalse())
@@ -441,7 +443,7 @@ var _ = Describe("Session", func() {
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
 			sess.shutdown()
@@ -460,7 +462,7 @@ var _ = Describe("Session", func() {
 				Expect(quicErr.ErrorMessage).To(Equal("test error"))
 				return &coalescedPacket{buffer: getPacketBuffer()}, nil
 			})
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().Close()
 			sess.CloseWithError(0x1337, "test error")
 			Eventually(areSessionsRunning).Should(BeFalse())
@@ -480,7 +482,7 @@ var _ = Describe("Session", func() {
 				Expect(quicErr.ErrorMessage).To(Equal("test error"))
 				return &coalescedPacket{buffer: getPacketBuffer()}, nil
 			})
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().Close()
 			sess.closeLocal(testErr)
 			Eventually(areSessionsRunning).Should(BeFalse())
@@ -515,7 +517,7 @@ var _ = Describe("Session", func() {
 				close(returned)
 			}()
 			Consistently(returned).ShouldNot(BeClosed())
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
 			Eventually(returned).Should(BeClosed())
@@ -552,7 +554,7 @@ var _ = Describe("Session", func() {
 			// don't EXPECT any calls to packer.PackPacket()
 			sess.handlePacket(&receivedPacket{
 				rcvTime:    time.Now(),
-				remoteAddr: &net.UDPAddr{},
+				remoteAddr: remoteAddr,
 				buffer:     getPacketBuffer(),
 				data:       buf.Bytes(),
 			})
@@ -615,6 +617,26 @@ var _ = Describe("Session", func() {
 			Expect(sess.handlePacketImpl(p)).To(BeFalse())
 		})
 
+		It("accepts packets that have the QUIC bit cleared, if greasing is enabled", func() {
+			sess.config.GreaseQUICBit = true
+			hdr := &wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			p := getPacket(hdr, nil)
+			p.data[0] ^= 0x40 // unset the QUIC bit
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    0x37,
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             hdr,
+				data:            []byte{0}, // one PADDING frame
+			}, nil)
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedPacket(hdr, protocol.ByteCount(len(p.data)), nil)
+			Expect(sess.handlePacketImpl(p)).To(BeTrue())
+		})
+
 		It("drops packets for which the version is unsupported", func() {
 			p := getPacket(&wire.ExtendedHeader{
 				Header: wire.Header{
@@ -667,13 +689,41 @@ var _ = Describe("Session", func() {
 			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
 			gomock.InOrder(
 				rph.EXPECT().IsPotentiallyDuplicate(protocol.PacketNumber(0x1337), protocol.EncryptionInitial),
-				rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.EncryptionInitial, rcvTime, false),
+				rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.ECNNon, protocol.EncryptionInitial, rcvTime, false),
 			)
 			sess.receivedPacketHandler = rph
 			packet.rcvTime = rcvTime
 			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			tracer.EXPECT().ReceivedPacket(hdr, protocol.ByteCount(len(packet.data)), nil)
 			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+			Expect(sess.packetsReceived).To(BeEquivalentTo(1))
+			Expect(sess.bytesReceived).To(Equal(protocol.ByteCount(len(packet.data))))
+		})
+
+		It("notes when data was received in 0-RTT packets", func() {
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketType0RTT,
+					DestConnectionID: srcConnID,
+					Version:          sess.version,
+					Length:           1,
+				},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			packet := getPacket(hdr, nil)
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    0x37,
+				encryptionLevel: protocol.Encryption0RTT,
+				hdr:             hdr,
+				data:            []byte{0}, // one PADDING frame
+			}, nil)
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedPacket(hdr, protocol.ByteCount(len(packet.data)), nil)
+			Expect(sess.Received0RTT()).To(BeFalse())
+			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+			Expect(sess.Received0RTT()).To(BeTrue())
 		})
 
 		It("informs the ReceivedPacketHandler about ack-eliciting packets", func() {
@@ -695,10 +745,11 @@ var _ = Describe("Session", func() {
 			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
 			gomock.InOrder(
 				rph.EXPECT().IsPotentiallyDuplicate(protocol.PacketNumber(0x1337), protocol.Encryption1RTT),
-				rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.Encryption1RTT, rcvTime, true),
+				rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.ECNCE, protocol.Encryption1RTT, rcvTime, true),
 			)
 			sess.receivedPacketHandler = rph
 			packet.rcvTime = rcvTime
+			packet.ecn = protocol.ECNCE
 			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			tracer.EXPECT().ReceivedPacket(hdr, protocol.ByteCount(len(packet.data)), []wire.Frame{&wire.PingFrame{}})
 			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
@@ -751,7 +802,7 @@ var _ = Describe("Session", func() {
 			Consistently(sess.Context().Done()).ShouldNot(BeClosed())
 			// make the go routine return
 			tracer.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			sess.closeLocal(errors.New("close"))
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
@@ -771,7 +822,32 @@ var _ = Describe("Session", func() {
 				close(done)
 			}()
 			expectReplaceWithClosed()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
+			packet := getPacket(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}, nil)
+			tracer.EXPECT().Close()
+			sess.handlePacket(packet)
+			Eventually(sess.Context().Done()).Should(BeClosed())
+		})
+
+		It("closes the session when the AEAD integrity limit is reached", func() {
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, qerr.NewError(qerr.AEADLimitReached, "too many invalid packets"))
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				err := sess.run()
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.AEADLimitReached))
+				close(done)
+			}()
+			expectReplaceWithClosed()
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			packet := getPacket(&wire.ExtendedHeader{
 				Header:          wire.Header{DestConnectionID: srcConnID},
 				PacketNumberLen: protocol.PacketNumberLen1,
@@ -802,7 +878,7 @@ var _ = Describe("Session", func() {
 			Consistently(runErr).ShouldNot(Receive())
 			// make the go routine return
 			tracer.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
@@ -824,7 +900,7 @@ var _ = Describe("Session", func() {
 				close(done)
 			}()
 			expectReplaceWithClosed()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().Close()
 			sess.handlePacket(getPacket(&wire.ExtendedHeader{
 				Header:          wire.Header{DestConnectionID: srcConnID},
@@ -898,20 +974,169 @@ var _ = Describe("Session", func() {
 		})
 
 		Context("updating the remote address", func() {
-			It("doesn't support connection migration", func() {
+			newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 100), Port: 4321}
+			var sph *mockackhandler.MockSentPacketHandler
+
+			BeforeEach(func() {
+				sess.handshakeConfirmed = true
+				sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedBytes(gomock.Any()).AnyTimes()
+				sess.sentPacketHandler = sph
+				tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			})
+
+			receivePacket := func(pn protocol.PacketNumber, addr net.Addr, data []byte) *receivedPacket {
 				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
 					encryptionLevel: protocol.Encryption1RTT,
-					hdr:             &wire.ExtendedHeader{},
-					data:            []byte{0}, // one PADDING frame
+					packetNumber:    pn,
+					hdr:             &wire.ExtendedHeader{PacketNumber: pn},
+					data:            data,
 				}, nil)
 				packet := getPacket(&wire.ExtendedHeader{
 					Header:          wire.Header{DestConnectionID: srcConnID},
+					PacketNumber:    pn,
 					PacketNumberLen: protocol.PacketNumberLen1,
 				}, nil)
-				packet.remoteAddr = &net.IPAddr{IP: net.IPv4(192, 168, 0, 100)}
-				tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
-				tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet.data)), gomock.Any())
+				packet.remoteAddr = addr
 				Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+				return packet
+			}
+
+			expectPathChallenge := func(size protocol.ByteCount) *wire.PathChallengeFrame {
+				var challenge *wire.PathChallengeFrame
+				packer.EXPECT().PackPathProbePacket(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ protocol.ConnectionID, frames []ackhandler.Frame, _ protocol.ByteCount) (*packedPacket, error) {
+					Expect(frames).To(HaveLen(1))
+					challenge = frames[0].Frame.(*wire.PathChallengeFrame)
+					buffer := getPacketBuffer()
+					buffer.Data = buffer.Data[:size]
+					return &packedPacket{
+						packetContents: &packetContents{
+							header: &wire.ExtendedHeader{PacketNumber: 10},
+							frames: frames,
+						},
+						buffer: buffer,
+					}, nil
+				})
+				return challenge
+			}
+
+			It("doesn't validate a new path for packets only containing probing frames", func() {
+				receivePacket(1, newAddr, []byte{0}) // one PADDING frame
+				Expect(sess.peerPathValidation).To(BeNil())
+			})
+
+			It("doesn't validate a new path before the handshake is confirmed", func() {
+				sess.handshakeConfirmed = false
+				receivePacket(1, newAddr, []byte{0x1}) // one PING frame
+				Expect(sess.peerPathValidation).To(BeNil())
+			})
+
+			It("doesn't validate a new path for reordered packets", func() {
+				receivePacket(10, remoteAddr, []byte{0x1}) // one PING frame
+				receivePacket(9, newAddr, []byte{0x1})     // one PING frame
+				Expect(sess.peerPathValidation).To(BeNil())
+			})
+
+			It("validates a new path and switches to it", func() {
+				tracer.EXPECT().StartedPathValidation(newAddr)
+				expectPathChallenge(20)
+				sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) { p.ECN = protocol.ECT0 })
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				mconn.EXPECT().WriteTo(gomock.Any(), newAddr, protocol.ECT0)
+				receivePacket(1, newAddr, []byte{0x1}) // one PING frame
+				Expect(sess.peerPathValidation).ToNot(BeNil())
+				challenge := sess.peerPathValidation.validator.challenges[0]
+				gomock.InOrder(
+					sph.EXPECT().OnConnectionMigration(),
+					mconn.EXPECT().SetCurrentRemoteAddr(newAddr),
+				)
+				tracer.EXPECT().UpdatedPath(newAddr)
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: challenge}, protocol.Encryption1RTT, nil)).To(Succeed())
+				Expect(sess.peerPathValidation).To(BeNil())
+			})
+
+			It("doesn't reset the congestion controller if only the port changed", func() {
+				rebindAddr := &net.UDPAddr{IP: remoteAddr.IP, Port: remoteAddr.Port + 1}
+				tracer.EXPECT().StartedPathValidation(rebindAddr)
+				expectPathChallenge(20)
+				sph.EXPECT().SentPacket(gomock.Any())
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				mconn.EXPECT().WriteTo(gomock.Any(), rebindAddr, protocol.ECNNon)
+				receivePacket(1, rebindAddr, []byte{0x1}) // one PING frame
+				challenge := sess.peerPathValidation.validator.challenges[0]
+				mconn.EXPECT().SetCurrentRemoteAddr(rebindAddr)
+				tracer.EXPECT().UpdatedPath(rebindAddr)
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: challenge}, protocol.Encryption1RTT, nil)).To(Succeed())
+			})
+
+			It("responds to PATH_CHALLENGE frames on the path they were received on", func() {
+				// The client is migrating, and packets sent on the old path are dropped.
+				// The PATH_RESPONSE therefore needs to be sent on the new path.
+				challenge := &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
+				b := &bytes.Buffer{}
+				Expect(challenge.Write(b, sess.version)).To(Succeed())
+				var size protocol.ByteCount
+				packer.EXPECT().PackPathProbePacket(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ protocol.ConnectionID, frames []ackhandler.Frame, maxSize protocol.ByteCount) (*packedPacket, error) {
+					Expect(frames).To(HaveLen(1))
+					Expect(frames[0].Frame).To(Equal(&wire.PathResponseFrame{Data: challenge.Data}))
+					size = maxSize
+					return &packedPacket{
+						packetContents: &packetContents{
+							header: &wire.ExtendedHeader{PacketNumber: 10},
+							frames: frames,
+						},
+						buffer: getPacketBuffer(),
+					}, nil
+				})
+				sph.EXPECT().SentPacket(gomock.Any())
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				mconn.EXPECT().WriteTo(gomock.Any(), newAddr, protocol.ECNNon)
+				p := receivePacket(1, newAddr, b.Bytes())
+				Expect(size).To(Equal(utils.MinByteCount(protocol.MinInitialPacketSize, protocol.AmplificationFactor*protocol.ByteCount(len(p.data)))))
+				// nothing is sent on the old path
+				frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+				Expect(frames).To(BeEmpty())
+				Expect(sess.peerPathValidation).To(BeNil())
+			})
+
+			It("respects the anti-amplification limit on the new path", func() {
+				receivePacket(1, remoteAddr, []byte{0x1}) // one PING frame
+				sess.peerPathValidation = &peerPathValidation{
+					remoteAddr:    newAddr,
+					validator:     newPathValidator(),
+					bytesReceived: 5,
+				}
+				Expect(protocol.AmplificationFactor * 5).To(BeNumerically("<", maxPathChallengePacketSize(sess.connIDManager.Get(), sess.version)))
+				sess.sendPeerPathChallenge()
+				Expect(sess.peerPathValidation.bytesSent).To(BeZero())
+				// No PATH_CHALLENGE was used up.
+				Expect(sess.peerPathValidation.validator.challenges).To(BeEmpty())
+				Expect(sess.peerPathValidation.validator.Deadline()).To(BeTemporally(">", time.Now()))
+				// Once enough data was received, the PATH_CHALLENGE is sent.
+				sess.peerPathValidation.bytesReceived = 100
+				expectPathChallenge(200)
+				sph.EXPECT().SentPacket(gomock.Any())
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				mconn.EXPECT().WriteTo(gomock.Any(), newAddr, protocol.ECNNon)
+				sess.sendPeerPathChallenge()
+				Expect(sess.peerPathValidation.bytesSent).To(Equal(protocol.ByteCount(200)))
+				Expect(sess.peerPathValidation.validator.challenges).To(HaveLen(1))
+			})
+
+			It("fails path validation if the peer doesn't send enough data on the new path", func() {
+				receivePacket(1, remoteAddr, []byte{0x1}) // one PING frame
+				sess.peerPathValidation = &peerPathValidation{
+					remoteAddr:    newAddr,
+					validator:     newPathValidator(),
+					bytesReceived: 5,
+				}
+				for i := 0; i < protocol.MaxPathChallengePostponements; i++ {
+					sess.sendPeerPathChallenge()
+					Expect(sess.peerPathValidation).ToNot(BeNil())
+				}
+				sess.sendPeerPathChallenge()
+				Expect(sess.peerPathValidation).To(BeNil())
 			})
 		})
 
@@ -1043,7 +1268,7 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
@@ -1073,17 +1298,62 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackPacket().Return(p, nil)
 			packer.EXPECT().PackPacket().Return(nil, nil).AnyTimes()
 			sent := make(chan struct{})
-			mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(sent) })
+			mconn.EXPECT().WritePackets(gomock.Any(), gomock.Any()).Do(func([][]byte, protocol.ECN) { close(sent) })
 			tracer.EXPECT().SentPacket(p.header, p.buffer.Len(), nil, []wire.Frame{})
 			sess.scheduleSending()
 			Eventually(sent).Should(BeClosed())
 		})
 
+		It("sets the ECN marking chosen by the SentPacketHandler", func() {
+			sess.handshakeConfirmed = true
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) { p.ECN = protocol.ECT0 })
+			sess.sentPacketHandler = sph
+			runSession()
+			p := getPacket(1)
+			packer.EXPECT().PackPacket().Return(p, nil)
+			packer.EXPECT().PackPacket().Return(nil, nil).AnyTimes()
+			sent := make(chan struct{})
+			mconn.EXPECT().WritePackets(gomock.Any(), protocol.ECT0).Do(func([][]byte, protocol.ECN) { close(sent) })
+			tracer.EXPECT().SentPacket(p.header, p.buffer.Len(), nil, []wire.Frame{})
+			sess.scheduleSending()
+			Eventually(sent).Should(BeClosed())
+		})
+
+		It("sends Path MTU probe packets", func() {
+			sess.handshakeConfirmed = true
+			sess.mtuDiscoverer = newMTUDiscoverer(sess.rttStats, 1000, 2000, func(protocol.ByteCount, bool) {})
+			sess.mtuDiscoverer.lastProbeTime = time.Now().Add(-time.Hour)
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			sph.EXPECT().SentPacket(gomock.Any())
+			sess.sentPacketHandler = sph
+			p := getPacket(1)
+			packer.EXPECT().PackMTUProbePacket(gomock.Any(), protocol.ByteCount(1500)).DoAndReturn(func(ping ackhandler.Frame, _ protocol.ByteCount) (*packedPacket, error) {
+				Expect(ping.Frame).To(Equal(&wire.PingFrame{}))
+				return p, nil
+			})
+			packer.EXPECT().PackPacket().Return(nil, nil).AnyTimes()
+			sent := make(chan struct{})
+			mconn.EXPECT().WritePackets(gomock.Any(), gomock.Any()).Do(func([][]byte, protocol.ECN) { close(sent) })
+			tracer.EXPECT().SentPacket(p.header, p.buffer.Len(), nil, []wire.Frame{})
+			runSession()
+			sess.scheduleSending()
+			Eventually(sent).Should(BeClosed())
+		})
+
 		It("doesn't send packets if there's nothing to send", func() {
 			sess.handshakeConfirmed = true
 			runSession()
 			packer.EXPECT().PackPacket().Return(nil, nil).AnyTimes()
-			sess.receivedPacketHandler.ReceivedPacket(0x035e, protocol.Encryption1RTT, time.Now(), true)
+			sess.receivedPacketHandler.ReceivedPacket(0x035e, protocol.ECNNon, protocol.Encryption1RTT, time.Now(), true)
 			sess.scheduleSending()
 			time.Sleep(50 * time.Millisecond) // make sure there are no calls to mconn.Write()
 		})
@@ -1119,7 +1389,7 @@ var _ = Describe("Session", func() {
 			sess.connFlowController = fc
 			runSession()
 			sent := make(chan struct{})
-			mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(sent) })
+			mconn.EXPECT().WritePackets(gomock.Any(), gomock.Any()).Do(func([][]byte, protocol.ECN) { close(sent) })
 			tracer.EXPECT().SentPacket(p.header, p.length, nil, []wire.Frame{})
 			sess.scheduleSending()
 			Eventually(sent).Should(BeClosed())
@@ -1174,7 +1444,7 @@ var _ = Describe("Session", func() {
 					sess.sentPacketHandler = sph
 					runSession()
 					sent := make(chan struct{})
-					mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(sent) })
+					mconn.EXPECT().WritePackets(gomock.Any(), gomock.Any()).Do(func([][]byte, protocol.ECN) { close(sent) })
 					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
 					sess.scheduleSending()
 					Eventually(sent).Should(BeClosed())
@@ -1195,7 +1465,7 @@ var _ = Describe("Session", func() {
 					sess.sentPacketHandler = sph
 					runSession()
 					sent := make(chan struct{})
-					mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(sent) })
+					mconn.EXPECT().WritePackets(gomock.Any(), gomock.Any()).Do(func([][]byte, protocol.ECN) { close(sent) })
 					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
 					sess.scheduleSending()
 					Eventually(sent).Should(BeClosed())
@@ -1207,6 +1477,50 @@ var _ = Describe("Session", func() {
 		}
 	})
 
+	Context("Path MTU Discovery", func() {
+		It("starts Path MTU Discovery when the handshake is confirmed", func() {
+			sess.peerParams = &wire.TransportParameters{MaxUDPPayloadSize: 1400}
+			mconn.EXPECT().SupportsDF().Return(true)
+			tracer.EXPECT().DroppedEncryptionLevel(protocol.EncryptionHandshake)
+			sess.dropEncryptionLevel(protocol.EncryptionHandshake)
+			Expect(sess.mtuDiscoverer).ToNot(BeNil())
+			Expect(sess.mtuDiscoverer.CurrentSize()).To(BeEquivalentTo(protocol.MaxPacketSizeIPv4))
+			Expect(sess.mtuDiscoverer.max).To(Equal(protocol.ByteCount(1400)))
+		})
+
+		It("doesn't start Path MTU Discovery if the DF bit can't be set", func() {
+			mconn.EXPECT().SupportsDF().Return(false)
+			tracer.EXPECT().DroppedEncryptionLevel(protocol.EncryptionHandshake)
+			sess.dropEncryptionLevel(protocol.EncryptionHandshake)
+			Expect(sess.mtuDiscoverer).To(BeNil())
+		})
+
+		It("doesn't start Path MTU Discovery if the peer doesn't accept larger packets", func() {
+			sess.peerParams = &wire.TransportParameters{MaxUDPPayloadSize: 1200}
+			mconn.EXPECT().SupportsDF().Return(true)
+			tracer.EXPECT().DroppedEncryptionLevel(protocol.EncryptionHandshake)
+			sess.dropEncryptionLevel(protocol.EncryptionHandshake)
+			Expect(sess.mtuDiscoverer).To(BeNil())
+		})
+
+		It("updates the max packet size when a larger MTU is found", func() {
+			packer.EXPECT().SetMaxPacketSize(protocol.ByteCount(1400))
+			tracer.EXPECT().UpdatedMTU(protocol.ByteCount(1400), true)
+			sess.onMTUChange(1400, true)
+		})
+
+		It("resets the MTU when the connection is migrated", func() {
+			sess.mtuDiscoverer = newMTUDiscoverer(sess.rttStats, 1000, 1400, sess.onMTUChange)
+			sess.mtuDiscoverer.current = 1300
+			mconn.EXPECT().SupportsDF().Return(true)
+			packer.EXPECT().SetMaxPacketSize(protocol.ByteCount(protocol.MaxPacketSizeIPv4))
+			tracer.EXPECT().UpdatedMTU(protocol.ByteCount(protocol.MaxPacketSizeIPv4), false)
+			sess.resetMTU()
+			Expect(sess.mtuDiscoverer.CurrentSize()).To(BeEquivalentTo(protocol.MaxPacketSizeIPv4))
+			Expect(sess.mtuDiscoverer.max).To(Equal(protocol.MaxJumboPacketSize))
+		})
+	})
+
 	Context("packet pacing", func() {
 		var sph *mockackhandler.MockSentPacketHandler
 
@@ -1225,7 +1539,7 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
@@ -1239,13 +1553,21 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).Times(3)
 			packer.EXPECT().PackPacket().Return(getPacket(10), nil)
 			packer.EXPECT().PackPacket().Return(getPacket(11), nil)
-			mconn.EXPECT().Write(gomock.Any()).Times(2)
+			written := make(chan struct{}, 2)
+			// the send queue might write both packets in a single batch
+			mconn.EXPECT().WritePackets(gomock.Any(), gomock.Any()).DoAndReturn(func(p [][]byte, _ protocol.ECN) (int, error) {
+				for range p {
+					written <- struct{}{}
+				}
+				return len(p), nil
+			}).MinTimes(1).MaxTimes(2)
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
 				sess.run()
 			}()
 			sess.scheduleSending()
+			Eventually(written).Should(HaveLen(2))
 			time.Sleep(50 * time.Millisecond) // make sure that only 2 packes are sent
 		})
 
@@ -1257,7 +1579,7 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny)
 			sph.EXPECT().SendMode().Return(ackhandler.SendAck)
 			packer.EXPECT().PackPacket().Return(getPacket(100), nil)
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().WritePackets(gomock.Any(), gomock.Any())
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1283,8 +1605,10 @@ var _ = Describe("Session", func() {
 				sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour)),
 			)
 			written := make(chan struct{}, 2)
-			mconn.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
-				written <- struct{}{}
+			mconn.EXPECT().WritePackets(gomock.Any(), gomock.Any()).DoAndReturn(func(p [][]byte, _ protocol.ECN) (int, error) {
+				for range p {
+					written <- struct{}{}
+				}
 				return len(p), nil
 			}).Times(2)
 			go func() {
@@ -1308,10 +1632,13 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackPacket().Return(getPacket(1001), nil)
 			packer.EXPECT().PackPacket().Return(getPacket(1002), nil)
 			written := make(chan struct{}, 3)
-			mconn.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
-				written <- struct{}{}
+			// the send queue might write multiple packets in a single batch
+			mconn.EXPECT().WritePackets(gomock.Any(), gomock.Any()).DoAndReturn(func(p [][]byte, _ protocol.ECN) (int, error) {
+				for range p {
+					written <- struct{}{}
+				}
 				return len(p), nil
-			}).Times(3)
+			}).MinTimes(1).MaxTimes(3)
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1347,7 +1674,7 @@ var _ = Describe("Session", func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
@@ -1373,7 +1700,7 @@ var _ = Describe("Session", func() {
 			time.Sleep(50 * time.Millisecond)
 			// only EXPECT calls after scheduleSending is called
 			written := make(chan struct{})
-			mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(written) })
+			mconn.EXPECT().WritePackets(gomock.Any(), gomock.Any()).Do(func([][]byte, protocol.ECN) { close(written) })
 			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 			sess.scheduleSending()
 			Eventually(written).Should(BeClosed())
@@ -1397,7 +1724,7 @@ var _ = Describe("Session", func() {
 			sess.receivedPacketHandler = rph
 
 			written := make(chan struct{})
-			mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(written) })
+			mconn.EXPECT().WritePackets(gomock.Any(), gomock.Any()).Do(func([][]byte, protocol.ECN) { close(written) })
 			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 			go func() {
 				defer GinkgoRecover()
@@ -1469,7 +1796,7 @@ var _ = Describe("Session", func() {
 		)
 
 		sent := make(chan struct{})
-		mconn.EXPECT().Write([]byte("foobar")).Do(func([]byte) { close(sent) })
+		mconn.EXPECT().WritePackets([][]byte{[]byte("foobar")}, protocol.ECNNon).Do(func([][]byte, protocol.ECN) { close(sent) })
 
 		go func() {
 			defer GinkgoRecover()
@@ -1485,7 +1812,7 @@ var _ = Describe("Session", func() {
 		expectReplaceWithClosed()
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
 		Eventually(sess.Context().Done()).Should(BeClosed())
@@ -1521,7 +1848,7 @@ var _ = Describe("Session", func() {
 		expectReplaceWithClosed()
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
 		Eventually(sess.Context().Done()).Should(BeClosed())
@@ -1565,12 +1892,34 @@ var _ = Describe("Session", func() {
 		expectReplaceWithClosed()
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
 		Eventually(sess.Context().Done()).Should(BeClosed())
 	})
 
+	It("embeds application data in the token sent in the NEW_TOKEN frame", func() {
+		sess.config.GetTokenData = func(addr net.Addr) []byte {
+			Expect(addr).To(Equal(sess.conn.RemoteAddr()))
+			return []byte("foobar")
+		}
+		sessionRunner.EXPECT().Retire(clientDestConnID)
+		cryptoSetup.EXPECT().DropHandshakeKeys()
+		cryptoSetup.EXPECT().GetSessionTicket()
+		sess.handleHandshakeComplete()
+		frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+		var newTokenFrame *wire.NewTokenFrame
+		for _, f := range frames {
+			if ntf, ok := f.Frame.(*wire.NewTokenFrame); ok {
+				newTokenFrame = ntf
+			}
+		}
+		Expect(newTokenFrame).ToNot(BeNil())
+		token, err := sess.tokenGenerator.DecodeToken(newTokenFrame.Token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token.ApplicationData).To(Equal([]byte("foobar")))
+	})
+
 	It("doesn't cancel the HandshakeComplete context when the handshake fails", func() {
 		packer.EXPECT().PackCoalescedPacket(protocol.MaxByteCount).AnyTimes()
 		streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -1585,7 +1934,7 @@ var _ = Describe("Session", func() {
 		}()
 		handshakeCtx := sess.HandshakeComplete()
 		Consistently(handshakeCtx.Done()).ShouldNot(BeClosed())
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		sess.closeLocal(errors.New("handshake error"))
 		Consistently(handshakeCtx.Done()).ShouldNot(BeClosed())
 		Eventually(sess.Context().Done()).Should(BeClosed())
@@ -1620,7 +1969,6 @@ var _ = Describe("Session", func() {
 			cryptoSetup.EXPECT().RunHandshake()
 			cryptoSetup.EXPECT().DropHandshakeKeys()
 			cryptoSetup.EXPECT().GetSessionTicket()
-			mconn.EXPECT().Write(gomock.Any())
 			close(sess.handshakeCompleteChan)
 			sess.run()
 		}()
@@ -1630,6 +1978,7 @@ var _ = Describe("Session", func() {
 		expectReplaceWithClosed()
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
 		Eventually(sess.Context().Done()).Should(BeClosed())
@@ -1647,7 +1996,7 @@ var _ = Describe("Session", func() {
 		expectReplaceWithClosed()
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
 		Eventually(done).Should(BeClosed())
@@ -1667,7 +2016,7 @@ var _ = Describe("Session", func() {
 		expectReplaceWithClosed()
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().Close()
 		Expect(sess.CloseWithError(0x1337, testErr.Error())).To(Succeed())
 		Eventually(done).Should(BeClosed())
@@ -1694,6 +2043,166 @@ var _ = Describe("Session", func() {
 			sess.processTransportParameters(params)
 			Expect(sess.earlySessionReady()).To(BeClosed())
 		})
+
+		It("switches to a compatible version", func() {
+			sess.config.Versions = []protocol.VersionNumber{protocol.Version2, protocol.Version1}
+			params := &wire.TransportParameters{
+				InitialSourceConnectionID: destConnID,
+				VersionInformation: &wire.VersionInformation{
+					ChosenVersion:     protocol.Version1,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version1, protocol.Version2},
+				},
+			}
+			streamManager.EXPECT().UpdateLimits(params)
+			packer.EXPECT().SetVersion(protocol.Version2)
+			packer.EXPECT().HandleTransportParameters(params)
+			packer.EXPECT().PackCoalescedPacket(protocol.MaxByteCount).MaxTimes(3)
+			sessionRunner.EXPECT().GetStatelessResetToken(gomock.Any()).AnyTimes()
+			sessionRunner.EXPECT().Add(gomock.Any(), sess).AnyTimes()
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			sess.processTransportParameters(params)
+			Expect(sess.version).To(Equal(protocol.Version2))
+		})
+
+		It("accepts 0-RTT packets using the original version, after switching to a compatible version", func() {
+			sess.version = protocol.Version2
+			Expect(sess.acceptVersion(&wire.Header{Type: protocol.PacketType0RTT, Version: protocol.Version1})).To(BeTrue())
+			Expect(sess.acceptVersion(&wire.Header{Type: protocol.PacketTypeHandshake, Version: protocol.Version1})).To(BeFalse())
+		})
+
+		It("errors if the client's chosen version doesn't match the version of its Initial", func() {
+			params := &wire.TransportParameters{
+				InitialSourceConnectionID: destConnID,
+				VersionInformation: &wire.VersionInformation{
+					ChosenVersion:     protocol.Version2,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version1, protocol.Version2},
+				},
+			}
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			Expect(sess.processTransportParametersImpl(params)).To(MatchError("VERSION_NEGOTIATION_ERROR: client's chosen version (v2) doesn't match the version of its Initial (v1)"))
+		})
+	})
+
+	Context("statistics", func() {
+		It("returns the statistics, also after the session was closed", func() {
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			sess.rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+			sess.rttStats.UpdateRTT(50*time.Millisecond, 0, time.Now())
+			sess.handshakeDuration = 42 * time.Millisecond
+			sess.packetsReceived = 5
+			sess.bytesReceived = 5000
+			sess.peerParams = &wire.TransportParameters{MaxIdleTimeout: 30 * time.Second}
+			sph.EXPECT().TransmissionStats().Return(ackhandler.TransmissionStats{
+				PacketsSent:          10,
+				BytesSent:            10000,
+				PacketsLost:          2,
+				BytesLost:            2000,
+				PacketsRetransmitted: 3,
+				BytesRetransmitted:   2500,
+			}).AnyTimes()
+			sph.EXPECT().GetStats().Return(&quictrace.TransportState{
+				CongestionWindow: 20000,
+				BytesInFlight:    3000,
+			}).AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendNone).AnyTimes()
+			packer.EXPECT().PackCoalescedPacket(gomock.Any()).AnyTimes()
+			packer.EXPECT().PackPacket().AnyTimes()
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+				close(done)
+			}()
+
+			stats := sess.Stats()
+			Expect(stats.MinRTT).To(Equal(50 * time.Millisecond))
+			Expect(stats.LatestRTT).To(Equal(50 * time.Millisecond))
+			Expect(stats.SmoothedRTT).To(Equal(sess.rttStats.SmoothedRTT()))
+			Expect(stats.RTTVariance).To(Equal(sess.rttStats.MeanDeviation()))
+			Expect(stats.CongestionWindow).To(Equal(protocol.ByteCount(20000)))
+			Expect(stats.BytesInFlight).To(Equal(protocol.ByteCount(3000)))
+			Expect(stats.PacketsSent).To(BeEquivalentTo(10))
+			Expect(stats.BytesSent).To(Equal(protocol.ByteCount(10000)))
+			Expect(stats.PacketsReceived).To(BeEquivalentTo(5))
+			Expect(stats.BytesReceived).To(Equal(protocol.ByteCount(5000)))
+			Expect(stats.PacketsLost).To(BeEquivalentTo(2))
+			Expect(stats.BytesLost).To(Equal(protocol.ByteCount(2000)))
+			Expect(stats.PacketsRetransmitted).To(BeEquivalentTo(3))
+			Expect(stats.BytesRetransmitted).To(Equal(protocol.ByteCount(2500)))
+			Expect(stats.HandshakeDuration).To(Equal(42 * time.Millisecond))
+			Expect(stats.PeerTransportParameters).To(Equal(&wire.TransportParameters{MaxIdleTimeout: 30 * time.Second}))
+			// modifying the returned transport parameters doesn't modify the session's state
+			stats.PeerTransportParameters.MaxIdleTimeout = time.Second
+			Expect(sess.peerParams.MaxIdleTimeout).To(Equal(30 * time.Second))
+
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
+			tracer.EXPECT().Close()
+			sess.shutdown()
+			Eventually(done).Should(BeClosed())
+			Expect(sess.Stats().PacketsSent).To(BeEquivalentTo(10))
+			Expect(sess.Stats().PacketsReceived).To(BeEquivalentTo(5))
+		})
+	})
+
+	Context("key updates", func() {
+		var done chan struct{}
+
+		BeforeEach(func() {
+			packer.EXPECT().PackCoalescedPacket(gomock.Any()).AnyTimes()
+			packer.EXPECT().PackPacket().AnyTimes()
+		})
+
+		runSession := func() {
+			done = make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+				close(done)
+			}()
+		}
+
+		closeSession := func() {
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
+			tracer.EXPECT().Close()
+			sess.shutdown()
+			Eventually(done).Should(BeClosed())
+		}
+
+		It("initiates a key update", func() {
+			sess.handshakeConfirmed = true
+			runSession()
+			cryptoSetup.EXPECT().InitiateKeyUpdate()
+			Expect(sess.InitiateKeyUpdate()).To(Succeed())
+			testErr := errors.New("key update not allowed")
+			cryptoSetup.EXPECT().InitiateKeyUpdate().Return(testErr)
+			Expect(sess.InitiateKeyUpdate()).To(MatchError(testErr))
+			closeSession()
+		})
+
+		It("doesn't initiate a key update before the handshake is confirmed", func() {
+			runSession()
+			Expect(sess.InitiateKeyUpdate()).To(MatchError("cannot update keys before the handshake is confirmed"))
+			closeSession()
+		})
+
+		It("errors when the session is already closed", func() {
+			runSession()
+			closeSession()
+			Expect(sess.InitiateKeyUpdate()).To(MatchError("session closed"))
+		})
 	})
 
 	Context("keep-alives", func() {
@@ -1718,7 +2227,7 @@ var _ = Describe("Session", func() {
 		BeforeEach(func() {
 			sess.config.MaxIdleTimeout = 30 * time.Second
 			sess.config.KeepAlive = true
-			sess.receivedPacketHandler.ReceivedPacket(0, protocol.EncryptionHandshake, time.Now(), true)
+			sess.receivedPacketHandler.ReceivedPacket(0, protocol.ECNNon, protocol.EncryptionHandshake, time.Now(), true)
 		})
 
 		AfterEach(func() {
@@ -1727,7 +2236,7 @@ var _ = Describe("Session", func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
@@ -1848,7 +2357,7 @@ var _ = Describe("Session", func() {
 			// make the go routine return
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
@@ -1896,7 +2405,7 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
@@ -1972,6 +2481,25 @@ var _ = Describe("Session", func() {
 	It("returns the remote address", func() {
 		Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
 	})
+
+	It("refuses to migrate", func() {
+		Expect(sess.MigrateTo(newMockPacketConn())).To(MatchError("only clients can migrate"))
+	})
+
+	It("generates the preferred_address", func() {
+		var connID protocol.ConnectionID
+		sessionRunner.EXPECT().Add(gomock.Any(), sess).Do(func(c protocol.ConnectionID, _ packetHandler) { connID = c })
+		sessionRunner.EXPECT().GetStatelessResetToken(gomock.Any()).Return([16]byte{1, 2, 3, 4})
+		pa, err := sess.newPreferredAddress(&PreferredAddress{IPv4: &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pa.IPv4.Equal(net.IPv4(10, 0, 0, 1))).To(BeTrue())
+		Expect(pa.IPv4Port).To(BeEquivalentTo(443))
+		Expect(pa.IPv6).To(Equal(net.IPv6zero))
+		Expect(pa.IPv6Port).To(BeZero())
+		Expect(pa.ConnectionID).To(Equal(connID))
+		Expect(pa.ConnectionID.Len()).To(Equal(srcConnID.Len()))
+		Expect(pa.StatelessResetToken).To(Equal([16]byte{1, 2, 3, 4}))
+	})
 })
 
 var _ = Describe("Client Session", func() {
@@ -1984,6 +2512,7 @@ var _ = Describe("Client Session", func() {
 		tracer        *mocks.MockConnectionTracer
 		tlsConf       *tls.Config
 		quicConf      *Config
+		sentParams    *wire.TransportParameters
 	)
 	srcConnID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
 	destConnID := protocol.ConnectionID{8, 7, 6, 5, 4, 3, 2, 1}
@@ -2015,13 +2544,14 @@ var _ = Describe("Client Session", func() {
 		mconn = NewMockConnection(mockCtrl)
 		mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{}).Times(2)
 		mconn.EXPECT().LocalAddr().Return(&net.UDPAddr{})
+		mconn.EXPECT().SupportsECN()
 		if tlsConf == nil {
 			mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{})
 			tlsConf = &tls.Config{}
 		}
 		sessionRunner = NewMockSessionRunner(mockCtrl)
 		tracer = mocks.NewMockConnectionTracer(mockCtrl)
-		tracer.EXPECT().SentTransportParameters(gomock.Any())
+		tracer.EXPECT().SentTransportParameters(gomock.Any()).Do(func(params *wire.TransportParameters) { sentParams = params })
 		tracer.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
 		sess = newClientSession(
 			mconn,
@@ -2044,6 +2574,26 @@ var _ = Describe("Client Session", func() {
 		sess.cryptoStreamHandler = cryptoSetup
 	})
 
+	It("greases the available versions", func() {
+		Expect(sentParams.VersionInformation.ChosenVersion).To(Equal(protocol.VersionTLS))
+		Expect(sentParams.VersionInformation.AvailableVersions).To(HaveLen(len(quicConf.Versions) + 1))
+		Expect(protocol.StripGreasedVersions(sentParams.VersionInformation.AvailableVersions)).To(Equal(quicConf.Versions))
+	})
+
+	It("doesn't send the grease_quic_bit transport parameter by default", func() {
+		Expect(sentParams.GreaseQUICBit).To(BeFalse())
+	})
+
+	Context("greasing the QUIC bit", func() {
+		BeforeEach(func() {
+			quicConf.GreaseQUICBit = true
+		})
+
+		It("sends the grease_quic_bit transport parameter", func() {
+			Expect(sentParams.GreaseQUICBit).To(BeTrue())
+		})
+	})
+
 	It("changes the connection ID when receiving the first packet from the server", func() {
 		unpacker := NewMockUnpacker(mockCtrl)
 		unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, _ time.Time, data []byte) (*unpackedPacket, error) {
@@ -2077,7 +2627,7 @@ var _ = Describe("Client Session", func() {
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		expectReplaceWithClosed()
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
 		Eventually(sess.Context().Done()).Should(BeClosed())
@@ -2110,11 +2660,311 @@ var _ = Describe("Client Session", func() {
 		Expect(sess.handleSinglePacket(&receivedPacket{buffer: getPacketBuffer()}, hdr)).To(BeTrue())
 	})
 
+	Context("compatible version negotiation", func() {
+		getInitial := func(v protocol.VersionNumber) *receivedPacket {
+			return getPacket(&wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  destConnID,
+					DestConnectionID: srcConnID,
+					Length:           2 + 6,
+					Version:          v,
+				},
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}, []byte("foobar"))
+		}
+
+		It("switches to a compatible version, if the server switched", func() {
+			unpacker := NewMockUnpacker(mockCtrl)
+			sess.unpacker = unpacker
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, _ time.Time, _ []byte) (*unpackedPacket, error) {
+				Expect(hdr.Version).To(Equal(protocol.Version2))
+				Expect(hdr.Type).To(Equal(protocol.PacketTypeInitial))
+				return &unpackedPacket{
+					hdr:             &wire.ExtendedHeader{Header: *hdr},
+					data:            []byte{0},
+					encryptionLevel: protocol.EncryptionInitial,
+				}, nil
+			})
+			cryptoSetup.EXPECT().ChangeVersion(protocol.Version2)
+			packer.EXPECT().SetVersion(protocol.Version2)
+			p := getInitial(protocol.Version2)
+			tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(p.data)), gomock.Any())
+			Expect(sess.handlePacketImpl(p)).To(BeTrue())
+			Expect(sess.version).To(Equal(protocol.Version2))
+			Expect(sess.switchedToCompatibleVersion).To(BeTrue())
+		})
+
+		It("doesn't switch to a compatible version, if the packet can't be decrypted", func() {
+			unpacker := NewMockUnpacker(mockCtrl)
+			sess.unpacker = unpacker
+			version := sess.version
+			gomock.InOrder(
+				cryptoSetup.EXPECT().ChangeVersion(protocol.Version2),
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed),
+				cryptoSetup.EXPECT().ChangeVersion(version),
+			)
+			p := getInitial(protocol.Version2)
+			tracer.EXPECT().DroppedPacket(logging.PacketTypeInitial, protocol.ByteCount(len(p.data)), logging.PacketDropPayloadDecryptError)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			Expect(sess.version).To(Equal(version))
+			Expect(sess.switchedToCompatibleVersion).To(BeFalse())
+		})
+
+		It("doesn't switch back to the original version", func() {
+			sess.version = protocol.Version2
+			sess.switchedToCompatibleVersion = true
+			p := getInitial(protocol.Version1)
+			tracer.EXPECT().DroppedPacket(logging.PacketTypeInitial, protocol.ByteCount(len(p.data)), logging.PacketDropUnexpectedVersion)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+		})
+
+		It("doesn't switch to a version that isn't compatible", func() {
+			p := getInitial(protocol.VersionDraft29)
+			tracer.EXPECT().DroppedPacket(logging.PacketTypeInitial, protocol.ByteCount(len(p.data)), logging.PacketDropUnexpectedVersion)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+		})
+
+		It("doesn't switch to a version that it didn't offer", func() {
+			sess.config.Versions = []protocol.VersionNumber{protocol.Version1}
+			p := getInitial(protocol.Version2)
+			tracer.EXPECT().DroppedPacket(logging.PacketTypeInitial, protocol.ByteCount(len(p.data)), logging.PacketDropUnexpectedVersion)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+		})
+	})
+
 	It("handles HANDSHAKE_DONE frames", func() {
 		cryptoSetup.EXPECT().DropHandshakeKeys()
 		Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
 	})
 
+	Context("migrating", func() {
+		var (
+			newRunner *MockPacketHandlerManager
+			pconn     *mockPacketConn
+			sph       *mockackhandler.MockSentPacketHandler
+			m         *pathMigration
+		)
+		newConnID := protocol.ConnectionID{1, 3, 3, 7}
+
+		JustBeforeEach(func() {
+			newRunner = NewMockPacketHandlerManager(mockCtrl)
+			pconn = newMockPacketConn()
+			m = &pathMigration{
+				pconn:  pconn,
+				runner: newRunner,
+				result: make(chan error, 1),
+			}
+			sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			sess.handshakeConfirmed = true
+			sess.peerParams = &wire.TransportParameters{}
+			Expect(sess.handleNewConnectionIDFrame(&wire.NewConnectionIDFrame{
+				SequenceNumber:      1,
+				ConnectionID:        newConnID,
+				StatelessResetToken: [16]byte{1, 2, 3, 4},
+			})).To(Succeed())
+		})
+
+		sendPathChallenge := func() *wire.PathChallengeFrame {
+			var challenge *wire.PathChallengeFrame
+			packer.EXPECT().PackPathProbePacket(newConnID, gomock.Any(), protocol.ByteCount(protocol.MinInitialPacketSize)).DoAndReturn(func(_ protocol.ConnectionID, frames []ackhandler.Frame, _ protocol.ByteCount) (*packedPacket, error) {
+				Expect(frames).To(HaveLen(1))
+				Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.PathChallengeFrame{}))
+				challenge = frames[0].Frame.(*wire.PathChallengeFrame)
+				return &packedPacket{
+					packetContents: &packetContents{
+						header: &wire.ExtendedHeader{
+							Header:       wire.Header{DestConnectionID: newConnID},
+							PacketNumber: 10,
+						},
+						frames: frames,
+					},
+					buffer: getPacketBuffer(),
+				}, nil
+			})
+			sph.EXPECT().SentPacket(gomock.Any())
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234})
+			return challenge
+		}
+
+		It("validates the new path and migrates", func() {
+			newRunner.EXPECT().Add(srcConnID, sess)
+			sendPathChallenge()
+			sess.startPathMigration(m)
+			Expect(sess.migration).ToNot(BeNil())
+			Expect(pconn.dataWritten).To(Receive())
+			Consistently(m.result).ShouldNot(Receive())
+			f := m.validator.challenges[0]
+			// switch to the new path
+			newRunner.EXPECT().Add(srcConnID, sess)
+			sessionRunner.EXPECT().Retire(srcConnID)
+			sessionRunner.EXPECT().RetireResetToken(gomock.Any()).AnyTimes()
+			newRunner.EXPECT().AddResetToken([16]byte{1, 2, 3, 4}, sess)
+			newRunner.EXPECT().RetireResetToken(gomock.Any()).AnyTimes()
+			mconn.EXPECT().SetPacketConn(pconn)
+			sph.EXPECT().OnConnectionMigration()
+			Expect(sess.handleFrame(&wire.PathResponseFrame{Data: f}, protocol.Encryption1RTT, nil)).To(Succeed())
+			Expect(m.result).To(Receive(BeNil()))
+			Expect(sess.migration).To(BeNil())
+			Expect(sess.runner).To(Equal(newRunner))
+			Expect(sess.connIDManager.Get()).To(Equal(newConnID))
+		})
+
+		It("fails the migration if path validation fails", func() {
+			newRunner.EXPECT().Add(srcConnID, sess)
+			sendPathChallenge()
+			sess.startPathMigration(m)
+			for i := 1; i < protocol.MaxPathChallenges; i++ {
+				sendPathChallenge()
+				sess.sendPathChallenge()
+			}
+			// The runner is not destroyed, since the PacketConn might be used by other sessions.
+			newRunner.EXPECT().Remove(srcConnID)
+			sess.sendPathChallenge()
+			Expect(m.result).To(Receive(MatchError("path validation failed")))
+			Expect(sess.migration).To(BeNil())
+			Expect(sess.runner).To(Equal(sessionRunner))
+			// the connection ID reserved for the new path was retired
+			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(ContainElement(ackhandler.Frame{Frame: &wire.RetireConnectionIDFrame{SequenceNumber: 1}}))
+		})
+
+		It("refuses to migrate while another migration is in progress", func() {
+			newRunner.EXPECT().Add(srcConnID, sess)
+			sendPathChallenge()
+			sess.startPathMigration(m)
+			Expect(pconn.dataWritten).To(Receive())
+			runner := NewMockPacketHandlerManager(mockCtrl)
+			m2 := &pathMigration{pconn: newMockPacketConn(), runner: runner, result: make(chan error, 1)}
+			sess.startPathMigration(m2)
+			Expect(m2.result).To(Receive(MatchError("migration already in progress")))
+			Expect(sess.migration).To(Equal(m))
+		})
+
+		It("doesn't close the PacketConn if it is already in use", func() {
+			sess.runner = newRunner
+			sess.startPathMigration(m)
+			Expect(m.result).To(Receive(MatchError("already using this PacketConn")))
+		})
+
+		It("responds to PATH_CHALLENGE frames on the new path", func() {
+			newRunner.EXPECT().Add(srcConnID, sess)
+			sendPathChallenge()
+			sess.startPathMigration(m)
+			Expect(pconn.dataWritten).To(Receive())
+			packer.EXPECT().PackPathProbePacket(newConnID, gomock.Any(), gomock.Any()).DoAndReturn(func(_ protocol.ConnectionID, frames []ackhandler.Frame, _ protocol.ByteCount) (*packedPacket, error) {
+				Expect(frames).To(HaveLen(1))
+				Expect(frames[0].Frame).To(Equal(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}))
+				return &packedPacket{
+					packetContents: &packetContents{
+						header: &wire.ExtendedHeader{PacketNumber: 11},
+						frames: frames,
+					},
+					buffer: getPacketBuffer(),
+				}, nil
+			})
+			sph.EXPECT().SentPacket(gomock.Any())
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			p := &receivedPacket{
+				remoteAddr: &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234},
+				conn:       pconn,
+				data:       make([]byte, protocol.MinInitialPacketSize),
+			}
+			Expect(sess.handleFrame(&wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.Encryption1RTT, p)).To(Succeed())
+			Expect(pconn.dataWritten).To(Receive())
+			// nothing is sent on the old path
+			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(BeEmpty())
+		})
+
+		It("refuses to migrate before the handshake is confirmed", func() {
+			sess.handshakeConfirmed = false
+			sess.startPathMigration(m)
+			Expect(m.result).To(Receive(MatchError("cannot migrate before the handshake is confirmed")))
+			Expect(sess.migration).To(BeNil())
+		})
+
+		It("refuses to migrate if the peer disabled active migration", func() {
+			sess.peerParams = &wire.TransportParameters{DisableActiveMigration: true}
+			sess.startPathMigration(m)
+			Expect(m.result).To(Receive(MatchError("peer disabled active migration")))
+		})
+
+		It("refuses to migrate if there's no unused connection ID", func() {
+			_, ok := sess.connIDManager.GetUnused()
+			Expect(ok).To(BeTrue())
+			sess.startPathMigration(m)
+			Expect(m.result).To(Receive(MatchError("no unused connection ID available")))
+		})
+	})
+
+	Context("migrating to the preferred address", func() {
+		var sph *mockackhandler.MockSentPacketHandler
+		preferredConnID := protocol.ConnectionID{1, 3, 3, 7}
+		preferredAddr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}
+
+		JustBeforeEach(func() {
+			sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			sess.handshakeConfirmed = true
+			sess.peerParams = &wire.TransportParameters{
+				PreferredAddress: &wire.PreferredAddress{
+					IPv4:     preferredAddr.IP,
+					IPv4Port: uint16(preferredAddr.Port),
+					IPv6:     net.IPv6zero,
+				},
+			}
+			sess.preferredAddressConnID = &utils.NewConnectionID{
+				SequenceNumber:      1,
+				ConnectionID:        preferredConnID,
+				StatelessResetToken: &[16]byte{1, 2, 3, 4},
+			}
+			cryptoSetup.EXPECT().DropHandshakeKeys()
+		})
+
+		It("validates the preferred address and migrates", func() {
+			mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 443}).AnyTimes()
+			var challenge *wire.PathChallengeFrame
+			packer.EXPECT().PackPathProbePacket(preferredConnID, gomock.Any(), protocol.ByteCount(protocol.MinInitialPacketSize)).DoAndReturn(func(_ protocol.ConnectionID, frames []ackhandler.Frame, _ protocol.ByteCount) (*packedPacket, error) {
+				Expect(frames).To(HaveLen(1))
+				challenge = frames[0].Frame.(*wire.PathChallengeFrame)
+				return &packedPacket{
+					packetContents: &packetContents{
+						header: &wire.ExtendedHeader{PacketNumber: 10},
+						frames: frames,
+					},
+					buffer: getPacketBuffer(),
+				}, nil
+			})
+			sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) { p.ECN = protocol.ECT0 })
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			mconn.EXPECT().WriteTo(gomock.Any(), preferredAddr, protocol.ECT0)
+			Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
+			Expect(sess.preferredAddressConnID).To(BeNil())
+			Expect(challenge).ToNot(BeNil())
+			// now receive the PATH_RESPONSE
+			sessionRunner.EXPECT().AddResetToken([16]byte{1, 2, 3, 4}, sess)
+			mconn.EXPECT().SetCurrentRemoteAddr(preferredAddr)
+			sph.EXPECT().OnConnectionMigration()
+			Expect(sess.handleFrame(&wire.PathResponseFrame{Data: challenge.Data}, protocol.Encryption1RTT, nil)).To(Succeed())
+			Expect(sess.migration).To(BeNil())
+			Expect(sess.connIDManager.Get()).To(Equal(preferredConnID))
+		})
+
+		It("doesn't migrate to a preferred address of a different address family", func() {
+			mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv6loopback, Port: 443}).AnyTimes()
+			Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
+			Expect(sess.migration).To(BeNil())
+			Expect(sess.preferredAddressConnID).To(BeNil())
+			// the connection ID is retired
+			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.RetireConnectionIDFrame{SequenceNumber: 1}}}))
+		})
+	})
+
 	Context("handling tokens", func() {
 		var mockTokenStore *MockTokenStore
 
@@ -2127,7 +2977,10 @@ var _ = Describe("Client Session", func() {
 		})
 
 		It("handles NEW_TOKEN frames", func() {
-			mockTokenStore.EXPECT().Put("server", &ClientToken{data: []byte("foobar")})
+			mockTokenStore.EXPECT().Put("server", gomock.Any()).Do(func(_ string, token *ClientToken) {
+				Expect(token.data).To(Equal([]byte("foobar")))
+				Expect(token.rcvTime).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+			})
 			Expect(sess.handleNewTokenFrame(&wire.NewTokenFrame{Token: []byte("foobar")})).To(Succeed())
 		})
 	})
@@ -2228,7 +3081,7 @@ var _ = Describe("Client Session", func() {
 		getRetryTag := func(hdr *wire.ExtendedHeader) []byte {
 			buf := &bytes.Buffer{}
 			hdr.Write(buf, sess.version)
-			return handshake.GetRetryIntegrityTag(buf.Bytes(), origDestConnID)[:]
+			return handshake.GetRetryIntegrityTag(buf.Bytes(), origDestConnID, hdr.Version)[:]
 		}
 
 		It("handles Retry packets", func() {
@@ -2294,7 +3147,7 @@ var _ = Describe("Client Session", func() {
 				})
 				packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil).MaxTimes(1)
 				cryptoSetup.EXPECT().Close()
-				mconn.EXPECT().Write(gomock.Any())
+				mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 				tracer.EXPECT().Close()
 			}
 			closed = true
@@ -2307,7 +3160,7 @@ var _ = Describe("Client Session", func() {
 			Eventually(errChan).Should(BeClosed())
 		})
 
-		It("uses the preferred_address connection ID", func() {
+		It("saves the preferred_address connection ID for migrating after the handshake", func() {
 			params := &wire.TransportParameters{
 				OriginalDestinationConnectionID: destConnID,
 				InitialSourceConnectionID:       destConnID,
@@ -2325,11 +3178,12 @@ var _ = Describe("Client Session", func() {
 			// make sure the connection ID is not retired
 			cf, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
 			Expect(cf).To(BeEmpty())
-			sessionRunner.EXPECT().AddResetToken([16]byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, sess)
-			Expect(sess.connIDManager.Get()).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
-			// shut down
-			sessionRunner.EXPECT().RemoveResetToken([16]byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1})
-			expectClose()
+			Expect(sess.preferredAddressConnID).ToNot(BeNil())
+			Expect(sess.preferredAddressConnID.SequenceNumber).To(BeEquivalentTo(1))
+			Expect(sess.preferredAddressConnID.ConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+			Expect(*sess.preferredAddressConnID.StatelessResetToken).To(Equal([16]byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}))
+			// the connection ID is only used once the client migrates to the preferred address
+			Expect(sess.connIDManager.Get()).To(Equal(destConnID))
 		})
 
 		It("uses the minimum of the peers' idle timeouts", func() {
@@ -2398,6 +3252,57 @@ var _ = Describe("Client Session", func() {
 			Eventually(errChan).Should(Receive(MatchError("TRANSPORT_PARAMETER_ERROR: received retry_source_connection_id, although no Retry was performed")))
 		})
 
+		It("errors if the server's chosen version doesn't match the version in use", func() {
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+				StatelessResetToken:             &[16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+				VersionInformation: &wire.VersionInformation{
+					ChosenVersion:     protocol.Version2,
+					AvailableVersions: []protocol.VersionNumber{protocol.Version2, protocol.Version1},
+				},
+			}
+			expectClose()
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			sess.processTransportParameters(params)
+			Eventually(errChan).Should(Receive(MatchError("VERSION_NEGOTIATION_ERROR: server's chosen version (v2) doesn't match the negotiated version (v1)")))
+		})
+
+		It("detects a version downgrade after receiving a Version Negotiation packet", func() {
+			sess.versionNegotiated = true
+			sess.version = protocol.VersionDraft29
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+				StatelessResetToken:             &[16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+				VersionInformation: &wire.VersionInformation{
+					ChosenVersion:     protocol.VersionDraft29,
+					AvailableVersions: []protocol.VersionNumber{protocol.VersionDraft29, protocol.Version1},
+				},
+			}
+			expectClose()
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			sess.processTransportParameters(params)
+			Eventually(errChan).Should(Receive(MatchError("VERSION_NEGOTIATION_ERROR: version downgrade detected: server supports [draft-29 v1]")))
+		})
+
+		It("accepts the version information after a legitimate Version Negotiation", func() {
+			sess.versionNegotiated = true
+			sess.version = protocol.VersionDraft29
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+				VersionInformation: &wire.VersionInformation{
+					ChosenVersion:     protocol.VersionDraft29,
+					AvailableVersions: []protocol.VersionNumber{protocol.VersionDraft29},
+				},
+			}
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			sess.processTransportParameters(params)
+			Expect(sess.peerParams).To(Equal(params))
+		})
+
 		It("errors if the transport parameters contain a wrong original_destination_connection_id", func() {
 			sess.origDestConnID = protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
 			params := &wire.TransportParameters{
@@ -2532,3 +3437,19 @@ var _ = Describe("Client Session", func() {
 
 	})
 })
+
+var _ = Describe("Address comparison", func() {
+	It("compares UDP addresses", func() {
+		addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 100), Port: 4321}
+		Expect(equalAddr(addr, &net.UDPAddr{IP: net.IPv4(192, 168, 0, 100), Port: 4321})).To(BeTrue())
+		Expect(equalAddr(addr, &net.UDPAddr{IP: addr.IP.To4(), Port: addr.Port})).To(BeTrue())
+		Expect(equalAddr(addr, &net.UDPAddr{IP: addr.IP, Port: addr.Port + 1})).To(BeFalse())
+		Expect(equalAddr(addr, &net.UDPAddr{IP: net.IPv4(192, 168, 0, 101), Port: addr.Port})).To(BeFalse())
+	})
+
+	It("compares addresses of different types", func() {
+		addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 100), Port: 4321}
+		Expect(equalAddr(addr, &net.TCPAddr{IP: addr.IP, Port: addr.Port})).To(BeFalse())
+		Expect(equalAddr(&net.TCPAddr{IP: addr.IP, Port: addr.Port}, &net.TCPAddr{IP: addr.IP, Port: addr.Port})).To(BeTrue())
+	})
+})
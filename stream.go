@@ -14,7 +14,7 @@ import (
 // The streamSender is notified by the stream about various events.
 type streamSender interface {
 	queueControlFrame(wire.Frame)
-	onHasStreamData(protocol.StreamID)
+	onHasStreamData(protocol.StreamID, StreamPriority)
 	// must be called without holding the mutex that is acquired by closeForShutdown
 	onStreamCompleted(protocol.StreamID)
 }
@@ -30,8 +30,8 @@ func (s *uniStreamSender) queueControlFrame(f wire.Frame) {
 	s.streamSender.queueControlFrame(f)
 }
 
-func (s *uniStreamSender) onHasStreamData(id protocol.StreamID) {
-	s.streamSender.onHasStreamData(id)
+func (s *uniStreamSender) onHasStreamData(id protocol.StreamID, priority StreamPriority) {
+	s.streamSender.onHasStreamData(id, priority)
 }
 
 func (s *uniStreamSender) onStreamCompleted(protocol.StreamID) {
@@ -128,6 +128,17 @@ func (s *stream) StreamID() protocol.StreamID {
 	return s.sendStream.StreamID()
 }
 
+// need to define Stats() here, since both receiveStream and sendStream have a Stats()
+func (s *stream) Stats() StreamStats {
+	stats := s.sendStream.Stats()
+	receiveStats := s.receiveStream.Stats()
+	stats.BytesReceived = receiveStats.BytesReceived
+	stats.BytesRead = receiveStats.BytesRead
+	stats.BytesBufferedOutOfOrder = receiveStats.BytesBufferedOutOfOrder
+	stats.ReceiveWindow = receiveStats.ReceiveWindow
+	return stats
+}
+
 func (s *stream) Close() error {
 	if err := s.sendStream.Close(); err != nil {
 		return err
@@ -0,0 +1,164 @@
+package quic
+
+import "sort"
+
+const maxStreamUrgency = 7
+
+const (
+	defaultStreamUrgency     = 3
+	defaultStreamIncremental = true
+)
+
+// defaultStreamPriority is the priority of new streams.
+// All streams with the default priority share the bandwidth equally.
+func defaultStreamPriority() StreamPriority {
+	return StreamPriority{Urgency: defaultStreamUrgency, Incremental: defaultStreamIncremental}
+}
+
+func streamUrgency(p StreamPriority) int {
+	if p.Urgency > maxStreamUrgency {
+		return maxStreamUrgency
+	}
+	return int(p.Urgency)
+}
+
+// A priorityLevel holds the scheduled streams of one urgency.
+type priorityLevel struct {
+	// non-incremental streams, sorted by stream ID
+	sequential []StreamID
+	// incremental streams, served round-robin
+	incremental []StreamID
+}
+
+func (l *priorityLevel) Len() int {
+	return len(l.sequential) + len(l.incremental)
+}
+
+func (l *priorityLevel) push(id StreamID, incremental bool) {
+	if incremental {
+		l.incremental = append(l.incremental, id)
+		return
+	}
+	i := sort.Search(len(l.sequential), func(i int) bool { return l.sequential[i] >= id })
+	l.sequential = append(l.sequential, 0)
+	copy(l.sequential[i+1:], l.sequential[i:])
+	l.sequential[i] = id
+}
+
+// pop returns the next stream.
+// Non-incremental streams are sent before incremental streams.
+func (l *priorityLevel) pop() StreamID {
+	if len(l.sequential) > 0 {
+		id := l.sequential[0]
+		l.sequential = l.sequential[1:]
+		return id
+	}
+	id := l.incremental[0]
+	l.incremental = l.incremental[1:]
+	return id
+}
+
+func (l *priorityLevel) remove(id StreamID, incremental bool) {
+	ids := &l.sequential
+	if incremental {
+		ids = &l.incremental
+	}
+	for i, sid := range *ids {
+		if sid == id {
+			*ids = append((*ids)[:i], (*ids)[i+1:]...)
+			return
+		}
+	}
+}
+
+// The priorityQueue keeps track of the scheduled streams for every urgency.
+type priorityQueue struct {
+	levels     [maxStreamUrgency + 1]priorityLevel
+	priorities map[StreamID]StreamPriority
+}
+
+func newPriorityQueue() priorityQueue {
+	return priorityQueue{priorities: make(map[StreamID]StreamPriority)}
+}
+
+func (q *priorityQueue) Push(id StreamID, p StreamPriority) {
+	if old, ok := q.priorities[id]; ok {
+		if old == p {
+			return
+		}
+		q.levels[streamUrgency(old)].remove(id, old.Incremental)
+	}
+	q.priorities[id] = p
+	q.levels[streamUrgency(p)].push(id, p.Incremental)
+}
+
+func (q *priorityQueue) Len() int {
+	return len(q.priorities)
+}
+
+func (q *priorityQueue) popFrom(urgency int) StreamID {
+	id := q.levels[urgency].pop()
+	delete(q.priorities, id)
+	return id
+}
+
+type strictPriorityScheduler struct {
+	priorityQueue
+}
+
+var _ StreamScheduler = &strictPriorityScheduler{}
+
+// NewStrictPriorityScheduler creates a StreamScheduler that always sends the most urgent stream first.
+// Less urgent streams are only sent when no more urgent stream has data to send.
+// This is the scheduler used if Config.StreamScheduler is not set.
+func NewStrictPriorityScheduler() StreamScheduler {
+	return &strictPriorityScheduler{priorityQueue: newPriorityQueue()}
+}
+
+func (s *strictPriorityScheduler) Pop() (StreamID, bool) {
+	for urgency := range s.levels {
+		if s.levels[urgency].Len() > 0 {
+			return s.popFrom(urgency), true
+		}
+	}
+	return 0, false
+}
+
+type weightedFairScheduler struct {
+	priorityQueue
+
+	currentWeights [maxStreamUrgency + 1]int
+}
+
+var _ StreamScheduler = &weightedFairScheduler{}
+
+// NewWeightedFairScheduler creates a StreamScheduler that shares the bandwidth between urgencies.
+// Streams with urgency u get a share proportional to 8-u, such that the most urgent streams are sent
+// 8 times as often as the least urgent streams, but no stream is starved.
+// It uses smooth weighted round-robin scheduling between urgencies.
+func NewWeightedFairScheduler() StreamScheduler {
+	return &weightedFairScheduler{priorityQueue: newPriorityQueue()}
+}
+
+func (s *weightedFairScheduler) Pop() (StreamID, bool) {
+	var totalWeight int
+	best := -1
+	for urgency := range s.levels {
+		if s.levels[urgency].Len() == 0 {
+			// Don't accumulate credit while there's nothing to send.
+			s.currentWeights[urgency] = 0
+			continue
+		}
+		weight := maxStreamUrgency + 1 - urgency
+		s.currentWeights[urgency] += weight
+		totalWeight += weight
+		if best == -1 || s.currentWeights[urgency] > s.currentWeights[best] {
+			best = urgency
+		}
+	}
+	if best == -1 {
+		return 0, false
+	}
+	s.currentWeights[best] -= totalWeight
+	return s.popFrom(best), true
+}
@@ -0,0 +1,158 @@
+package quic
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream Scheduler", func() {
+	popAll := func(s StreamScheduler) []StreamID {
+		var ids []StreamID
+		for {
+			id, ok := s.Pop()
+			if !ok {
+				return ids
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	Context("strict priority", func() {
+		var s StreamScheduler
+
+		BeforeEach(func() {
+			s = NewStrictPriorityScheduler()
+		})
+
+		It("is empty at the beginning", func() {
+			Expect(s.Len()).To(BeZero())
+			_, ok := s.Pop()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("serves incremental streams of the same urgency round-robin", func() {
+			s.Push(8, defaultStreamPriority())
+			s.Push(4, defaultStreamPriority())
+			Expect(s.Len()).To(Equal(2))
+			id, ok := s.Pop()
+			Expect(ok).To(BeTrue())
+			Expect(id).To(Equal(StreamID(8)))
+			s.Push(8, defaultStreamPriority()) // stream 8 has more data
+			Expect(popAll(s)).To(Equal([]StreamID{4, 8}))
+			Expect(s.Len()).To(BeZero())
+		})
+
+		It("serves non-incremental streams of the same urgency one after the other, by stream ID", func() {
+			s.Push(8, StreamPriority{Urgency: 3})
+			s.Push(4, StreamPriority{Urgency: 3})
+			id, _ := s.Pop()
+			Expect(id).To(Equal(StreamID(4)))
+			s.Push(4, StreamPriority{Urgency: 3}) // stream 4 has more data
+			Expect(popAll(s)).To(Equal([]StreamID{4, 8}))
+		})
+
+		It("serves non-incremental streams before incremental streams of the same urgency", func() {
+			s.Push(4, StreamPriority{Urgency: 3, Incremental: true})
+			s.Push(8, StreamPriority{Urgency: 3})
+			Expect(popAll(s)).To(Equal([]StreamID{8, 4}))
+		})
+
+		It("serves more urgent streams first", func() {
+			s.Push(4, StreamPriority{Urgency: 7})
+			s.Push(8, StreamPriority{Urgency: 2})
+			s.Push(12, StreamPriority{Urgency: 5})
+			s.Push(16, StreamPriority{Urgency: 0})
+			Expect(popAll(s)).To(Equal([]StreamID{16, 8, 12, 4}))
+		})
+
+		It("treats urgencies larger than 7 as 7", func() {
+			s.Push(4, StreamPriority{Urgency: 200, Incremental: true})
+			s.Push(8, StreamPriority{Urgency: 7, Incremental: true})
+			s.Push(12, StreamPriority{Urgency: 6})
+			Expect(popAll(s)).To(Equal([]StreamID{12, 4, 8}))
+		})
+
+		It("updates the priority of a stream", func() {
+			s.Push(4, StreamPriority{Urgency: 3})
+			s.Push(8, StreamPriority{Urgency: 3})
+			s.Push(8, StreamPriority{Urgency: 1})
+			Expect(s.Len()).To(Equal(2))
+			Expect(popAll(s)).To(Equal([]StreamID{8, 4}))
+		})
+
+		It("doesn't add a stream twice", func() {
+			s.Push(4, defaultStreamPriority())
+			s.Push(4, defaultStreamPriority())
+			Expect(s.Len()).To(Equal(1))
+			Expect(popAll(s)).To(Equal([]StreamID{4}))
+		})
+	})
+
+	Context("weighted fair", func() {
+		var s StreamScheduler
+
+		BeforeEach(func() {
+			s = NewWeightedFairScheduler()
+		})
+
+		It("is empty at the beginning", func() {
+			Expect(s.Len()).To(BeZero())
+			_, ok := s.Pop()
+			Expect(ok).To(BeFalse())
+		})
+
+		// pop streams, and push them again, as if they always had more data to send
+		popAndRequeue := func(n int, priorities map[StreamID]StreamPriority) map[StreamID]int {
+			for id, p := range priorities {
+				s.Push(id, p)
+			}
+			counts := make(map[StreamID]int)
+			for i := 0; i < n; i++ {
+				id, ok := s.Pop()
+				Expect(ok).To(BeTrue())
+				counts[id]++
+				s.Push(id, priorities[id])
+			}
+			return counts
+		}
+
+		It("shares the bandwidth according to the urgency", func() {
+			counts := popAndRequeue(10*13, map[StreamID]StreamPriority{
+				4:  {Urgency: 0, Incremental: true},
+				8:  {Urgency: 4, Incremental: true},
+				12: {Urgency: 7, Incremental: true},
+			})
+			Expect(counts).To(Equal(map[StreamID]int{4: 80, 8: 40, 12: 10}))
+		})
+
+		It("doesn't starve the least urgent stream", func() {
+			counts := popAndRequeue(9, map[StreamID]StreamPriority{
+				4:  {Urgency: 0, Incremental: true},
+				12: {Urgency: 7, Incremental: true},
+			})
+			Expect(counts).To(Equal(map[StreamID]int{4: 8, 12: 1}))
+		})
+
+		It("uses the stream order of each urgency", func() {
+			s.Push(8, StreamPriority{Urgency: 3})
+			s.Push(4, StreamPriority{Urgency: 3})
+			s.Push(12, StreamPriority{Urgency: 3, Incremental: true})
+			Expect(popAll(s)).To(Equal([]StreamID{4, 8, 12}))
+		})
+
+		It("serves the only urgency that has data", func() {
+			s.Push(4, StreamPriority{Urgency: 6})
+			s.Push(8, StreamPriority{Urgency: 6})
+			Expect(popAll(s)).To(Equal([]StreamID{4, 8}))
+			s.Push(12, StreamPriority{Urgency: 0})
+			Expect(popAll(s)).To(Equal([]StreamID{12}))
+		})
+
+		It("updates the priority of a stream", func() {
+			s.Push(4, StreamPriority{Urgency: 3})
+			s.Push(4, StreamPriority{Urgency: 5})
+			Expect(s.Len()).To(Equal(1))
+			Expect(popAll(s)).To(Equal([]StreamID{4}))
+		})
+	})
+})
@@ -55,6 +55,18 @@ var _ = Describe("Stream", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	It("combines the statistics of the send and the receive side", func() {
+		mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+		Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+		mockFC.EXPECT().StreamSendWindowSize().Return(protocol.ByteCount(1000))
+		mockFC.EXPECT().ReceiveWindowSize().Return(protocol.ByteCount(2000))
+		stats := str.Stats()
+		Expect(stats.BytesReceived).To(Equal(protocol.ByteCount(6)))
+		Expect(stats.ReceiveWindow).To(Equal(protocol.ByteCount(2000)))
+		Expect(stats.SendWindow).To(Equal(protocol.ByteCount(1000)))
+		Expect(stats.BytesWritten).To(BeZero())
+	})
+
 	Context("deadlines", func() {
 		It("sets a write deadline, when SetDeadline is called", func() {
 			str.SetDeadline(time.Now().Add(-time.Second))
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package quic
+
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// Batched sends are not implemented on this platform.
+func newBatchWriter(c net.PacketConn) batchWriter {
+	return &packetWriter{conn: c}
+}
+
+// Batched receives are not implemented on this platform.
+func newBatchReader(net.PacketConn, protocol.ByteCount) batchReader {
+	return nil
+}
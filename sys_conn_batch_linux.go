@@ -0,0 +1,318 @@
+//go:build linux
+// +build linux
+
+package quic
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	"golang.org/x/net/ipv4"
+)
+
+// By setting one of these environment variables, batched sends and receives can be disabled.
+// This is not needed in production, but useful for benchmarking and for debugging.
+const (
+	disableSendmmsgEnv = "QUIC_GO_DISABLE_SENDMMSG"
+	disableGSOEnv      = "QUIC_GO_DISABLE_GSO"
+	disableRecvmmsgEnv = "QUIC_GO_DISABLE_RECVMMSG"
+	disableGROEnv      = "QUIC_GO_DISABLE_GRO"
+)
+
+const (
+	// the UDP_SEGMENT and UDP_GRO socket options, from linux/udp.h
+	udpSegment = 103
+	udpGRO     = 104
+	// The kernel doesn't accept more segments than this in a single GSO send (UDP_MAX_SEGMENTS).
+	maxGSOSegments = 64
+	// The total size of a GSO send must not exceed the maximum size of an IP packet.
+	maxGSOSize = 65000
+
+	// the number of packets read in a single recvmmsg call
+	recvBatchSize = 32
+	// When using GRO, the kernel coalesces packets into datagrams of up to 64 kB.
+	// We use fewer (but larger) buffers in that case.
+	numGROBuffers = 8
+	groBufferSize = 1 << 16
+)
+
+// The mmsgWriter writes batches of packets using sendmmsg.
+// If the kernel supports UDP Generic Segmentation Offload (GSO),
+// consecutive packets of the same size are sent as a single large datagram,
+// which is then split into separate packets by the kernel (or the NIC).
+type mmsgWriter struct {
+	conn       net.PacketConn
+	writeBatch func([]ipv4.Message, int) (int, error)
+	gso        bool
+	// once sendmmsg failed, we fall back to sending one packet per syscall
+	fallback *packetWriter
+
+	msgs []ipv4.Message
+}
+
+func newBatchWriter(c net.PacketConn) batchWriter {
+	udpConn, ok := c.(*net.UDPConn)
+	if !ok || os.Getenv(disableSendmmsgEnv) == "true" {
+		return &packetWriter{conn: c}
+	}
+	return &mmsgWriter{
+		conn:       c,
+		writeBatch: ipv4.NewPacketConn(udpConn).WriteBatch,
+		gso:        os.Getenv(disableGSOEnv) != "true" && supportsGSO(udpConn),
+	}
+}
+
+// supportsGSO checks if the kernel supports the UDP_SEGMENT socket option.
+func supportsGSO(c *net.UDPConn) bool {
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var serr error
+	if err := rawConn.Control(func(fd uintptr) {
+		_, serr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_UDP, udpSegment)
+	}); err != nil {
+		return false
+	}
+	return serr == nil
+}
+
+func (w *mmsgWriter) WriteBatch(packets [][]byte, addr net.Addr, ecn protocol.ECN) (int, error) {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if w.fallback != nil || !ok {
+		return (&packetWriter{conn: w.conn}).WriteBatch(packets, addr, ecn)
+	}
+	var oob []byte
+	if ecn != protocol.ECNNon {
+		oob = appendECN(make([]byte, 0, ecnOOBSize), ecn, udpAddr.IP.To4() != nil)
+	}
+	w.msgs = w.msgs[:0]
+	for i := 0; i < len(packets); {
+		n := 1
+		if w.gso {
+			n = numGSOSegments(packets[i:])
+		}
+		msg := ipv4.Message{Buffers: packets[i : i+n], Addr: udpAddr, OOB: oob}
+		if n > 1 {
+			msg.OOB = appendUDPSegment(append(make([]byte, 0, len(oob)+syscall.CmsgSpace(2)), oob...), len(packets[i]))
+		}
+		w.msgs = append(w.msgs, msg)
+		i += n
+	}
+
+	var sent int
+	msgs := w.msgs
+	for len(msgs) > 0 {
+		n, err := w.writeBatch(msgs, 0)
+		for _, msg := range msgs[:n] {
+			sent += len(msg.Buffers)
+		}
+		msgs = msgs[n:]
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, syscall.ENOSYS) {
+			w.fallback = &packetWriter{conn: w.conn}
+			m, err := w.fallback.WriteBatch(packets[sent:], addr, ecn)
+			return sent + m, err
+		}
+		// If the GSO send failed, retry without GSO.
+		// EIO means that the NIC doesn't support GSO (e.g. if checksum offloading is disabled).
+		// EINVAL is returned if the segment size exceeds the MTU, which is the case for Path MTU probe packets.
+		// When sending them without GSO, the send fails with EMSGSIZE, which is handled by the caller.
+		if len(msgs[0].Buffers) > 1 && (errors.Is(err, syscall.EIO) || errors.Is(err, syscall.EINVAL)) {
+			useGSO := w.gso && !errors.Is(err, syscall.EIO)
+			w.gso = false
+			m, err := w.WriteBatch(packets[sent:], addr, ecn)
+			w.gso = useGSO
+			return sent + m, err
+		}
+		return sent, err
+	}
+	return sent, nil
+}
+
+// numGSOSegments determines how many packets can be sent in a single GSO send.
+// All segments need to have the same size, only the last segment may be shorter.
+func numGSOSegments(packets [][]byte) int {
+	size := len(packets[0])
+	n := 1
+	for n < len(packets) && n < maxGSOSegments && (n+1)*size <= maxGSOSize {
+		l := len(packets[n])
+		if l > size {
+			break
+		}
+		n++
+		if l < size {
+			break
+		}
+	}
+	return n
+}
+
+// appendUDPSegment appends the control message setting the GSO segment size.
+func appendUDPSegment(oob []byte, size int) []byte {
+	start := len(oob)
+	oob = append(oob, make([]byte, syscall.CmsgSpace(2))...)
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&oob[start]))
+	h.Level = syscall.IPPROTO_UDP
+	h.Type = udpSegment
+	h.SetLen(syscall.CmsgLen(2))
+	nativeEndian().PutUint16(oob[start+syscall.CmsgLen(0):], uint16(size))
+	return oob
+}
+
+// The mmsgReader reads batches of packets using recvmmsg.
+// If the kernel supports UDP Generic Receive Offload (GRO),
+// consecutive packets from the same sender are coalesced into a single large datagram,
+// which is then split into separate packets.
+type mmsgReader struct {
+	conn      ecnCapableConn
+	readBatch func([]ipv4.Message, int) (int, error)
+	gro       bool
+	// packets larger than this are truncated
+	maxPacketSize protocol.ByteCount
+
+	msgs []ipv4.Message
+	// Without GRO, packets are read directly into packet buffers.
+	// Buffers are replaced once they are handed out.
+	buffers []*packetBuffer
+	packets []receivedPacket
+}
+
+func newBatchReader(c net.PacketConn, maxPacketSize protocol.ByteCount) batchReader {
+	udpConn, ok := c.(*net.UDPConn)
+	if !ok || os.Getenv(disableRecvmmsgEnv) == "true" {
+		return nil
+	}
+	r := &mmsgReader{
+		conn:          udpConn,
+		readBatch:     ipv4.NewPacketConn(udpConn).ReadBatch,
+		gro:           os.Getenv(disableGROEnv) != "true" && setGRO(udpConn),
+		maxPacketSize: maxPacketSize,
+	}
+	r.init()
+	return r
+}
+
+// setGRO enables UDP GRO on the connection.
+// It returns false if the kernel doesn't support it.
+func setGRO(c *net.UDPConn) bool {
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var serr error
+	if err := rawConn.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_UDP, udpGRO, 1)
+	}); err != nil {
+		return false
+	}
+	return serr == nil
+}
+
+func (r *mmsgReader) init() {
+	oobSize := ecnOOBSize + syscall.CmsgSpace(4)
+	if r.gro {
+		r.msgs = make([]ipv4.Message, numGROBuffers)
+		for i := range r.msgs {
+			r.msgs[i].Buffers = [][]byte{make([]byte, groBufferSize)}
+			r.msgs[i].OOB = make([]byte, oobSize)
+		}
+		return
+	}
+	r.msgs = make([]ipv4.Message, recvBatchSize)
+	r.buffers = make([]*packetBuffer, recvBatchSize)
+	for i := range r.msgs {
+		r.buffers[i] = getPacketBufferWithSize(r.maxPacketSize)
+		r.msgs[i].Buffers = [][]byte{r.buffers[i].Data[:r.maxPacketSize]}
+		r.msgs[i].OOB = make([]byte, oobSize)
+	}
+}
+
+func (r *mmsgReader) ReadBatch() ([]receivedPacket, error) {
+	n, err := r.readBatch(r.msgs, 0)
+	if err != nil {
+		if !errors.Is(err, syscall.ENOSYS) {
+			return nil, err
+		}
+		// recvmmsg is not supported. Read one packet per syscall.
+		r.readBatch = r.readOne
+		n, err = r.readBatch(r.msgs, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r.packets = r.packets[:0]
+	for i, msg := range r.msgs[:n] {
+		addr := msg.Addr
+		ecn := parseECN(msg.OOB[:msg.NN])
+		if !r.gro {
+			buffer := r.buffers[i]
+			r.packets = append(r.packets, receivedPacket{
+				remoteAddr: addr,
+				ecn:        ecn,
+				buffer:     buffer,
+				data:       buffer.Data[:msg.N],
+			})
+			r.buffers[i] = getPacketBufferWithSize(r.maxPacketSize)
+			r.msgs[i].Buffers[0] = r.buffers[i].Data[:r.maxPacketSize]
+			continue
+		}
+		data := msg.Buffers[0][:msg.N]
+		segmentSize := parseGROSegmentSize(msg.OOB[:msg.NN])
+		if segmentSize == 0 {
+			segmentSize = len(data)
+		}
+		for len(data) > 0 {
+			l := utils.Min(segmentSize, len(data))
+			// The packet size should not exceed maxPacketSize bytes
+			// If it does, we only read a truncated packet, which will then end up undecryptable
+			size := utils.MinByteCount(protocol.ByteCount(l), r.maxPacketSize)
+			buffer := getPacketBufferWithSize(size)
+			buffer.Data = buffer.Data[:copy(buffer.Data[:size], data[:l])]
+			r.packets = append(r.packets, receivedPacket{
+				remoteAddr: addr,
+				ecn:        ecn,
+				buffer:     buffer,
+				data:       buffer.Data,
+			})
+			data = data[l:]
+		}
+	}
+	return r.packets, nil
+}
+
+// readOne reads a single packet.
+// It is used if the kernel doesn't support recvmmsg.
+func (r *mmsgReader) readOne(msgs []ipv4.Message, _ int) (int, error) {
+	n, oobn, _, addr, err := r.conn.ReadMsgUDP(msgs[0].Buffers[0], msgs[0].OOB)
+	if err != nil {
+		return 0, err
+	}
+	msgs[0].N = n
+	msgs[0].NN = oobn
+	msgs[0].Addr = addr
+	return 1, nil
+}
+
+// parseGROSegmentSize extracts the segment size from the control messages received with a GRO datagram.
+// It returns 0 if the datagram was not coalesced.
+func parseGROSegmentSize(oob []byte) int {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0
+	}
+	for _, msg := range msgs {
+		if msg.Header.Level == syscall.IPPROTO_UDP && msg.Header.Type == udpGRO && len(msg.Data) >= 4 {
+			return int(nativeEndian().Uint32(msg.Data))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,297 @@
+// +build linux
+
+package quic
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	"golang.org/x/net/ipv4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Batch Writer", func() {
+	It("uses the packetWriter for other connections", func() {
+		Expect(newBatchWriter(newMockPacketConn())).To(BeAssignableToTypeOf(&packetWriter{}))
+	})
+
+	Context("determining the number of GSO segments", func() {
+		It("groups packets of the same size", func() {
+			Expect(numGSOSegments([][]byte{make([]byte, 1000), make([]byte, 1000), make([]byte, 1000)})).To(Equal(3))
+		})
+
+		It("allows the last segment to be shorter", func() {
+			Expect(numGSOSegments([][]byte{make([]byte, 1000), make([]byte, 500), make([]byte, 500)})).To(Equal(2))
+		})
+
+		It("doesn't group larger packets", func() {
+			Expect(numGSOSegments([][]byte{make([]byte, 1000), make([]byte, 1200)})).To(Equal(1))
+		})
+
+		It("limits the number of segments", func() {
+			packets := make([][]byte, 2*maxGSOSegments)
+			for i := range packets {
+				packets[i] = make([]byte, 100)
+			}
+			Expect(numGSOSegments(packets)).To(Equal(maxGSOSegments))
+		})
+
+		It("limits the total size", func() {
+			packets := make([][]byte, maxGSOSegments)
+			for i := range packets {
+				packets[i] = make([]byte, 1400)
+			}
+			Expect(numGSOSegments(packets)).To(Equal(maxGSOSize / 1400))
+		})
+	})
+
+	It("encodes the UDP_SEGMENT control message", func() {
+		oob := appendUDPSegment(nil, 1337)
+		msgs, err := syscall.ParseSocketControlMessage(oob)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msgs).To(HaveLen(1))
+		Expect(msgs[0].Header.Level).To(BeEquivalentTo(syscall.IPPROTO_UDP))
+		Expect(msgs[0].Header.Type).To(BeEquivalentTo(udpSegment))
+		Expect(nativeEndian().Uint16(msgs[0].Data)).To(BeEquivalentTo(1337))
+	})
+
+	It("falls back to sending one packet per syscall if sendmmsg is not supported", func() {
+		packetConn := newMockPacketConn()
+		w := &mmsgWriter{
+			conn:       packetConn,
+			writeBatch: func([]ipv4.Message, int) (int, error) { return 0, syscall.ENOSYS },
+		}
+		addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+		n, err := w.WriteBatch([][]byte{[]byte("foo"), []byte("bar")}, addr, protocol.ECNNon)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(2))
+		Expect(packetConn.dataWritten).To(HaveLen(2))
+		Expect(w.fallback).ToNot(BeNil())
+	})
+
+	It("disables GSO if the NIC doesn't support it", func() {
+		var numMsgs []int
+		w := &mmsgWriter{
+			conn: newMockPacketConn(),
+			gso:  true,
+			writeBatch: func(msgs []ipv4.Message, _ int) (int, error) {
+				numMsgs = append(numMsgs, len(msgs))
+				if len(msgs[0].Buffers) > 1 {
+					return 0, syscall.EIO
+				}
+				return len(msgs), nil
+			},
+		}
+		addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+		n, err := w.WriteBatch([][]byte{[]byte("foo"), []byte("bar")}, addr, protocol.ECNNon)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(2))
+		Expect(numMsgs).To(Equal([]int{1, 2}))
+		Expect(w.gso).To(BeFalse())
+	})
+
+	It("only disables GSO for the current batch if a segment is too large", func() {
+		w := &mmsgWriter{
+			conn: newMockPacketConn(),
+			gso:  true,
+			writeBatch: func(msgs []ipv4.Message, _ int) (int, error) {
+				if len(msgs[0].Buffers) > 1 {
+					return 0, syscall.EINVAL
+				}
+				return len(msgs), nil
+			},
+		}
+		addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+		n, err := w.WriteBatch([][]byte{[]byte("foo"), []byte("bar")}, addr, protocol.ECNNon)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(2))
+		Expect(w.gso).To(BeTrue())
+	})
+
+	for _, v := range []struct {
+		network string
+		ip      net.IP
+	}{
+		{"udp4", net.IPv4(127, 0, 0, 1)},
+		{"udp6", net.IPv6loopback},
+	} {
+		network := v.network
+		ip := v.ip
+
+		for _, g := range []bool{true, false} {
+			gso := g
+			name := "without GSO"
+			if gso {
+				name = "with GSO"
+			}
+
+			It("sends a batch of packets "+name+", on "+network, func() {
+				server, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+				if err != nil {
+					Skip("network not supported: " + err.Error())
+				}
+				defer server.Close()
+				client, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+				Expect(err).ToNot(HaveOccurred())
+				defer client.Close()
+
+				w := newBatchWriter(client)
+				Expect(w).To(BeAssignableToTypeOf(&mmsgWriter{}))
+				if gso && !w.(*mmsgWriter).gso {
+					Skip("GSO not supported")
+				}
+				w.(*mmsgWriter).gso = gso
+
+				packets := make([][]byte, 10)
+				for i := range packets {
+					packets[i] = bytes.Repeat([]byte{byte(i)}, 1000)
+				}
+				packets[9] = packets[9][:500]
+				n, err := w.WriteBatch(packets, server.LocalAddr(), protocol.ECT0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(len(packets)))
+
+				Expect(server.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+				for _, p := range packets {
+					b := make([]byte, 2000)
+					n, _, err := server.ReadFrom(b)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(b[:n]).To(Equal(p))
+				}
+			})
+		}
+	}
+})
+
+var _ = Describe("Batch Reader", func() {
+	It("doesn't read batches on other connections", func() {
+		Expect(newBatchReader(newMockPacketConn(), protocol.MaxReceivePacketSize)).To(BeNil())
+	})
+
+	It("parses the GRO segment size", func() {
+		oob := make([]byte, syscall.CmsgSpace(4))
+		h := (*syscall.Cmsghdr)(unsafe.Pointer(&oob[0]))
+		h.Level = syscall.IPPROTO_UDP
+		h.Type = udpGRO
+		h.SetLen(syscall.CmsgLen(4))
+		nativeEndian().PutUint32(oob[syscall.CmsgLen(0):], 1234)
+		Expect(parseGROSegmentSize(oob)).To(Equal(1234))
+		Expect(parseGROSegmentSize(appendECN(nil, protocol.ECT0, true))).To(BeZero())
+	})
+
+	// readAll reads until the expected number of packets was received
+	readAll := func(r batchReader, num int) []receivedPacket {
+		var packets []receivedPacket
+		for len(packets) < num {
+			ps, err := r.ReadBatch()
+			Expect(err).ToNot(HaveOccurred())
+			packets = append(packets, ps...)
+		}
+		return packets
+	}
+
+	It("falls back to reading one packet per syscall if recvmmsg is not supported", func() {
+		server, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+		client, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer client.Close()
+
+		r := &mmsgReader{
+			conn:          server,
+			readBatch:     func([]ipv4.Message, int) (int, error) { return 0, syscall.ENOSYS },
+			maxPacketSize: protocol.MaxReceivePacketSize,
+		}
+		r.init()
+		_, err = client.WriteTo([]byte("foo"), server.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		_, err = client.WriteTo([]byte("bar"), server.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(server.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+		packets, err := r.ReadBatch()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(packets).To(HaveLen(1))
+		Expect(packets[0].data).To(Equal([]byte("foo")))
+		Expect(packets[0].remoteAddr.String()).To(Equal(client.LocalAddr().String()))
+		packets, err = r.ReadBatch()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(packets).To(HaveLen(1))
+		Expect(packets[0].data).To(Equal([]byte("bar")))
+	})
+
+	for _, v := range []struct {
+		network string
+		ip      net.IP
+	}{
+		{"udp4", net.IPv4(127, 0, 0, 1)},
+		{"udp6", net.IPv6loopback},
+	} {
+		network := v.network
+		ip := v.ip
+
+		for _, g := range []bool{true, false} {
+			gro := g
+			name := "without GRO"
+			if gro {
+				name = "with GRO"
+			}
+
+			It("receives a batch of packets "+name+", on "+network, func() {
+				server, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+				if err != nil {
+					Skip("network not supported: " + err.Error())
+				}
+				defer server.Close()
+				Expect(setReceiveECN(server)).To(BeTrue())
+				client, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+				Expect(err).ToNot(HaveOccurred())
+				defer client.Close()
+
+				if !gro {
+					Expect(os.Setenv(disableGROEnv, "true")).To(Succeed())
+					defer os.Unsetenv(disableGROEnv)
+				}
+				r := newBatchReader(server, protocol.MaxReceivePacketSize)
+				Expect(r).To(BeAssignableToTypeOf(&mmsgReader{}))
+				if gro && !r.(*mmsgReader).gro {
+					Skip("GRO not supported")
+				}
+				Expect(r.(*mmsgReader).gro).To(Equal(gro))
+				// GRO only coalesces packets sent using GSO
+				w := newBatchWriter(client)
+				Expect(w).To(BeAssignableToTypeOf(&mmsgWriter{}))
+				if gro && !w.(*mmsgWriter).gso {
+					Skip("GSO not supported")
+				}
+
+				packets := make([][]byte, 10)
+				for i := range packets {
+					packets[i] = bytes.Repeat([]byte{byte(i)}, 1000)
+				}
+				packets[9] = packets[9][:500]
+				n, err := w.WriteBatch(packets, server.LocalAddr(), protocol.ECT0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(len(packets)))
+
+				Expect(server.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+				received := readAll(r, len(packets))
+				Expect(received).To(HaveLen(len(packets)))
+				for i, p := range received {
+					Expect(p.data).To(Equal(packets[i]))
+					Expect(p.ecn).To(Equal(protocol.ECT0))
+					Expect(p.remoteAddr.String()).To(Equal(client.LocalAddr().String()))
+					p.buffer.Release()
+				}
+			})
+		}
+	}
+})
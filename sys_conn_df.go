@@ -0,0 +1,15 @@
+// +build !linux
+
+package quic
+
+import "net"
+
+// setDF is not implemented on this platform,
+// which means that Path MTU Discovery is not used.
+func setDF(net.PacketConn) bool {
+	return false
+}
+
+func isMsgSizeErr(error) bool {
+	return false
+}
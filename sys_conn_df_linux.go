@@ -0,0 +1,38 @@
+// +build linux
+
+package quic
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// setDF sets the Don't Fragment (DF) bit on packets sent on the given connection.
+// This is required for Path MTU Discovery.
+// It returns false if the DF bit couldn't be set.
+func setDF(c net.PacketConn) bool {
+	sc, ok := c.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	rawConn, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var errDFIPv4, errDFIPv6 error
+	if err := rawConn.Control(func(fd uintptr) {
+		errDFIPv4 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER, syscall.IP_PMTUDISC_DO)
+		errDFIPv6 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_MTU_DISCOVER, syscall.IPV6_PMTUDISC_DO)
+	}); err != nil {
+		return false
+	}
+	// On a dual-stack socket, both options are set.
+	// On an IPv4-only socket, setting the IPv6 option fails, and vice versa.
+	return errDFIPv4 == nil || errDFIPv6 == nil
+}
+
+// isMsgSizeErr says if sending a packet failed because it exceeded the MTU of the local interface.
+func isMsgSizeErr(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE)
+}
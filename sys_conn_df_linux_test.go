@@ -0,0 +1,33 @@
+// +build linux
+
+package quic
+
+import (
+	"net"
+	"os"
+	"syscall"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Setting the DF bit", func() {
+	It("sets the DF bit on UDP sockets", func() {
+		for _, network := range []string{"udp4", "udp"} {
+			conn, err := net.ListenUDP(network, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(setDF(conn)).To(BeTrue())
+			conn.Close()
+		}
+	})
+
+	It("doesn't set the DF bit on other connections", func() {
+		Expect(setDF(newMockPacketConn())).To(BeFalse())
+	})
+
+	It("detects errors caused by packets exceeding the MTU", func() {
+		err := &net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.EMSGSIZE)}
+		Expect(isMsgSizeErr(err)).To(BeTrue())
+		Expect(isMsgSizeErr(&net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.ECONNREFUSED)})).To(BeFalse())
+	})
+})
@@ -0,0 +1,24 @@
+// +build !linux
+
+package quic
+
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// Reading and setting the ECN bits is not implemented on this platform.
+var ecnOOBSize = 0
+
+func setReceiveECN(net.PacketConn) bool {
+	return false
+}
+
+func parseECN([]byte) protocol.ECN {
+	return protocol.ECNNon
+}
+
+func appendECN(oob []byte, _ protocol.ECN, _ bool) []byte {
+	return oob
+}
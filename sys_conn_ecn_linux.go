@@ -0,0 +1,84 @@
+// +build linux
+
+package quic
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// ecnOOBSize is the size of the buffer used to receive the control messages carrying the ECN bits.
+var ecnOOBSize = syscall.CmsgSpace(4)
+
+// setReceiveECN enables reception of the ECN bits on the given connection.
+// It returns false if the ECN bits can't be read on this connection.
+func setReceiveECN(c net.PacketConn) bool {
+	if _, ok := c.(ecnCapableConn); !ok {
+		return false
+	}
+	sc, ok := c.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	rawConn, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var errIPv4, errIPv6 error
+	if err := rawConn.Control(func(fd uintptr) {
+		errIPv4 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_RECVTOS, 1)
+		errIPv6 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_RECVTCLASS, 1)
+	}); err != nil {
+		return false
+	}
+	// On a dual-stack socket, both options are set.
+	// On an IPv4-only socket, setting the IPv6 option fails, and vice versa.
+	return errIPv4 == nil || errIPv6 == nil
+}
+
+// parseECN extracts the ECN bits from the control messages received with a packet.
+func parseECN(oob []byte) protocol.ECN {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return protocol.ECNNon
+	}
+	for _, msg := range msgs {
+		switch {
+		// The TOS byte is sent as a single byte for IPv4 ...
+		case msg.Header.Level == syscall.IPPROTO_IP && msg.Header.Type == syscall.IP_TOS && len(msg.Data) >= 1:
+			return protocol.ECN(msg.Data[0] & ecnMask)
+		// ... and the traffic class as an int for IPv6.
+		case msg.Header.Level == syscall.IPPROTO_IPV6 && msg.Header.Type == syscall.IPV6_TCLASS && len(msg.Data) >= 4:
+			return protocol.ECN(nativeEndian().Uint32(msg.Data) & ecnMask)
+		}
+	}
+	return protocol.ECNNon
+}
+
+// appendECN appends a control message that sets the ECN bits on an outgoing packet.
+func appendECN(oob []byte, ecn protocol.ECN, isIPv4 bool) []byte {
+	level, typ := syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS
+	if isIPv4 {
+		level, typ = syscall.IPPROTO_IP, syscall.IP_TOS
+	}
+	start := len(oob)
+	oob = append(oob, make([]byte, syscall.CmsgSpace(4))...)
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&oob[start]))
+	h.Level = int32(level)
+	h.Type = int32(typ)
+	h.SetLen(syscall.CmsgLen(4))
+	nativeEndian().PutUint32(oob[start+syscall.CmsgLen(0):], uint32(ecn))
+	return oob
+}
+
+func nativeEndian() binary.ByteOrder {
+	var x uint16 = 1
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
@@ -0,0 +1,61 @@
+// +build linux
+
+package quic
+
+import (
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ECN", func() {
+	It("doesn't read the ECN bits on other connections", func() {
+		Expect(setReceiveECN(newMockPacketConn())).To(BeFalse())
+	})
+
+	It("encodes and parses the ECN control messages", func() {
+		for _, isIPv4 := range []bool{true, false} {
+			for _, ecn := range []protocol.ECN{protocol.ECT0, protocol.ECT1, protocol.ECNCE} {
+				Expect(parseECN(appendECN(nil, ecn, isIPv4))).To(Equal(ecn))
+			}
+		}
+	})
+
+	for _, v := range []struct {
+		network string
+		ip      net.IP
+	}{
+		{"udp4", net.IPv4(127, 0, 0, 1)},
+		{"udp6", net.IPv6loopback},
+	} {
+		network := v.network
+		ip := v.ip
+
+		It("sends and receives ECN marked packets, on "+network, func() {
+			server, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+			if err != nil {
+				Skip("network not supported: " + err.Error())
+			}
+			defer server.Close()
+			Expect(setReceiveECN(server)).To(BeTrue())
+			client, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+			Expect(err).ToNot(HaveOccurred())
+			defer client.Close()
+
+			for _, ecn := range []protocol.ECN{protocol.ECNNon, protocol.ECT0, protocol.ECNCE} {
+				Expect(writePacket(client, []byte("foobar"), server.LocalAddr(), ecn)).To(Succeed())
+				b := make([]byte, 100)
+				oob := make([]byte, ecnOOBSize)
+				Expect(server.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+				n, oobn, _, _, err := server.ReadMsgUDP(b, oob)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("foobar")))
+				Expect(parseECN(oob[:oobn])).To(Equal(ecn))
+			}
+		})
+	}
+})
@@ -35,6 +35,15 @@ func (s *singleOriginTokenStore) Len() int {
 	return s.len
 }
 
+// Tokens returns all tokens, starting with the oldest one.
+func (s *singleOriginTokenStore) Tokens() []*ClientToken {
+	tokens := make([]*ClientToken, 0, s.len)
+	for i := s.len; i > 0; i-- {
+		tokens = append(tokens, s.tokens[s.index(s.p-i)])
+	}
+	return tokens
+}
+
 func (s *singleOriginTokenStore) index(i int) int {
 	mod := len(s.tokens)
 	return (i + mod) % mod